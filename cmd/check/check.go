@@ -0,0 +1,67 @@
+package check
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"github.com/wim-web/tnnl/cmd"
+	"github.com/wim-web/tnnl/cmd/globalflag"
+	"github.com/wim-web/tnnl/cmd/inputfile"
+	"github.com/wim-web/tnnl/cmd/targetflag"
+	"github.com/wim-web/tnnl/internal/handler"
+	"github.com/wim-web/tnnl/internal/input"
+)
+
+var inputFileName = "input-file"
+
+type checkRunner func(context.Context, input.CheckInput) error
+
+func newCheckCommand(run checkRunner) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "check",
+		Short: "Check the ECS Exec prerequisites of a task",
+		Long: "Check why ECS Exec may fail for a running task, eligible or not.\n\n" +
+			"tnnl check inspects the session client, the cluster's execute command configuration,\n" +
+			"the task, its task definition and task role, and the ExecuteCommandAgent of each container.\n" +
+			"The task role's policies are tested with IAM policy simulation, which needs\n" +
+			"iam:SimulatePrincipalPolicy; without it the permission checks are warnings.\n" +
+			"Each failure or warning is followed by a fix. The command fails when any check fails.\n" +
+			"--task, --family, and --strategy select the task without prompting; --container limits the\n" +
+			"container checks to one container.",
+		Example: "  tnnl check\n" +
+			"  tnnl check --input-file check-input.json --task 0123456789abcdef0",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := cmd.Flags().GetString(inputFileName)
+			if err != nil {
+				return err
+			}
+			connection, err := globalflag.Connection(cmd)
+			if err != nil {
+				return err
+			}
+			ecs, err := targetflag.ECS(cmd)
+			if err != nil {
+				return err
+			}
+			resolved, err := input.ResolveCheck(path, input.CheckOverrides{Ecs: ecs, Connection: connection})
+			if err != nil {
+				return err
+			}
+			return run(cmd.Context(), resolved)
+		},
+	}
+	c.Flags().String(inputFileName, "", "input JSON generated by tnnl check make-input-file; explicit flags override input JSON values")
+	targetflag.Register(c.Flags())
+	// Every task is listed already; the flag only applies to the connecting commands.
+	_ = c.Flags().MarkHidden(targetflag.ShowIneligibleName)
+	return c
+}
+
+var CheckCmd = newCheckCommand(handler.CheckHandler)
+
+var MakeInputFileCmd = inputfile.New("check", "check-input.json", input.CheckInput{})
+
+func init() {
+	CheckCmd.AddCommand(MakeInputFileCmd)
+	cmd.RootCmd.AddCommand(CheckCmd)
+}
@@ -0,0 +1,57 @@
+package check
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/wim-web/tnnl/cmd/targetflag"
+	"github.com/wim-web/tnnl/internal/input"
+)
+
+func TestCheckCommandFlagsOverrideFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "check-input.json")
+	if err := os.WriteFile(path, []byte(`{"cluster":"production","service":"web","task":"task-file"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var got input.CheckInput
+	command := newCheckCommand(func(_ context.Context, in input.CheckInput) error {
+		got = in
+		return nil
+	})
+	command.SetArgs([]string{"--input-file", path, "--task", "task-flag", "--container", "app"})
+
+	if err := command.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("ExecuteContext() error = %v", err)
+	}
+	want := input.CheckInput{EcsParameter: input.EcsParameter{Cluster: "production", Service: "web", Task: "task-flag", Container: "app"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("runner input = %#v, want %#v", got, want)
+	}
+}
+
+func TestCheckCommandInvalidInputDoesNotInvokeRunner(t *testing.T) {
+	calls := 0
+	command := newCheckCommand(func(context.Context, input.CheckInput) error {
+		calls++
+		return nil
+	})
+	command.SetArgs([]string{"--strategy", "oldest"})
+
+	if err := command.ExecuteContext(context.Background()); err == nil || !strings.Contains(err.Error(), "strategy") {
+		t.Fatalf("ExecuteContext() error = %v, want strategy validation error", err)
+	}
+	if calls != 0 {
+		t.Fatalf("runner calls = %d, want 0", calls)
+	}
+}
+
+func TestCheckCommandHidesShowIneligible(t *testing.T) {
+	command := newCheckCommand(func(context.Context, input.CheckInput) error { return nil })
+	if flag := command.Flags().Lookup(targetflag.ShowIneligibleName); flag == nil || !flag.Hidden {
+		t.Fatalf("--%s = %#v, want a hidden flag", targetflag.ShowIneligibleName, flag)
+	}
+}
@@ -0,0 +1,77 @@
+package cp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/wim-web/tnnl/cmd"
+	"github.com/wim-web/tnnl/cmd/globalflag"
+	"github.com/wim-web/tnnl/cmd/inputfile"
+	"github.com/wim-web/tnnl/cmd/targetflag"
+	"github.com/wim-web/tnnl/internal/handler"
+	"github.com/wim-web/tnnl/internal/input"
+)
+
+var inputFileName = "input-file"
+
+type copyRunner func(context.Context, input.CopyInput) error
+
+func newCopyCommand(run copyRunner) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "cp SOURCE DESTINATION",
+		Short: "Copy files and directories to or from an ECS container",
+		Long: "Copy a file or directory between this machine and an eligible ECS container.\n\n" +
+			"Exactly one of SOURCE and DESTINATION is a container path, written with a leading colon.\n" +
+			"A copy into an existing directory keeps its name; otherwise it takes the destination's name.\n" +
+			"The copy travels as a tar archive over an exec session, so the container needs sh, tar, and base64.\n" +
+			"Progress is shown on stderr. Each copied file is then compared with its source by SHA-256;\n" +
+			"a mismatch fails the command, and a container without sha256sum only gets a warning.\n" +
+			"SOURCE and DESTINATION may instead come from the input JSON; arguments take precedence.\n" +
+			"--task, --container, --family, and --strategy select the target without prompting.",
+		Example: "  tnnl cp ./dump.sql :/tmp/\n" +
+			"  tnnl cp --task 0123456789abcdef0 --container app :/app/log ./log\n" +
+			"  tnnl cp --input-file cp-input.json",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 && len(args) != 2 {
+				return fmt.Errorf("accepts SOURCE and DESTINATION or no arguments, received %d", len(args))
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := cmd.Flags().GetString(inputFileName)
+			if err != nil {
+				return err
+			}
+			connection, err := globalflag.Connection(cmd)
+			if err != nil {
+				return err
+			}
+			ecs, err := targetflag.ECS(cmd)
+			if err != nil {
+				return err
+			}
+			overrides := input.CopyOverrides{Ecs: ecs, Connection: connection}
+			if len(args) == 2 {
+				overrides.Source, overrides.Destination = &args[0], &args[1]
+			}
+			resolved, err := input.ResolveCopy(path, overrides)
+			if err != nil {
+				return err
+			}
+			return run(cmd.Context(), resolved)
+		},
+	}
+	c.Flags().String(inputFileName, "", "input JSON generated by tnnl cp make-input-file; arguments and explicit flags override input JSON values")
+	targetflag.Register(c.Flags())
+	return c
+}
+
+var CopyCmd = newCopyCommand(handler.CopyHandler)
+
+var MakeInputFileCmd = inputfile.New("cp", "cp-input.json", input.CopyInput{})
+
+func init() {
+	CopyCmd.AddCommand(MakeInputFileCmd)
+	cmd.RootCmd.AddCommand(CopyCmd)
+}
@@ -0,0 +1,90 @@
+package cp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/wim-web/tnnl/internal/input"
+)
+
+func TestCopyCommandArgumentsOverrideFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cp-input.json")
+	if err := os.WriteFile(path, []byte(`{"cluster":"production","source":"file.txt","destination":":/tmp/file.txt"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		args []string
+		want input.CopyInput
+	}{
+		{
+			name: "input file",
+			args: []string{"--input-file", path, "--task", "task-flag"},
+			want: input.CopyInput{
+				EcsParameter: input.EcsParameter{Cluster: "production", Task: "task-flag"},
+				Source:       "file.txt",
+				Destination:  ":/tmp/file.txt",
+			},
+		},
+		{
+			name: "arguments",
+			args: []string{"--input-file", path, ":/app/log", "./log"},
+			want: input.CopyInput{
+				EcsParameter: input.EcsParameter{Cluster: "production"},
+				Source:       ":/app/log",
+				Destination:  "./log",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got input.CopyInput
+			command := newCopyCommand(func(_ context.Context, in input.CopyInput) error {
+				got = in
+				return nil
+			})
+			command.SetArgs(tt.args)
+
+			if err := command.ExecuteContext(context.Background()); err != nil {
+				t.Fatalf("ExecuteContext() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("runner input = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCopyCommandInvalidInputDoesNotInvokeRunner(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "one argument", args: []string{"file.txt"}, wantErr: "received 1"},
+		{name: "no container path", args: []string{"file.txt", "copy.txt"}, wantErr: "container path"},
+		{name: "missing paths", args: nil, wantErr: "source and destination are required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			command := newCopyCommand(func(context.Context, input.CopyInput) error {
+				calls++
+				return nil
+			})
+			command.SetArgs(tt.args)
+			command.SilenceUsage, command.SilenceErrors = true, true
+
+			if err := command.ExecuteContext(context.Background()); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ExecuteContext() error = %v, want %q", err, tt.wantErr)
+			}
+			if calls != 0 {
+				t.Fatalf("runner calls = %d, want 0", calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package dryrunflag
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var DryRunName = "dry-run"
+
+// Register adds the dry-run flag of the session commands to flags.
+func Register(flags *pflag.FlagSet) {
+	flags.Bool(DryRunName, false, "run preflight and target resolution, then print the resolved target, session parameters, and session client invocation (token redacted) without starting a session")
+}
+
+// DryRun returns the dry-run flag when it is explicitly set for c.
+func DryRun(c *cobra.Command) (*bool, error) {
+	if !c.Flags().Changed(DryRunName) {
+		return nil, nil
+	}
+	value, err := c.Flags().GetBool(DryRunName)
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
@@ -0,0 +1,39 @@
+package dryrunflag
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestDryRunReturnsOnlyAnExplicitFlag(t *testing.T) {
+	yes, no := true, false
+	for _, tt := range []struct {
+		args []string
+		want *bool
+	}{
+		{args: nil, want: nil},
+		{args: []string{"--dry-run"}, want: &yes},
+		{args: []string{"--dry-run=false"}, want: &no},
+	} {
+		var got *bool
+		c := &cobra.Command{
+			Use: "child",
+			RunE: func(c *cobra.Command, _ []string) error {
+				var err error
+				got, err = DryRun(c)
+				return err
+			},
+		}
+		Register(c.Flags())
+		c.SetArgs(tt.args)
+
+		if err := c.ExecuteContext(context.Background()); err != nil {
+			t.Fatalf("ExecuteContext(%q) error = %v", tt.args, err)
+		}
+		if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+			t.Fatalf("DryRun(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
@@ -5,33 +5,76 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/wim-web/tnnl/cmd"
+	"github.com/wim-web/tnnl/cmd/dryrunflag"
+	"github.com/wim-web/tnnl/cmd/globalflag"
+	"github.com/wim-web/tnnl/cmd/targetflag"
 	"github.com/wim-web/tnnl/internal/handler"
 	"github.com/wim-web/tnnl/internal/input"
 )
 
 var cmdName = "command"
 var waitName = "wait"
+var batchName = "batch"
+var timeoutName = "timeout"
+var allName = "all"
+var parallelName = "parallel"
+var recordName = "record"
 var inputFileName = "input-file"
 
 type execRunner func(context.Context, input.ExecInput) error
 
-func newExecCommand(run execRunner) *cobra.Command {
+// newExecCommand builds the command. A non-nil saved source replaces --input-file
+// when tnnl run replays a saved target.
+func newExecCommand(run execRunner, saved input.Source) *cobra.Command {
 	c := &cobra.Command{
 		Use:   "exec",
 		Short: "Run an interactive command in an ECS container",
 		Long: "Run an interactive command in an eligible ECS container.\n\n" +
 			"Input values use this precedence: explicit flag > input JSON > default.\n" +
 			"--wait 0 performs one logical eligibility lookup. A positive --wait polls readiness after cluster selection\n" +
-			"until an eligible task is ready or the timeout expires.",
+			"until an eligible task is ready or the timeout expires.\n" +
+			"--service narrows the tasks to one service; without it, tnnl offers the cluster's services\n" +
+			"with running/desired counts before the task chooser.\n" +
+			"--show-ineligible lists tasks and containers that cannot be used, greyed out with the reason and\n" +
+			"whether the fix is to wait, redeploy, change the task definition, or grant the IAM role.\n" +
+			"--task, --container, --family, and --strategy select the target without prompting and fail\n" +
+			"when the selectors match no eligible target or more than one.\n" +
+			"--batch runs the command without a terminal for scripts and CI: the remote stdout and stderr go to\n" +
+			"tnnl's stdout and stderr, and tnnl exits with the command's exit status. --timeout limits a batch run\n" +
+			"to that many seconds and exits with status 124 when it expires.\n" +
+			"--all runs the command in batch mode on every eligible task of the service, or of the tasks matching\n" +
+			"--task/--family/--container, at most --parallel at a time. Each output line starts with the task ID,\n" +
+			"a summary of each task's exit status follows on stderr, and tnnl exits 1 when any task failed.\n" +
+			"--record writes the interactive session, with what was typed and shown, to a new asciinema cast\n" +
+			"file that tnnl replay plays back. Recording uses the native session client.\n" +
+			"--dry-run stops after preflight and target resolution and prints the target, the ExecuteCommand\n" +
+			"request, and the session client invocation with the token redacted.",
 		Example: "  tnnl exec --command sh --wait 0\n" +
-			"  tnnl exec --input-file exec-input.json",
+			"  tnnl exec --input-file exec-input.json\n" +
+			"  tnnl exec --family web --container app --strategy newest --command 'rails console'\n" +
+			"  tnnl exec --batch --timeout 600 --command 'rake db:migrate'\n" +
+			"  tnnl exec --service api --container app --all --parallel 8 --command 'kill -QUIT 1'\n" +
+			"  tnnl exec --service web --record incident.cast\n" +
+			"  tnnl exec --service web --container app --dry-run",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			path, err := cmd.Flags().GetString(inputFileName)
+			source := saved
+			if source == nil {
+				path, err := cmd.Flags().GetString(inputFileName)
+				if err != nil {
+					return err
+				}
+				source = input.FileSource(path)
+			}
+
+			connection, err := globalflag.Connection(cmd)
 			if err != nil {
 				return err
 			}
-
-			overrides := input.ExecOverrides{}
+			ecs, err := targetflag.ECS(cmd)
+			if err != nil {
+				return err
+			}
+			overrides := input.ExecOverrides{Ecs: ecs, Connection: connection}
 			if cmd.Flags().Changed(cmdName) {
 				value, err := cmd.Flags().GetString(cmdName)
 				if err != nil {
@@ -46,8 +89,47 @@ func newExecCommand(run execRunner) *cobra.Command {
 				}
 				overrides.Wait = &value
 			}
+			if cmd.Flags().Changed(batchName) {
+				value, err := cmd.Flags().GetBool(batchName)
+				if err != nil {
+					return err
+				}
+				overrides.Batch = &value
+			}
+			if cmd.Flags().Changed(timeoutName) {
+				value, err := cmd.Flags().GetInt(timeoutName)
+				if err != nil {
+					return err
+				}
+				overrides.Timeout = &value
+			}
+			if cmd.Flags().Changed(allName) {
+				value, err := cmd.Flags().GetBool(allName)
+				if err != nil {
+					return err
+				}
+				overrides.All = &value
+			}
+			if cmd.Flags().Changed(parallelName) {
+				value, err := cmd.Flags().GetInt(parallelName)
+				if err != nil {
+					return err
+				}
+				overrides.Parallel = &value
+			}
+			if cmd.Flags().Changed(recordName) {
+				value, err := cmd.Flags().GetString(recordName)
+				if err != nil {
+					return err
+				}
+				overrides.Record = &value
+			}
 
-			resolved, err := input.ResolveExec(path, overrides)
+			if overrides.DryRun, err = dryrunflag.DryRun(cmd); err != nil {
+				return err
+			}
+
+			resolved, err := input.ResolveExecFrom(source, overrides)
 			if err != nil {
 				return err
 			}
@@ -56,12 +138,24 @@ func newExecCommand(run execRunner) *cobra.Command {
 	}
 	c.Flags().String(cmdName, "sh", "command to run; precedence: explicit flag > input JSON > default")
 	c.Flags().Int(waitName, 0, "seconds to wait; --wait 0 performs one logical eligibility lookup, positive values poll readiness after cluster selection; precedence: explicit flag > input JSON > default")
-	c.Flags().String(inputFileName, "", "input JSON generated by tnnl exec make-input-file; explicit flags override input JSON values")
+	c.Flags().Bool(batchName, false, "run the command without a terminal, keep stdout and stderr apart, and exit with its status; precedence: explicit flag > input JSON > default")
+	c.Flags().Int(timeoutName, 0, "seconds a --batch run may take before tnnl stops it and exits 124; 0 means no limit; precedence: explicit flag > input JSON > default")
+	c.Flags().Bool(allName, false, "run the command in batch mode on every matching eligible task and summarise their exit statuses; precedence: explicit flag > input JSON > default")
+	c.Flags().Int(parallelName, 0, "sessions --all runs at once; 0 uses 4; precedence: explicit flag > input JSON > default")
+	c.Flags().String(recordName, "", "record the interactive session to this new asciinema cast file; precedence: explicit flag > input JSON > default")
+	dryrunflag.Register(c.Flags())
+	if saved == nil {
+		c.Flags().String(inputFileName, "", "input JSON generated by tnnl exec make-input-file; explicit flags override input JSON values")
+	}
+	targetflag.Register(c.Flags())
 	return c
 }
 
-var ExecCmd = newExecCommand(handler.ExecHandler)
+var ExecCmd = newExecCommand(handler.ExecHandler, nil)
 
 func init() {
 	cmd.RootCmd.AddCommand(ExecCmd)
+	cmd.RegisterSaved("exec", func(source input.Source) *cobra.Command {
+		return newExecCommand(handler.ExecHandler, source)
+	})
 }
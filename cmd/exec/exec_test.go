@@ -19,7 +19,7 @@ func TestExecCommandExplicitFlagsOverrideFile(t *testing.T) {
 	command := newExecCommand(func(_ context.Context, in input.ExecInput) error {
 		got = in
 		return nil
-	})
+	}, nil)
 	command.SetArgs([]string{"--input-file", path, "--command", "zsh", "--wait", "0"})
 
 	if err := command.ExecuteContext(context.Background()); err != nil {
@@ -35,13 +35,93 @@ func TestExecCommandExplicitFlagsOverrideFile(t *testing.T) {
 	}
 }
 
+func TestExecCommandBatchFlagsOverrideFile(t *testing.T) {
+	path := writeExecFixture(t, `{"cluster":"cluster","command":"rake db:migrate","batch":false,"timeout":0}`)
+	var got input.ExecInput
+	command := newExecCommand(func(_ context.Context, in input.ExecInput) error {
+		got = in
+		return nil
+	}, nil)
+	command.SetArgs([]string{"--input-file", path, "--batch", "--timeout", "600"})
+
+	if err := command.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("ExecuteContext() error = %v", err)
+	}
+	if !got.Batch || got.Timeout != 600 || got.Cmd != "rake db:migrate" {
+		t.Fatalf("runner input = %#v, want batch with 600s timeout", got)
+	}
+}
+
+func TestExecCommandAllFlags(t *testing.T) {
+	var got input.ExecInput
+	command := newExecCommand(func(_ context.Context, in input.ExecInput) error {
+		got = in
+		return nil
+	}, nil)
+	command.SetArgs([]string{"--all", "--parallel", "8", "--timeout", "30", "--command", "jstack 1"})
+
+	if err := command.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("ExecuteContext() error = %v", err)
+	}
+	if !got.All || got.Parallel != 8 || got.Timeout != 30 || got.Batch {
+		t.Fatalf("runner input = %#v, want all with parallel 8 and timeout 30", got)
+	}
+}
+
+func TestExecCommandRecordFlag(t *testing.T) {
+	var got input.ExecInput
+	command := newExecCommand(func(_ context.Context, in input.ExecInput) error {
+		got = in
+		return nil
+	}, nil)
+	command.SetArgs([]string{"--record", "incident.cast", "--command", "bash"})
+
+	if err := command.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("ExecuteContext() error = %v", err)
+	}
+	if got.Record != "incident.cast" || got.Cmd != "bash" {
+		t.Fatalf("runner input = %#v, want a recording of bash to incident.cast", got)
+	}
+}
+
+func TestExecCommandTimeoutWithoutBatchDoesNotInvokeRunner(t *testing.T) {
+	command := newExecCommand(func(_ context.Context, _ input.ExecInput) error {
+		t.Fatal("runner called, want validation error")
+		return nil
+	}, nil)
+	command.SetArgs([]string{"--timeout", "30"})
+
+	err := command.ExecuteContext(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "timeout requires batch mode") {
+		t.Fatalf("ExecuteContext() error = %v, want timeout validation error", err)
+	}
+}
+
+func TestExecCommandSelectorFlagsOverrideFile(t *testing.T) {
+	path := writeExecFixture(t, `{"cluster":"cluster","task":"task-file","family":"web","strategy":"first"}`)
+	var got input.ExecInput
+	command := newExecCommand(func(_ context.Context, in input.ExecInput) error {
+		got = in
+		return nil
+	}, nil)
+	command.SetArgs([]string{"--input-file", path, "--service", " api ", "--task", "task-flag", "--container", "app", "--strategy", "Newest"})
+
+	if err := command.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("ExecuteContext() error = %v", err)
+	}
+	want := input.EcsParameter{Cluster: "cluster", Service: "api", Task: "task-flag", Container: "app", Family: "web", Strategy: "newest"}
+	if got.EcsParameter != want {
+		t.Fatalf("runner EcsParameter = %#v, want %#v", got.EcsParameter, want)
+	}
+}
+
 func TestExecCommandInvalidInputDoesNotInvokeRunner(t *testing.T) {
 	path := writeExecFixture(t, `{"command":" ","wait":-1}`)
 	calls := 0
 	command := newExecCommand(func(_ context.Context, _ input.ExecInput) error {
 		calls++
 		return nil
-	})
+	}, nil)
 	command.SetArgs([]string{"--input-file", path})
 
 	err := command.ExecuteContext(context.Background())
@@ -66,7 +146,7 @@ func TestExecCommandPassesExecuteContextToRunner(t *testing.T) {
 	command := newExecCommand(func(ctx context.Context, _ input.ExecInput) error {
 		got = ctx.Value(contextKey{})
 		return nil
-	})
+	}, nil)
 	command.SetArgs([]string{})
 
 	if err := command.ExecuteContext(ctx); err != nil {
@@ -78,7 +158,7 @@ func TestExecCommandPassesExecuteContextToRunner(t *testing.T) {
 }
 
 func TestExecCommandInputFileHelpNamesParent(t *testing.T) {
-	command := newExecCommand(func(context.Context, input.ExecInput) error { return nil })
+	command := newExecCommand(func(context.Context, input.ExecInput) error { return nil }, nil)
 	if command.Short == "" {
 		t.Fatal("Short is empty")
 	}
@@ -92,7 +172,7 @@ func TestExecCommandInputFileHelpNamesParent(t *testing.T) {
 }
 
 func TestExecHelpDocumentsWaitAndInputPrecedence(t *testing.T) {
-	command := newExecCommand(func(context.Context, input.ExecInput) error { return nil })
+	command := newExecCommand(func(context.Context, input.ExecInput) error { return nil }, nil)
 
 	assertHelpContains(t, command,
 		"--wait 0 performs one logical eligibility lookup",
@@ -126,3 +206,24 @@ func writeExecFixture(t *testing.T, content string) string {
 	}
 	return path
 }
+
+func TestExecCommandSavedSourceReplacesInputFile(t *testing.T) {
+	var got input.ExecInput
+	saved := input.SavedSource("web", []byte(`{"cluster":"cluster","command":"bash","wait":10}`), input.ConnectionParameter{})
+	command := newExecCommand(func(_ context.Context, in input.ExecInput) error {
+		got = in
+		return nil
+	}, saved)
+	if command.Flags().Lookup(inputFileName) != nil {
+		t.Fatal("saved command registered --input-file")
+	}
+	command.SetArgs([]string{"--wait", "0"})
+
+	if err := command.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("ExecuteContext() error = %v", err)
+	}
+	want := input.ExecInput{EcsParameter: input.EcsParameter{Cluster: "cluster"}, Cmd: "bash", Wait: 0}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("runner input = %#v, want %#v", got, want)
+	}
+}
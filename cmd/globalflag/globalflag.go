@@ -0,0 +1,95 @@
+package globalflag
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/wim-web/tnnl/internal/event"
+	"github.com/wim-web/tnnl/internal/input"
+	"github.com/wim-web/tnnl/internal/picker"
+)
+
+var SessionClientName = "session-client"
+var ProfileName = "profile"
+var RegionName = "region"
+var EndpointURLName = "endpoint-url"
+var RoleARNName = "role-arn"
+var ExternalIDName = "external-id"
+var RoleSessionNameName = "role-session-name"
+var MFASerialName = "mfa-serial"
+var DiscoverRegionsName = "discover-regions"
+var DiscoverProfilesName = "discover-profiles"
+var OutputName = "output"
+var PickerName = "picker"
+
+// Register adds the flags shared by every session command to flags.
+func Register(flags *pflag.FlagSet) {
+	flags.String(SessionClientName, "", "session client: plugin runs session-manager-plugin (default), native uses the built-in data channel and owns port forward listeners; precedence: explicit flag > input JSON > default")
+	flags.String(ProfileName, "", "AWS shared config profile for the AWS APIs and session-manager-plugin; precedence: explicit flag > input JSON > AWS_PROFILE")
+	flags.String(RegionName, "", "AWS Region; precedence: explicit flag > input JSON > AWS SDK default configuration")
+	flags.String(EndpointURLName, "", "endpoint URL for the ECS and SSM APIs and session-manager-plugin; precedence: explicit flag > input JSON > AWS_ENDPOINT_URL")
+	flags.String(RoleARNName, "", "IAM role to assume with the loaded credentials; session-manager-plugin receives the temporary credentials; precedence: explicit flag > input JSON")
+	flags.String(ExternalIDName, "", "external ID for --role-arn; precedence: explicit flag > input JSON")
+	flags.String(RoleSessionNameName, "", "session name for --role-arn (default tnnl-<unix time>); precedence: explicit flag > input JSON")
+	flags.String(MFASerialName, "", "MFA device ARN or serial for --role-arn; tnnl prompts for the code on the terminal; precedence: explicit flag > input JSON")
+	flags.String(DiscoverRegionsName, "", "comma-separated Regions whose clusters the picker lists together, labelled with account and Region; precedence: explicit flag > input JSON")
+	flags.String(DiscoverProfilesName, "", "comma-separated AWS profiles whose clusters the picker lists together; combined with --discover-regions; precedence: explicit flag > input JSON")
+	flags.String(OutputName, event.FormatText, "output format: text, or json for one JSON event per line on stdout (resolved target, local port, session ID, lifecycle, and coded errors)")
+	flags.String(PickerName, "", "how to choose: auto (tui on a terminal, none otherwise), tui, prompt for a numbered prompt, none to fail with the options, or a finder command such as fzf or peco; precedence: explicit flag > $"+picker.EnvName+" > auto")
+}
+
+// Output returns the --output format of c. The persistent flag is looked up
+// directly since make-input-file has a local --output of its own.
+func Output(c *cobra.Command) (string, error) {
+	var flag *pflag.Flag
+	for parent := c; parent != nil && flag == nil; parent = parent.Parent() {
+		flag = parent.PersistentFlags().Lookup(OutputName)
+	}
+	if flag == nil {
+		return event.FormatText, nil
+	}
+	switch format := flag.Value.String(); format {
+	case event.FormatText, event.FormatJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("output must be %q or %q: %q", event.FormatText, event.FormatJSON, format)
+	}
+}
+
+// Picker returns the --picker backend explicitly set for c, or else the one
+// $TNNL_PICKER names.
+func Picker(c *cobra.Command) (string, error) {
+	if !c.Flags().Changed(PickerName) {
+		return os.Getenv(picker.EnvName), nil
+	}
+	return c.Flags().GetString(PickerName)
+}
+
+// Connection returns the global connection flags explicitly set for c.
+func Connection(c *cobra.Command) (input.ConnectionOverrides, error) {
+	overrides := input.ConnectionOverrides{}
+	for name, target := range map[string]**string{
+		SessionClientName:    &overrides.SessionClient,
+		ProfileName:          &overrides.Profile,
+		RegionName:           &overrides.Region,
+		EndpointURLName:      &overrides.EndpointURL,
+		RoleARNName:          &overrides.RoleARN,
+		ExternalIDName:       &overrides.ExternalID,
+		RoleSessionNameName:  &overrides.RoleSessionName,
+		MFASerialName:        &overrides.MFASerial,
+		DiscoverRegionsName:  &overrides.DiscoverRegions,
+		DiscoverProfilesName: &overrides.DiscoverProfiles,
+	} {
+		if !c.Flags().Changed(name) {
+			continue
+		}
+		value, err := c.Flags().GetString(name)
+		if err != nil {
+			return input.ConnectionOverrides{}, err
+		}
+		*target = &value
+	}
+	return overrides, nil
+}
@@ -0,0 +1,210 @@
+package globalflag
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/wim-web/tnnl/internal/input"
+)
+
+func TestConnectionReturnsOnlyExplicitInheritedFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want *string
+	}{
+		{name: "omitted", args: []string{"child"}},
+		{name: "explicit", args: []string{"--session-client", "native", "child"}, want: stringPointer("native")},
+		{name: "explicit empty", args: []string{"child", "--session-client", ""}, want: stringPointer("")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got input.ConnectionOverrides
+			root := &cobra.Command{Use: "root"}
+			Register(root.PersistentFlags())
+			child := &cobra.Command{
+				Use: "child",
+				RunE: func(c *cobra.Command, _ []string) error {
+					var err error
+					got, err = Connection(c)
+					return err
+				},
+			}
+			root.AddCommand(child)
+			root.SetArgs(tt.args)
+
+			if err := root.ExecuteContext(context.Background()); err != nil {
+				t.Fatalf("ExecuteContext() error = %v", err)
+			}
+			if (got.SessionClient == nil) != (tt.want == nil) {
+				t.Fatalf("SessionClient = %v, want %v", got.SessionClient, tt.want)
+			}
+			if tt.want != nil && *got.SessionClient != *tt.want {
+				t.Fatalf("SessionClient = %q, want %q", *got.SessionClient, *tt.want)
+			}
+		})
+	}
+}
+
+func TestConnectionReturnsAWSFlags(t *testing.T) {
+	var got input.ConnectionOverrides
+	root := &cobra.Command{Use: "root"}
+	Register(root.PersistentFlags())
+	child := &cobra.Command{
+		Use: "child",
+		RunE: func(c *cobra.Command, _ []string) error {
+			var err error
+			got, err = Connection(c)
+			return err
+		},
+	}
+	root.AddCommand(child)
+	root.SetArgs([]string{
+		"--profile", "prod", "child", "--region", "us-east-1", "--endpoint-url", "http://localhost:4566",
+		"--role-arn", "arn:aws:iam::123456789012:role/ops", "--external-id", "ext",
+		"--role-session-name", "alice", "--mfa-serial", "arn:aws:iam::111111111111:mfa/alice",
+		"--discover-regions", "ap-northeast-1,us-east-1", "--discover-profiles", "prod,stg",
+	})
+
+	if err := root.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("ExecuteContext() error = %v", err)
+	}
+	if got.SessionClient != nil {
+		t.Fatalf("SessionClient = %q, want unset", *got.SessionClient)
+	}
+	for name, tt := range map[string]struct {
+		got  *string
+		want string
+	}{
+		"profile":           {got.Profile, "prod"},
+		"region":            {got.Region, "us-east-1"},
+		"endpoint-url":      {got.EndpointURL, "http://localhost:4566"},
+		"role-arn":          {got.RoleARN, "arn:aws:iam::123456789012:role/ops"},
+		"external-id":       {got.ExternalID, "ext"},
+		"role-session-name": {got.RoleSessionName, "alice"},
+		"mfa-serial":        {got.MFASerial, "arn:aws:iam::111111111111:mfa/alice"},
+		"discover-regions":  {got.DiscoverRegions, "ap-northeast-1,us-east-1"},
+		"discover-profiles": {got.DiscoverProfiles, "prod,stg"},
+	} {
+		if tt.got == nil || *tt.got != tt.want {
+			t.Errorf("--%s override = %v, want %q", name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestConnectionWithoutRegisteredFlagsReturnsNoOverrides(t *testing.T) {
+	got, err := Connection(&cobra.Command{Use: "standalone"})
+	if err != nil {
+		t.Fatalf("Connection() error = %v", err)
+	}
+	if got != (input.ConnectionOverrides{}) {
+		t.Fatalf("Connection() = %#v, want no overrides", got)
+	}
+}
+
+func stringPointer(value string) *string {
+	return &value
+}
+
+func TestOutputReadsTheInheritedFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr string
+	}{
+		{name: "default", args: []string{"child"}, want: "text"},
+		{name: "json", args: []string{"--output", "json", "child"}, want: "json"},
+		{name: "invalid", args: []string{"child", "--output", "yaml"}, wantErr: `output must be "text" or "json": "yaml"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			root := &cobra.Command{Use: "root"}
+			Register(root.PersistentFlags())
+			child := &cobra.Command{
+				Use: "child",
+				RunE: func(c *cobra.Command, _ []string) error {
+					var err error
+					got, err = Output(c)
+					return err
+				},
+			}
+			root.AddCommand(child)
+			root.SetArgs(tt.args)
+
+			err := root.ExecuteContext(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("ExecuteContext() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("Output() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutputIgnoresALocalOutputFlag(t *testing.T) {
+	var got string
+	root := &cobra.Command{Use: "root"}
+	Register(root.PersistentFlags())
+	child := &cobra.Command{
+		Use: "make-input-file",
+		RunE: func(c *cobra.Command, _ []string) error {
+			var err error
+			got, err = Output(c)
+			return err
+		},
+	}
+	child.Flags().StringP("output", "o", "input.json", "output path")
+	root.AddCommand(child)
+	root.SetArgs([]string{"make-input-file", "--output", "custom.json"})
+
+	if err := root.ExecuteContext(context.Background()); err != nil || got != "text" {
+		t.Fatalf("Output() = %q, %v, want text", got, err)
+	}
+}
+
+func TestPickerPrefersTheFlagToTheEnvironment(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  string
+		want string
+	}{
+		{name: "omitted", args: []string{"child"}},
+		{name: "environment", args: []string{"child"}, env: "fzf", want: "fzf"},
+		{name: "explicit", args: []string{"--picker", "prompt", "child"}, env: "fzf", want: "prompt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TNNL_PICKER", tt.env)
+			var got string
+			root := &cobra.Command{Use: "root"}
+			Register(root.PersistentFlags())
+			child := &cobra.Command{
+				Use: "child",
+				RunE: func(c *cobra.Command, _ []string) error {
+					var err error
+					got, err = Picker(c)
+					return err
+				},
+			}
+			root.AddCommand(child)
+			root.SetArgs(tt.args)
+
+			if err := root.ExecuteContext(context.Background()); err != nil {
+				t.Fatalf("ExecuteContext() error = %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("Picker() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wim-web/tnnl/cmd"
+	"github.com/wim-web/tnnl/internal/audit"
+	"github.com/wim-web/tnnl/internal/event"
+	"github.com/wim-web/tnnl/internal/target"
+)
+
+var commandName = "command"
+var clusterName = "cluster"
+var taskName = "task"
+var containerName = "container"
+var callerName = "caller"
+var sinceName = "since"
+var failedName = "failed"
+var limitName = "limit"
+var jsonName = "json"
+
+type dependencies struct {
+	path func() (string, error)
+	now  func() time.Time
+}
+
+func productionDependencies() dependencies {
+	return dependencies{path: audit.Path, now: time.Now}
+}
+
+func newHistoryCommand(deps dependencies) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "history",
+		Short: "Show the audit log of sessions tnnl opened",
+		Long: "Show the sessions tnnl opened, read from the append-only audit log at\n" +
+			"$XDG_STATE_HOME/tnnl/audit.jsonl (~/.local/state/tnnl/audit.jsonl when XDG_STATE_HOME is unset).\n\n" +
+			"Each session is logged when it ends with who ran it (the STS caller identity), when, the account,\n" +
+			"Region, cluster, task, container, and Session Manager document, the command or ports, the SSM\n" +
+			"session ID, its duration, and tnnl's exit status. The flags filter the entries; --cluster and --task\n" +
+			"match part of the ARN. --json prints the entries as JSON lines, including the input a re-run uses.\n" +
+			"tnnl history rerun ID runs an entry again.",
+		Example: "  tnnl history\n" +
+			"  tnnl history --cluster production --since 24h --failed\n" +
+			"  tnnl history --json --limit 0 | jq .\n" +
+			"  tnnl history rerun 42",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filter, limit, asJSON, err := historyFlags(cmd, deps.now())
+			if err != nil {
+				return err
+			}
+			path, err := deps.path()
+			if err != nil {
+				return err
+			}
+			entries, err := audit.Read(path)
+			if err != nil {
+				return err
+			}
+			selected := audit.Select(entries, filter, limit)
+			if events := event.FromContext(cmd.Context()); events.Enabled() {
+				return emitEntries(events, selected)
+			}
+			if asJSON {
+				return writeJSON(cmd.OutOrStdout(), selected)
+			}
+			if len(selected) == 0 {
+				_, err := fmt.Fprintf(cmd.OutOrStdout(), "no sessions in %s\n", path)
+				return err
+			}
+			return writeTable(cmd.OutOrStdout(), selected)
+		},
+	}
+	c.Flags().String(commandName, "", "only sessions of this tnnl command, such as exec or portforward")
+	c.Flags().String(clusterName, "", "only sessions whose cluster ARN contains this")
+	c.Flags().String(taskName, "", "only sessions whose task ARN contains this")
+	c.Flags().String(containerName, "", "only sessions in this container")
+	c.Flags().String(callerName, "", "only sessions whose caller ARN contains this")
+	c.Flags().String(sinceName, "", "only sessions started within this duration, such as 24h, or since this RFC 3339 time or date")
+	c.Flags().Bool(failedName, false, "only sessions that did not exit 0")
+	c.Flags().Int(limitName, 20, "show the latest this many sessions; 0 shows all")
+	c.Flags().Bool(jsonName, false, "print JSON lines instead of a table")
+	c.AddCommand(newRerunCommand(deps))
+	return c
+}
+
+func historyFlags(c *cobra.Command, now time.Time) (audit.Filter, int, bool, error) {
+	var filter audit.Filter
+	for name, value := range map[string]*string{
+		commandName:   &filter.Command,
+		clusterName:   &filter.Cluster,
+		taskName:      &filter.Task,
+		containerName: &filter.Container,
+		callerName:    &filter.Caller,
+	} {
+		flagValue, err := c.Flags().GetString(name)
+		if err != nil {
+			return audit.Filter{}, 0, false, err
+		}
+		*value = strings.TrimSpace(flagValue)
+	}
+	since, err := c.Flags().GetString(sinceName)
+	if err != nil {
+		return audit.Filter{}, 0, false, err
+	}
+	if filter.Since, err = parseSince(strings.TrimSpace(since), now); err != nil {
+		return audit.Filter{}, 0, false, err
+	}
+	if filter.Failed, err = c.Flags().GetBool(failedName); err != nil {
+		return audit.Filter{}, 0, false, err
+	}
+	limit, err := c.Flags().GetInt(limitName)
+	if err != nil {
+		return audit.Filter{}, 0, false, err
+	}
+	if limit < 0 {
+		return audit.Filter{}, 0, false, fmt.Errorf("limit must be non-negative")
+	}
+	asJSON, err := c.Flags().GetBool(jsonName)
+	if err != nil {
+		return audit.Filter{}, 0, false, err
+	}
+	return filter, limit, asJSON, nil
+}
+
+// parseSince reads a duration before now, an RFC 3339 time, or a date.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if duration, err := time.ParseDuration(value); err == nil && duration > 0 {
+		return now.Add(-duration), nil
+	}
+	if since, err := time.Parse(time.RFC3339, value); err == nil {
+		return since, nil
+	}
+	if since, err := time.ParseInLocation(time.DateOnly, value, now.Location()); err == nil {
+		return since, nil
+	}
+	return time.Time{}, fmt.Errorf("since must be a positive duration such as 24h, an RFC 3339 time, or a date: %q", value)
+}
+
+func writeJSON(w io.Writer, entries []audit.Entry) error {
+	encoder := json.NewEncoder(w)
+	for _, entry := range entries {
+		err := encoder.Encode(struct {
+			ID int `json:"id"`
+			audit.Entry
+		}{ID: entry.ID, Entry: entry})
+		if err != nil {
+			return fmt.Errorf("write audit entries: %w", err)
+		}
+	}
+	return nil
+}
+
+// emitEntries reports each entry as an audit_entry event. The entry is
+// nested since its time is not the event's.
+func emitEntries(events *event.Emitter, entries []audit.Entry) error {
+	for _, entry := range entries {
+		err := events.Emit(event.AuditEntry, struct {
+			ID    int         `json:"id"`
+			Entry audit.Entry `json:"entry"`
+		}{ID: entry.ID, Entry: entry})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeTable(w io.Writer, entries []audit.Entry) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "ID\tTIME\tCALLER\tCOMMAND\tCLUSTER\tTASK\tCONTAINER\tTARGET\tDURATION\tSTATUS")
+	for _, entry := range entries {
+		fmt.Fprintf(
+			table,
+			"%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\n",
+			entry.ID,
+			entry.Time.Local().Format("2006-01-02 15:04:05"),
+			callerLabel(entry.Caller),
+			entry.Command,
+			shortName(entry.Cluster, target.ClusterName),
+			shortName(entry.Task, target.TaskID),
+			entry.Container,
+			sessionTarget(entry),
+			time.Duration(entry.Duration*float64(time.Second)).Round(time.Second).String(),
+			entry.ExitStatus,
+		)
+	}
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("write audit entries: %w", err)
+	}
+	return nil
+}
+
+// callerLabel shortens a caller ARN to the user or role session after its
+// last slash.
+func callerLabel(arn string) string {
+	if i := strings.LastIndex(arn, "/"); i >= 0 {
+		return arn[i+1:]
+	}
+	return arn
+}
+
+func shortName(arn string, parse func(string) (string, error)) string {
+	if name, err := parse(arn); err == nil {
+		return name
+	}
+	return arn
+}
+
+// sessionTarget describes what the session ran or forwarded.
+func sessionTarget(entry audit.Entry) string {
+	if entry.RemotePort == "" {
+		return entry.Remote
+	}
+	remote := entry.RemotePort
+	if entry.Host != "" {
+		remote = entry.Host + ":" + remote
+	}
+	return fmt.Sprintf("localhost:%s->%s", entry.LocalPort, remote)
+}
+
+var HistoryCmd = newHistoryCommand(productionDependencies())
+
+func init() {
+	cmd.RootCmd.AddCommand(HistoryCmd)
+}
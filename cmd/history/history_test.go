@@ -0,0 +1,258 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wim-web/tnnl/cmd"
+	"github.com/wim-web/tnnl/cmd/globalflag"
+	"github.com/wim-web/tnnl/internal/audit"
+	"github.com/wim-web/tnnl/internal/event"
+	"github.com/wim-web/tnnl/internal/input"
+)
+
+const fakeCommandName = "fake-exec"
+
+// fakeRuns records the input each run of the fake saved command resolved.
+var fakeRuns []input.ExecInput
+
+func init() {
+	cmd.RegisterSaved(fakeCommandName, func(source input.Source) *cobra.Command {
+		c := &cobra.Command{
+			Use: fakeCommandName,
+			RunE: func(c *cobra.Command, _ []string) error {
+				connection, err := globalflag.Connection(c)
+				if err != nil {
+					return err
+				}
+				overrides := input.ExecOverrides{Connection: connection}
+				if c.Flags().Changed("command") {
+					value, _ := c.Flags().GetString("command")
+					overrides.Command = &value
+				}
+				resolved, err := input.ResolveExecFrom(source, overrides)
+				if err != nil {
+					return err
+				}
+				fakeRuns = append(fakeRuns, resolved)
+				return nil
+			},
+		}
+		c.Flags().String("command", "sh", "command")
+		return c
+	})
+}
+
+var testNow = time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC)
+
+func newTestDependencies(t *testing.T, entries ...audit.Entry) dependencies {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	for _, entry := range entries {
+		if err := audit.Append(path, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fakeRuns = nil
+	return dependencies{path: func() (string, error) { return path, nil }, now: func() time.Time { return testNow }}
+}
+
+var testEntries = []audit.Entry{
+	{
+		Time:      testNow.Add(-48 * time.Hour),
+		Duration:  61.4,
+		Caller:    "arn:aws:sts::123456789012:assumed-role/dev/alice",
+		Region:    "ap-northeast-1",
+		Command:   fakeCommandName,
+		Cluster:   "arn:aws:ecs:ap-northeast-1:123456789012:cluster/production",
+		Task:      "arn:aws:ecs:ap-northeast-1:123456789012:task/production/0123456789abcdef0",
+		Container: "app",
+		Remote:    "rails console",
+		SessionID: "session-1",
+		Input:     json.RawMessage(`{"cluster":"production","container":"app","command":"rails console","region":"ap-northeast-1"}`),
+	},
+	{
+		Time:       testNow.Add(-time.Hour),
+		Duration:   5,
+		Command:    "portforward",
+		Cluster:    "arn:aws:ecs:ap-northeast-1:123456789012:cluster/staging",
+		Task:       "arn:aws:ecs:ap-northeast-1:123456789012:task/staging/fedcba9876543210f",
+		Container:  "db",
+		LocalPort:  "15432",
+		RemotePort: "5432",
+		SessionID:  "session-2",
+		ExitStatus: 1,
+		Error:      "context canceled",
+	},
+}
+
+func TestHistoryCommandPrintsTable(t *testing.T) {
+	command := newHistoryCommand(newTestDependencies(t, testEntries...))
+	var out bytes.Buffer
+	command.SetOut(&out)
+	command.SetArgs([]string{})
+
+	if err := command.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("ExecuteContext() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "ID") {
+		t.Fatalf("output = %q, want a header and two entries", out.String())
+	}
+	for _, want := range []string{"alice", "production", "0123456789abcdef0", "rails console", "1m1s"} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("first entry = %q, want %q", lines[1], want)
+		}
+	}
+	for _, want := range []string{"staging", "localhost:15432->5432", "5s", " 1"} {
+		if !strings.Contains(lines[2], want) {
+			t.Errorf("second entry = %q, want %q", lines[2], want)
+		}
+	}
+}
+
+func TestHistoryCommandFiltersAndPrintsJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantIDs []int
+	}{
+		{name: "since duration", args: []string{"--since", "24h"}, wantIDs: []int{2}},
+		{name: "since date", args: []string{"--since", "2026-01-01"}, wantIDs: []int{1, 2}},
+		{name: "cluster", args: []string{"--cluster", "production"}, wantIDs: []int{1}},
+		{name: "failed", args: []string{"--failed"}, wantIDs: []int{2}},
+		{name: "limit", args: []string{"--limit", "1"}, wantIDs: []int{2}},
+		{name: "caller", args: []string{"--caller", "bob"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command := newHistoryCommand(newTestDependencies(t, testEntries...))
+			var out bytes.Buffer
+			command.SetOut(&out)
+			command.SetArgs(append([]string{"--json"}, tt.args...))
+
+			if err := command.ExecuteContext(context.Background()); err != nil {
+				t.Fatalf("ExecuteContext() error = %v", err)
+			}
+			var ids []int
+			decoder := json.NewDecoder(&out)
+			for decoder.More() {
+				var entry struct {
+					ID        int    `json:"id"`
+					SessionID string `json:"session_id"`
+				}
+				if err := decoder.Decode(&entry); err != nil {
+					t.Fatalf("decode output: %v", err)
+				}
+				ids = append(ids, entry.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Fatalf("entry IDs = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestHistoryCommandEmitsAuditEntryEvents(t *testing.T) {
+	command := newHistoryCommand(newTestDependencies(t, testEntries...))
+	var out bytes.Buffer
+	command.SetOut(io.Discard)
+	command.SetArgs([]string{"--failed"})
+	events := event.NewEmitter(&out, func() time.Time { return testNow })
+
+	if err := command.ExecuteContext(event.NewContext(context.Background(), events)); err != nil {
+		t.Fatalf("ExecuteContext() error = %v", err)
+	}
+	var got struct {
+		Time  time.Time `json:"time"`
+		Event string    `json:"event"`
+		ID    int       `json:"id"`
+		Entry struct {
+			Time      time.Time `json:"time"`
+			SessionID string    `json:"session_id"`
+		} `json:"entry"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("output %q is not one event: %v", out.String(), err)
+	}
+	if got.Event != "audit_entry" || got.ID != 2 || got.Entry.SessionID != "session-2" || !got.Entry.Time.Equal(testEntries[1].Time) {
+		t.Fatalf("event = %+v, want audit entry 2", got)
+	}
+}
+
+func TestHistoryCommandRejectsInvalidFlags(t *testing.T) {
+	for _, args := range [][]string{{"--since", "yesterday"}, {"--since", "-1h"}, {"--limit", "-1"}} {
+		command := newHistoryCommand(newTestDependencies(t))
+		command.SetArgs(args)
+		command.SilenceUsage, command.SilenceErrors = true, true
+		if err := command.ExecuteContext(context.Background()); err == nil {
+			t.Fatalf("ExecuteContext(%q) error = nil, want validation error", args)
+		}
+	}
+}
+
+func TestHistoryRerunAppliesFlagsOverLoggedInput(t *testing.T) {
+	command := newHistoryCommand(newTestDependencies(t, testEntries...))
+	command.SetArgs([]string{"rerun", "1", "--command", "bash", "--profile", "prod"})
+
+	if err := command.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("ExecuteContext() error = %v", err)
+	}
+	want := []input.ExecInput{{
+		EcsParameter:        input.EcsParameter{Cluster: "production", Container: "app"},
+		ConnectionParameter: input.ConnectionParameter{Profile: "prod", Region: "ap-northeast-1"},
+		Cmd:                 "bash",
+	}}
+	if !reflect.DeepEqual(fakeRuns, want) {
+		t.Fatalf("runs = %#v, want %#v", fakeRuns, want)
+	}
+}
+
+func TestHistoryRerunReportsThroughTheRerunEmitter(t *testing.T) {
+	command := newHistoryCommand(newTestDependencies(t, testEntries...))
+	command.SetArgs([]string{"rerun", "1", "--output", "json"})
+
+	ran, err := command.ExecuteContextC(context.Background())
+	if err != nil {
+		t.Fatalf("ExecuteContextC() error = %v", err)
+	}
+	if ran.Name() != "rerun" || !event.FromContext(ran.Context()).Enabled() {
+		t.Fatalf("command %q leaves no emitter on its context to report errors through", ran.Name())
+	}
+}
+
+func TestHistoryRerunErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "missing ID", args: []string{"rerun"}, wantErr: "audit entry ID is required"},
+		{name: "not a number", args: []string{"rerun", "last"}, wantErr: "must be a number"},
+		{name: "unknown entry", args: []string{"rerun", "9"}, wantErr: "audit entry 9 not found"},
+		{name: "command without re-run", args: []string{"rerun", "2"}, wantErr: "portforward sessions cannot be re-run"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command := newHistoryCommand(newTestDependencies(t, testEntries...))
+			command.SetArgs(tt.args)
+			command.SilenceUsage, command.SilenceErrors = true, true
+
+			err := command.ExecuteContext(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ExecuteContext() error = %v, want %q", err, tt.wantErr)
+			}
+			if len(fakeRuns) != 0 {
+				t.Fatalf("runs = %#v, want none", fakeRuns)
+			}
+		})
+	}
+}
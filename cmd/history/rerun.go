@@ -0,0 +1,87 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wim-web/tnnl/cmd"
+	"github.com/wim-web/tnnl/cmd/globalflag"
+	"github.com/wim-web/tnnl/internal/audit"
+	"github.com/wim-web/tnnl/internal/input"
+)
+
+func newRerunCommand(deps dependencies) *cobra.Command {
+	return &cobra.Command{
+		Use:   "rerun ID [flags]",
+		Short: "Run a session from the audit log again",
+		Long: "Run the session logged as ID again with the same command, input, cluster, service, container,\n" +
+			"and Region. The task is chosen again, by the logged selectors or the picker, since the logged\n" +
+			"task may be gone.\n\n" +
+			"Flags after ID are the logged command's flags and take precedence over the logged input.",
+		Example: "  tnnl history rerun 42\n" +
+			"  tnnl history rerun 42 --local-port 15432",
+		DisableFlagParsing: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+				return c.Help()
+			}
+			if len(args) == 0 {
+				return errors.New("audit entry ID is required; list entries with tnnl history")
+			}
+			saved, err := rerunCommand(deps, args[0])
+			if err != nil {
+				return err
+			}
+			// A nil argument slice would make cobra parse os.Args instead.
+			saved.SetArgs(append([]string{}, args[1:]...))
+			saved.SetIn(c.InOrStdin())
+			saved.SetOut(c.OutOrStdout())
+			saved.SetErr(c.ErrOrStderr())
+			err = saved.ExecuteContext(c.Context())
+			// The re-run chose the output format, so its errors are reported
+			// through its emitter.
+			c.SetContext(saved.Context())
+			return err
+		},
+	}
+}
+
+// rerunCommand builds the command that runs the audit entry id again.
+func rerunCommand(deps dependencies, id string) (*cobra.Command, error) {
+	number, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("audit entry ID must be a number: %q", id)
+	}
+	path, err := deps.path()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := audit.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := audit.Lookup(entries, number)
+	if err != nil {
+		return nil, fmt.Errorf("%w in %s; list entries with tnnl history", err, path)
+	}
+	build, ok := cmd.Saved(entry.Command)
+	if !ok {
+		return nil, fmt.Errorf(
+			"audit entry %d: %s sessions cannot be re-run; the command must be one of %s",
+			number,
+			entry.Command,
+			strings.Join(cmd.SavedNames(), ", "),
+		)
+	}
+
+	saved := build(input.SavedSource(fmt.Sprintf("audit entry %d", number), entry.Input, input.ConnectionParameter{}))
+	saved.Use = "history rerun " + id
+	saved.SilenceErrors = true
+	saved.SilenceUsage = true
+	globalflag.Register(saved.PersistentFlags())
+	saved.PersistentPreRunE = cmd.Prepare
+	return saved, nil
+}
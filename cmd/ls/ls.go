@@ -0,0 +1,94 @@
+package ls
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"github.com/wim-web/tnnl/cmd"
+	"github.com/wim-web/tnnl/cmd/globalflag"
+	"github.com/wim-web/tnnl/cmd/targetflag"
+	"github.com/wim-web/tnnl/internal/handler"
+	"github.com/wim-web/tnnl/internal/input"
+)
+
+type listRunner func(context.Context, input.ListInput) error
+
+func newLsCommand(clusters, services, tasks, containers listRunner) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "ls",
+		Short: "List ECS clusters, services, tasks, or containers without opening a session",
+		Long: "List what the choosers offer, as a table or with --output json as one event per line.\n" +
+			"Without --cluster every cluster of the account and Region is listed, or of every\n" +
+			"--discover-regions and --discover-profiles location. Tasks and containers are listed\n" +
+			"eligible or not, with the reason an ineligible one cannot be used.",
+		Example: "  tnnl ls clusters --discover-regions ap-northeast-1,us-east-1\n" +
+			"  tnnl ls tasks --cluster production --service web\n" +
+			"  tnnl ls containers --cluster production --output json | jq -r 'select(.eligible) | .ssm_target'",
+	}
+	c.AddCommand(
+		newListCommand("clusters", "List ECS clusters with their account and Region", clusters),
+		newListCommand("services", "List ECS services with their task counts and whether execute command is enabled", services, targetflag.ClusterName),
+		newListCommand("tasks", "List running ECS tasks and whether each is eligible", tasks, targetflag.ClusterName, targetflag.ServiceName),
+		newListCommand("containers", "List the containers of running ECS tasks and whether each is eligible", containers, targetflag.ClusterName, targetflag.ServiceName, targetflag.TaskName),
+	)
+	return c
+}
+
+// newListCommand creates one ls subcommand that accepts the named selector
+// flags.
+func newListCommand(use, short string, run listRunner, flags ...string) *cobra.Command {
+	c := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			connection, err := globalflag.Connection(cmd)
+			if err != nil {
+				return err
+			}
+			overrides := input.ListOverrides{Connection: connection}
+			for _, flag := range []struct {
+				name   string
+				target **string
+			}{
+				{targetflag.ClusterName, &overrides.Cluster},
+				{targetflag.ServiceName, &overrides.Service},
+				{targetflag.TaskName, &overrides.Task},
+			} {
+				if cmd.Flags().Lookup(flag.name) == nil || !cmd.Flags().Changed(flag.name) {
+					continue
+				}
+				value, err := cmd.Flags().GetString(flag.name)
+				if err != nil {
+					return err
+				}
+				*flag.target = &value
+			}
+			resolved, err := input.ResolveList(overrides)
+			if err != nil {
+				return err
+			}
+			return run(cmd.Context(), resolved)
+		},
+	}
+	usages := map[string]string{
+		targetflag.ClusterName: "ECS cluster name or ARN to list; every cluster when omitted",
+		targetflag.ServiceName: "ECS service whose tasks to list; requires --cluster",
+		targetflag.TaskName:    "task ID or ARN whose containers to list; requires --cluster",
+	}
+	for _, name := range flags {
+		c.Flags().String(name, "", usages[name])
+	}
+	return c
+}
+
+var LsCmd = newLsCommand(
+	handler.ListClustersHandler,
+	handler.ListServicesHandler,
+	handler.ListTasksHandler,
+	handler.ListContainersHandler,
+)
+
+func init() {
+	cmd.RootCmd.AddCommand(LsCmd)
+}
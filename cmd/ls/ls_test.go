@@ -0,0 +1,83 @@
+package ls
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/wim-web/tnnl/internal/input"
+)
+
+func newTestCommand(got *[]string, inputs *[]input.ListInput) *cobra.Command {
+	record := func(name string) listRunner {
+		return func(_ context.Context, in input.ListInput) error {
+			*got = append(*got, name)
+			*inputs = append(*inputs, in)
+			return nil
+		}
+	}
+	return newLsCommand(record("clusters"), record("services"), record("tasks"), record("containers"))
+}
+
+func TestLsCommandsResolveInput(t *testing.T) {
+	tests := []struct {
+		args []string
+		run  string
+		want input.ListInput
+	}{
+		{args: []string{"clusters"}, run: "clusters", want: input.ListInput{}},
+		{args: []string{"services", "--cluster", " production "}, run: "services", want: input.ListInput{Cluster: "production"}},
+		{
+			args: []string{"tasks", "--cluster", "production", "--service", "web"},
+			run:  "tasks",
+			want: input.ListInput{Cluster: "production", Service: "web"},
+		},
+		{
+			args: []string{"containers", "--cluster", "production", "--task", "0123456789abcdef0"},
+			run:  "containers",
+			want: input.ListInput{Cluster: "production", Task: "0123456789abcdef0"},
+		},
+	}
+	for _, tt := range tests {
+		var got []string
+		var inputs []input.ListInput
+		command := newTestCommand(&got, &inputs)
+		command.SetArgs(tt.args)
+
+		if err := command.ExecuteContext(context.Background()); err != nil {
+			t.Fatalf("ExecuteContext(%q) error = %v", tt.args, err)
+		}
+		if !reflect.DeepEqual(got, []string{tt.run}) || !reflect.DeepEqual(inputs, []input.ListInput{tt.want}) {
+			t.Fatalf("ExecuteContext(%q) ran %q with %#v, want %q with %#v", tt.args, got, inputs, tt.run, tt.want)
+		}
+	}
+}
+
+func TestLsCommandsRejectInvalidSelection(t *testing.T) {
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{args: []string{"tasks", "--service", "web"}, wantErr: "service and task require a cluster"},
+		{args: []string{"clusters", "--cluster", "production"}, wantErr: "unknown flag: --cluster"},
+		{args: []string{"services", "--service", "web"}, wantErr: "unknown flag: --service"},
+		{args: []string{"tasks", "extra"}, wantErr: "unknown command"},
+	}
+	for _, tt := range tests {
+		var got []string
+		var inputs []input.ListInput
+		command := newTestCommand(&got, &inputs)
+		command.SetArgs(tt.args)
+		command.SilenceUsage, command.SilenceErrors = true, true
+
+		err := command.ExecuteContext(context.Background())
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Fatalf("ExecuteContext(%q) error = %v, want it to contain %q", tt.args, err, tt.wantErr)
+		}
+		if len(got) != 0 {
+			t.Fatalf("ExecuteContext(%q) ran %q, want no runner", tt.args, got)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package multiportforward
+
+import (
+	"github.com/wim-web/tnnl/cmd/inputfile"
+	"github.com/wim-web/tnnl/internal/input"
+)
+
+var MakeInputFileCmd = inputfile.New("multiportforward", "multiportforward-input.json", input.MultiPortForwardInput{
+	Forwards: []input.ForwardParameter{
+		{Name: "app", TargetPortNumber: "8080"},
+		{Name: "db", TargetPortNumber: "5432", Host: "db.internal"},
+	},
+})
+
+func init() {
+	MultiPortforwardCmd.AddCommand(MakeInputFileCmd)
+}
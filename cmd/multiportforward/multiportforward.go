@@ -0,0 +1,65 @@
+package multiportforward
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"github.com/wim-web/tnnl/cmd"
+	"github.com/wim-web/tnnl/cmd/globalflag"
+	"github.com/wim-web/tnnl/internal/handler"
+	"github.com/wim-web/tnnl/internal/input"
+)
+
+var inputFileName = "input-file"
+
+type multiPortforwardRunner func(context.Context, input.MultiPortForwardInput) error
+
+// newMultiPortforwardCommand builds the command. A non-nil saved source replaces --input-file
+// when tnnl run replays a saved target.
+func newMultiPortforwardCommand(run multiPortforwardRunner, saved input.Source) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "multiportforward",
+		Short: "Run several port forwards to ECS containers at once",
+		Long: "Run every port forward listed in an input JSON file at the same time.\n\n" +
+			"Each forward names its own ECS target; forwards with identical target fields share one\n" +
+			"selection. A forward with a host forwards through the container to that remote host.\n" +
+			"An omitted local port uses automatic local-port selection. tnnl prints a table of local\n" +
+			"endpoints once every session has started. When any forward ends or fails, or on Ctrl+C,\n" +
+			"every session is terminated. Generate input with tnnl multiportforward make-input-file.",
+		Example: "  tnnl multiportforward --input-file multiportforward-input.json\n" +
+			"  tnnl multiportforward make-input-file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			source := saved
+			if source == nil {
+				path, err := cmd.Flags().GetString(inputFileName)
+				if err != nil {
+					return err
+				}
+				source = input.FileSource(path)
+			}
+
+			connection, err := globalflag.Connection(cmd)
+			if err != nil {
+				return err
+			}
+			resolved, err := input.ResolveMultiPortForwardFrom(source, input.MultiPortForwardOverrides{Connection: connection})
+			if err != nil {
+				return err
+			}
+			return run(cmd.Context(), resolved)
+		},
+	}
+	if saved == nil {
+		c.Flags().String(inputFileName, "", "input JSON generated by tnnl multiportforward make-input-file; required")
+	}
+	return c
+}
+
+var MultiPortforwardCmd = newMultiPortforwardCommand(handler.MultiPortforwardHandler, nil)
+
+func init() {
+	cmd.RootCmd.AddCommand(MultiPortforwardCmd)
+	cmd.RegisterSaved("multiportforward", func(source input.Source) *cobra.Command {
+		return newMultiPortforwardCommand(handler.MultiPortforwardHandler, source)
+	})
+}
@@ -0,0 +1,94 @@
+package multiportforward
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/wim-web/tnnl/internal/input"
+)
+
+func TestMultiPortforwardCommandResolvesInputFile(t *testing.T) {
+	path := writeMultiPortforwardFixture(t, `{
+		"forwards":[
+			{"name":"app","cluster":"cluster","target_port_number":"8080"},
+			{"name":"db","cluster":"cluster","target_port_number":"5432","local_port_number":"15432","host":"db.internal"}
+		]
+	}`)
+	var got input.MultiPortForwardInput
+	calls := 0
+	command := newMultiPortforwardCommand(func(_ context.Context, in input.MultiPortForwardInput) error {
+		calls++
+		got = in
+		return nil
+	}, nil)
+	command.SetArgs([]string{"--input-file", path})
+
+	if err := command.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("ExecuteContext() error = %v", err)
+	}
+	want := input.MultiPortForwardInput{Forwards: []input.ForwardParameter{
+		{Name: "app", EcsParameter: input.EcsParameter{Cluster: "cluster"}, TargetPortNumber: "8080"},
+		{
+			Name:             "db",
+			EcsParameter:     input.EcsParameter{Cluster: "cluster"},
+			TargetPortNumber: "5432",
+			LocalPortNumber:  "15432",
+			Host:             "db.internal",
+		},
+	}}
+	if calls != 1 || !reflect.DeepEqual(got, want) {
+		t.Fatalf("runner calls/input = %d/%#v, want 1/%#v", calls, got, want)
+	}
+}
+
+func TestMultiPortforwardCommandRequiresInputFile(t *testing.T) {
+	calls := 0
+	command := newMultiPortforwardCommand(func(context.Context, input.MultiPortForwardInput) error {
+		calls++
+		return nil
+	}, nil)
+	command.SetArgs([]string{})
+
+	err := command.ExecuteContext(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "input file is required") {
+		t.Fatalf("ExecuteContext() error = %v, want input file requirement", err)
+	}
+	var invalid *input.InvalidError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("ExecuteContext() error = %v, want *input.InvalidError", err)
+	}
+	if calls != 0 {
+		t.Fatalf("runner calls = %d, want 0", calls)
+	}
+}
+
+func TestMultiPortforwardHelpDocumentsTeardown(t *testing.T) {
+	command := newMultiPortforwardCommand(func(context.Context, input.MultiPortForwardInput) error { return nil }, nil)
+	var output bytes.Buffer
+	command.SetOut(&output)
+	command.SetErr(&output)
+	if err := command.Help(); err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []string{"tnnl multiportforward make-input-file", "table of local", "every session is terminated"} {
+		if !strings.Contains(output.String(), value) {
+			t.Errorf("help does not contain %q:\n%s", value, output.String())
+		}
+	}
+}
+
+func writeMultiPortforwardFixture(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "multiportforward.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write input fixture: %v", err)
+	}
+	return path
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wim-web/tnnl/cmd/globalflag"
+	"github.com/wim-web/tnnl/internal/event"
+)
+
+// ApplyOutput reads --output for c and, for json, puts an emitter writing to
+// c's output on its context for the handlers to report through.
+func ApplyOutput(c *cobra.Command, _ []string) error {
+	format, err := globalflag.Output(c)
+	if err != nil {
+		return err
+	}
+	var events *event.Emitter
+	if format == event.FormatJSON {
+		events = event.NewEmitter(c.OutOrStdout(), time.Now)
+	}
+	c.SetContext(event.NewContext(c.Context(), events))
+	return nil
+}
@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wim-web/tnnl/cmd/globalflag"
+	"github.com/wim-web/tnnl/internal/picker"
+)
+
+// ApplyPicker puts the picker that --picker, or else $TNNL_PICKER, selects
+// on c's context for the handlers to ask through.
+func ApplyPicker(c *cobra.Command, _ []string) error {
+	backend, err := globalflag.Picker(c)
+	if err != nil {
+		return err
+	}
+	c.SetContext(picker.NewContext(c.Context(), picker.New(c.Context(), backend)))
+	return nil
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/wim-web/tnnl/cmd/globalflag"
+	"github.com/wim-web/tnnl/internal/listview"
+	"github.com/wim-web/tnnl/internal/picker"
+)
+
+func TestPreparePutsTheSelectedPickerOnTheContext(t *testing.T) {
+	t.Setenv(picker.EnvName, "prompt")
+	var err error
+	root := &cobra.Command{Use: "root", PersistentPreRunE: Prepare}
+	globalflag.Register(root.PersistentFlags())
+	root.AddCommand(&cobra.Command{
+		Use: "child",
+		RunE: func(c *cobra.Command, _ []string) error {
+			_, _, err = picker.FromContext(c.Context()).Choose("Select an ECS task", []listview.Option{{Label: "a"}, {Label: "b"}})
+			return nil
+		},
+	})
+	root.SetArgs([]string{"child", "--picker", "none"})
+
+	if executeErr := root.ExecuteContext(context.Background()); executeErr != nil {
+		t.Fatalf("ExecuteContext() error = %v", executeErr)
+	}
+	var refused *picker.NotInteractiveError
+	if !errors.As(err, &refused) {
+		t.Fatalf("Choose() error = %v, want the none picker of --picker", err)
+	}
+}
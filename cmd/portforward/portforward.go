@@ -5,6 +5,10 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/wim-web/tnnl/cmd"
+	"github.com/wim-web/tnnl/cmd/dryrunflag"
+	"github.com/wim-web/tnnl/cmd/globalflag"
+	"github.com/wim-web/tnnl/cmd/reconnectflag"
+	"github.com/wim-web/tnnl/cmd/targetflag"
 	"github.com/wim-web/tnnl/internal/handler"
 	"github.com/wim-web/tnnl/internal/input"
 )
@@ -15,24 +19,56 @@ var inputFileName = "input-file"
 
 type portforwardRunner func(context.Context, input.PortForwardInput) error
 
-func newPortforwardCommand(run portforwardRunner) *cobra.Command {
+// newPortforwardCommand builds the command. A non-nil saved source replaces --input-file
+// when tnnl run replays a saved target.
+func newPortforwardCommand(run portforwardRunner, saved input.Source) *cobra.Command {
 	c := &cobra.Command{
 		Use:   "portforward",
 		Short: "Forward a local port to an ECS container",
 		Long: "Forward a local port to an eligible ECS container.\n\n" +
 			"Input values use this precedence: explicit flag > input JSON > default.\n" +
 			"When the local port is omitted or the zero value (an empty string), tnnl uses\n" +
-			"automatic local-port selection. Generate input with tnnl portforward make-input-file.",
+			"automatic local-port selection. Generate input with tnnl portforward make-input-file.\n" +
+			"--service narrows the tasks to one service; without it, tnnl offers the cluster's services\n" +
+			"with running/desired counts before the task chooser.\n" +
+			"--show-ineligible lists tasks and containers that cannot be used, greyed out with the reason and\n" +
+			"whether the fix is to wait, redeploy, change the task definition, or grant the IAM role.\n" +
+			"--task, --container, --family, and --strategy select the target without prompting and fail\n" +
+			"when the selectors match no eligible target or more than one.\n" +
+			"--reconnect keeps the local port open across task replacement: when the session ends, tnnl\n" +
+			"waits for an eligible task in the same cluster and service and starts a new session, backing\n" +
+			"off between attempts and giving up after --reconnect-attempts consecutive failures.\n" +
+			"--dry-run stops after preflight and target resolution and prints the target, the SSM document and\n" +
+			"parameters, and the session client invocation with the token redacted.",
 		Example: "  tnnl portforward --target-port 8080\n" +
 			"  tnnl portforward --input-file portforward-input.json\n" +
+			"  tnnl portforward --target-port 8080 --task 0123456789abcdef --container app\n" +
+			"  tnnl portforward --target-port 8080 --local-port 18080 --reconnect\n" +
+			"  tnnl portforward --target-port 8080 --service web --dry-run\n" +
 			"  tnnl portforward make-input-file",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			path, err := cmd.Flags().GetString(inputFileName)
+			source := saved
+			if source == nil {
+				path, err := cmd.Flags().GetString(inputFileName)
+				if err != nil {
+					return err
+				}
+				source = input.FileSource(path)
+			}
+
+			connection, err := globalflag.Connection(cmd)
 			if err != nil {
 				return err
 			}
-
-			overrides := input.PortForwardOverrides{}
+			ecs, err := targetflag.ECS(cmd)
+			if err != nil {
+				return err
+			}
+			reconnect, err := reconnectflag.Reconnect(cmd)
+			if err != nil {
+				return err
+			}
+			overrides := input.PortForwardOverrides{Ecs: ecs, Connection: connection, Reconnect: reconnect}
 			if cmd.Flags().Changed(targetPortName) {
 				value, err := cmd.Flags().GetString(targetPortName)
 				if err != nil {
@@ -48,7 +84,11 @@ func newPortforwardCommand(run portforwardRunner) *cobra.Command {
 				overrides.LocalPort = &value
 			}
 
-			resolved, err := input.ResolvePortForward(path, overrides)
+			if overrides.DryRun, err = dryrunflag.DryRun(cmd); err != nil {
+				return err
+			}
+
+			resolved, err := input.ResolvePortForwardFrom(source, overrides)
 			if err != nil {
 				return err
 			}
@@ -57,12 +97,20 @@ func newPortforwardCommand(run portforwardRunner) *cobra.Command {
 	}
 	c.Flags().StringP(localPortName, "l", "", "local port; omit it (empty zero value) for automatic local-port selection; precedence: explicit flag > input JSON > default")
 	c.Flags().StringP(targetPortName, "t", "", "target port; precedence: explicit flag > input JSON > default; a value is required")
-	c.Flags().String(inputFileName, "", "input JSON generated by tnnl portforward make-input-file; explicit flags override input JSON values")
+	if saved == nil {
+		c.Flags().String(inputFileName, "", "input JSON generated by tnnl portforward make-input-file; explicit flags override input JSON values")
+	}
+	targetflag.Register(c.Flags())
+	reconnectflag.Register(c.Flags())
+	dryrunflag.Register(c.Flags())
 	return c
 }
 
-var PortforwardCmd = newPortforwardCommand(handler.PortforwardHandler)
+var PortforwardCmd = newPortforwardCommand(handler.PortforwardHandler, nil)
 
 func init() {
 	cmd.RootCmd.AddCommand(PortforwardCmd)
+	cmd.RegisterSaved("portforward", func(source input.Source) *cobra.Command {
+		return newPortforwardCommand(handler.PortforwardHandler, source)
+	})
 }
@@ -62,7 +62,7 @@ func TestPortforwardCommandOverridesOnlyExplicitFlags(t *testing.T) {
 				calls++
 				got = in
 				return nil
-			})
+			}, nil)
 			command.SetArgs(append([]string{"--input-file", path}, tt.args...))
 
 			if err := command.ExecuteContext(context.Background()); err != nil {
@@ -84,7 +84,7 @@ func TestPortforwardCommandPreservesExplicitEmptyLocalPort(t *testing.T) {
 	command := newPortforwardCommand(func(_ context.Context, in input.PortForwardInput) error {
 		got = in
 		return nil
-	})
+	}, nil)
 	command.SetArgs([]string{"--input-file", path, "--local-port", ""})
 
 	if err := command.ExecuteContext(context.Background()); err != nil {
@@ -95,6 +95,24 @@ func TestPortforwardCommandPreservesExplicitEmptyLocalPort(t *testing.T) {
 	}
 }
 
+func TestPortforwardCommandReconnectFlagsOverrideFile(t *testing.T) {
+	path := writePortforwardFixture(t, `{"target_port_number":"80","reconnect":false,"reconnect_attempts":2,"reconnect_wait":30}`)
+	var got input.PortForwardInput
+	command := newPortforwardCommand(func(_ context.Context, in input.PortForwardInput) error {
+		got = in
+		return nil
+	}, nil)
+	command.SetArgs([]string{"--input-file", path, "--reconnect", "--reconnect-wait", "60"})
+
+	if err := command.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("ExecuteContext() error = %v", err)
+	}
+	want := input.ReconnectParameter{Reconnect: true, ReconnectAttempts: 2, ReconnectWait: 60}
+	if got.ReconnectParameter != want {
+		t.Fatalf("runner ReconnectParameter = %#v, want %#v", got.ReconnectParameter, want)
+	}
+}
+
 func TestPortforwardCommandPassesExecuteContextToRunner(t *testing.T) {
 	type contextKey struct{}
 	want := "portforward context value"
@@ -103,7 +121,7 @@ func TestPortforwardCommandPassesExecuteContextToRunner(t *testing.T) {
 	command := newPortforwardCommand(func(ctx context.Context, _ input.PortForwardInput) error {
 		got = ctx.Value(contextKey{})
 		return nil
-	})
+	}, nil)
 	command.SetArgs([]string{"--target-port", "80"})
 
 	if err := command.ExecuteContext(ctx); err != nil {
@@ -132,7 +150,7 @@ func TestPortforwardCommandInvalidExplicitFlagDoesNotInvokeRunner(t *testing.T)
 			command := newPortforwardCommand(func(_ context.Context, _ input.PortForwardInput) error {
 				calls++
 				return nil
-			})
+			}, nil)
 			command.SetArgs(append([]string{"--input-file", path}, tt.args...))
 
 			err := command.ExecuteContext(context.Background())
@@ -147,7 +165,7 @@ func TestPortforwardCommandInvalidExplicitFlagDoesNotInvokeRunner(t *testing.T)
 }
 
 func TestPortforwardCommandInputFileHelpNamesParent(t *testing.T) {
-	command := newPortforwardCommand(func(context.Context, input.PortForwardInput) error { return nil })
+	command := newPortforwardCommand(func(context.Context, input.PortForwardInput) error { return nil }, nil)
 	flag := command.Flags().Lookup(inputFileName)
 	if flag == nil {
 		t.Fatal("input-file flag = nil")
@@ -158,7 +176,7 @@ func TestPortforwardCommandInputFileHelpNamesParent(t *testing.T) {
 }
 
 func TestPortforwardHelpDocumentsInputAndAutomaticLocalPort(t *testing.T) {
-	command := newPortforwardCommand(func(context.Context, input.PortForwardInput) error { return nil })
+	command := newPortforwardCommand(func(context.Context, input.PortForwardInput) error { return nil }, nil)
 
 	assertHelpContains(t, command,
 		"tnnl portforward make-input-file",
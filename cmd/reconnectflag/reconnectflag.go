@@ -0,0 +1,45 @@
+package reconnectflag
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/wim-web/tnnl/internal/input"
+)
+
+var ReconnectName = "reconnect"
+var AttemptsName = "reconnect-attempts"
+var WaitName = "reconnect-wait"
+
+// Register adds the supervised reconnect flags for port forwards to flags.
+func Register(flags *pflag.FlagSet) {
+	flags.Bool(ReconnectName, false, "start a new session on the same local port when the forward ends, following task replacement; precedence: explicit flag > input JSON > default")
+	flags.Int(AttemptsName, 0, "consecutive reconnect attempts before giving up; 0 uses 5; precedence: explicit flag > input JSON > default")
+	flags.Int(WaitName, 0, "seconds to wait for a replacement task on each attempt; 0 uses 120; precedence: explicit flag > input JSON > default")
+}
+
+// Reconnect returns the reconnect flags explicitly set for c.
+func Reconnect(c *cobra.Command) (input.ReconnectOverrides, error) {
+	overrides := input.ReconnectOverrides{}
+	if c.Flags().Changed(ReconnectName) {
+		value, err := c.Flags().GetBool(ReconnectName)
+		if err != nil {
+			return input.ReconnectOverrides{}, err
+		}
+		overrides.Reconnect = &value
+	}
+	if c.Flags().Changed(AttemptsName) {
+		value, err := c.Flags().GetInt(AttemptsName)
+		if err != nil {
+			return input.ReconnectOverrides{}, err
+		}
+		overrides.Attempts = &value
+	}
+	if c.Flags().Changed(WaitName) {
+		value, err := c.Flags().GetInt(WaitName)
+		if err != nil {
+			return input.ReconnectOverrides{}, err
+		}
+		overrides.Wait = &value
+	}
+	return overrides, nil
+}
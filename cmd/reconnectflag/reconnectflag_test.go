@@ -0,0 +1,46 @@
+package reconnectflag
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/wim-web/tnnl/internal/input"
+)
+
+func TestReconnectReturnsOnlyExplicitFlags(t *testing.T) {
+	var got input.ReconnectOverrides
+	c := &cobra.Command{
+		Use: "child",
+		RunE: func(c *cobra.Command, _ []string) error {
+			var err error
+			got, err = Reconnect(c)
+			return err
+		},
+	}
+	Register(c.Flags())
+	c.SetArgs([]string{"--reconnect", "--reconnect-attempts", "0"})
+
+	if err := c.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("ExecuteContext() error = %v", err)
+	}
+	if got.Reconnect == nil || !*got.Reconnect {
+		t.Fatalf("Reconnect = %v, want true", got.Reconnect)
+	}
+	if got.Attempts == nil || *got.Attempts != 0 {
+		t.Fatalf("Attempts = %v, want explicit 0", got.Attempts)
+	}
+	if got.Wait != nil {
+		t.Fatalf("Wait = %v, want omitted", *got.Wait)
+	}
+}
+
+func TestReconnectWithoutRegisteredFlagsReturnsNoOverrides(t *testing.T) {
+	got, err := Reconnect(&cobra.Command{Use: "standalone"})
+	if err != nil {
+		t.Fatalf("Reconnect() error = %v", err)
+	}
+	if got != (input.ReconnectOverrides{}) {
+		t.Fatalf("Reconnect() = %#v, want no overrides", got)
+	}
+}
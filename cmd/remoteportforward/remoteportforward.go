@@ -5,6 +5,10 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/wim-web/tnnl/cmd"
+	"github.com/wim-web/tnnl/cmd/dryrunflag"
+	"github.com/wim-web/tnnl/cmd/globalflag"
+	"github.com/wim-web/tnnl/cmd/reconnectflag"
+	"github.com/wim-web/tnnl/cmd/targetflag"
 	"github.com/wim-web/tnnl/internal/handler"
 	"github.com/wim-web/tnnl/internal/input"
 )
@@ -16,24 +20,55 @@ var inputFileName = "input-file"
 
 type remotePortforwardRunner func(context.Context, input.RemotePortForwardInput) error
 
-func newRemotePortforwardCommand(run remotePortforwardRunner) *cobra.Command {
+// newRemotePortforwardCommand builds the command. A non-nil saved source replaces --input-file
+// when tnnl run replays a saved target.
+func newRemotePortforwardCommand(run remotePortforwardRunner, saved input.Source) *cobra.Command {
 	c := &cobra.Command{
 		Use:   "remoteportforward",
 		Short: "Forward a local port through an ECS container to a remote host",
 		Long: "Forward a local port through an eligible ECS container to a remote host.\n\n" +
 			"Input values use this precedence: explicit flag > input JSON > default.\n" +
 			"When the local port is omitted or the zero value (an empty string), tnnl uses\n" +
-			"automatic local-port selection. Generate input with tnnl remoteportforward make-input-file.",
+			"automatic local-port selection. Generate input with tnnl remoteportforward make-input-file.\n" +
+			"--service narrows the tasks to one service; without it, tnnl offers the cluster's services\n" +
+			"with running/desired counts before the task chooser.\n" +
+			"--show-ineligible lists tasks and containers that cannot be used, greyed out with the reason and\n" +
+			"whether the fix is to wait, redeploy, change the task definition, or grant the IAM role.\n" +
+			"--task, --container, --family, and --strategy select the target without prompting and fail\n" +
+			"when the selectors match no eligible target or more than one.\n" +
+			"--reconnect keeps the local port open across task replacement: when the session ends, tnnl\n" +
+			"waits for an eligible task in the same cluster and service and starts a new session, backing\n" +
+			"off between attempts and giving up after --reconnect-attempts consecutive failures.\n" +
+			"--dry-run stops after preflight and target resolution and prints the target, the SSM document and\n" +
+			"parameters, and the session client invocation with the token redacted.",
 		Example: "  tnnl remoteportforward --remote-port 3306 --host db.internal\n" +
 			"  tnnl remoteportforward --input-file remoteportforward-input.json\n" +
+			"  tnnl remoteportforward --remote-port 3306 --host db.internal --family api --strategy first\n" +
+			"  tnnl remoteportforward --remote-port 3306 --host db.internal --dry-run\n" +
 			"  tnnl remoteportforward make-input-file",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			path, err := cmd.Flags().GetString(inputFileName)
+			source := saved
+			if source == nil {
+				path, err := cmd.Flags().GetString(inputFileName)
+				if err != nil {
+					return err
+				}
+				source = input.FileSource(path)
+			}
+
+			connection, err := globalflag.Connection(cmd)
 			if err != nil {
 				return err
 			}
-
-			overrides := input.RemotePortForwardOverrides{}
+			ecs, err := targetflag.ECS(cmd)
+			if err != nil {
+				return err
+			}
+			reconnect, err := reconnectflag.Reconnect(cmd)
+			if err != nil {
+				return err
+			}
+			overrides := input.RemotePortForwardOverrides{Ecs: ecs, Connection: connection, Reconnect: reconnect}
 			if cmd.Flags().Changed(remotePortName) {
 				value, err := cmd.Flags().GetString(remotePortName)
 				if err != nil {
@@ -56,7 +91,11 @@ func newRemotePortforwardCommand(run remotePortforwardRunner) *cobra.Command {
 				overrides.Host = &value
 			}
 
-			resolved, err := input.ResolveRemotePortForward(path, overrides)
+			if overrides.DryRun, err = dryrunflag.DryRun(cmd); err != nil {
+				return err
+			}
+
+			resolved, err := input.ResolveRemotePortForwardFrom(source, overrides)
 			if err != nil {
 				return err
 			}
@@ -66,12 +105,20 @@ func newRemotePortforwardCommand(run remotePortforwardRunner) *cobra.Command {
 	c.Flags().StringP(localPortName, "l", "", "local port; omit it (empty zero value) for automatic local-port selection; precedence: explicit flag > input JSON > default")
 	c.Flags().StringP(remotePortName, "r", "", "remote port; precedence: explicit flag > input JSON > default; a value is required")
 	c.Flags().String(hostName, "", "remote host; precedence: explicit flag > input JSON > default; a value is required")
-	c.Flags().String(inputFileName, "", "input JSON generated by tnnl remoteportforward make-input-file; explicit flags override input JSON values")
+	if saved == nil {
+		c.Flags().String(inputFileName, "", "input JSON generated by tnnl remoteportforward make-input-file; explicit flags override input JSON values")
+	}
+	targetflag.Register(c.Flags())
+	reconnectflag.Register(c.Flags())
+	dryrunflag.Register(c.Flags())
 	return c
 }
 
-var RemoteportforwardCmd = newRemotePortforwardCommand(handler.RemotePortforwardHandler)
+var RemoteportforwardCmd = newRemotePortforwardCommand(handler.RemotePortforwardHandler, nil)
 
 func init() {
 	cmd.RootCmd.AddCommand(RemoteportforwardCmd)
+	cmd.RegisterSaved("remoteportforward", func(source input.Source) *cobra.Command {
+		return newRemotePortforwardCommand(handler.RemotePortforwardHandler, source)
+	})
 }
@@ -76,7 +76,7 @@ func TestRemotePortforwardCommandOverridesOnlyExplicitFlags(t *testing.T) {
 				calls++
 				got = in
 				return nil
-			})
+			}, nil)
 			command.SetArgs(append([]string{"--input-file", path}, tt.args...))
 
 			if err := command.ExecuteContext(context.Background()); err != nil {
@@ -102,7 +102,7 @@ func TestRemotePortforwardCommandAllExplicitFlagsOverrideFile(t *testing.T) {
 	command := newRemotePortforwardCommand(func(_ context.Context, in input.RemotePortForwardInput) error {
 		got = in
 		return nil
-	})
+	}, nil)
 	command.SetArgs([]string{
 		"--input-file", path,
 		"--remote-port", "443",
@@ -131,7 +131,7 @@ func TestRemotePortforwardCommandPassesExecuteContextToRunner(t *testing.T) {
 	command := newRemotePortforwardCommand(func(ctx context.Context, _ input.RemotePortForwardInput) error {
 		got = ctx.Value(contextKey{})
 		return nil
-	})
+	}, nil)
 	command.SetArgs([]string{"--remote-port", "22", "--host", "example.com"})
 
 	if err := command.ExecuteContext(ctx); err != nil {
@@ -164,7 +164,7 @@ func TestRemotePortforwardCommandInvalidRequiredValueDoesNotInvokeRunner(t *test
 			command := newRemotePortforwardCommand(func(_ context.Context, _ input.RemotePortForwardInput) error {
 				calls++
 				return nil
-			})
+			}, nil)
 			command.SetArgs(append([]string{"--input-file", path}, tt.args...))
 
 			err := command.ExecuteContext(context.Background())
@@ -179,7 +179,7 @@ func TestRemotePortforwardCommandInvalidRequiredValueDoesNotInvokeRunner(t *test
 }
 
 func TestRemotePortforwardCommandInputFileHelpNamesParent(t *testing.T) {
-	command := newRemotePortforwardCommand(func(context.Context, input.RemotePortForwardInput) error { return nil })
+	command := newRemotePortforwardCommand(func(context.Context, input.RemotePortForwardInput) error { return nil }, nil)
 	flag := command.Flags().Lookup(inputFileName)
 	if flag == nil {
 		t.Fatal("input-file flag = nil")
@@ -190,7 +190,7 @@ func TestRemotePortforwardCommandInputFileHelpNamesParent(t *testing.T) {
 }
 
 func TestRemotePortforwardHelpDocumentsRemoteHostAndAutomaticLocalPort(t *testing.T) {
-	command := newRemotePortforwardCommand(func(context.Context, input.RemotePortForwardInput) error { return nil })
+	command := newRemotePortforwardCommand(func(context.Context, input.RemotePortForwardInput) error { return nil }, nil)
 
 	assertHelpContains(t, command,
 		"tnnl remoteportforward make-input-file",
@@ -0,0 +1,76 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wim-web/tnnl/cmd"
+	"github.com/wim-web/tnnl/internal/cast"
+)
+
+var speedName = "speed"
+var idleLimitName = "idle-limit"
+
+type replayRunner func(context.Context, string, cast.PlayOptions, io.Writer) error
+
+func newReplayCommand(run replayRunner) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "replay FILE",
+		Short: "Play back a session recorded with tnnl exec --record",
+		Long: "Play back an asciinema cast file, such as one written by tnnl exec --record, in the terminal.\n\n" +
+			"The output is shown at the recorded pace. --speed plays it faster or slower, and --idle-limit\n" +
+			"shortens pauses longer than that many seconds. What was typed is recorded but, as in the\n" +
+			"session itself, only shown where the remote terminal echoed it.",
+		Example: "  tnnl replay incident.cast\n" +
+			"  tnnl replay --speed 2 --idle-limit 1 incident.cast",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			speed, err := cmd.Flags().GetFloat64(speedName)
+			if err != nil {
+				return err
+			}
+			idleLimit, err := cmd.Flags().GetInt(idleLimitName)
+			if err != nil {
+				return err
+			}
+			var errs []error
+			if speed <= 0 {
+				errs = append(errs, errors.New("speed must be positive"))
+			}
+			if idleLimit < 0 {
+				errs = append(errs, errors.New("idle limit must be non-negative"))
+			}
+			if err := errors.Join(errs...); err != nil {
+				return err
+			}
+			options := cast.PlayOptions{Speed: speed, IdleLimit: time.Duration(idleLimit) * time.Second}
+			return run(cmd.Context(), args[0], options, cmd.OutOrStdout())
+		},
+	}
+	c.Flags().Float64(speedName, 1, "playback speed; 2 plays twice as fast")
+	c.Flags().Int(idleLimitName, 0, "longest pause in seconds; 0 keeps the recorded pauses")
+	return c
+}
+
+func playFile(ctx context.Context, path string, options cast.PlayOptions, out io.Writer) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open session recording: %w", err)
+	}
+	defer file.Close()
+	if _, err := cast.Play(ctx, file, out, options); err != nil {
+		return fmt.Errorf("replay %s: %w", path, err)
+	}
+	return nil
+}
+
+var ReplayCmd = newReplayCommand(playFile)
+
+func init() {
+	cmd.RootCmd.AddCommand(ReplayCmd)
+}
@@ -0,0 +1,74 @@
+package replay
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wim-web/tnnl/internal/cast"
+)
+
+func TestReplayCommandPassesOptions(t *testing.T) {
+	var gotPath string
+	var gotOptions cast.PlayOptions
+	command := newReplayCommand(func(_ context.Context, path string, options cast.PlayOptions, _ io.Writer) error {
+		gotPath, gotOptions = path, options
+		return nil
+	})
+	command.SetArgs([]string{"--speed", "2.5", "--idle-limit", "3", "incident.cast"})
+
+	if err := command.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("ExecuteContext() error = %v", err)
+	}
+	if want := (cast.PlayOptions{Speed: 2.5, IdleLimit: 3 * time.Second}); gotPath != "incident.cast" || gotOptions != want {
+		t.Fatalf("runner got %q, %#v, want incident.cast, %#v", gotPath, gotOptions, want)
+	}
+}
+
+func TestReplayCommandInvalidOptionsDoNotInvokeRunner(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "zero speed", args: []string{"--speed", "0", "incident.cast"}, wantErr: "speed must be positive"},
+		{name: "negative idle limit", args: []string{"--idle-limit", "-1", "incident.cast"}, wantErr: "idle limit must be non-negative"},
+		{name: "no file", args: nil, wantErr: "accepts 1 arg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command := newReplayCommand(func(context.Context, string, cast.PlayOptions, io.Writer) error {
+				t.Fatal("runner called, want validation error")
+				return nil
+			})
+			command.SetArgs(tt.args)
+			command.SilenceUsage, command.SilenceErrors = true, true
+
+			if err := command.ExecuteContext(context.Background()); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ExecuteContext() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlayFileWritesRecordedOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.cast")
+	recorded := `{"version":2,"width":80,"height":24}` + "\n" + `[0,"o","$ "]` + "\n" + `[0,"i","ls\r"]` + "\n"
+	if err := os.WriteFile(path, []byte(recorded), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := playFile(context.Background(), path, cast.PlayOptions{}, &out); err != nil {
+		t.Fatalf("playFile() error = %v", err)
+	}
+	if out.String() != "$ " {
+		t.Fatalf("output = %q, want the recorded output", out.String())
+	}
+	if err := playFile(context.Background(), filepath.Join(t.TempDir(), "missing.cast"), cast.PlayOptions{}, &out); err == nil || !strings.Contains(err.Error(), "open session recording") {
+		t.Fatalf("playFile(missing) error = %v, want open error", err)
+	}
+}
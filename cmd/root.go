@@ -5,7 +5,9 @@ import (
 	"fmt"
 
 	"github.com/spf13/cobra"
+	"github.com/wim-web/tnnl/cmd/globalflag"
 	"github.com/wim-web/tnnl/internal/buildinfo"
+	"github.com/wim-web/tnnl/internal/event"
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -15,9 +17,13 @@ var RootCmd = &cobra.Command{
 	Long: "tnnl selects a ready ECS task and container for exec or port forwarding.\n" +
 		"AWS credentials and Region come from the AWS SDK default configuration chain; set\n" +
 		"AWS_PROFILE/AWS_REGION or run through tools such as `aws-vault exec NAME -- tnnl ...`.\n" +
-		"session-manager-plugin (Session Manager Plugin) must be installed and available on PATH.",
-	SilenceErrors: true,
-	SilenceUsage:  true,
+		"--profile, --region, and --endpoint-url (or the matching input JSON fields) override them\n" +
+		"for one invocation, for both the AWS APIs and session-manager-plugin.\n" +
+		"session-manager-plugin (Session Manager Plugin) must be installed and available on PATH,\n" +
+		"unless --session-client native selects the built-in Session Manager data channel.",
+	SilenceErrors:     true,
+	SilenceUsage:      true,
+	PersistentPreRunE: Prepare,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if shortVersion {
 			return writeVersion(cmd)
@@ -27,12 +33,29 @@ var RootCmd = &cobra.Command{
 	},
 }
 
+// Prepare applies the output format and the picker for c. Commands built
+// outside the tree, such as saved commands, set it as their
+// PersistentPreRunE.
+func Prepare(c *cobra.Command, args []string) error {
+	if err := ApplyOutput(c, args); err != nil {
+		return err
+	}
+	return ApplyPicker(c, args)
+}
+
 func ExecuteContext(ctx context.Context) error {
 	return RootCmd.ExecuteContext(ctx)
 }
 
+// ExecuteContextC is ExecuteContext that also returns the command that ran,
+// whose context carries the emitter its errors are reported through.
+func ExecuteContextC(ctx context.Context) (*cobra.Command, error) {
+	return RootCmd.ExecuteContextC(ctx)
+}
+
 func init() {
 	RootCmd.AddCommand(versionCmd)
+	globalflag.Register(RootCmd.PersistentFlags())
 	RootCmd.Flags().BoolVarP(&shortVersion, "version", "v", false, "Print the version")
 }
 
@@ -48,6 +71,11 @@ var versionCmd = &cobra.Command{
 }
 
 func writeVersion(cmd *cobra.Command) error {
+	if events := event.FromContext(cmd.Context()); events.Enabled() {
+		return events.Emit(event.Version, struct {
+			Version string `json:"version"`
+		}{Version: Version})
+	}
 	if _, err := fmt.Fprintln(cmd.OutOrStdout(), Version); err != nil {
 		return fmt.Errorf("write version: %w", err)
 	}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"strings"
@@ -10,7 +11,9 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/wim-web/tnnl/cmd/globalflag"
 	"github.com/wim-web/tnnl/internal/buildinfo"
+	"github.com/wim-web/tnnl/internal/event"
 )
 
 func TestExecuteContextPropagatesCancellation(t *testing.T) {
@@ -126,7 +129,7 @@ func TestRootCommandMetadataAndFlags(t *testing.T) {
 		t.Errorf("RootCmd --version shorthand = %q, want %q", versionFlag.Shorthand, "v")
 	}
 
-	for _, name := range []string{"profile", "region", "cluster", "service"} {
+	for _, name := range []string{"cluster", "service"} {
 		if flag := RootCmd.Flags().Lookup(name); flag != nil {
 			t.Errorf("RootCmd unexpectedly defines --%s", name)
 		}
@@ -134,6 +137,11 @@ func TestRootCommandMetadataAndFlags(t *testing.T) {
 			t.Errorf("RootCmd unexpectedly defines persistent --%s", name)
 		}
 	}
+	for _, name := range []string{"profile", "region", "endpoint-url", "session-client"} {
+		if flag := RootCmd.PersistentFlags().Lookup(name); flag == nil {
+			t.Errorf("RootCmd persistent --%s flag is missing", name)
+		}
+	}
 }
 
 func TestRootHelpDocumentsAWSSetup(t *testing.T) {
@@ -143,6 +151,9 @@ func TestRootHelpDocumentsAWSSetup(t *testing.T) {
 		"AWS SDK default configuration chain",
 		"AWS_PROFILE",
 		"AWS_REGION",
+		"--profile",
+		"--region",
+		"--endpoint-url",
 		"`aws-vault exec NAME -- tnnl ...`",
 		"session-manager-plugin",
 		"PATH",
@@ -189,6 +200,54 @@ func TestVersionCommandsWriteIdenticalOutput(t *testing.T) {
 	}
 }
 
+func TestOutputJSONWritesVersionEvent(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	prepareRootCommandTest(t, []string{"--output", "json", "version"}, &stdout, &stderr)
+	t.Cleanup(func() { resetOutputFlag(t) })
+	Version = "1.2.3"
+
+	ran, err := ExecuteContextC(context.Background())
+	if err != nil {
+		t.Fatalf("ExecuteContextC() error = %v", err)
+	}
+	var got struct {
+		Event   string `json:"event"`
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("output %q is not one JSON event: %v", stdout.String(), err)
+	}
+	if got.Event != "version" || got.Version != "1.2.3" {
+		t.Fatalf("event = %+v, want version 1.2.3", got)
+	}
+	if !event.FromContext(ran.Context()).Enabled() {
+		t.Fatal("the emitter on the context of the command that ran is not enabled after --output json")
+	}
+}
+
+func TestOutputRejectsUnknownFormat(t *testing.T) {
+	var stdout bytes.Buffer
+	prepareRootCommandTest(t, []string{"--output", "yaml", "version"}, &stdout, io.Discard)
+	t.Cleanup(func() { resetOutputFlag(t) })
+
+	err := ExecuteContext(context.Background())
+	if err == nil || err.Error() != `output must be "text" or "json": "yaml"` {
+		t.Fatalf("ExecuteContext() error = %v, want invalid output format", err)
+	}
+	if stdout.Len() != 0 {
+		t.Fatalf("stdout = %q, want nothing", stdout.String())
+	}
+}
+
+func resetOutputFlag(t *testing.T) {
+	t.Helper()
+	flag := RootCmd.PersistentFlags().Lookup(globalflag.OutputName)
+	if err := flag.Value.Set("text"); err != nil {
+		t.Fatal(err)
+	}
+	flag.Changed = false
+}
+
 func TestVersionCommandsReturnWriteErrors(t *testing.T) {
 	tests := []struct {
 		name string
@@ -0,0 +1,97 @@
+package run
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/wim-web/tnnl/cmd"
+	"github.com/wim-web/tnnl/internal/event"
+	"github.com/wim-web/tnnl/internal/input"
+	"github.com/wim-web/tnnl/internal/savedtarget"
+)
+
+func newListCommand(deps dependencies) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List saved targets",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := deps.path()
+			if err != nil {
+				return err
+			}
+			file, err := savedtarget.Load(path)
+			if err != nil {
+				return err
+			}
+			if events := event.FromContext(cmd.Context()); events.Enabled() {
+				return emitSavedTargets(events, file)
+			}
+			if len(file.Targets) == 0 {
+				_, err := fmt.Fprintf(cmd.OutOrStdout(), "no saved targets in %s\n", path)
+				return err
+			}
+
+			table := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(table, "NAME\tCOMMAND\tAWS_PROFILE\tREGION\tCLUSTER\tSERVICE")
+			for _, name := range file.Names() {
+				target := file.Targets[name]
+				// Summaries are best effort; tnnl run reports invalid input.
+				var summary input.EcsParameter
+				_ = json.Unmarshal(target.Input, &summary)
+				fmt.Fprintf(
+					table,
+					"%s\t%s\t%s\t%s\t%s\t%s\n",
+					name,
+					target.Command,
+					target.AWSProfile,
+					target.Region,
+					summary.Cluster,
+					summary.Service,
+				)
+			}
+			if err := table.Flush(); err != nil {
+				return fmt.Errorf("write saved targets: %w", err)
+			}
+			return nil
+		},
+	}
+}
+
+// savedTarget is a saved_target event.
+type savedTarget struct {
+	Name       string `json:"name"`
+	Command    string `json:"command"`
+	AWSProfile string `json:"aws_profile,omitempty"`
+	Region     string `json:"region,omitempty"`
+	Cluster    string `json:"cluster,omitempty"`
+	Service    string `json:"service,omitempty"`
+}
+
+func emitSavedTargets(events *event.Emitter, file savedtarget.File) error {
+	for _, name := range file.Names() {
+		target := file.Targets[name]
+		var summary input.EcsParameter
+		_ = json.Unmarshal(target.Input, &summary)
+		err := events.Emit(event.SavedTarget, savedTarget{
+			Name:       name,
+			Command:    target.Command,
+			AWSProfile: target.AWSProfile,
+			Region:     target.Region,
+			Cluster:    summary.Cluster,
+			Service:    summary.Service,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var ListCmd = newListCommand(productionDependencies())
+
+func init() {
+	cmd.RootCmd.AddCommand(ListCmd)
+}
@@ -0,0 +1,121 @@
+package run
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/wim-web/tnnl/cmd"
+	"github.com/wim-web/tnnl/cmd/globalflag"
+	"github.com/wim-web/tnnl/internal/input"
+	"github.com/wim-web/tnnl/internal/savedtarget"
+)
+
+type dependencies struct {
+	path func() (string, error)
+}
+
+func productionDependencies() dependencies {
+	return dependencies{path: savedtarget.Path}
+}
+
+func newRunCommand(deps dependencies) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "run NAME [flags]",
+		Short: "Run a saved target",
+		Long: "Run a named target saved in $XDG_CONFIG_HOME/tnnl/targets.json (~/.config/tnnl/targets.json\n" +
+			"when XDG_CONFIG_HOME is unset). Each target names a command (exec, portforward,\n" +
+			"remoteportforward, or multiportforward), the input JSON that command's make-input-file\n" +
+			"generates, and optionally an AWS profile and Region.\n\n" +
+			"Flags after NAME are the saved command's flags. Input values use this precedence:\n" +
+			"explicit flag > saved target > default. The saved AWS profile and Region take the\n" +
+			"place of the input's profile and region, so --profile and --region still override them.",
+		Example: "  tnnl run api-db\n" +
+			"  tnnl run api-db --local-port 15432\n" +
+			"  tnnl list",
+		DisableFlagParsing: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Flag parsing is left to the saved command, so global flags given
+			// before NAME are collected here and passed on.
+			leading := pflag.NewFlagSet("run", pflag.ContinueOnError)
+			leading.SetOutput(io.Discard)
+			leading.SetInterspersed(false)
+			globalflag.Register(leading)
+			help := leading.BoolP("help", "h", false, "help for run")
+			if err := leading.Parse(args); err != nil {
+				return err
+			}
+			if *help {
+				return cmd.Help()
+			}
+			if leading.NArg() == 0 {
+				return errors.New("saved target name is required; list saved targets with tnnl list")
+			}
+
+			saved, err := savedCommand(deps, leading.Arg(0))
+			if err != nil {
+				return err
+			}
+			// A nil argument slice would make cobra parse os.Args instead.
+			forwarded := []string{}
+			leading.Visit(func(flag *pflag.Flag) {
+				if flag.Name != "help" {
+					forwarded = append(forwarded, "--"+flag.Name+"="+flag.Value.String())
+				}
+			})
+			saved.SetArgs(append(forwarded, leading.Args()[1:]...))
+			saved.SetIn(cmd.InOrStdin())
+			saved.SetOut(cmd.OutOrStdout())
+			saved.SetErr(cmd.ErrOrStderr())
+			err = saved.ExecuteContext(cmd.Context())
+			// The saved command chose the output format, so its errors are
+			// reported through its emitter.
+			cmd.SetContext(saved.Context())
+			return err
+		},
+	}
+	return c
+}
+
+// savedCommand builds the command that replays the saved target name.
+func savedCommand(deps dependencies, name string) (*cobra.Command, error) {
+	path, err := deps.path()
+	if err != nil {
+		return nil, err
+	}
+	file, err := savedtarget.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	target, err := file.Lookup(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w in %s; list saved targets with tnnl list", err, path)
+	}
+	build, ok := cmd.Saved(target.Command)
+	if !ok {
+		return nil, fmt.Errorf(
+			"saved target %q: command must be one of %s: %q",
+			name,
+			strings.Join(cmd.SavedNames(), ", "),
+			target.Command,
+		)
+	}
+
+	aws := input.ConnectionParameter{Profile: target.AWSProfile, Region: target.Region}
+	saved := build(input.SavedSource(name, target.Input, aws))
+	saved.Use = "run " + name
+	saved.SilenceErrors = true
+	saved.SilenceUsage = true
+	globalflag.Register(saved.PersistentFlags())
+	saved.PersistentPreRunE = cmd.Prepare
+	return saved, nil
+}
+
+var RunCmd = newRunCommand(productionDependencies())
+
+func init() {
+	cmd.RootCmd.AddCommand(RunCmd)
+}
@@ -0,0 +1,215 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wim-web/tnnl/cmd"
+	"github.com/wim-web/tnnl/cmd/globalflag"
+	"github.com/wim-web/tnnl/internal/event"
+	"github.com/wim-web/tnnl/internal/input"
+)
+
+const fakeCommandName = "fake-exec"
+
+// fakeRuns records the input each run of the fake saved command resolved,
+// and fakeEvents whether each run reported JSON events.
+var (
+	fakeRuns   []input.ExecInput
+	fakeEvents []bool
+)
+
+func init() {
+	cmd.RegisterSaved(fakeCommandName, func(source input.Source) *cobra.Command {
+		c := &cobra.Command{
+			Use: fakeCommandName,
+			RunE: func(c *cobra.Command, _ []string) error {
+				connection, err := globalflag.Connection(c)
+				if err != nil {
+					return err
+				}
+				overrides := input.ExecOverrides{Connection: connection}
+				if c.Flags().Changed("command") {
+					value, _ := c.Flags().GetString("command")
+					overrides.Command = &value
+				}
+				resolved, err := input.ResolveExecFrom(source, overrides)
+				if err != nil {
+					return err
+				}
+				fakeRuns = append(fakeRuns, resolved)
+				fakeEvents = append(fakeEvents, event.FromContext(c.Context()).Enabled())
+				return nil
+			},
+		}
+		c.Flags().String("command", "sh", "command")
+		return c
+	})
+}
+
+type testEnv struct {
+	deps dependencies
+}
+
+func newTestEnv(t *testing.T, content string) *testEnv {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "targets.json")
+	if content != "" {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fakeRuns, fakeEvents = nil, nil
+	return &testEnv{deps: dependencies{path: func() (string, error) { return path, nil }}}
+}
+
+const savedTargets = `{"targets": {
+	"api": {
+		"command": "fake-exec",
+		"aws_profile": "prod",
+		"region": "ap-northeast-1",
+		"input": {"cluster": "production", "service": "api", "command": "bash"}
+	},
+	"broken": {"command": "deploy"}
+}}`
+
+func TestRunCommandAppliesFlagsOverSavedTarget(t *testing.T) {
+	env := newTestEnv(t, savedTargets)
+	command := newRunCommand(env.deps)
+	command.SetArgs([]string{"--session-client", "native", "api", "--command", "zsh", "--region", "us-east-1"})
+
+	if err := command.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("ExecuteContext() error = %v", err)
+	}
+	want := []input.ExecInput{{
+		EcsParameter:        input.EcsParameter{Cluster: "production", Service: "api"},
+		ConnectionParameter: input.ConnectionParameter{SessionClient: "native", Profile: "prod", Region: "us-east-1"},
+		Cmd:                 "zsh",
+	}}
+	if !reflect.DeepEqual(fakeRuns, want) {
+		t.Fatalf("saved runs = %#v, want %#v", fakeRuns, want)
+	}
+}
+
+func TestRunCommandUsesSavedValuesWithoutFlags(t *testing.T) {
+	env := newTestEnv(t, savedTargets)
+	command := newRunCommand(env.deps)
+	command.SetArgs([]string{"api"})
+
+	if err := command.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("ExecuteContext() error = %v", err)
+	}
+	if len(fakeRuns) != 1 || fakeRuns[0].Cmd != "bash" || fakeRuns[0].Region != "ap-northeast-1" {
+		t.Fatalf("saved runs = %#v, want the saved command and Region", fakeRuns)
+	}
+	if !reflect.DeepEqual(fakeEvents, []bool{false}) {
+		t.Fatalf("saved runs reported events = %v, want text output", fakeEvents)
+	}
+}
+
+func TestRunCommandPassesOutputFormatOn(t *testing.T) {
+	for _, args := range [][]string{{"--output", "json", "api"}, {"api", "--output=json"}} {
+		env := newTestEnv(t, savedTargets)
+		command := newRunCommand(env.deps)
+		command.SetArgs(args)
+
+		if err := command.ExecuteContext(context.Background()); err != nil {
+			t.Fatalf("ExecuteContext(%v) error = %v", args, err)
+		}
+		if !reflect.DeepEqual(fakeEvents, []bool{true}) {
+			t.Fatalf("saved runs of %v reported events = %v, want JSON output", args, fakeEvents)
+		}
+		if !event.FromContext(command.Context()).Enabled() {
+			t.Fatalf("run %v leaves no emitter on its context to report errors through", args)
+		}
+	}
+}
+
+func TestRunCommandRejectsUnknownTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "missing name", args: []string{}, wantErr: "saved target name is required"},
+		{name: "unknown target", args: []string{"web"}, wantErr: `saved target "web" not found`},
+		{name: "unknown command", args: []string{"broken"}, wantErr: `command must be one of`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, savedTargets)
+			command := newRunCommand(env.deps)
+			command.SetOut(io.Discard)
+			command.SetErr(io.Discard)
+			command.SetArgs(tt.args)
+
+			err := command.ExecuteContext(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ExecuteContext() error = %v, want %q", err, tt.wantErr)
+			}
+			if len(fakeRuns) != 0 {
+				t.Fatalf("saved runs = %v, want none", fakeRuns)
+			}
+		})
+	}
+}
+
+func TestListCommandPrintsSavedTargets(t *testing.T) {
+	env := newTestEnv(t, savedTargets)
+	var stdout bytes.Buffer
+	command := newListCommand(env.deps)
+	command.SetOut(&stdout)
+	command.SetArgs([]string{})
+
+	if err := command.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("ExecuteContext() error = %v", err)
+	}
+	want := "NAME    COMMAND    AWS_PROFILE  REGION          CLUSTER     SERVICE\n" +
+		"api     fake-exec  prod         ap-northeast-1  production  api\n" +
+		"broken  deploy                                              \n"
+	if stdout.String() != want {
+		t.Fatalf("list output = %q, want %q", stdout.String(), want)
+	}
+}
+
+func TestListCommandEmitsSavedTargetEvents(t *testing.T) {
+	env := newTestEnv(t, savedTargets)
+	var stdout bytes.Buffer
+	command := newListCommand(env.deps)
+	command.SetOut(io.Discard)
+	command.SetArgs([]string{})
+	events := event.NewEmitter(&stdout, func() time.Time { return time.Unix(0, 0) })
+
+	if err := command.ExecuteContext(event.NewContext(context.Background(), events)); err != nil {
+		t.Fatalf("ExecuteContext() error = %v", err)
+	}
+	want := `{"time":"1970-01-01T00:00:00Z","event":"saved_target","name":"api","command":"fake-exec","aws_profile":"prod","region":"ap-northeast-1","cluster":"production","service":"api"}
+{"time":"1970-01-01T00:00:00Z","event":"saved_target","name":"broken","command":"deploy"}
+`
+	if stdout.String() != want {
+		t.Fatalf("list events =\n%s\nwant\n%s", stdout.String(), want)
+	}
+}
+
+func TestListCommandReportsEmptyConfig(t *testing.T) {
+	env := newTestEnv(t, "")
+	var stdout bytes.Buffer
+	command := newListCommand(env.deps)
+	command.SetOut(&stdout)
+	command.SetArgs([]string{})
+
+	if err := command.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("ExecuteContext() error = %v", err)
+	}
+	if !strings.HasPrefix(stdout.String(), "no saved targets in ") {
+		t.Fatalf("list output = %q", stdout.String())
+	}
+}
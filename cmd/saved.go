@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"slices"
+
+	"github.com/spf13/cobra"
+	"github.com/wim-web/tnnl/internal/input"
+)
+
+// SavedCommand builds a fresh copy of a session command whose input comes
+// from source instead of --input-file, so tnnl run can replay a saved target
+// while the command's own flags still take precedence.
+type SavedCommand func(source input.Source) *cobra.Command
+
+var savedCommands = map[string]SavedCommand{}
+
+// RegisterSaved makes the command name available to saved targets.
+func RegisterSaved(name string, build SavedCommand) {
+	savedCommands[name] = build
+}
+
+// Saved returns the builder registered for name.
+func Saved(name string) (SavedCommand, bool) {
+	build, ok := savedCommands[name]
+	return build, ok
+}
+
+// SavedNames returns the registered command names in sorted order.
+func SavedNames() []string {
+	names := make([]string, 0, len(savedCommands))
+	for name := range savedCommands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
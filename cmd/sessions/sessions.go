@@ -0,0 +1,93 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+
+	"github.com/spf13/cobra"
+	"github.com/wim-web/tnnl/cmd"
+	"github.com/wim-web/tnnl/cmd/globalflag"
+	"github.com/wim-web/tnnl/internal/handler"
+	"github.com/wim-web/tnnl/internal/input"
+)
+
+var allMineName = "all-mine"
+
+type sessionsRunner func(context.Context, input.SessionsInput) error
+
+func newSessionsCommand(list, terminate sessionsRunner) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "sessions",
+		Short: "List or terminate your active Session Manager sessions on ECS tasks",
+		Long: "Session Manager keeps a session open until it is terminated or times out.\n" +
+			"tnnl terminates its sessions when they end, but a terminal that dies can leave one behind.\n" +
+			"tnnl sessions list shows the active sessions on ECS tasks started by the caller,\n" +
+			"and tnnl sessions terminate ends them.",
+	}
+	c.AddCommand(newListCommand(list), newTerminateCommand(terminate))
+	return c
+}
+
+func newListCommand(run sessionsRunner) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List your active Session Manager sessions on ECS tasks",
+		Long: "List the active Session Manager sessions on ECS tasks started by the caller,\n" +
+			"the identity sts:GetCallerIdentity returns for the current credentials, with the\n" +
+			"cluster, task, and container runtime ID of each target.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resolved, err := resolveSessions(cmd, nil)
+			if err != nil {
+				return err
+			}
+			return run(cmd.Context(), resolved)
+		},
+	}
+}
+
+func newTerminateCommand(run sessionsRunner) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "terminate [SESSION_ID...]",
+		Short: "Terminate Session Manager sessions",
+		Long: "Terminate the named Session Manager sessions, or with --all-mine every active\n" +
+			"session on ECS tasks started by the caller. A failure to terminate one session\n" +
+			"does not stop the others.",
+		Example: "  tnnl sessions terminate alice-0123456789abcdef0\n" +
+			"  tnnl sessions terminate --all-mine",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resolved, err := resolveSessions(cmd, args)
+			if err != nil {
+				return err
+			}
+			if len(resolved.SessionIDs) == 0 && !resolved.AllMine {
+				return errors.New("a session ID or --all-mine is required")
+			}
+			return run(cmd.Context(), resolved)
+		},
+	}
+	c.Flags().Bool(allMineName, false, "terminate every active session on ECS tasks started by the caller")
+	return c
+}
+
+func resolveSessions(cmd *cobra.Command, ids []string) (input.SessionsInput, error) {
+	connection, err := globalflag.Connection(cmd)
+	if err != nil {
+		return input.SessionsInput{}, err
+	}
+	overrides := input.SessionsOverrides{Connection: connection, SessionIDs: ids}
+	if cmd.Flags().Changed(allMineName) {
+		value, err := cmd.Flags().GetBool(allMineName)
+		if err != nil {
+			return input.SessionsInput{}, err
+		}
+		overrides.AllMine = &value
+	}
+	return input.ResolveSessions(overrides)
+}
+
+var SessionsCmd = newSessionsCommand(handler.SessionsListHandler, handler.SessionsTerminateHandler)
+
+func init() {
+	cmd.RootCmd.AddCommand(SessionsCmd)
+}
@@ -0,0 +1,67 @@
+package sessions
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/wim-web/tnnl/internal/input"
+)
+
+func newTestCommand(got *[]input.SessionsInput) *cobra.Command {
+	record := func(_ context.Context, in input.SessionsInput) error {
+		*got = append(*got, in)
+		return nil
+	}
+	return newSessionsCommand(record, record)
+}
+
+func TestSessionsCommandsResolveInput(t *testing.T) {
+	tests := []struct {
+		args []string
+		want input.SessionsInput
+	}{
+		{args: []string{"list"}, want: input.SessionsInput{}},
+		{args: []string{"terminate", "alice-1", " alice-2 "}, want: input.SessionsInput{SessionIDs: []string{"alice-1", "alice-2"}}},
+		{args: []string{"terminate", "--all-mine"}, want: input.SessionsInput{AllMine: true}},
+	}
+	for _, tt := range tests {
+		var got []input.SessionsInput
+		command := newTestCommand(&got)
+		command.SetArgs(tt.args)
+
+		if err := command.ExecuteContext(context.Background()); err != nil {
+			t.Fatalf("ExecuteContext(%q) error = %v", tt.args, err)
+		}
+		if !reflect.DeepEqual(got, []input.SessionsInput{tt.want}) {
+			t.Fatalf("ExecuteContext(%q) runner input = %#v, want %#v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestSessionsTerminateRejectsInvalidSelection(t *testing.T) {
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{args: []string{"terminate"}, wantErr: "a session ID or --all-mine is required"},
+		{args: []string{"terminate", "--all-mine", "alice-1"}, wantErr: "mutually exclusive"},
+		{args: []string{"list", "alice-1"}, wantErr: "unknown command"},
+	}
+	for _, tt := range tests {
+		var got []input.SessionsInput
+		command := newTestCommand(&got)
+		command.SetArgs(tt.args)
+		command.SilenceUsage, command.SilenceErrors = true, true
+
+		err := command.ExecuteContext(context.Background())
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Fatalf("ExecuteContext(%q) error = %v, want %q", tt.args, err, tt.wantErr)
+		}
+		if len(got) != 0 {
+			t.Fatalf("ExecuteContext(%q) ran %#v", tt.args, got)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package targetflag
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/wim-web/tnnl/internal/input"
+)
+
+var ClusterName = "cluster"
+var ServiceName = "service"
+var TaskName = "task"
+var ContainerName = "container"
+var FamilyName = "family"
+var StrategyName = "strategy"
+var ShowIneligibleName = "show-ineligible"
+
+// Register adds the cluster, the service, and the non-interactive task and
+// container selectors to flags.
+func Register(flags *pflag.FlagSet) {
+	flags.String(ClusterName, "", "ECS cluster name or ARN to use without the cluster chooser; precedence: explicit flag > input JSON > default")
+	flags.String(ServiceName, "", "ECS service whose tasks to list without the service chooser; precedence: explicit flag > input JSON > default")
+	flags.String(TaskName, "", "task ID or ARN to select without the task chooser; precedence: explicit flag > input JSON > default")
+	flags.String(ContainerName, "", "container name to select without the container chooser; precedence: explicit flag > input JSON > default")
+	flags.String(FamilyName, "", "task definition family that eligible tasks must use; precedence: explicit flag > input JSON > default")
+	flags.String(StrategyName, "", "first, random, or newest: pick among several matching tasks instead of failing; precedence: explicit flag > input JSON > default")
+	flags.Bool(ShowIneligibleName, false, "list tasks and containers that cannot be used, greyed out with the reason, and explain an empty result; precedence: explicit flag > input JSON > default")
+}
+
+// ECS returns the cluster, service, and selector flags explicitly set for c.
+func ECS(c *cobra.Command) (input.EcsOverrides, error) {
+	overrides := input.EcsOverrides{}
+	for _, flag := range []struct {
+		name   string
+		target **string
+	}{
+		{ClusterName, &overrides.Cluster},
+		{ServiceName, &overrides.Service},
+		{TaskName, &overrides.Task},
+		{ContainerName, &overrides.Container},
+		{FamilyName, &overrides.Family},
+		{StrategyName, &overrides.Strategy},
+	} {
+		if !c.Flags().Changed(flag.name) {
+			continue
+		}
+		value, err := c.Flags().GetString(flag.name)
+		if err != nil {
+			return input.EcsOverrides{}, err
+		}
+		*flag.target = &value
+	}
+	if c.Flags().Changed(ShowIneligibleName) {
+		value, err := c.Flags().GetBool(ShowIneligibleName)
+		if err != nil {
+			return input.EcsOverrides{}, err
+		}
+		overrides.ShowIneligible = &value
+	}
+	return overrides, nil
+}
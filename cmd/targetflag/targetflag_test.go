@@ -0,0 +1,55 @@
+package targetflag
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/wim-web/tnnl/internal/input"
+)
+
+func TestECSReturnsOnlyExplicitFlags(t *testing.T) {
+	var got input.EcsOverrides
+	c := &cobra.Command{
+		Use: "child",
+		RunE: func(c *cobra.Command, _ []string) error {
+			var err error
+			got, err = ECS(c)
+			return err
+		},
+	}
+	Register(c.Flags())
+	c.SetArgs([]string{"--cluster", "production", "--service", "web", "--task", "abc", "--strategy", "", "--show-ineligible"})
+
+	if err := c.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("ExecuteContext() error = %v", err)
+	}
+	if got.Cluster == nil || *got.Cluster != "production" {
+		t.Fatalf("Cluster = %v, want production", got.Cluster)
+	}
+	if got.Service == nil || *got.Service != "web" {
+		t.Fatalf("Service = %v, want web", got.Service)
+	}
+	if got.Task == nil || *got.Task != "abc" {
+		t.Fatalf("Task = %v, want abc", got.Task)
+	}
+	if got.Strategy == nil || *got.Strategy != "" {
+		t.Fatalf("Strategy = %v, want explicit empty value", got.Strategy)
+	}
+	if got.ShowIneligible == nil || !*got.ShowIneligible {
+		t.Fatalf("ShowIneligible = %v, want true", got.ShowIneligible)
+	}
+	if got.Container != nil || got.Family != nil {
+		t.Fatalf("Container/Family = %v/%v, want omitted", got.Container, got.Family)
+	}
+}
+
+func TestECSWithoutRegisteredFlagsReturnsNoOverrides(t *testing.T) {
+	got, err := ECS(&cobra.Command{Use: "standalone"})
+	if err != nil {
+		t.Fatalf("ECS() error = %v", err)
+	}
+	if got != (input.EcsOverrides{}) {
+		t.Fatalf("ECS() = %#v, want no overrides", got)
+	}
+}
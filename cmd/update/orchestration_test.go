@@ -17,6 +17,8 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/wim-web/tnnl/internal/event"
 )
 
 const (
@@ -176,6 +178,20 @@ func TestUpdaterAlreadyLatestMakesNoAssetRequests(t *testing.T) {
 	fixture.assertCurrentUnchanged(t)
 }
 
+func TestUpdaterReportsUpdateEvent(t *testing.T) {
+	fixture := newUpdaterFixture(t, "1.2.3", "1.2.3")
+	var output bytes.Buffer
+	events := event.NewEmitter(&output, func() time.Time { return time.Unix(0, 0) })
+
+	if err := fixture.updater().run(event.NewContext(context.Background(), events), io.Discard); err != nil {
+		t.Fatalf("updater.run() error = %v", err)
+	}
+	want := `{"time":"1970-01-01T00:00:00Z","event":"update","current_version":"v1.2.3","latest_version":"v1.2.3","updated":false}` + "\n"
+	if got := output.String(); got != want {
+		t.Fatalf("update events = %q, want %q", got, want)
+	}
+}
+
 func TestUpdaterReturnsOutputError(t *testing.T) {
 	fixture := newUpdaterFixture(t, "1.2.3", "1.2.3")
 	wantErr := errors.New("write failed")
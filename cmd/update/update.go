@@ -19,6 +19,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/wim-web/tnnl/cmd"
 	"github.com/wim-web/tnnl/internal/buildinfo"
+	"github.com/wim-web/tnnl/internal/event"
 )
 
 const (
@@ -95,10 +96,7 @@ func (u updater) run(ctx context.Context, out io.Writer) error {
 	}
 	latestVersion := normalizeVersion(latest.TagName)
 	if current == latestVersion {
-		if _, err := fmt.Fprintf(out, "already latest version: %s\n", latest.TagName); err != nil {
-			return fmt.Errorf("write update status: %w", err)
-		}
-		return nil
+		return writeStatus(ctx, out, current, latest.TagName, false)
 	}
 
 	assetName := fmt.Sprintf("%s_%s_%s.tar.gz", binaryName, u.goos, u.goarch)
@@ -148,7 +146,25 @@ func (u updater) run(ctx context.Context, out io.Writer) error {
 		return err
 	}
 
-	if _, err := fmt.Fprintf(out, "updated: v%s -> %s\n", current, latest.TagName); err != nil {
+	return writeStatus(ctx, out, current, latest.TagName, true)
+}
+
+// writeStatus reports whether the release tagged latest replaced the current
+// version, as an update event for --output json.
+func writeStatus(ctx context.Context, out io.Writer, current, latest string, updated bool) error {
+	var err error
+	if events := event.FromContext(ctx); events.Enabled() {
+		err = events.Emit(event.Update, struct {
+			Current string `json:"current_version"`
+			Latest  string `json:"latest_version"`
+			Updated bool   `json:"updated"`
+		}{Current: "v" + current, Latest: latest, Updated: updated})
+	} else if updated {
+		_, err = fmt.Fprintf(out, "updated: v%s -> %s\n", current, latest)
+	} else {
+		_, err = fmt.Fprintf(out, "already latest version: %s\n", latest)
+	}
+	if err != nil {
 		return fmt.Errorf("write update status: %w", err)
 	}
 	return nil
@@ -0,0 +1,167 @@
+// Package audit keeps the local append-only log of the sessions tnnl opens,
+// one JSON object per line, and reads it back for tnnl history.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	appName  = "tnnl"
+	fileName = "audit.jsonl"
+)
+
+// Entry records one session. Input is the command input that re-runs it,
+// with the cluster, service, and container it resolved to filled in.
+type Entry struct {
+	// ID is the entry's line number in the log. It is not stored.
+	ID int `json:"-"`
+
+	Time       time.Time `json:"time"`
+	Duration   float64   `json:"duration_seconds"`
+	Caller     string    `json:"caller_arn,omitempty"`
+	Account    string    `json:"account,omitempty"`
+	Region     string    `json:"region"`
+	Command    string    `json:"command"`
+	Cluster    string    `json:"cluster"`
+	Task       string    `json:"task"`
+	Container  string    `json:"container"`
+	Document   string    `json:"document"`
+	Remote     string    `json:"remote_command,omitempty"`
+	LocalPort  string    `json:"local_port,omitempty"`
+	RemotePort string    `json:"remote_port,omitempty"`
+	Host       string    `json:"host,omitempty"`
+	SessionID  string    `json:"session_id"`
+	// ExitStatus is tnnl's exit status for the session; in batch mode it is
+	// the remote command's.
+	ExitStatus int             `json:"exit_status"`
+	Error      string          `json:"error,omitempty"`
+	Input      json.RawMessage `json:"input,omitempty"`
+}
+
+// Path returns $XDG_STATE_HOME/tnnl/audit.jsonl, falling back to
+// ~/.local/state when XDG_STATE_HOME is unset.
+func Path() (string, error) {
+	return path(os.Getenv, os.UserHomeDir)
+}
+
+func path(getenv func(string) string, home func() (string, error)) (string, error) {
+	if dir := getenv("XDG_STATE_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, appName, fileName), nil
+	}
+	dir, err := home()
+	if err != nil {
+		return "", fmt.Errorf("locate audit log: %w", err)
+	}
+	return filepath.Join(dir, ".local", "state", appName, fileName), nil
+}
+
+// Append adds entry to the log at path, creating it readable only by the
+// user. Each entry is a single write, so concurrent tnnl processes do not
+// interleave their lines.
+func Append(path string, entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encode audit entry: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create audit log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("open audit log: %w", err)
+	}
+	_, writeErr := file.Write(append(line, '\n'))
+	if err := errors.Join(writeErr, file.Close()); err != nil {
+		return fmt.Errorf("write audit log %s: %w", path, err)
+	}
+	return nil
+}
+
+// Read returns every entry of the log at path in the order they were
+// written. A missing log has no entries.
+func Read(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("decode audit log %s line %d: %w", path, line, err)
+		}
+		entry.ID = line
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read audit log %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// Lookup returns the entry with id.
+func Lookup(entries []Entry, id int) (Entry, error) {
+	for _, entry := range entries {
+		if entry.ID == id {
+			return entry, nil
+		}
+	}
+	return Entry{}, fmt.Errorf("audit entry %d not found", id)
+}
+
+// Filter selects entries. Empty fields match every entry; Cluster and Task
+// also match a name or ID contained in the recorded ARN.
+type Filter struct {
+	Since     time.Time
+	Command   string
+	Cluster   string
+	Task      string
+	Container string
+	Caller    string
+	Failed    bool
+}
+
+// Match reports whether entry passes the filter.
+func (f Filter) Match(entry Entry) bool {
+	return (f.Since.IsZero() || !entry.Time.Before(f.Since)) &&
+		(f.Command == "" || entry.Command == f.Command) &&
+		(f.Cluster == "" || strings.Contains(entry.Cluster, f.Cluster)) &&
+		(f.Task == "" || strings.Contains(entry.Task, f.Task)) &&
+		(f.Container == "" || entry.Container == f.Container) &&
+		(f.Caller == "" || strings.Contains(entry.Caller, f.Caller)) &&
+		(!f.Failed || entry.ExitStatus != 0)
+}
+
+// Select returns the entries passing filter, keeping only the last limit
+// when limit is positive.
+func Select(entries []Entry, filter Filter, limit int) []Entry {
+	var selected []Entry
+	for _, entry := range entries {
+		if filter.Match(entry) {
+			selected = append(selected, entry)
+		}
+	}
+	if limit > 0 && len(selected) > limit {
+		selected = selected[len(selected)-limit:]
+	}
+	return selected
+}
@@ -0,0 +1,126 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPathUsesXDGStateHomeOrHome(t *testing.T) {
+	tests := []struct {
+		name  string
+		state string
+		want  string
+	}{
+		{name: "XDG_STATE_HOME", state: "/state", want: "/state/tnnl/audit.jsonl"},
+		{name: "relative XDG_STATE_HOME", state: "state", want: "/home/user/.local/state/tnnl/audit.jsonl"},
+		{name: "unset", want: "/home/user/.local/state/tnnl/audit.jsonl"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := path(func(string) string { return tt.state }, func() (string, error) { return "/home/user", nil })
+			if err != nil || got != tt.want {
+				t.Fatalf("path() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+	if _, err := path(func(string) string { return "" }, func() (string, error) { return "", errors.New("no home") }); err == nil {
+		t.Fatal("path() error = nil, want home directory error")
+	}
+}
+
+func TestAppendAndReadRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "audit.jsonl")
+	first := Entry{
+		Time:      time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Duration:  12.5,
+		Caller:    "arn:aws:sts::123456789012:assumed-role/dev/alice",
+		Account:   "123456789012",
+		Region:    "ap-northeast-1",
+		Command:   "exec",
+		Cluster:   "arn:aws:ecs:ap-northeast-1:123456789012:cluster/production",
+		Task:      "arn:aws:ecs:ap-northeast-1:123456789012:task/production/abc",
+		Container: "app",
+		Document:  "AmazonECS-ExecuteInteractiveCommand",
+		Remote:    "sh",
+		SessionID: "session-1",
+		Input:     json.RawMessage(`{"command":"sh"}`),
+	}
+	second := Entry{Time: first.Time.Add(time.Hour), Command: "portforward", LocalPort: "15432", RemotePort: "5432", ExitStatus: 1, Error: "boom"}
+	for _, entry := range []Entry{first, second} {
+		if err := Append(path, entry); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	entries, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	first.ID, second.ID = 1, 2
+	if !reflect.DeepEqual(entries, []Entry{first, second}) {
+		t.Fatalf("Read() = %#v, want both entries with IDs", entries)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("audit log mode = %v, %v, want 0600", info.Mode(), err)
+	}
+}
+
+func TestReadMissingAndMalformedLogs(t *testing.T) {
+	dir := t.TempDir()
+	if entries, err := Read(filepath.Join(dir, "missing.jsonl")); err != nil || entries != nil {
+		t.Fatalf("Read(missing) = %#v, %v, want no entries", entries, err)
+	}
+	path := filepath.Join(dir, "audit.jsonl")
+	if err := os.WriteFile(path, []byte(`{"command":"exec"}`+"\nnot json\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(path); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("Read(malformed) error = %v, want line 2", err)
+	}
+}
+
+func TestSelectFiltersAndLimits(t *testing.T) {
+	base := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{ID: 1, Time: base, Command: "exec", Cluster: "arn:aws:ecs:r:1:cluster/production", Task: "arn:aws:ecs:r:1:task/production/aaa"},
+		{ID: 2, Time: base.Add(time.Hour), Command: "portforward", Cluster: "arn:aws:ecs:r:1:cluster/staging", ExitStatus: 1},
+		{ID: 3, Time: base.Add(2 * time.Hour), Command: "exec", Cluster: "arn:aws:ecs:r:1:cluster/production", Task: "arn:aws:ecs:r:1:task/production/bbb"},
+	}
+	tests := []struct {
+		name   string
+		filter Filter
+		limit  int
+		want   []int
+	}{
+		{name: "all", want: []int{1, 2, 3}},
+		{name: "limit keeps the latest", limit: 2, want: []int{2, 3}},
+		{name: "command", filter: Filter{Command: "exec"}, want: []int{1, 3}},
+		{name: "cluster name", filter: Filter{Cluster: "production"}, want: []int{1, 3}},
+		{name: "task ID", filter: Filter{Task: "bbb"}, want: []int{3}},
+		{name: "since", filter: Filter{Since: base.Add(time.Hour)}, want: []int{2, 3}},
+		{name: "failed", filter: Filter{Failed: true}, want: []int{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, entry := range Select(entries, tt.filter, tt.limit) {
+				got = append(got, entry.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Select() IDs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if _, err := Lookup(entries, 4); err == nil {
+		t.Fatal("Lookup(4) error = nil, want not found")
+	}
+	if entry, err := Lookup(entries, 2); err != nil || entry.Command != "portforward" {
+		t.Fatalf("Lookup(2) = %#v, %v", entry, err)
+	}
+}
@@ -0,0 +1,164 @@
+// Package cast records terminal sessions as asciinema cast files (format
+// version 2) and plays them back.
+package cast
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const Version = 2
+
+// Event codes of the cast format.
+const (
+	EventOutput = "o"
+	EventInput  = "i"
+	EventResize = "r"
+)
+
+// Default terminal size for a header whose size was never reported.
+const (
+	defaultWidth  = 80
+	defaultHeight = 24
+)
+
+// Header is the first line of a cast file.
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Command   string            `json:"command,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Recorder writes the traffic of one terminal session to a cast file. The
+// header is written with the first event, so a size reported before any
+// traffic becomes the recording's terminal size. It is safe for concurrent
+// use.
+type Recorder struct {
+	mu      sync.Mutex
+	w       io.Writer
+	header  Header
+	started bool
+	start   time.Time
+	now     func() time.Time
+	// pending holds the start of a UTF-8 sequence split across writes, per
+	// event code, since each event must be a whole string.
+	pending map[string][]byte
+	err     error
+}
+
+// NewRecorder returns a Recorder writing to w. Timestamp is filled in from
+// now when the header leaves it zero.
+func NewRecorder(w io.Writer, header Header, now func() time.Time) *Recorder {
+	header.Version = Version
+	start := now()
+	if header.Timestamp == 0 {
+		header.Timestamp = start.Unix()
+	}
+	return &Recorder{w: w, header: header, start: start, now: now, pending: make(map[string][]byte)}
+}
+
+// Output records bytes written to the terminal.
+func (r *Recorder) Output(p []byte) {
+	r.event(EventOutput, p)
+}
+
+// Input records bytes typed at the terminal.
+func (r *Recorder) Input(p []byte) {
+	r.event(EventInput, p)
+}
+
+// Resize records a change of the terminal size.
+func (r *Recorder) Resize(cols, rows int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.started {
+		r.header.Width, r.header.Height = cols, rows
+		return
+	}
+	r.write(EventResize, fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// Close flushes any partial UTF-8 sequence and writes the header of a
+// recording without events. It returns the first write error.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, code := range []string{EventInput, EventOutput} {
+		if rest := r.pending[code]; len(rest) > 0 {
+			r.pending[code] = nil
+			r.write(code, string(rest))
+		}
+	}
+	r.writeHeader()
+	return r.err
+}
+
+func (r *Recorder) event(code string, p []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data := append(r.pending[code], p...)
+	cut := completeUTF8(data)
+	r.pending[code] = append([]byte(nil), data[cut:]...)
+	if cut > 0 {
+		r.write(code, string(data[:cut]))
+	}
+}
+
+func (r *Recorder) write(code, data string) {
+	r.writeHeader()
+	elapsed := r.now().Sub(r.start).Seconds()
+	line, err := json.Marshal([]any{json.Number(fmt.Sprintf("%.6f", elapsed)), code, data})
+	if err == nil {
+		err = r.writeLine(line)
+	}
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *Recorder) writeHeader() {
+	if r.started {
+		return
+	}
+	r.started = true
+	if r.header.Width <= 0 || r.header.Height <= 0 {
+		r.header.Width, r.header.Height = defaultWidth, defaultHeight
+	}
+	line, err := json.Marshal(r.header)
+	if err == nil {
+		err = r.writeLine(line)
+	}
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *Recorder) writeLine(line []byte) error {
+	if r.err != nil {
+		return r.err
+	}
+	_, err := r.w.Write(append(line, '\n'))
+	return err
+}
+
+// completeUTF8 returns the length of data without a trailing incomplete UTF-8
+// sequence. Invalid bytes count as complete.
+func completeUTF8(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(data)
+}
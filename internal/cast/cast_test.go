@@ -0,0 +1,77 @@
+package cast
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// stepClock advances by step every time it is read.
+type stepClock struct {
+	now  time.Time
+	step time.Duration
+}
+
+func (c *stepClock) Now() time.Time {
+	now := c.now
+	c.now = c.now.Add(c.step)
+	return now
+}
+
+func TestRecorderWritesHeaderAndEvents(t *testing.T) {
+	clock := &stepClock{now: time.Unix(1700000000, 0), step: 500 * time.Millisecond}
+	var out strings.Builder
+	recorder := NewRecorder(&out, Header{Command: "sh"}, clock.Now)
+
+	recorder.Resize(120, 40)
+	recorder.Output([]byte("$ "))
+	recorder.Input([]byte("ls\r"))
+	recorder.Resize(100, 30)
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	want := `{"version":2,"width":120,"height":40,"timestamp":1700000000,"command":"sh"}
+[0.500000,"o","$ "]
+[1.000000,"i","ls\r"]
+[1.500000,"r","100x30"]
+`
+	if out.String() != want {
+		t.Fatalf("cast =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestRecorderKeepsSplitUTF8Together(t *testing.T) {
+	clock := &stepClock{now: time.Unix(1700000000, 0), step: time.Second}
+	var out strings.Builder
+	recorder := NewRecorder(&out, Header{}, clock.Now)
+
+	word := []byte("日本")
+	recorder.Output(word[:2])
+	recorder.Output(word[2:4])
+	recorder.Output(word[4:])
+	recorder.Output([]byte{0xe6})
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	want := `{"version":2,"width":80,"height":24,"timestamp":1700000000}
+[1.000000,"o","日"]
+[2.000000,"o","本"]
+[3.000000,"o","�"]
+`
+	if out.String() != want {
+		t.Fatalf("cast =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestRecorderWritesHeaderWithoutEvents(t *testing.T) {
+	clock := &stepClock{now: time.Unix(1700000000, 0)}
+	var out strings.Builder
+	if err := NewRecorder(&out, Header{Width: 90, Height: 20}, clock.Now).Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if want := `{"version":2,"width":90,"height":20,"timestamp":1700000000}` + "\n"; out.String() != want {
+		t.Fatalf("cast = %q, want %q", out.String(), want)
+	}
+}
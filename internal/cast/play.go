@@ -0,0 +1,93 @@
+package cast
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// PlayOptions controls the pace of Play. Speed divides every delay and
+// defaults to 1; a positive IdleLimit caps each delay before that.
+type PlayOptions struct {
+	Speed     float64
+	IdleLimit time.Duration
+}
+
+// Play writes the output events of the cast read from r to w at their
+// recorded pace and returns its header. Input and resize events are not
+// played back.
+func Play(ctx context.Context, r io.Reader, w io.Writer, options PlayOptions) (Header, error) {
+	return play(ctx, r, w, options, sleep)
+}
+
+func play(
+	ctx context.Context,
+	r io.Reader,
+	w io.Writer,
+	options PlayOptions,
+	wait func(context.Context, time.Duration) error,
+) (Header, error) {
+	if options.Speed <= 0 {
+		options.Speed = 1
+	}
+	decoder := json.NewDecoder(r)
+	var header Header
+	if err := decoder.Decode(&header); err != nil {
+		return Header{}, fmt.Errorf("read cast header: %w", err)
+	}
+	if header.Version != Version {
+		return Header{}, fmt.Errorf("cast version %d is not supported; want %d", header.Version, Version)
+	}
+
+	var last float64
+	for line := 2; ; line++ {
+		var raw []json.RawMessage
+		if err := decoder.Decode(&raw); errors.Is(err, io.EOF) {
+			return header, nil
+		} else if err != nil {
+			return header, fmt.Errorf("read cast event %d: %w", line, err)
+		}
+		var (
+			at   float64
+			code string
+			data string
+		)
+		if len(raw) != 3 ||
+			json.Unmarshal(raw[0], &at) != nil ||
+			json.Unmarshal(raw[1], &code) != nil ||
+			json.Unmarshal(raw[2], &data) != nil {
+			return header, fmt.Errorf("read cast event %d: want [time, code, data]", line)
+		}
+		if code != EventOutput {
+			continue
+		}
+
+		delay := time.Duration((at - last) * float64(time.Second))
+		last = at
+		if options.IdleLimit > 0 {
+			delay = min(delay, options.IdleLimit)
+		}
+		if delay > 0 {
+			if err := wait(ctx, time.Duration(float64(delay)/options.Speed)); err != nil {
+				return header, err
+			}
+		}
+		if _, err := io.WriteString(w, data); err != nil {
+			return header, err
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
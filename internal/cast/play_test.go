@@ -0,0 +1,89 @@
+package cast
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testCast = `{"version":2,"width":80,"height":24,"timestamp":1700000000}
+[0.5,"o","$ "]
+[1.0,"i","ls\r"]
+[1.5,"o","ls\r\n"]
+[11.5,"r","100x30"]
+[21.5,"o","done\r\n"]
+`
+
+func TestPlayWritesOutputAtRecordedPace(t *testing.T) {
+	tests := []struct {
+		name       string
+		options    PlayOptions
+		wantDelays []time.Duration
+	}{
+		{
+			name:       "recorded pace",
+			wantDelays: []time.Duration{500 * time.Millisecond, time.Second, 20 * time.Second},
+		},
+		{
+			name:       "double speed with idle limit",
+			options:    PlayOptions{Speed: 2, IdleLimit: 2 * time.Second},
+			wantDelays: []time.Duration{250 * time.Millisecond, 500 * time.Millisecond, time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var delays []time.Duration
+			var out strings.Builder
+			header, err := play(context.Background(), strings.NewReader(testCast), &out, tt.options, func(_ context.Context, d time.Duration) error {
+				delays = append(delays, d)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("play() error = %v", err)
+			}
+			if header.Width != 80 || header.Height != 24 {
+				t.Fatalf("header = %#v, want 80x24", header)
+			}
+			if want := "$ ls\r\ndone\r\n"; out.String() != want {
+				t.Fatalf("output = %q, want %q", out.String(), want)
+			}
+			if !reflect.DeepEqual(delays, tt.wantDelays) {
+				t.Fatalf("delays = %v, want %v", delays, tt.wantDelays)
+			}
+		})
+	}
+}
+
+func TestPlayRejectsInvalidCasts(t *testing.T) {
+	tests := []struct {
+		name    string
+		cast    string
+		wantErr string
+	}{
+		{name: "not JSON", cast: "hello", wantErr: "read cast header"},
+		{name: "version 1", cast: `{"version":1}`, wantErr: "cast version 1 is not supported"},
+		{name: "malformed event", cast: `{"version":2}` + "\n" + `[0.5,"o"]`, wantErr: "read cast event 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := play(context.Background(), strings.NewReader(tt.cast), &strings.Builder{}, PlayOptions{}, func(context.Context, time.Duration) error { return nil })
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("play() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlayStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var out strings.Builder
+	if _, err := Play(ctx, strings.NewReader(testCast), &out, PlayOptions{}); err != context.Canceled {
+		t.Fatalf("Play() error = %v, want %v", err, context.Canceled)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("output = %q, want nothing after cancellation", out.String())
+	}
+}
@@ -0,0 +1,333 @@
+// Package check inspects the AWS settings ECS Exec depends on for one task
+// and reports them as a checklist.
+package check
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/wim-web/tnnl/internal/target"
+)
+
+// Status is the outcome of one check.
+type Status string
+
+const (
+	StatusPass Status = "PASS"
+	StatusWarn Status = "WARN"
+	StatusFail Status = "FAIL"
+)
+
+// Result is one line of the checklist. Hint says how to fix a warning or
+// failure.
+type Result struct {
+	Name   string
+	Status Status
+	Detail string
+	Hint   string
+}
+
+// sessionActions are the permissions the task role needs for the agent to
+// open the Session Manager channels.
+var sessionActions = []string{
+	"ssmmessages:CreateControlChannel",
+	"ssmmessages:CreateDataChannel",
+	"ssmmessages:OpenControlChannel",
+	"ssmmessages:OpenDataChannel",
+}
+
+var remedyHints = map[target.Remedy]string{
+	target.RemedyWait:           "wait for the task and its containers to reach RUNNING, then check again",
+	target.RemedyDeployment:     "enable execute command on the service or run-task call and start new tasks, e.g. aws ecs update-service --enable-execute-command --force-new-deployment",
+	target.RemedyTaskDefinition: "fix the task definition and redeploy",
+	target.RemedyIAM:            "grant the task role " + strings.Join(sessionActions, ", ") + "; in private subnets the task also needs a route to ssmmessages through a VPC endpoint or NAT",
+}
+
+// ECSAPI is the subset of ECS the checks read.
+type ECSAPI interface {
+	DescribeClusters(context.Context, *ecs.DescribeClustersInput, ...func(*ecs.Options)) (*ecs.DescribeClustersOutput, error)
+	DescribeTaskDefinition(context.Context, *ecs.DescribeTaskDefinitionInput, ...func(*ecs.Options)) (*ecs.DescribeTaskDefinitionOutput, error)
+}
+
+// IAMAPI is the subset of IAM used to simulate the task role's policies.
+type IAMAPI interface {
+	SimulatePrincipalPolicy(context.Context, *iam.SimulatePrincipalPolicyInput, ...func(*iam.Options)) (*iam.SimulatePrincipalPolicyOutput, error)
+}
+
+// Checker runs the ECS Exec prerequisite checks.
+type Checker struct {
+	ecs ECSAPI
+	iam IAMAPI
+}
+
+func NewChecker(ecsClient ECSAPI, iamClient IAMAPI) *Checker {
+	return &Checker{ecs: ecsClient, iam: iamClient}
+}
+
+// Run checks the cluster, the task, its task definition, the task role's
+// permissions, and the agent of each container. A non-empty container limits
+// the container checks to that container. Failed lookups become failed
+// checks so the list is always complete.
+func (c *Checker) Run(ctx context.Context, cluster string, diagnosis target.TaskDiagnosis, container string) []Result {
+	clusterResult, execConfig := c.cluster(ctx, cluster)
+	results := []Result{clusterResult, taskResult(diagnosis)}
+
+	definitionResults, roleARN := c.taskDefinition(ctx, diagnosis.Task, container)
+	results = append(results, definitionResults...)
+	if roleARN != "" {
+		results = append(results, c.rolePermissions(ctx, roleARN, execConfig)...)
+	}
+	return append(results, containerResults(diagnosis, container)...)
+}
+
+func (c *Checker) cluster(ctx context.Context, cluster string) (Result, *ecstypes.ExecuteCommandConfiguration) {
+	result := Result{Name: "cluster"}
+	out, err := c.ecs.DescribeClusters(ctx, &ecs.DescribeClustersInput{
+		Clusters: []string{cluster},
+		Include:  []ecstypes.ClusterField{ecstypes.ClusterFieldConfigurations},
+	})
+	if err == nil && (out == nil || len(out.Clusters) == 0) {
+		err = errors.New("cluster not found")
+	}
+	if err != nil {
+		result.Status, result.Detail = StatusFail, fmt.Sprintf("describe ECS cluster %q: %v", cluster, err)
+		result.Hint = "check the cluster name and that the caller may call ecs:DescribeClusters"
+		return result, nil
+	}
+
+	described := out.Clusters[0]
+	if status := aws.ToString(described.Status); status != "ACTIVE" {
+		result.Status, result.Detail = StatusFail, fmt.Sprintf("cluster is %s, not ACTIVE", status)
+		result.Hint = "use an active cluster"
+		return result, nil
+	}
+	var execConfig *ecstypes.ExecuteCommandConfiguration
+	if described.Configuration != nil {
+		execConfig = described.Configuration.ExecuteCommandConfiguration
+	}
+	result.Status, result.Detail = StatusPass, describeExecConfig(execConfig)
+	return result, execConfig
+}
+
+func describeExecConfig(config *ecstypes.ExecuteCommandConfiguration) string {
+	if config == nil {
+		return "execute command uses the default logging and no KMS key"
+	}
+	logging := string(config.Logging)
+	if logging == "" {
+		logging = string(ecstypes.ExecuteCommandLoggingDefault)
+	}
+	parts := []string{"logging " + logging}
+	if config.LogConfiguration != nil {
+		if group := aws.ToString(config.LogConfiguration.CloudWatchLogGroupName); group != "" {
+			parts = append(parts, "CloudWatch log group "+group)
+		}
+		if bucket := aws.ToString(config.LogConfiguration.S3BucketName); bucket != "" {
+			parts = append(parts, "S3 bucket "+bucket)
+		}
+	}
+	if key := aws.ToString(config.KmsKeyId); key != "" {
+		parts = append(parts, "KMS key "+key)
+	} else {
+		parts = append(parts, "no KMS key")
+	}
+	return "execute command " + strings.Join(parts, ", ")
+}
+
+func taskResult(diagnosis target.TaskDiagnosis) Result {
+	result := Result{Name: "task", Status: StatusPass, Detail: "task is RUNNING with execute command enabled"}
+	if len(diagnosis.Reasons) == 0 {
+		return result
+	}
+	messages := make([]string, 0, len(diagnosis.Reasons))
+	for _, reason := range diagnosis.Reasons {
+		messages = append(messages, reason.Message)
+	}
+	result.Status, result.Detail = StatusFail, strings.Join(messages, "; ")
+	result.Hint = remedyHints[diagnosis.Reasons[0].Remedy]
+	return result
+}
+
+// taskDefinition checks the task role and the containers' file systems and
+// returns the task role ARN the task runs with, honoring a run-time override.
+func (c *Checker) taskDefinition(ctx context.Context, task ecstypes.Task, container string) ([]Result, string) {
+	result := Result{Name: "task definition"}
+	definitionARN := aws.ToString(task.TaskDefinitionArn)
+	out, err := c.ecs.DescribeTaskDefinition(ctx, &ecs.DescribeTaskDefinitionInput{TaskDefinition: aws.String(definitionARN)})
+	if err == nil && (out == nil || out.TaskDefinition == nil) {
+		err = errors.New("task definition not found")
+	}
+	if err != nil {
+		result.Status, result.Detail = StatusFail, fmt.Sprintf("describe task definition %q: %v", definitionARN, err)
+		result.Hint = "check that the caller may call ecs:DescribeTaskDefinition"
+		return []Result{result}, ""
+	}
+
+	definition := out.TaskDefinition
+	roleARN := aws.ToString(definition.TaskRoleArn)
+	if task.Overrides != nil && aws.ToString(task.Overrides.TaskRoleArn) != "" {
+		roleARN = aws.ToString(task.Overrides.TaskRoleArn)
+	}
+	if roleARN == "" {
+		result.Status, result.Detail = StatusFail, "task definition has no task role"
+		result.Hint = "set taskRoleArn to a role with " + strings.Join(sessionActions, ", ") + " and redeploy"
+	} else {
+		result.Status, result.Detail = StatusPass, "task role "+roleARN
+	}
+
+	results := []Result{result}
+	for _, definitionContainer := range definition.ContainerDefinitions {
+		name := aws.ToString(definitionContainer.Name)
+		if container != "" && name != container {
+			continue
+		}
+		if aws.ToBool(definitionContainer.ReadonlyRootFilesystem) {
+			results = append(results, Result{
+				Name:   "container " + name + " file system",
+				Status: StatusWarn,
+				Detail: "root file system is read-only, which keeps the exec agent from starting",
+				Hint:   "set readonlyRootFilesystem to false for containers you exec into",
+			})
+		}
+	}
+	return results, roleARN
+}
+
+// rolePermissions simulates the task role's policies for the Session Manager
+// channels and, when the cluster sets them, the KMS key and log destinations.
+func (c *Checker) rolePermissions(ctx context.Context, roleARN string, config *ecstypes.ExecuteCommandConfiguration) []Result {
+	results := []Result{c.simulate(ctx, "task role Session Manager", roleARN, sessionActions, "*", remedyHints[target.RemedyIAM])}
+	if config == nil {
+		return results
+	}
+	if key := aws.ToString(config.KmsKeyId); key != "" {
+		resource := "*"
+		if arn.IsARN(key) {
+			resource = key
+		}
+		results = append(results, c.simulate(ctx, "task role KMS", roleARN, []string{"kms:Decrypt"}, resource,
+			"allow kms:Decrypt on the cluster's execute command KMS key"))
+	}
+	if config.Logging != ecstypes.ExecuteCommandLoggingOverride || config.LogConfiguration == nil {
+		return results
+	}
+	if aws.ToString(config.LogConfiguration.CloudWatchLogGroupName) != "" {
+		results = append(results, c.simulate(ctx, "task role CloudWatch Logs", roleARN,
+			[]string{"logs:CreateLogStream", "logs:DescribeLogGroups", "logs:DescribeLogStreams", "logs:PutLogEvents"}, "*",
+			"allow the task role to write to the execute command log group"))
+	}
+	if bucket := aws.ToString(config.LogConfiguration.S3BucketName); bucket != "" {
+		resource := "*"
+		if parsed, err := arn.Parse(roleARN); err == nil {
+			resource = fmt.Sprintf("arn:%s:s3:::%s/%s*", parsed.Partition, bucket, aws.ToString(config.LogConfiguration.S3KeyPrefix))
+		}
+		results = append(results, c.simulate(ctx, "task role S3", roleARN, []string{"s3:PutObject"}, resource,
+			"allow s3:PutObject on the execute command log bucket"))
+	}
+	return results
+}
+
+func (c *Checker) simulate(ctx context.Context, name, roleARN string, actions []string, resource, hint string) Result {
+	result := Result{Name: name}
+	var (
+		denied []string
+		marker *string
+	)
+	for {
+		out, err := c.iam.SimulatePrincipalPolicy(ctx, &iam.SimulatePrincipalPolicyInput{
+			PolicySourceArn: aws.String(roleARN),
+			ActionNames:     actions,
+			ResourceArns:    []string{resource},
+			Marker:          marker,
+		})
+		if err == nil && out == nil {
+			err = errors.New("empty response")
+		}
+		if err != nil {
+			result.Status, result.Detail = StatusWarn, fmt.Sprintf("could not simulate %s: %v", strings.Join(actions, ", "), err)
+			result.Hint = "allow the caller iam:SimulatePrincipalPolicy, or review the task role's policies by hand"
+			return result
+		}
+		for _, evaluation := range out.EvaluationResults {
+			if evaluation.EvalDecision != iamtypes.PolicyEvaluationDecisionTypeAllowed {
+				denied = append(denied, fmt.Sprintf("%s (%s)", aws.ToString(evaluation.EvalActionName), evaluation.EvalDecision))
+			}
+		}
+		if !out.IsTruncated || aws.ToString(out.Marker) == "" {
+			break
+		}
+		marker = out.Marker
+	}
+
+	if len(denied) > 0 {
+		result.Status, result.Detail, result.Hint = StatusFail, "denied: "+strings.Join(denied, ", "), hint
+		return result
+	}
+	result.Status, result.Detail = StatusPass, "allows "+strings.Join(actions, ", ")
+	return result
+}
+
+func containerResults(diagnosis target.TaskDiagnosis, container string) []Result {
+	var results []Result
+	for _, containerDiagnosis := range diagnosis.Containers {
+		name := aws.ToString(containerDiagnosis.Container.Name)
+		if container != "" && name != container {
+			continue
+		}
+		result := Result{Name: "container " + name, Status: StatusPass, Detail: "ExecuteCommandAgent is RUNNING"}
+		if !containerDiagnosis.Eligible() {
+			messages := make([]string, 0, len(containerDiagnosis.Reasons))
+			for _, reason := range containerDiagnosis.Reasons {
+				messages = append(messages, reason.Message)
+			}
+			result.Status, result.Detail = StatusFail, strings.Join(messages, "; ")
+			result.Hint = remedyHints[containerDiagnosis.Reasons[0].Remedy]
+		}
+		results = append(results, result)
+	}
+	if container != "" && len(results) == 0 {
+		results = append(results, Result{
+			Name:   "container " + container,
+			Status: StatusFail,
+			Detail: "task has no container with this name",
+			Hint:   "check the container name in the task definition",
+		})
+	}
+	return results
+}
+
+// Failed counts the failed results.
+func Failed(results []Result) int {
+	failed := 0
+	for _, result := range results {
+		if result.Status == StatusFail {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Write prints results one per line, followed by the fix for each warning
+// or failure.
+func Write(w io.Writer, results []Result) error {
+	for _, result := range results {
+		if _, err := fmt.Fprintf(w, "[%s] %s: %s\n", result.Status, result.Name, result.Detail); err != nil {
+			return err
+		}
+		if result.Status != StatusPass && result.Hint != "" {
+			if _, err := fmt.Fprintf(w, "       fix: %s\n", result.Hint); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package check
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/wim-web/tnnl/internal/target"
+)
+
+const (
+	checkTaskARN = "arn:aws:ecs:us-east-1:123456789012:task/production/abc"
+	checkRoleARN = "arn:aws:iam::123456789012:role/app-task"
+)
+
+type fakeECS struct {
+	cluster        *ecstypes.Cluster
+	clusterErr     error
+	taskDefinition *ecstypes.TaskDefinition
+
+	clusterInput    *ecs.DescribeClustersInput
+	definitionInput *ecs.DescribeTaskDefinitionInput
+}
+
+func (f *fakeECS) DescribeClusters(_ context.Context, in *ecs.DescribeClustersInput, _ ...func(*ecs.Options)) (*ecs.DescribeClustersOutput, error) {
+	f.clusterInput = in
+	if f.clusterErr != nil {
+		return nil, f.clusterErr
+	}
+	out := &ecs.DescribeClustersOutput{}
+	if f.cluster != nil {
+		out.Clusters = []ecstypes.Cluster{*f.cluster}
+	}
+	return out, nil
+}
+
+func (f *fakeECS) DescribeTaskDefinition(_ context.Context, in *ecs.DescribeTaskDefinitionInput, _ ...func(*ecs.Options)) (*ecs.DescribeTaskDefinitionOutput, error) {
+	f.definitionInput = in
+	return &ecs.DescribeTaskDefinitionOutput{TaskDefinition: f.taskDefinition}, nil
+}
+
+type fakeIAM struct {
+	denied map[string]iamtypes.PolicyEvaluationDecisionType
+	err    error
+
+	inputs []*iam.SimulatePrincipalPolicyInput
+}
+
+func (f *fakeIAM) SimulatePrincipalPolicy(_ context.Context, in *iam.SimulatePrincipalPolicyInput, _ ...func(*iam.Options)) (*iam.SimulatePrincipalPolicyOutput, error) {
+	f.inputs = append(f.inputs, in)
+	if f.err != nil {
+		return nil, f.err
+	}
+	out := &iam.SimulatePrincipalPolicyOutput{}
+	for _, action := range in.ActionNames {
+		decision := iamtypes.PolicyEvaluationDecisionTypeAllowed
+		if denied, ok := f.denied[action]; ok {
+			decision = denied
+		}
+		out.EvaluationResults = append(out.EvaluationResults, iamtypes.EvaluationResult{
+			EvalActionName: aws.String(action),
+			EvalDecision:   decision,
+		})
+	}
+	return out, nil
+}
+
+func TestCheckerRunPassesForReadyTask(t *testing.T) {
+	ecsClient := readyECS()
+	iamClient := &fakeIAM{}
+
+	results := NewChecker(ecsClient, iamClient).Run(context.Background(), "production", target.DiagnoseTask(readyTask()), "")
+
+	want := []string{"cluster", "task", "task definition", "task role Session Manager", "container app"}
+	if got := resultNames(results); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Run() names = %v, want %v", got, want)
+	}
+	if failed := Failed(results); failed != 0 {
+		t.Fatalf("Failed() = %d, want 0 in %#v", failed, results)
+	}
+	if got := ecsClient.clusterInput.Include; !reflect.DeepEqual(got, []ecstypes.ClusterField{ecstypes.ClusterFieldConfigurations}) {
+		t.Fatalf("DescribeClusters Include = %v, want configurations", got)
+	}
+	if got := aws.ToString(iamClient.inputs[0].PolicySourceArn); got != checkRoleARN {
+		t.Fatalf("SimulatePrincipalPolicy role = %q, want %q", got, checkRoleARN)
+	}
+	if got := iamClient.inputs[0].ActionNames; !reflect.DeepEqual(got, sessionActions) {
+		t.Fatalf("SimulatePrincipalPolicy actions = %v, want %v", got, sessionActions)
+	}
+}
+
+func TestCheckerRunReportsFailuresWithHints(t *testing.T) {
+	ecsClient := readyECS()
+	ecsClient.cluster.Configuration = &ecstypes.ClusterConfiguration{ExecuteCommandConfiguration: &ecstypes.ExecuteCommandConfiguration{
+		KmsKeyId: aws.String("arn:aws:kms:us-east-1:123456789012:key/k"),
+		Logging:  ecstypes.ExecuteCommandLoggingOverride,
+		LogConfiguration: &ecstypes.ExecuteCommandLogConfiguration{
+			S3BucketName: aws.String("exec-logs"),
+			S3KeyPrefix:  aws.String("prod/"),
+		},
+	}}
+	ecsClient.taskDefinition.ContainerDefinitions[0].ReadonlyRootFilesystem = aws.Bool(true)
+	iamClient := &fakeIAM{denied: map[string]iamtypes.PolicyEvaluationDecisionType{
+		"ssmmessages:OpenDataChannel": iamtypes.PolicyEvaluationDecisionTypeImplicitDeny,
+		"kms:Decrypt":                 iamtypes.PolicyEvaluationDecisionTypeExplicitDeny,
+	}}
+	task := readyTask()
+	task.EnableExecuteCommand = false
+	task.Containers[0].ManagedAgents[0].LastStatus = aws.String("STOPPED")
+
+	results := NewChecker(ecsClient, iamClient).Run(context.Background(), "production", target.DiagnoseTask(task), "")
+
+	byName := make(map[string]Result)
+	for _, result := range results {
+		byName[result.Name] = result
+	}
+	tests := []struct {
+		name   string
+		status Status
+		detail string
+		hint   string
+	}{
+		{name: "cluster", status: StatusPass, detail: "KMS key arn:aws:kms:us-east-1:123456789012:key/k"},
+		{name: "task", status: StatusFail, detail: "execute command is not enabled", hint: "--enable-execute-command"},
+		{name: "container app file system", status: StatusWarn, detail: "read-only", hint: "readonlyRootFilesystem"},
+		{name: "task role Session Manager", status: StatusFail, detail: "ssmmessages:OpenDataChannel (implicitDeny)", hint: "VPC endpoint"},
+		{name: "task role KMS", status: StatusFail, detail: "kms:Decrypt (explicitDeny)", hint: "kms:Decrypt"},
+		{name: "task role S3", status: StatusPass},
+		{name: "container app", status: StatusFail, detail: "ExecuteCommandAgent is STOPPED", hint: "ssmmessages:CreateControlChannel"},
+	}
+	for _, tt := range tests {
+		got, ok := byName[tt.name]
+		if !ok {
+			t.Errorf("Run() has no %q result in %v", tt.name, resultNames(results))
+			continue
+		}
+		if got.Status != tt.status || !strings.Contains(got.Detail, tt.detail) || !strings.Contains(got.Hint, tt.hint) {
+			t.Errorf("%s = %#v, want %s with detail %q and hint %q", tt.name, got, tt.status, tt.detail, tt.hint)
+		}
+	}
+	if got := iamClient.inputs[1].ResourceArns; !reflect.DeepEqual(got, []string{"arn:aws:kms:us-east-1:123456789012:key/k"}) {
+		t.Errorf("KMS simulation resources = %v", got)
+	}
+	if got := iamClient.inputs[2].ResourceArns; !reflect.DeepEqual(got, []string{"arn:aws:s3:::exec-logs/prod/*"}) {
+		t.Errorf("S3 simulation resources = %v", got)
+	}
+	if failed := Failed(results); failed != 4 {
+		t.Fatalf("Failed() = %d, want 4", failed)
+	}
+}
+
+func TestCheckerRunWithoutTaskRoleOrSimulation(t *testing.T) {
+	ecsClient := readyECS()
+	ecsClient.taskDefinition.TaskRoleArn = nil
+	iamClient := &fakeIAM{}
+
+	results := NewChecker(ecsClient, iamClient).Run(context.Background(), "production", target.DiagnoseTask(readyTask()), "")
+	if definition := results[2]; definition.Status != StatusFail || !strings.Contains(definition.Hint, "taskRoleArn") {
+		t.Fatalf("task definition = %#v, want missing task role failure", definition)
+	}
+	if len(iamClient.inputs) != 0 {
+		t.Fatalf("SimulatePrincipalPolicy calls = %d, want none without a task role", len(iamClient.inputs))
+	}
+
+	task := readyTask()
+	task.Overrides = &ecstypes.TaskOverride{TaskRoleArn: aws.String("arn:aws:iam::123456789012:role/override")}
+	iamClient = &fakeIAM{err: errors.New("AccessDenied")}
+	results = NewChecker(ecsClient, iamClient).Run(context.Background(), "production", target.DiagnoseTask(task), "")
+	if got := aws.ToString(iamClient.inputs[0].PolicySourceArn); got != "arn:aws:iam::123456789012:role/override" {
+		t.Fatalf("simulated role = %q, want the task override", got)
+	}
+	if simulation := results[3]; simulation.Status != StatusWarn || !strings.Contains(simulation.Detail, "AccessDenied") {
+		t.Fatalf("simulation = %#v, want a warning", simulation)
+	}
+}
+
+func TestCheckerRunFailsForMissingClusterAndContainer(t *testing.T) {
+	ecsClient := readyECS()
+	ecsClient.cluster = nil
+
+	results := NewChecker(ecsClient, &fakeIAM{}).Run(context.Background(), "production", target.DiagnoseTask(readyTask()), "worker")
+	if cluster := results[0]; cluster.Status != StatusFail || !strings.Contains(cluster.Detail, "cluster not found") {
+		t.Fatalf("cluster = %#v, want not found failure", cluster)
+	}
+	last := results[len(results)-1]
+	if last.Name != "container worker" || last.Status != StatusFail {
+		t.Fatalf("last result = %#v, want missing container failure", last)
+	}
+}
+
+func TestWritePrintsHintsForProblemsOnly(t *testing.T) {
+	var out bytes.Buffer
+	err := Write(&out, []Result{
+		{Name: "cluster", Status: StatusPass, Detail: "ok", Hint: "unused"},
+		{Name: "task", Status: StatusFail, Detail: "stopped", Hint: "start it"},
+	})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	want := "[PASS] cluster: ok\n[FAIL] task: stopped\n       fix: start it\n"
+	if out.String() != want {
+		t.Fatalf("Write() = %q, want %q", out.String(), want)
+	}
+}
+
+func readyECS() *fakeECS {
+	return &fakeECS{
+		cluster: &ecstypes.Cluster{ClusterName: aws.String("production"), Status: aws.String("ACTIVE")},
+		taskDefinition: &ecstypes.TaskDefinition{
+			TaskRoleArn:          aws.String(checkRoleARN),
+			ContainerDefinitions: []ecstypes.ContainerDefinition{{Name: aws.String("app")}},
+		},
+	}
+}
+
+func readyTask() ecstypes.Task {
+	return ecstypes.Task{
+		TaskArn:              aws.String(checkTaskARN),
+		TaskDefinitionArn:    aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/app:3"),
+		LastStatus:           aws.String("RUNNING"),
+		EnableExecuteCommand: true,
+		Containers: []ecstypes.Container{{
+			Name:       aws.String("app"),
+			LastStatus: aws.String("RUNNING"),
+			RuntimeId:  aws.String("runtime-app"),
+			ManagedAgents: []ecstypes.ManagedAgent{{
+				Name:       ecstypes.ManagedAgentNameExecuteCommandAgent,
+				LastStatus: aws.String("RUNNING"),
+			}},
+		}},
+	}
+}
+
+func resultNames(results []Result) []string {
+	names := make([]string, 0, len(results))
+	for _, result := range results {
+		names = append(names, result.Name)
+	}
+	return names
+}
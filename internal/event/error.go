@@ -0,0 +1,101 @@
+package event
+
+import (
+	"errors"
+
+	"github.com/aws/smithy-go"
+	"github.com/wim-web/tnnl/internal/input"
+	"github.com/wim-web/tnnl/internal/interrupt"
+	"github.com/wim-web/tnnl/internal/target"
+	"github.com/wim-web/tnnl/pkg/command"
+)
+
+// Error codes. Like event names they are stable; scripts branch on them
+// instead of on messages.
+const (
+	// CodeInterrupted: a signal ended the command.
+	CodeInterrupted = "interrupted"
+	// CodeRemoteExit: the remote command exited with a non-zero status.
+	CodeRemoteExit = "remote_exit"
+	// CodeTimeout: the remote command ran out of time.
+	CodeTimeout = "timeout"
+	// CodeInvalidInput: the flags or input file are not valid.
+	CodeInvalidInput = "invalid_input"
+	// CodeTargetNotFound: no eligible task or container matched.
+	CodeTargetNotFound = "target_not_found"
+	// CodeTargetAmbiguous: several tasks or containers matched.
+	CodeTargetAmbiguous = "target_ambiguous"
+	// CodeSessionClient: the session client is missing or broken.
+	CodeSessionClient = "session_client_unavailable"
+	// CodeAWSConfig: the AWS configuration or credentials could not be loaded.
+	CodeAWSConfig = "aws_config"
+	// CodeAWSAPI: an AWS API call failed; the AWS error code comes with it.
+	CodeAWSAPI = "aws_api"
+	// CodeUnknown: any other failure.
+	CodeUnknown = "error"
+)
+
+// ErrorInfo is an error event, and the error of a session_ended event.
+type ErrorInfo struct {
+	Code         string `json:"code"`
+	Message      string `json:"message"`
+	AWSErrorCode string `json:"aws_error_code,omitempty"`
+	ExitStatus   int    `json:"exit_status"`
+}
+
+type codedError struct {
+	code string
+	err  error
+}
+
+func (e *codedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *codedError) Unwrap() error {
+	return e.err
+}
+
+// WithCode marks err with the error code Describe reports for it, for
+// failures that have no type of their own. The message is unchanged.
+func WithCode(code string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &codedError{code: code, err: err}
+}
+
+// Describe classifies err. The exit status is left for the caller, which
+// knows how the process ends.
+func Describe(err error) ErrorInfo {
+	info := ErrorInfo{Code: CodeUnknown, Message: err.Error()}
+	var (
+		caught  *interrupt.Error
+		coded   *codedError
+		exitErr *command.ExitError
+		invalid *input.InvalidError
+		apiErr  smithy.APIError
+	)
+	switch {
+	case errors.As(err, &caught):
+		info.Code = CodeInterrupted
+	case errors.Is(err, command.ErrTimedOut):
+		info.Code = CodeTimeout
+	case errors.As(err, &exitErr):
+		info.Code = CodeRemoteExit
+	case errors.As(err, &coded):
+		info.Code = coded.code
+	case errors.As(err, &invalid):
+		info.Code = CodeInvalidInput
+	case errors.Is(err, target.ErrNoMatch), errors.Is(err, target.ErrNoEligible):
+		info.Code = CodeTargetNotFound
+	case errors.Is(err, target.ErrAmbiguous):
+		info.Code = CodeTargetAmbiguous
+	case errors.As(err, &apiErr):
+		info.Code = CodeAWSAPI
+	}
+	if errors.As(err, &apiErr) {
+		info.AWSErrorCode = apiErr.ErrorCode()
+	}
+	return info
+}
@@ -0,0 +1,56 @@
+package event
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+
+	"github.com/aws/smithy-go"
+	"github.com/wim-web/tnnl/internal/input"
+	"github.com/wim-web/tnnl/internal/interrupt"
+	"github.com/wim-web/tnnl/internal/target"
+	"github.com/wim-web/tnnl/pkg/command"
+)
+
+func TestDescribeClassifiesErrors(t *testing.T) {
+	apiErr := &smithy.GenericAPIError{Code: "AccessDeniedException", Message: "not allowed"}
+	tests := []struct {
+		name    string
+		err     error
+		code    string
+		awsCode string
+	}{
+		{name: "signal", err: fmt.Errorf("run: %w", &interrupt.Error{Signal: syscall.SIGTERM}), code: CodeInterrupted},
+		{name: "timeout", err: &command.ExitError{Code: 124, Err: fmt.Errorf("%w after 1s", command.ErrTimedOut)}, code: CodeTimeout},
+		{name: "remote exit", err: &command.ExitError{Code: 3}, code: CodeRemoteExit},
+		{name: "coded", err: fmt.Errorf("run: %w", WithCode(CodeSessionClient, errors.New("missing"))), code: CodeSessionClient},
+		{name: "invalid input", err: &input.InvalidError{Err: errors.New("bad")}, code: CodeInvalidInput},
+		{name: "no match", err: fmt.Errorf("select: %w", target.ErrNoMatch), code: CodeTargetNotFound},
+		{name: "no eligible", err: fmt.Errorf("select: %w", target.ErrNoEligible), code: CodeTargetNotFound},
+		{name: "ambiguous", err: fmt.Errorf("select: %w", target.ErrAmbiguous), code: CodeTargetAmbiguous},
+		{name: "aws api", err: fmt.Errorf("list tasks: %w", apiErr), code: CodeAWSAPI, awsCode: "AccessDeniedException"},
+		{name: "coded aws api", err: WithCode(CodeAWSConfig, apiErr), code: CodeAWSConfig, awsCode: "AccessDeniedException"},
+		{name: "other", err: errors.New("boom"), code: CodeUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Describe(tt.err)
+			want := ErrorInfo{Code: tt.code, Message: tt.err.Error(), AWSErrorCode: tt.awsCode}
+			if got != want {
+				t.Fatalf("Describe() = %#v, want %#v", got, want)
+			}
+		})
+	}
+}
+
+func TestWithCodeKeepsTheMessage(t *testing.T) {
+	cause := errors.New("session-manager-plugin is required")
+	err := WithCode(CodeSessionClient, cause)
+	if err.Error() != cause.Error() || !errors.Is(err, cause) {
+		t.Fatalf("WithCode() = %v, want %v unwrapping to it", err, cause)
+	}
+	if WithCode(CodeSessionClient, nil) != nil {
+		t.Fatal("WithCode(nil) != nil")
+	}
+}
@@ -0,0 +1,155 @@
+// Package event writes the output of --output json: one JSON object per line
+// on standard output, each naming the event it reports. Human-readable
+// messages and warnings stay on standard error either way.
+package event
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/wim-web/tnnl/internal/target"
+)
+
+// Output formats selected by --output.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Event names. They are part of the output format and do not change.
+const (
+	TargetResolved    = "target_resolved"
+	SessionStarted    = "session_started"
+	SessionEnded      = "session_ended"
+	Reconnecting      = "reconnecting"
+	Output            = "output"
+	Forward           = "forward"
+	Error             = "error"
+	Version           = "version"
+	Update            = "update"
+	Check             = "check"
+	Session           = "session"
+	SessionTerminated = "session_terminated"
+	SavedTarget       = "saved_target"
+	AuditEntry        = "audit_entry"
+	Cluster           = "cluster"
+	Service           = "service"
+	Task              = "task"
+	Container         = "container"
+	DryRun            = "dry_run"
+)
+
+// Emitter writes events to one writer. It is safe for concurrent use, and a
+// nil *Emitter stands for text output.
+type Emitter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	now func() time.Time
+}
+
+func NewEmitter(w io.Writer, now func() time.Time) *Emitter {
+	return &Emitter{w: w, now: now}
+}
+
+// Enabled reports whether e writes events.
+func (e *Emitter) Enabled() bool {
+	return e != nil
+}
+
+// Emit writes the event name with the fields of fields, a struct or map
+// that encodes as a JSON object, after its time and name. fields may be nil.
+func (e *Emitter) Emit(name string, fields any) error {
+	if e == nil {
+		return nil
+	}
+	line, err := json.Marshal(struct {
+		Time  time.Time `json:"time"`
+		Event string    `json:"event"`
+	}{Time: e.now().UTC(), Event: name})
+	if err != nil {
+		return fmt.Errorf("encode %s event: %w", name, err)
+	}
+	if fields != nil {
+		encoded, err := json.Marshal(fields)
+		if err != nil {
+			return fmt.Errorf("encode %s event: %w", name, err)
+		}
+		if len(encoded) < 2 || encoded[0] != '{' {
+			return fmt.Errorf("encode %s event: fields are not a JSON object", name)
+		}
+		if !bytes.Equal(encoded, []byte("{}")) {
+			line = append(line[:len(line)-1], ',')
+			line = append(line, encoded[1:]...)
+		}
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, err := e.w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write %s event: %w", name, err)
+	}
+	return nil
+}
+
+type contextKey struct{}
+
+// NewContext returns ctx carrying e.
+func NewContext(ctx context.Context, e *Emitter) context.Context {
+	return context.WithValue(ctx, contextKey{}, e)
+}
+
+// FromContext returns the emitter ctx carries, nil for text output.
+func FromContext(ctx context.Context) *Emitter {
+	e, _ := ctx.Value(contextKey{}).(*Emitter)
+	return e
+}
+
+// Target is the container a session runs against.
+type Target struct {
+	Cluster    string `json:"cluster"`
+	ClusterARN string `json:"cluster_arn"`
+	Service    string `json:"service,omitempty"`
+	TaskARN    string `json:"task_arn"`
+	TaskID     string `json:"task_id"`
+	Container  string `json:"container"`
+	RuntimeID  string `json:"runtime_id"`
+	SSMTarget  string `json:"ssm_target"`
+}
+
+func NewTarget(resolved target.Resolved) Target {
+	return Target{
+		Cluster:    resolved.ClusterName,
+		ClusterARN: resolved.ECSCluster,
+		Service:    resolved.Service,
+		TaskARN:    resolved.TaskARN,
+		TaskID:     resolved.TaskID,
+		Container:  resolved.ContainerName,
+		RuntimeID:  resolved.RuntimeID,
+		SSMTarget:  resolved.SSMTarget(),
+	}
+}
+
+// SessionInfo describes one Session Manager session: a session_started event,
+// and the start of a session_ended event.
+type SessionInfo struct {
+	SessionID  string `json:"session_id"`
+	Command    string `json:"command"`
+	Document   string `json:"document"`
+	Target     Target `json:"target"`
+	Remote     string `json:"remote_command,omitempty"`
+	LocalPort  string `json:"local_port,omitempty"`
+	RemotePort string `json:"remote_port,omitempty"`
+	Host       string `json:"host,omitempty"`
+}
+
+// Ended is a session_ended event.
+type Ended struct {
+	SessionInfo
+	Duration   float64    `json:"duration_seconds"`
+	ExitStatus int        `json:"exit_status"`
+	Error      *ErrorInfo `json:"error,omitempty"`
+}
@@ -0,0 +1,99 @@
+package event
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/wim-web/tnnl/internal/target"
+)
+
+var fixedTime = time.Date(2026, 5, 1, 9, 30, 0, 0, time.FixedZone("JST", 9*60*60))
+
+func newTestEmitter() (*Emitter, *bytes.Buffer) {
+	var out bytes.Buffer
+	return NewEmitter(&out, func() time.Time { return fixedTime }), &out
+}
+
+func TestEmitWritesOneLinePerEvent(t *testing.T) {
+	events, out := newTestEmitter()
+	if err := events.Emit(Version, map[string]string{"version": "v1.2.3"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := events.Emit(SessionStarted, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := events.Emit(SessionEnded, struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"time":"2026-05-01T00:30:00Z","event":"version","version":"v1.2.3"}
+{"time":"2026-05-01T00:30:00Z","event":"session_started"}
+{"time":"2026-05-01T00:30:00Z","event":"session_ended"}
+`
+	if out.String() != want {
+		t.Fatalf("output =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestEmitRejectsFieldsThatAreNotAnObject(t *testing.T) {
+	events, out := newTestEmitter()
+	err := events.Emit(Output, []string{"line"})
+	if err == nil || !strings.Contains(err.Error(), "not a JSON object") {
+		t.Fatalf("Emit() error = %v, want not a JSON object", err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("output = %q, want nothing", out.String())
+	}
+}
+
+func TestNilEmitterIsTextOutput(t *testing.T) {
+	var events *Emitter
+	if events.Enabled() {
+		t.Fatal("nil emitter is enabled")
+	}
+	if err := events.Emit(Version, nil); err != nil {
+		t.Fatalf("Emit() error = %v", err)
+	}
+	if got := FromContext(context.Background()); got != nil {
+		t.Fatalf("FromContext() = %v, want nil", got)
+	}
+}
+
+func TestContextCarriesTheEmitter(t *testing.T) {
+	events, _ := newTestEmitter()
+	if got := FromContext(NewContext(context.Background(), events)); got != events || !got.Enabled() {
+		t.Fatalf("FromContext() = %v, want the emitter", got)
+	}
+}
+
+func TestNewTargetCarriesTheSSMTarget(t *testing.T) {
+	resolved, err := target.NewResolved(
+		"arn:aws:ecs:ap-northeast-1:123456789012:cluster/production",
+		types.Task{TaskArn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task/production/0123456789abcdef")},
+		types.Container{Name: aws.String("app"), RuntimeId: aws.String("0123456789abcdef-1234567890")},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved.Service = "web"
+
+	got := NewTarget(resolved)
+	want := Target{
+		Cluster:    "production",
+		ClusterARN: "arn:aws:ecs:ap-northeast-1:123456789012:cluster/production",
+		Service:    "web",
+		TaskARN:    "arn:aws:ecs:ap-northeast-1:123456789012:task/production/0123456789abcdef",
+		TaskID:     "0123456789abcdef",
+		Container:  "app",
+		RuntimeID:  "0123456789abcdef-1234567890",
+		SSMTarget:  "ecs:production_0123456789abcdef_0123456789abcdef-1234567890",
+	}
+	if got != want {
+		t.Fatalf("NewTarget() = %#v, want %#v", got, want)
+	}
+}
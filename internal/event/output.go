@@ -0,0 +1,56 @@
+package event
+
+import (
+	"bytes"
+	"sync"
+)
+
+// OutputLine is an output event: one line the remote command printed on
+// standard output.
+type OutputLine struct {
+	TaskID string `json:"task_id"`
+	Line   string `json:"line"`
+}
+
+// Lines turns what is written to it into output events, one per line, for
+// the task taskID.
+type Lines struct {
+	events *Emitter
+	taskID string
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func NewLines(events *Emitter, taskID string) *Lines {
+	return &Lines{events: events, taskID: taskID}
+}
+
+func (l *Lines) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := string(bytes.TrimSuffix(l.buf[:i], []byte("\r")))
+		l.buf = l.buf[i+1:]
+		if err := l.events.Emit(Output, OutputLine{TaskID: l.taskID, Line: line}); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+// Close emits a final line that did not end in a newline.
+func (l *Lines) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.buf) == 0 {
+		return nil
+	}
+	line := string(l.buf)
+	l.buf = nil
+	return l.events.Emit(Output, OutputLine{TaskID: l.taskID, Line: line})
+}
@@ -0,0 +1,27 @@
+package event
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLinesEmitsOneOutputEventPerLine(t *testing.T) {
+	events, out := newTestEmitter()
+	lines := NewLines(events, "abc")
+	for _, chunk := range []string{"fir", "st\r\nsecond\n", "last"} {
+		if _, err := fmt.Fprint(lines, chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := lines.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"time":"2026-05-01T00:30:00Z","event":"output","task_id":"abc","line":"first"}
+{"time":"2026-05-01T00:30:00Z","event":"output","task_id":"abc","line":"second"}
+{"time":"2026-05-01T00:30:00Z","event":"output","task_id":"abc","line":"last"}
+`
+	if out.String() != want {
+		t.Fatalf("output =\n%s\nwant\n%s", out.String(), want)
+	}
+}
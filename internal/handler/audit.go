@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/wim-web/tnnl/internal/audit"
+	"github.com/wim-web/tnnl/internal/event"
+	"github.com/wim-web/tnnl/internal/interrupt"
+	"github.com/wim-web/tnnl/internal/target"
+	"github.com/wim-web/tnnl/pkg/command"
+)
+
+// execDocument is the Session Manager document behind ECS Exec sessions.
+const execDocument = "AmazonECS-ExecuteInteractiveCommand"
+
+// callerIdentityTimeout bounds the lookup of who ran a session, which happens
+// after the session ended and must not hold up the exit.
+const callerIdentityTimeout = 5 * time.Second
+
+type stsAPI interface {
+	GetCallerIdentity(context.Context, *sts.GetCallerIdentityInput, ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+}
+
+func appendAuditEntry(entry audit.Entry) error {
+	path, err := audit.Path()
+	if err != nil {
+		return err
+	}
+	return audit.Append(path, entry)
+}
+
+// sessionAudit writes an audit log entry for every session one command runs,
+// and reports its start and end as events for --output json. The caller is
+// looked up once, when the first session ends. Failing to write the log is
+// reported but does not fail the session. A nil *sessionAudit records
+// nothing.
+type sessionAudit struct {
+	deps dependencies
+	sts  stsAPI
+	base audit.Entry
+
+	once    sync.Once
+	caller  string
+	account string
+}
+
+// newSessionAudit prepares the entries of command. input is the command
+// input a re-run starts from.
+func newSessionAudit(deps dependencies, cfg aws.Config, command string, input json.RawMessage) *sessionAudit {
+	if deps.appendAudit == nil && !deps.events.Enabled() {
+		return nil
+	}
+	a := &sessionAudit{deps: deps, base: audit.Entry{Region: cfg.Region, Command: command, Input: input}}
+	if deps.newSTS != nil {
+		a.sts = deps.newSTS(cfg)
+	}
+	return a
+}
+
+// run runs one session on resolved and records it with the details already
+// set in entry: the document, the remote command, or the ports.
+func (a *sessionAudit) run(
+	ctx context.Context,
+	resolved target.Resolved,
+	remote command.RemoteSession,
+	entry audit.Entry,
+	run func() error,
+) error {
+	if a == nil {
+		return run()
+	}
+	info := event.SessionInfo{
+		SessionID:  remote.ID,
+		Command:    a.base.Command,
+		Document:   entry.Document,
+		Target:     event.NewTarget(resolved),
+		Remote:     entry.Remote,
+		LocalPort:  entry.LocalPort,
+		RemotePort: entry.RemotePort,
+		Host:       entry.Host,
+	}
+	a.emit(event.TargetResolved, struct {
+		Target event.Target `json:"target"`
+	}{Target: info.Target})
+	a.emit(event.SessionStarted, info)
+
+	startedAt := a.deps.clock.Now()
+	err := run()
+	duration := a.deps.clock.Now().Sub(startedAt).Seconds()
+	ended := event.Ended{SessionInfo: info, Duration: duration, ExitStatus: exitStatus(err)}
+	if err != nil {
+		described := event.Describe(err)
+		described.ExitStatus = ended.ExitStatus
+		ended.Error = &described
+	}
+	a.emit(event.SessionEnded, ended)
+	if a.deps.appendAudit == nil {
+		return err
+	}
+
+	a.once.Do(func() { a.caller, a.account = a.lookupCaller(ctx) })
+	entry.Time = startedAt.UTC()
+	entry.Duration = duration
+	entry.Caller, entry.Account = a.caller, a.account
+	entry.Region, entry.Command, entry.Input = a.base.Region, a.base.Command, a.base.Input
+	entry.Cluster, entry.Task, entry.Container = resolved.ECSCluster, resolved.TaskARN, resolved.ContainerName
+	entry.SessionID = remote.ID
+	entry.ExitStatus = exitStatus(err)
+	var exitErr *command.ExitError
+	if err != nil && (!errors.As(err, &exitErr) || exitErr.Err != nil) {
+		entry.Error = err.Error()
+	}
+	if appendErr := a.deps.appendAudit(entry); appendErr != nil {
+		fmt.Fprintf(a.deps.stderr, "warning: session %s was not recorded in the audit log: %v\n", remote.ID, appendErr)
+	}
+	return err
+}
+
+func (a *sessionAudit) emit(name string, fields any) {
+	emit(a.deps.events, a.deps.stderr, name, fields)
+}
+
+func (a *sessionAudit) lookupCaller(ctx context.Context) (string, string) {
+	if a.sts == nil {
+		return "", ""
+	}
+	lookupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), callerIdentityTimeout)
+	defer cancel()
+	identity, err := a.sts.GetCallerIdentity(lookupCtx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		fmt.Fprintf(a.deps.stderr, "warning: the audit log does not record who ran the session: %v\n", err)
+		return "", ""
+	}
+	return aws.ToString(identity.Arn), aws.ToString(identity.Account)
+}
+
+// exitStatus is the status tnnl exits with for err.
+func exitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *command.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	var caught *interrupt.Error
+	if errors.As(err, &caught) {
+		return caught.ExitCode()
+	}
+	return 1
+}
+
+// auditInput encodes the input of a command for a re-run, with the cluster,
+// service, container, and Region a session resolved to pinned, so the re-run
+// reaches the same place. The task is chosen again since it may be gone. An
+// input that cannot be encoded is reported and left out of the log.
+func auditInput(deps dependencies, in any, resolved target.Resolved, region string) json.RawMessage {
+	pinned, err := pinInput(in, resolved, region)
+	if err != nil {
+		fmt.Fprintf(deps.stderr, "warning: the audit log does not record the input for a re-run: %v\n", err)
+		return nil
+	}
+	return pinned
+}
+
+func pinInput(in any, resolved target.Resolved, region string) (json.RawMessage, error) {
+	encoded, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range map[string]string{
+		"cluster":   resolved.ECSCluster,
+		"service":   resolved.Service,
+		"container": resolved.ContainerName,
+		"region":    region,
+	} {
+		if value != "" {
+			fields[name] = value
+		}
+	}
+	return json.Marshal(fields)
+}
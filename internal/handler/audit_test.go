@@ -0,0 +1,304 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/wim-web/tnnl/internal/audit"
+	"github.com/wim-web/tnnl/internal/event"
+	"github.com/wim-web/tnnl/internal/input"
+	"github.com/wim-web/tnnl/internal/interrupt"
+	"github.com/wim-web/tnnl/internal/session_manager"
+	"github.com/wim-web/tnnl/internal/target"
+	"github.com/wim-web/tnnl/pkg/command"
+)
+
+const handlerCallerARN = "arn:aws:sts::123456789012:assumed-role/dev/alice"
+
+type handlerSTS struct {
+	calls int
+	err   error
+}
+
+func (f *handlerSTS) GetCallerIdentity(context.Context, *sts.GetCallerIdentityInput, ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &sts.GetCallerIdentityOutput{Arn: aws.String(handlerCallerARN), Account: aws.String("123456789012")}, nil
+}
+
+// auditDependencies collects audit entries in entries.
+func auditDependencies(deps dependencies, stsClient *handlerSTS, entries *[]audit.Entry) dependencies {
+	deps.newSTS = func(aws.Config) stsAPI { return stsClient }
+	deps.appendAudit = func(entry audit.Entry) error {
+		*entries = append(*entries, entry)
+		return nil
+	}
+	deps.clock = &reconnectClock{now: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}
+	return deps
+}
+
+func TestExecHandlerAuditsBatchSession(t *testing.T) {
+	var events []string
+	ecsClient := newHandlerECS(&events)
+	deps := handlerDependencies(t, &events, ecsClient, &handlerSSM{events: &events}, &handlerPlugin{events: &events})
+	deps.preflight = func(context.Context, session_manager.Options) (session_manager.Plugin, error) {
+		return &shellPlugin{ecsClient: ecsClient}, nil
+	}
+	var entries []audit.Entry
+	stsClient := &handlerSTS{}
+	deps = auditDependencies(deps, stsClient, &entries)
+	deps.stdout, deps.stderr = &strings.Builder{}, &strings.Builder{}
+	in := validExecHandlerInput()
+	in.Task, in.Cmd, in.Batch = "task-second", "exit 3", true
+
+	err := execHandler(context.Background(), in, deps)
+	var exitErr *command.ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 {
+		t.Fatalf("execHandler() error = %v, want exit status 3", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("audit entries = %#v, want one", entries)
+	}
+	got := entries[0]
+	if got.Command != "exec" || got.Document != execDocument || got.Remote != "exit 3" ||
+		got.Cluster != handlerClusterARN || got.Task != handlerSecondTaskARN || got.Container != handlerContainer ||
+		got.Region != handlerRegion || got.SessionID != aws.ToString(ecsClient.executeOutput.Session.SessionId) ||
+		got.Caller != handlerCallerARN || got.Account != "123456789012" ||
+		got.ExitStatus != 3 || got.Error != "" || !got.Time.Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Fatalf("audit entry = %#v", got)
+	}
+
+	var rerun input.ExecInput
+	if err := json.Unmarshal(got.Input, &rerun); err != nil {
+		t.Fatalf("decode re-run input: %v", err)
+	}
+	if rerun.Cluster != handlerClusterARN || rerun.Container != handlerContainer || rerun.Region != handlerRegion ||
+		rerun.Task != "task-second" || rerun.Cmd != "exit 3" || !rerun.Batch {
+		t.Fatalf("re-run input = %#v, want the resolved target pinned", rerun)
+	}
+}
+
+func TestRemotePortForwardHandlerAuditsPorts(t *testing.T) {
+	var events []string
+	ecsClient := newHandlerECS(&events)
+	ssmClient := &handlerSSM{events: &events, startOutput: validHandlerStartOutput()}
+	deps := handlerDependencies(t, &events, ecsClient, ssmClient, &handlerPlugin{events: &events})
+	var entries []audit.Entry
+	deps = auditDependencies(deps, &handlerSTS{}, &entries)
+	in := input.RemotePortForwardInput{
+		EcsParameter:     input.EcsParameter{Cluster: handlerClusterARN, Service: "service-web"},
+		RemotePortNumber: "3306",
+		LocalPortNumber:  "13306",
+		Host:             "db.internal",
+	}
+
+	if err := remotePortForwardHandler(context.Background(), in, deps); err != nil {
+		t.Fatalf("remotePortForwardHandler() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("audit entries = %#v, want one", entries)
+	}
+	got := entries[0]
+	if got.Command != "remoteportforward" || got.Document != string(command.REMOTE_PORT_FORWARD_DOCUMENT_NAME) ||
+		got.LocalPort != "13306" || got.RemotePort != "3306" || got.Host != "db.internal" ||
+		got.SessionID != aws.ToString(validHandlerStartOutput().SessionId) || got.ExitStatus != 0 {
+		t.Fatalf("audit entry = %#v", got)
+	}
+	var rerun input.RemotePortForwardInput
+	if err := json.Unmarshal(got.Input, &rerun); err != nil || rerun.Host != "db.internal" || rerun.Service != "service-web" {
+		t.Fatalf("re-run input = %#v, %v", rerun, err)
+	}
+}
+
+func TestSessionAuditFailuresOnlyWarn(t *testing.T) {
+	var events []string
+	ecsClient := newHandlerECS(&events)
+	deps := handlerDependencies(t, &events, ecsClient, &handlerSSM{events: &events}, &handlerPlugin{events: &events})
+	var entries []audit.Entry
+	stsClient := &handlerSTS{err: errors.New("expired token")}
+	deps = auditDependencies(deps, stsClient, &entries)
+	var stderr strings.Builder
+	deps.stderr = &stderr
+	appended := 0
+	deps.appendAudit = func(entry audit.Entry) error {
+		appended++
+		if entry.Caller != "" {
+			t.Errorf("caller = %q, want none after the lookup failed", entry.Caller)
+		}
+		return errors.New("disk full")
+	}
+
+	if err := execHandler(context.Background(), validExecHandlerInput(), deps); err != nil {
+		t.Fatalf("execHandler() error = %v, want the session result", err)
+	}
+	if appended != 1 || stsClient.calls != 1 {
+		t.Fatalf("append/STS calls = %d/%d, want 1/1", appended, stsClient.calls)
+	}
+	for _, want := range []string{"expired token", "was not recorded in the audit log: disk full"} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("stderr = %q, want %q", stderr.String(), want)
+		}
+	}
+}
+
+func TestAuditInputWarnsWhenTheInputCannotBeEncoded(t *testing.T) {
+	var stderr strings.Builder
+	deps := dependencies{stderr: &stderr}
+
+	if got := auditInput(deps, map[string]any{"wait": func() {}}, target.Resolved{}, handlerRegion); got != nil {
+		t.Fatalf("auditInput() = %s, want nil", got)
+	}
+	if !strings.HasPrefix(stderr.String(), "warning: the audit log does not record the input for a re-run: json: unsupported type") {
+		t.Fatalf("stderr = %q, want a warning", stderr.String())
+	}
+}
+
+func TestExitStatusMatchesTheProcessExit(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{err: nil, want: 0},
+		{err: &command.ExitError{Code: 3}, want: 3},
+		{err: fmt.Errorf("forward %q: %w", "db", &interrupt.Error{Signal: syscall.SIGINT}), want: 130},
+		{err: errors.New("plugin failed"), want: 1},
+	}
+	for _, tt := range tests {
+		if got := exitStatus(tt.err); got != tt.want {
+			t.Errorf("exitStatus(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+// decodeEvents decodes the events written for --output json.
+func decodeEvents(t *testing.T, output string) []map[string]any {
+	t.Helper()
+	var decoded []map[string]any
+	for line := range strings.Lines(output) {
+		var fields map[string]any
+		if err := json.Unmarshal([]byte(line), &fields); err != nil {
+			t.Fatalf("event %q is not a JSON object: %v", line, err)
+		}
+		decoded = append(decoded, fields)
+	}
+	return decoded
+}
+
+func eventNames(decoded []map[string]any) []string {
+	names := make([]string, 0, len(decoded))
+	for _, fields := range decoded {
+		names = append(names, fmt.Sprint(fields["event"]))
+	}
+	return names
+}
+
+func TestExecHandlerRejectsInteractiveSessionWithEvents(t *testing.T) {
+	var events []string
+	ecsClient := newHandlerECS(&events)
+	deps := handlerDependencies(t, &events, ecsClient, &handlerSSM{events: &events}, &handlerPlugin{events: &events})
+	// The events share stdout with the terminal, as they do in tnnl.
+	var stdout strings.Builder
+	deps.stdout = &stdout
+	deps.events = event.NewEmitter(&stdout, time.Now)
+
+	err := execHandler(context.Background(), validExecHandlerInput(), deps)
+	var invalid *input.InvalidError
+	if !errors.As(err, &invalid) || !strings.Contains(err.Error(), "--batch") {
+		t.Fatalf("execHandler() error = %v, want invalid input asking for --batch", err)
+	}
+	if ecsClient.executeCalls != 0 || len(events) != 0 {
+		t.Fatalf("ExecuteCommand calls = %d, events = %q; want nothing started", ecsClient.executeCalls, events)
+	}
+	decodeEvents(t, stdout.String())
+}
+
+func TestExecHandlerReportsBatchSessionEvents(t *testing.T) {
+	var events []string
+	ecsClient := newHandlerECS(&events)
+	deps := handlerDependencies(t, &events, ecsClient, &handlerSSM{events: &events}, &handlerPlugin{events: &events})
+	deps.preflight = func(context.Context, session_manager.Options) (session_manager.Plugin, error) {
+		return &shellPlugin{ecsClient: ecsClient}, nil
+	}
+	clock := &reconnectClock{now: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}
+	var stdout, reported strings.Builder
+	deps.clock, deps.stdout, deps.stderr = clock, &stdout, &strings.Builder{}
+	deps.events = event.NewEmitter(&reported, clock.Now)
+	in := validExecHandlerInput()
+	in.Task, in.Cmd, in.Batch = "task-second", "echo out; printf last; exit 3", true
+
+	err := execHandler(context.Background(), in, deps)
+	var exitErr *command.ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 {
+		t.Fatalf("execHandler() error = %v, want exit status 3", err)
+	}
+	if stdout.Len() != 0 {
+		t.Fatalf("stdout = %q, want only events", stdout.String())
+	}
+	decoded := decodeEvents(t, reported.String())
+	want := []string{"target_resolved", "session_started", "output", "output", "session_ended"}
+	if got := eventNames(decoded); !slices.Equal(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+
+	sessionID := aws.ToString(ecsClient.executeOutput.Session.SessionId)
+	resolved := decoded[0]["target"].(map[string]any)
+	if resolved["task_arn"] != handlerSecondTaskARN || resolved["container"] != handlerContainer ||
+		resolved["ssm_target"] != "ecs:"+resolved["cluster"].(string)+"_"+resolved["task_id"].(string)+"_"+resolved["runtime_id"].(string) {
+		t.Fatalf("target_resolved = %v", decoded[0])
+	}
+	if decoded[1]["session_id"] != sessionID || decoded[1]["command"] != "exec" || decoded[1]["remote_command"] != in.Cmd {
+		t.Fatalf("session_started = %v", decoded[1])
+	}
+	if decoded[2]["line"] != "out" || decoded[3]["line"] != "last" || decoded[2]["task_id"] != resolved["task_id"] {
+		t.Fatalf("output events = %v, %v", decoded[2], decoded[3])
+	}
+	ended := decoded[4]
+	errorInfo, _ := ended["error"].(map[string]any)
+	if ended["session_id"] != sessionID || ended["exit_status"] != 3.0 || errorInfo["code"] != "remote_exit" || errorInfo["exit_status"] != 3.0 {
+		t.Fatalf("session_ended = %v", ended)
+	}
+}
+
+func TestRemotePortForwardHandlerReportsLocalPort(t *testing.T) {
+	var events []string
+	ecsClient := newHandlerECS(&events)
+	ssmClient := &handlerSSM{events: &events, startOutput: validHandlerStartOutput()}
+	deps := handlerDependencies(t, &events, ecsClient, ssmClient, &handlerPlugin{events: &events})
+	clock := &reconnectClock{now: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}
+	var reported strings.Builder
+	deps.clock = clock
+	deps.events = event.NewEmitter(&reported, clock.Now)
+	deps.availablePort = func() (int, error) { return 49152, nil }
+	in := input.RemotePortForwardInput{
+		EcsParameter:     input.EcsParameter{Cluster: handlerClusterARN, Service: "service-web"},
+		RemotePortNumber: "3306",
+		Host:             "db.internal",
+	}
+
+	if err := remotePortForwardHandler(context.Background(), in, deps); err != nil {
+		t.Fatalf("remotePortForwardHandler() error = %v", err)
+	}
+	decoded := decodeEvents(t, reported.String())
+	if got, want := eventNames(decoded), []string{"target_resolved", "session_started", "session_ended"}; !slices.Equal(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	started := decoded[1]
+	if started["local_port"] != "49152" || started["remote_port"] != "3306" || started["host"] != "db.internal" ||
+		started["session_id"] != aws.ToString(validHandlerStartOutput().SessionId) {
+		t.Fatalf("session_started = %v", started)
+	}
+	if _, failed := decoded[2]["error"]; failed || decoded[2]["exit_status"] != 0.0 {
+		t.Fatalf("session_ended = %v, want a clean end", decoded[2])
+	}
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/wim-web/tnnl/internal/check"
+	"github.com/wim-web/tnnl/internal/event"
+	"github.com/wim-web/tnnl/internal/input"
+	"github.com/wim-web/tnnl/internal/session_manager"
+	"github.com/wim-web/tnnl/internal/target"
+	"github.com/wim-web/tnnl/internal/view"
+)
+
+func CheckHandler(ctx context.Context, in input.CheckInput) error {
+	return checkHandler(ctx, in, productionDependencies(ctx))
+}
+
+// checkHandler prints the ECS Exec prerequisites of one running task and
+// fails when any check fails. The task does not need to be eligible; a
+// container selector limits the container checks instead of the task lookup.
+func checkHandler(ctx context.Context, in input.CheckInput, deps dependencies) error {
+	connection, cluster, quit, err := discoverCluster(ctx, deps, in.ConnectionParameter, in.Cluster, targetSelector(in.EcsParameter))
+	if err != nil || quit {
+		return err
+	}
+	in.ConnectionParameter, in.Cluster = connection, cluster
+
+	cfg, err := deps.loadConfig(ctx, in.ConnectionParameter)
+	if err != nil {
+		return fmt.Errorf("load AWS configuration: %w", err)
+	}
+
+	ecsClient := deps.newECS(cfg)
+	selector := targetSelector(in.EcsParameter)
+	selector.Container = ""
+	ecsCluster, diagnosis, quit, err := view.ResolveTask(ctx, target.NewResolver(ecsClient), deps.choose, recentTargets(deps), in.Cluster, in.Service, selector)
+	if err != nil || quit {
+		return err
+	}
+
+	results := []check.Result{sessionClientResult(ctx, deps, in.ConnectionParameter)}
+	results = append(results, check.NewChecker(ecsClient, deps.newIAM(cfg)).Run(ctx, ecsCluster, diagnosis, in.Container)...)
+
+	taskID, err := target.TaskID(aws.ToString(diagnosis.Task.TaskArn))
+	if err != nil {
+		taskID = aws.ToString(diagnosis.Task.TaskArn)
+	}
+	clusterName, err := target.ClusterName(ecsCluster)
+	if err != nil {
+		return err
+	}
+	if err := writeCheckResults(deps, clusterName, taskID, results); err != nil {
+		return err
+	}
+	if failed := check.Failed(results); failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(results))
+	}
+	return nil
+}
+
+// checkEvent carries the status of one check on the task, with the detail
+// and the hint shown beside it. One is emitted per check, in place of the
+// checklist, once every check has run.
+type checkEvent struct {
+	Cluster string       `json:"cluster"`
+	TaskID  string       `json:"task_id"`
+	Name    string       `json:"name"`
+	Status  check.Status `json:"status"`
+	Detail  string       `json:"detail"`
+	Hint    string       `json:"hint,omitempty"`
+}
+
+// writeCheckResults prints the checklist, or reports each result as a check
+// event for --output json.
+func writeCheckResults(deps dependencies, clusterName, taskID string, results []check.Result) error {
+	if !deps.events.Enabled() {
+		if _, err := fmt.Fprintf(deps.stdout, "ECS Exec prerequisites for task %s in cluster %s\n", taskID, clusterName); err != nil {
+			return err
+		}
+		return check.Write(deps.stdout, results)
+	}
+	for _, result := range results {
+		err := deps.events.Emit(event.Check, checkEvent{
+			Cluster: clusterName,
+			TaskID:  taskID,
+			Name:    result.Name,
+			Status:  result.Status,
+			Detail:  result.Detail,
+			Hint:    result.Hint,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sessionClientResult(ctx context.Context, deps dependencies, connection input.ConnectionParameter) check.Result {
+	client := connection.SessionClient
+	if client == "" {
+		client = session_manager.ClientPlugin
+	}
+	result := check.Result{Name: "session client", Status: check.StatusPass, Detail: client + " is ready"}
+	if _, err := deps.preflight(ctx, sessionOptions(connection)); err != nil {
+		result.Status, result.Detail = check.StatusFail, err.Error()
+		result.Hint = "install session-manager-plugin or use --session-client native"
+	}
+	return result
+}
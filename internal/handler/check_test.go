@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/wim-web/tnnl/internal/check"
+	"github.com/wim-web/tnnl/internal/event"
+	"github.com/wim-web/tnnl/internal/input"
+	"github.com/wim-web/tnnl/internal/session_manager"
+)
+
+type handlerIAM struct {
+	events *[]string
+}
+
+func (f *handlerIAM) SimulatePrincipalPolicy(_ context.Context, in *iam.SimulatePrincipalPolicyInput, _ ...func(*iam.Options)) (*iam.SimulatePrincipalPolicyOutput, error) {
+	appendEvent(f.events, "simulate")
+	out := &iam.SimulatePrincipalPolicyOutput{}
+	for _, action := range in.ActionNames {
+		out.EvaluationResults = append(out.EvaluationResults, iamtypes.EvaluationResult{
+			EvalActionName: aws.String(action),
+			EvalDecision:   iamtypes.PolicyEvaluationDecisionTypeAllowed,
+		})
+	}
+	return out, nil
+}
+
+func checkHandlerDependencies(t *testing.T, events *[]string, ecsClient *handlerECS, stdout *bytes.Buffer) dependencies {
+	t.Helper()
+	deps := handlerDependencies(t, events, ecsClient, &handlerSSM{events: events}, &handlerPlugin{events: events})
+	deps.newIAM = func(aws.Config) check.IAMAPI { return &handlerIAM{events: events} }
+	deps.stdout = stdout
+	return deps
+}
+
+func TestCheckHandlerPrintsChecklistForChosenTask(t *testing.T) {
+	var events []string
+	ecsClient := newHandlerECS(&events)
+	ecsClient.clusters = []ecstypes.Cluster{{Status: aws.String("ACTIVE")}}
+	ecsClient.taskDefinition = &ecstypes.TaskDefinition{TaskRoleArn: aws.String("arn:aws:iam::123456789012:role/task")}
+	var stdout bytes.Buffer
+	deps := checkHandlerDependencies(t, &events, ecsClient, &stdout)
+
+	in := input.CheckInput{EcsParameter: input.EcsParameter{Cluster: handlerClusterARN, Service: "service-web"}}
+	if err := checkHandler(context.Background(), in, deps); err != nil {
+		t.Fatalf("checkHandler() error = %v\n%s", err, stdout.String())
+	}
+	wantEvents := []string{"load-config", "list-tasks", "describe-targets", "choose-task", "preflight", "describe-clusters", "describe-task-definition", "simulate"}
+	if !reflect.DeepEqual(events, wantEvents) {
+		t.Fatalf("events = %v, want %v", events, wantEvents)
+	}
+	for _, want := range []string{"task task-second in cluster production", "[PASS] session client", "[PASS] task role Session Manager", "[PASS] container " + handlerContainer} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("stdout = %q, want it to contain %q", stdout.String(), want)
+		}
+	}
+}
+
+func TestCheckHandlerFailsWhenAnyCheckFails(t *testing.T) {
+	var events []string
+	ecsClient := newHandlerECS(&events)
+	ecsClient.clusters = []ecstypes.Cluster{{Status: aws.String("ACTIVE")}}
+	ecsClient.taskDefinition = &ecstypes.TaskDefinition{}
+	var stdout bytes.Buffer
+	deps := checkHandlerDependencies(t, &events, ecsClient, &stdout)
+	deps.preflight = func(context.Context, session_manager.Options) (session_manager.Plugin, error) {
+		return nil, errors.New("session-manager-plugin is required")
+	}
+
+	in := input.CheckInput{EcsParameter: input.EcsParameter{Cluster: handlerClusterARN, Service: "service-web", Task: "task-first"}}
+	err := checkHandler(context.Background(), in, deps)
+	if err == nil || err.Error() != "2 of 5 checks failed" {
+		t.Fatalf("checkHandler() error = %v, want 2 of 5 checks failed\n%s", err, stdout.String())
+	}
+	for _, want := range []string{"task task-first", "[FAIL] session client: session-manager-plugin is required", "[FAIL] task definition: task definition has no task role"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("stdout = %q, want it to contain %q", stdout.String(), want)
+		}
+	}
+}
+
+func TestCheckHandlerReportsCheckEvents(t *testing.T) {
+	var events []string
+	ecsClient := newHandlerECS(&events)
+	ecsClient.clusters = []ecstypes.Cluster{{Status: aws.String("ACTIVE")}}
+	ecsClient.taskDefinition = &ecstypes.TaskDefinition{TaskRoleArn: aws.String("arn:aws:iam::123456789012:role/task")}
+	var stdout bytes.Buffer
+	var reported strings.Builder
+	deps := checkHandlerDependencies(t, &events, ecsClient, &stdout)
+	deps.events = event.NewEmitter(&reported, time.Now)
+
+	in := input.CheckInput{EcsParameter: input.EcsParameter{Cluster: handlerClusterARN, Service: "service-web"}}
+	if err := checkHandler(context.Background(), in, deps); err != nil {
+		t.Fatalf("checkHandler() error = %v", err)
+	}
+	if stdout.Len() != 0 {
+		t.Fatalf("stdout = %q, want only events", stdout.String())
+	}
+	decoded := decodeEvents(t, reported.String())
+	if len(decoded) < 2 {
+		t.Fatalf("events = %v, want one per check", decoded)
+	}
+	first := decoded[0]
+	if first["event"] != "check" || first["name"] != "session client" || first["status"] != "PASS" ||
+		first["cluster"] != "production" || first["task_id"] != "task-second" {
+		t.Fatalf("first check event = %v", first)
+	}
+}
@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/wim-web/tnnl/internal/audit"
+	"github.com/wim-web/tnnl/internal/input"
+	"github.com/wim-web/tnnl/internal/session_manager"
+	"github.com/wim-web/tnnl/internal/target"
+	"github.com/wim-web/tnnl/internal/view"
+	"github.com/wim-web/tnnl/pkg/command"
+)
+
+func CopyHandler(ctx context.Context, in input.CopyInput) error {
+	return copyHandler(ctx, in, productionDependencies(ctx))
+}
+
+// copyHandler copies a file or directory between the local machine and an
+// eligible container over a batch exec session, and verifies the copy
+// against the container's sha256sum when it has one.
+func copyHandler(ctx context.Context, in input.CopyInput, deps dependencies) error {
+	var source string
+	if in.Upload() {
+		source = filepath.Clean(in.Source)
+		if _, err := os.Stat(source); err != nil {
+			return fmt.Errorf("copy source: %w", err)
+		}
+	}
+
+	connection, cluster, quit, err := discoverCluster(ctx, deps, in.ConnectionParameter, in.Cluster, targetSelector(in.EcsParameter))
+	if err != nil || quit {
+		return err
+	}
+	in.ConnectionParameter, in.Cluster = connection, cluster
+
+	plugin, err := deps.preflight(ctx, sessionOptions(in.ConnectionParameter))
+	if err != nil {
+		return err
+	}
+
+	cfg, err := deps.loadConfig(ctx, in.ConnectionParameter)
+	if err != nil {
+		return fmt.Errorf("load AWS configuration: %w", err)
+	}
+	plugin = withAssumedRole(plugin, in.ConnectionParameter, cfg)
+
+	ecsClient := deps.newECS(cfg)
+	resolved, quit, err := view.ResolveTarget(ctx, target.NewResolver(ecsClient), deps.choose, view.ResolveOptions{
+		Cluster:        in.Cluster,
+		Service:        in.Service,
+		Selector:       targetSelector(in.EcsParameter),
+		ShowIneligible: in.ShowIneligible,
+		History:        recentTargets(deps),
+		ChooseTable:    deps.chooseTable,
+	})
+	if err != nil || quit {
+		return err
+	}
+	rememberTarget(deps, resolved)
+
+	marker, err := command.NewBatchMarker()
+	if err != nil {
+		return err
+	}
+	var script string
+	if in.Upload() {
+		destination, _ := input.ContainerPath(in.Destination)
+		script = command.UploadScript(destination, filepath.Base(source), marker)
+	} else {
+		remotePath, _ := input.ContainerPath(in.Source)
+		script = command.DownloadScript(remotePath, marker)
+	}
+	remote, err := command.StartExecSession(
+		ctx,
+		ecsClient,
+		deps.newSSM(cfg),
+		command.ExecTarget{
+			Cluster:       resolved.ECSCluster,
+			TaskARN:       resolved.TaskARN,
+			ContainerName: resolved.ContainerName,
+		},
+		command.BatchCommand(script, marker),
+		cfg.Region,
+	)
+	if err != nil {
+		return err
+	}
+
+	// Progress and the command's stderr both go to stderr while the copy runs.
+	stderr := command.NewSharedOutput(deps.stderr)
+	sessionAudit := newSessionAudit(deps, cfg, "cp", auditInput(deps, in, resolved, cfg.Region))
+	entry := audit.Entry{Document: execDocument, Remote: fmt.Sprintf("cp %s %s", in.Source, in.Destination)}
+	return sessionAudit.run(ctx, resolved, remote, entry, func() error {
+		if in.Upload() {
+			return upload(ctx, remote, plugin, marker, source, stderr)
+		}
+		return download(ctx, remote, plugin, marker, in, stderr)
+	})
+}
+
+func upload(
+	ctx context.Context,
+	remote command.RemoteSession,
+	plugin session_manager.Plugin,
+	marker string,
+	source string,
+	stderr io.Writer,
+) error {
+	name := filepath.Base(source)
+	progress := command.NewProgress(stderr, name)
+	reader, writer := io.Pipe()
+	type archived struct {
+		sums command.Checksums
+		err  error
+	}
+	done := make(chan archived, 1)
+	go func() {
+		sums, err := command.WriteUpload(writer, source, name, progress)
+		writer.CloseWithError(err)
+		done <- archived{sums: sums, err: err}
+	}()
+
+	output := command.NewCopyOutput(marker, nil)
+	runErr := runBatch(ctx, remote, plugin, marker, 0, reader, output, stderr)
+	sent := <-done
+	progress.Done()
+	if runErr != nil {
+		return runErr
+	}
+	if sent.err != nil {
+		return fmt.Errorf("upload %s: %w", source, sent.err)
+	}
+	if err := output.Close(); err != nil {
+		return err
+	}
+	remoteSums, reported, err := output.Checksums()
+	if err != nil {
+		return err
+	}
+	return verifyCopy(stderr, sent.sums, remoteSums, reported, len(sent.sums))
+}
+
+func download(
+	ctx context.Context,
+	remote command.RemoteSession,
+	plugin session_manager.Plugin,
+	marker string,
+	in input.CopyInput,
+	stderr io.Writer,
+) error {
+	remotePath, _ := input.ContainerPath(in.Source)
+	name := path.Base(remotePath)
+	dir, localName := in.Destination, name
+	if info, err := os.Stat(in.Destination); err != nil || !info.IsDir() {
+		dir, localName = filepath.Dir(in.Destination), filepath.Base(in.Destination)
+	}
+
+	extractor := command.NewExtractor(dir, localName)
+	progress := command.NewProgress(stderr, name)
+	output := command.NewCopyOutput(marker, io.MultiWriter(extractor, progress))
+	runErr := runBatch(ctx, remote, plugin, marker, 0, nil, output, stderr)
+	closeErr := output.Close()
+	localSums, extractErr := extractor.Close()
+	progress.Done()
+	if runErr != nil {
+		return runErr
+	}
+	if err := errors.Join(closeErr, extractErr); err != nil {
+		return fmt.Errorf("download %s: %w", remotePath, err)
+	}
+	remoteSums, reported, err := output.Checksums()
+	if err != nil {
+		return err
+	}
+	return verifyCopy(stderr, remoteSums, localSums, reported, len(localSums))
+}
+
+// verifyCopy compares the checksums of the copied files with their source.
+// A container without sha256sum reports none; the copy is then only warned
+// about, unless there were no files to verify.
+func verifyCopy(w io.Writer, source, copied command.Checksums, reported bool, files int) error {
+	if !reported {
+		if files > 0 {
+			fmt.Fprintln(w, "warning: the container has no sha256sum; the copy was not verified")
+		}
+		return nil
+	}
+	if err := command.VerifyChecksums(source, copied); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "verified SHA-256 of %d files\n", len(source))
+	return err
+}
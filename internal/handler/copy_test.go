@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wim-web/tnnl/internal/input"
+	"github.com/wim-web/tnnl/internal/session_manager"
+	"github.com/wim-web/tnnl/pkg/command"
+)
+
+func TestCopyHandlerCopiesBothWaysAndVerifiesChecksums(t *testing.T) {
+	tests := []struct {
+		name     string
+		upload   bool
+		dir      bool
+		into     bool
+		wantPath string
+	}{
+		{name: "upload file into directory", upload: true, into: true, wantPath: "source"},
+		{name: "upload directory renamed", upload: true, dir: true, wantPath: "renamed"},
+		{name: "download file renamed", wantPath: "renamed"},
+		{name: "download directory into directory", dir: true, into: true, wantPath: "source"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := t.TempDir(), t.TempDir()
+			source := filepath.Join(from, "source")
+			writeCopyFixture(t, source, tt.dir)
+			destination := filepath.Join(to, "renamed")
+			if tt.into {
+				destination = to
+			}
+
+			var events []string
+			ecsClient := newHandlerECS(&events)
+			deps := handlerDependencies(t, &events, ecsClient, &handlerSSM{events: &events}, &handlerPlugin{events: &events})
+			deps.preflight = func(context.Context, session_manager.Options) (session_manager.Plugin, error) {
+				return &shellPlugin{ecsClient: ecsClient}, nil
+			}
+			var stderr strings.Builder
+			deps.stderr = &stderr
+			in := input.CopyInput{
+				EcsParameter: input.EcsParameter{Cluster: handlerClusterARN, Service: "service-web", Task: "task-second", Container: handlerContainer},
+				Source:       source,
+				Destination:  ":" + destination,
+			}
+			if !tt.upload {
+				in.Source, in.Destination = ":"+source, destination
+			}
+
+			if err := copyHandler(context.Background(), in, deps); err != nil {
+				t.Fatalf("copyHandler() error = %v, stderr %q", err, stderr.String())
+			}
+			assertCopyFixture(t, filepath.Join(to, tt.wantPath), tt.dir)
+			if !strings.Contains(stderr.String(), "verified SHA-256 of") {
+				t.Fatalf("stderr = %q, want the copy verified", stderr.String())
+			}
+		})
+	}
+}
+
+func TestCopyHandlerReportsMissingRemoteSource(t *testing.T) {
+	var events []string
+	ecsClient := newHandlerECS(&events)
+	deps := handlerDependencies(t, &events, ecsClient, &handlerSSM{events: &events}, &handlerPlugin{events: &events})
+	deps.preflight = func(context.Context, session_manager.Options) (session_manager.Plugin, error) {
+		return &shellPlugin{ecsClient: ecsClient}, nil
+	}
+	var stderr strings.Builder
+	deps.stderr = &stderr
+	missing := filepath.Join(t.TempDir(), "missing")
+	in := input.CopyInput{
+		EcsParameter: input.EcsParameter{Cluster: handlerClusterARN, Service: "service-web", Task: "task-second", Container: handlerContainer},
+		Source:       ":" + missing,
+		Destination:  t.TempDir(),
+	}
+
+	err := copyHandler(context.Background(), in, deps)
+	var exitErr *command.ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 1 {
+		t.Fatalf("copyHandler() error = %v, want exit status 1", err)
+	}
+	if !strings.Contains(stderr.String(), missing+": No such file or directory") {
+		t.Fatalf("stderr = %q, want the missing path reported", stderr.String())
+	}
+}
+
+func writeCopyFixture(t *testing.T, source string, dir bool) {
+	t.Helper()
+	if !dir {
+		if err := os.WriteFile(source, []byte("payload\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	if err := os.MkdirAll(filepath.Join(source, "nested"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"top.txt": "top\n", "nested/deep.txt": "deep\n"} {
+		if err := os.WriteFile(filepath.Join(source, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func assertCopyFixture(t *testing.T, copied string, dir bool) {
+	t.Helper()
+	want := map[string]string{"": "payload\n"}
+	if dir {
+		want = map[string]string{"top.txt": "top\n", "nested/deep.txt": "deep\n"}
+	}
+	for name, content := range want {
+		got, err := os.ReadFile(filepath.Join(copied, name))
+		if err != nil {
+			t.Fatalf("read copy: %v", err)
+		}
+		if string(got) != content {
+			t.Fatalf("copy of %q = %q, want %q", name, got, content)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/wim-web/tnnl/internal/input"
+	"github.com/wim-web/tnnl/internal/session_manager"
+)
+
+// assumeRole returns cached credentials for connection.RoleARN obtained with
+// the base credentials behind client. tokens is asked for an MFA code only
+// when a serial is configured.
+func assumeRole(
+	client stscreds.AssumeRoleAPIClient,
+	connection input.ConnectionParameter,
+	tokens func() (string, error),
+	now time.Time,
+) aws.CredentialsProvider {
+	sessionName := connection.RoleSessionName
+	if sessionName == "" {
+		sessionName = fmt.Sprintf("tnnl-%d", now.Unix())
+	}
+	return aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(client, connection.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = sessionName
+		if connection.ExternalID != "" {
+			o.ExternalID = aws.String(connection.ExternalID)
+		}
+		if connection.MFASerial != "" {
+			o.SerialNumber = aws.String(connection.MFASerial)
+			o.TokenProvider = tokens
+		}
+	}))
+}
+
+// roleKey identifies the assumed-role credentials a connection asks for.
+type roleKey struct {
+	profile, roleARN, externalID, sessionName, mfaSerial string
+}
+
+// roleProviders shares one assumed-role provider per profile, role, and MFA
+// device across the configurations a command loads. The discovery locations,
+// loaded concurrently, and the load after a cluster is chosen then assume the
+// role once and ask for one MFA code. Prompts are asked one at a time.
+type roleProviders struct {
+	prompt func(serial string) (string, error)
+	now    func() time.Time
+
+	mu        sync.Mutex
+	providers map[roleKey]aws.CredentialsProvider
+	promptMu  sync.Mutex
+}
+
+func newRoleProviders(prompt func(serial string) (string, error), now func() time.Time) *roleProviders {
+	return &roleProviders{prompt: prompt, now: now, providers: map[roleKey]aws.CredentialsProvider{}}
+}
+
+// provider returns the provider for connection, assuming the role through
+// client when it is first asked for.
+func (r *roleProviders) provider(client stscreds.AssumeRoleAPIClient, connection input.ConnectionParameter) aws.CredentialsProvider {
+	key := roleKey{
+		profile:     connection.Profile,
+		roleARN:     connection.RoleARN,
+		externalID:  connection.ExternalID,
+		sessionName: connection.RoleSessionName,
+		mfaSerial:   connection.MFASerial,
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if provider, ok := r.providers[key]; ok {
+		return provider
+	}
+	provider := assumeRole(client, connection, func() (string, error) {
+		r.promptMu.Lock()
+		defer r.promptMu.Unlock()
+		return r.prompt(connection.MFASerial)
+	}, r.now())
+	r.providers[key] = provider
+	return provider
+}
+
+// withAssumedRole hands the assumed role credentials to the session client so
+// that it does not fall back to the base profile.
+func withAssumedRole(plugin session_manager.Plugin, connection input.ConnectionParameter, cfg aws.Config) session_manager.Plugin {
+	if connection.RoleARN == "" {
+		return plugin
+	}
+	return session_manager.WithCredentials(plugin, cfg.Credentials)
+}
+
+// promptMFACode asks for the MFA code on the controlling terminal, falling
+// back to stdin and stderr when there is none.
+func promptMFACode(serial string) (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return readMFACode(os.Stdin, os.Stderr, serial)
+	}
+	defer tty.Close()
+	return readMFACode(tty, tty, serial)
+}
+
+func readMFACode(r io.Reader, w io.Writer, serial string) (string, error) {
+	fmt.Fprintf(w, "MFA code for %s: ", serial)
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", fmt.Errorf("read MFA code: %w", err)
+	}
+	code := strings.TrimSpace(line)
+	if len(code) != 6 || strings.Trim(code, "0123456789") != "" {
+		return "", fmt.Errorf("MFA code must be 6 digits")
+	}
+	return code, nil
+}
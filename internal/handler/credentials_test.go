@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/wim-web/tnnl/internal/input"
+	"github.com/wim-web/tnnl/internal/listview"
+	"github.com/wim-web/tnnl/internal/session_manager"
+	"github.com/wim-web/tnnl/internal/target"
+)
+
+type fakeSTS struct {
+	inputs []*sts.AssumeRoleInput
+}
+
+func (f *fakeSTS) AssumeRole(_ context.Context, in *sts.AssumeRoleInput, _ ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+	f.inputs = append(f.inputs, in)
+	return &sts.AssumeRoleOutput{Credentials: &ststypes.Credentials{
+		AccessKeyId:     aws.String("ASIAASSUMED"),
+		SecretAccessKey: aws.String("secret"),
+		SessionToken:    aws.String("token"),
+		Expiration:      aws.Time(time.Now().Add(time.Hour)),
+	}}, nil
+}
+
+func TestAssumeRolePassesRoleOptionsAndMFACode(t *testing.T) {
+	client := &fakeSTS{}
+	var prompts int
+	provider := assumeRole(client, input.ConnectionParameter{
+		RoleARN:    "arn:aws:iam::123456789012:role/ops",
+		ExternalID: "ext",
+		MFASerial:  "arn:aws:iam::111111111111:mfa/alice",
+	}, func() (string, error) {
+		prompts++
+		return "123456", nil
+	}, time.Unix(1700000000, 0))
+
+	for range 2 {
+		creds, err := provider.Retrieve(context.Background())
+		if err != nil {
+			t.Fatalf("Retrieve() error = %v", err)
+		}
+		if creds.AccessKeyID != "ASIAASSUMED" || creds.SessionToken != "token" {
+			t.Fatalf("Retrieve() = %#v, want the assumed role credentials", creds)
+		}
+	}
+	if len(client.inputs) != 1 || prompts != 1 {
+		t.Fatalf("AssumeRole calls = %d, prompts = %d; want the credentials cached", len(client.inputs), prompts)
+	}
+	got := client.inputs[0]
+	for name, tt := range map[string]struct{ got, want string }{
+		"RoleArn":         {aws.ToString(got.RoleArn), "arn:aws:iam::123456789012:role/ops"},
+		"ExternalId":      {aws.ToString(got.ExternalId), "ext"},
+		"RoleSessionName": {aws.ToString(got.RoleSessionName), "tnnl-1700000000"},
+		"SerialNumber":    {aws.ToString(got.SerialNumber), "arn:aws:iam::111111111111:mfa/alice"},
+		"TokenCode":       {aws.ToString(got.TokenCode), "123456"},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("AssumeRoleInput.%s = %q, want %q", name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestAssumeRoleWithoutMFADoesNotPrompt(t *testing.T) {
+	client := &fakeSTS{}
+	provider := assumeRole(client, input.ConnectionParameter{
+		RoleARN:         "arn:aws:iam::123456789012:role/ops",
+		RoleSessionName: "alice",
+	}, func() (string, error) {
+		t.Fatal("MFA prompt called without a serial")
+		return "", nil
+	}, time.Now())
+
+	if _, err := provider.Retrieve(context.Background()); err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	got := client.inputs[0]
+	if aws.ToString(got.RoleSessionName) != "alice" || got.ExternalId != nil || got.SerialNumber != nil || got.TokenCode != nil {
+		t.Fatalf("AssumeRoleInput = %#v, want only the role and session name", got)
+	}
+}
+
+func TestReadMFACode(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr string
+	}{
+		{name: "code", in: "123456\n", want: "123456"},
+		{name: "without newline", in: " 654321 ", want: "654321"},
+		{name: "too short", in: "12345\n", wantErr: "6 digits"},
+		{name: "not digits", in: "12a456\n", wantErr: "6 digits"},
+		{name: "empty input", in: "", wantErr: "read MFA code"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prompt bytes.Buffer
+			got, err := readMFACode(strings.NewReader(tt.in), &prompt, "mfa-serial")
+			if prompt.String() != "MFA code for mfa-serial: " {
+				t.Fatalf("prompt = %q", prompt.String())
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("readMFACode() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("readMFACode() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithAssumedRoleOnlyWrapsRoleConnections(t *testing.T) {
+	plugin := &session_manager.Runner{}
+	cfg := aws.Config{Credentials: aws.AnonymousCredentials{}}
+	if got := withAssumedRole(plugin, input.ConnectionParameter{}, cfg); got != session_manager.Plugin(plugin) {
+		t.Fatalf("withAssumedRole() without role = %#v, want the plugin unchanged", got)
+	}
+	if got := withAssumedRole(plugin, input.ConnectionParameter{RoleARN: "arn:aws:iam::123456789012:role/ops"}, cfg); got == session_manager.Plugin(plugin) {
+		t.Fatal("withAssumedRole() with role returned the plugin unchanged")
+	}
+}
+
+func TestDiscoveryLocationsShareOneMFAPrompt(t *testing.T) {
+	var events []string
+	ecsClient := newHandlerECS(&events)
+	ecsClient.listClustersOutput = &ecs.ListClustersOutput{ClusterArns: []string{handlerClusterARN}}
+	otherRegion := &handlerECS{listClustersOutput: &ecs.ListClustersOutput{
+		ClusterArns: []string{"arn:aws:ecs:us-east-1:210987654321:cluster/staging"},
+	}}
+	deps := handlerDependencies(t, &events, ecsClient, &handlerSSM{events: &events}, &handlerPlugin{events: &events})
+	client := &fakeSTS{}
+	var prompts atomic.Int32
+	roles := newRoleProviders(func(string) (string, error) {
+		prompts.Add(1)
+		// Keep the prompt open while the other location asks.
+		time.Sleep(10 * time.Millisecond)
+		return "123456", nil
+	}, time.Now)
+	// Each load signs a request with the role's credentials, as the ECS
+	// client listing the clusters does.
+	deps.loadConfig = func(ctx context.Context, connection input.ConnectionParameter) (aws.Config, error) {
+		cfg := aws.Config{Region: connection.Region}
+		cfg.Credentials = roles.provider(client, connection)
+		if _, err := cfg.Credentials.Retrieve(ctx); err != nil {
+			return aws.Config{}, err
+		}
+		return cfg, nil
+	}
+	deps.newECS = func(cfg aws.Config) ecsAPI {
+		if cfg.Region == "us-east-1" {
+			return otherRegion
+		}
+		return ecsClient
+	}
+	deps.choose = func(string, []listview.Option) (string, bool, error) {
+		return handlerClusterARN, false, nil
+	}
+	connection := input.ConnectionParameter{
+		Profile:         "prod",
+		RoleARN:         "arn:aws:iam::123456789012:role/ops",
+		MFASerial:       "arn:aws:iam::111111111111:mfa/alice",
+		DiscoverRegions: "us-east-1,ap-northeast-1",
+	}
+
+	chosen, cluster, quit, err := discoverCluster(context.Background(), deps, connection, "", target.Selector{})
+	if err != nil || quit || cluster != handlerClusterARN {
+		t.Fatalf("discoverCluster() = %q, %v, %v; want the chosen cluster", cluster, quit, err)
+	}
+	// The session loads the chosen location once more.
+	if _, err := deps.loadConfig(context.Background(), chosen); err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if got := prompts.Load(); got != 1 || len(client.inputs) != 1 {
+		t.Fatalf("MFA prompts = %d, AssumeRole calls = %d; want one of each", got, len(client.inputs))
+	}
+}
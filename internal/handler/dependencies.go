@@ -2,12 +2,23 @@ package handler
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
-	"github.com/wim-web/tnnl/internal/listview"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/wim-web/tnnl/internal/audit"
+	"github.com/wim-web/tnnl/internal/check"
+	"github.com/wim-web/tnnl/internal/event"
+	"github.com/wim-web/tnnl/internal/input"
+	"github.com/wim-web/tnnl/internal/picker"
+	"github.com/wim-web/tnnl/internal/recent"
 	"github.com/wim-web/tnnl/internal/session_manager"
 	"github.com/wim-web/tnnl/internal/target"
 	"github.com/wim-web/tnnl/internal/view"
@@ -17,6 +28,7 @@ import (
 
 type ecsAPI interface {
 	target.ECSAPI
+	check.ECSAPI
 	command.ExecSessionAPI
 }
 
@@ -25,18 +37,38 @@ type ssmAPI interface {
 }
 
 type dependencies struct {
-	loadConfig    func(context.Context) (aws.Config, error)
+	loadConfig    func(context.Context, input.ConnectionParameter) (aws.Config, error)
 	newECS        func(aws.Config) ecsAPI
 	newSSM        func(aws.Config) ssmAPI
-	preflight     func(context.Context) (session_manager.Plugin, error)
+	newIAM        func(aws.Config) check.IAMAPI
+	newSTS        func(aws.Config) stsAPI
+	preflight     func(context.Context, session_manager.Options) (session_manager.Plugin, error)
 	choose        view.Choose
+	chooseTable   view.ChooseTable
 	availablePort func() (int, error)
+	listen        func(string) (*port.Listener, error)
+	stdout        io.Writer
+	stderr        io.Writer
+	clock         target.Clock
+	// appendAudit adds an entry to the audit log; nil keeps no log.
+	appendAudit func(audit.Entry) error
+	// loadRecent reads the targets chosen lately and rememberTarget adds
+	// one; nil keeps no history.
+	loadRecent     func() (recent.History, error)
+	rememberTarget func(recent.Choice) error
+	// events reports sessions for --output json; nil is text output.
+	events *event.Emitter
 }
 
-func productionDependencies() dependencies {
+// productionDependencies reports through the emitter ctx carries and asks
+// through its picker.
+func productionDependencies(ctx context.Context) dependencies {
+	chooser := picker.FromContext(ctx)
+	roles := newRoleProviders(promptMFACode, time.Now)
 	return dependencies{
-		loadConfig: func(ctx context.Context) (aws.Config, error) {
-			return config.LoadDefaultConfig(ctx)
+		loadConfig: func(ctx context.Context, connection input.ConnectionParameter) (aws.Config, error) {
+			cfg, err := loadAWSConfig(ctx, connection, roles)
+			return cfg, event.WithCode(event.CodeAWSConfig, err)
 		},
 		newECS: func(cfg aws.Config) ecsAPI {
 			return ecs.NewFromConfig(cfg)
@@ -44,8 +76,84 @@ func productionDependencies() dependencies {
 		newSSM: func(cfg aws.Config) ssmAPI {
 			return ssm.NewFromConfig(cfg)
 		},
-		preflight:     session_manager.Preflight,
-		choose:        listview.RenderOptions,
-		availablePort: port.AvailablePort,
+		newIAM: func(cfg aws.Config) check.IAMAPI {
+			return iam.NewFromConfig(cfg)
+		},
+		newSTS: func(cfg aws.Config) stsAPI {
+			return sts.NewFromConfig(cfg)
+		},
+		preflight: func(ctx context.Context, options session_manager.Options) (session_manager.Plugin, error) {
+			plugin, err := session_manager.Preflight(ctx, options)
+			return plugin, event.WithCode(event.CodeSessionClient, err)
+		},
+		choose:         chooser.Choose,
+		chooseTable:    chooser.ChooseTable,
+		availablePort:  port.AvailablePort,
+		listen:         port.Listen,
+		stdout:         os.Stdout,
+		stderr:         os.Stderr,
+		clock:          target.RealClock(),
+		appendAudit:    appendAuditEntry,
+		loadRecent:     loadRecentTargets,
+		rememberTarget: rememberRecentTarget,
+		events:         event.FromContext(ctx),
+	}
+}
+
+// emit reports an event for --output json. The command goes on when the
+// event cannot be written.
+func emit(events *event.Emitter, stderr io.Writer, name string, fields any) {
+	if err := events.Emit(name, fields); err != nil {
+		fmt.Fprintf(stderr, "warning: %v\n", err)
+	}
+}
+
+// forwardPlugin keeps the standard output of a port forward's session client
+// off stdout for --output json, where it carries only events.
+func forwardPlugin(deps dependencies, plugin session_manager.Plugin) session_manager.Plugin {
+	if !deps.events.Enabled() {
+		return plugin
+	}
+	return session_manager.WithStreams(plugin, nil, deps.stderr, deps.stderr)
+}
+
+// loadAWSConfig loads the AWS SDK default configuration with the explicit
+// connection values layered on top. A role ARN replaces the loaded
+// credentials with the assumed role's, shared through roles.
+func loadAWSConfig(ctx context.Context, connection input.ConnectionParameter, roles *roleProviders) (aws.Config, error) {
+	var options []func(*config.LoadOptions) error
+	if connection.Profile != "" {
+		options = append(options, config.WithSharedConfigProfile(connection.Profile))
+	}
+	if connection.Region != "" {
+		options = append(options, config.WithRegion(connection.Region))
+	}
+	if connection.EndpointURL != "" {
+		options = append(options, config.WithBaseEndpoint(connection.EndpointURL))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return aws.Config{}, err
+	}
+	if connection.RoleARN != "" {
+		cfg.Credentials = roles.provider(sts.NewFromConfig(cfg), connection)
+	}
+	return cfg, nil
+}
+
+func sessionOptions(connection input.ConnectionParameter) session_manager.Options {
+	return session_manager.Options{
+		Client:   connection.SessionClient,
+		Profile:  connection.Profile,
+		Endpoint: connection.EndpointURL,
+	}
+}
+
+func targetSelector(ecsParam input.EcsParameter) target.Selector {
+	return target.Selector{
+		Task:      ecsParam.Task,
+		Container: ecsParam.Container,
+		Family:    ecsParam.Family,
+		Strategy:  ecsParam.Strategy,
 	}
 }
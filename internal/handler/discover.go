@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wim-web/tnnl/internal/input"
+	"github.com/wim-web/tnnl/internal/target"
+	"github.com/wim-web/tnnl/internal/view"
+)
+
+// discoverCluster lets the user pick the cluster from every discovery region
+// and profile when no cluster is given. The returned connection is narrowed
+// to the profile and Region of the chosen cluster so that the task lookup and
+// the session open there. An active selector is never asked: the cluster its
+// task ARN names is looked up among the discovered ones.
+func discoverCluster(
+	ctx context.Context,
+	deps dependencies,
+	connection input.ConnectionParameter,
+	cluster string,
+	selector target.Selector,
+) (input.ConnectionParameter, string, bool, error) {
+	if cluster != "" || !connection.Discovers() {
+		return connection, cluster, false, nil
+	}
+	var selected string
+	if selector.Active() {
+		var err error
+		if selected, err = view.SelectorCluster(selector); err != nil {
+			return connection, "", false, err
+		}
+	}
+
+	clusters, err := target.DiscoverClusters(ctx, discoveryLocations(connection), func(ctx context.Context, location target.Location) ([]string, error) {
+		cfg, err := deps.loadConfig(ctx, atLocation(connection, location.Profile, location.Region))
+		if err != nil {
+			return nil, fmt.Errorf("load AWS configuration: %w", err)
+		}
+		return target.NewResolver(deps.newECS(cfg)).Clusters(ctx)
+	})
+	if err != nil {
+		return connection, "", false, err
+	}
+	if selected != "" {
+		for _, discovered := range clusters {
+			if discovered.ARN == selected {
+				return atLocation(connection, discovered.Location.Profile, discovered.Region), discovered.ARN, false, nil
+			}
+		}
+		return connection, "", false, fmt.Errorf("ECS cluster %q of the selected task is in none of the discovery locations", selected)
+	}
+	chosen, quit, err := view.ChooseDiscoveredCluster(deps.choose, recentTargets(deps), clusters)
+	if err != nil || quit {
+		return connection, "", quit, err
+	}
+	return atLocation(connection, chosen.Location.Profile, chosen.Region), chosen.ARN, false, nil
+}
+
+// discoveryLocations combines every discovery profile with every discovery
+// Region. An unset list stands for the connection's own value.
+func discoveryLocations(connection input.ConnectionParameter) []target.Location {
+	profiles := connection.DiscoveryProfiles()
+	if len(profiles) == 0 {
+		profiles = []string{connection.Profile}
+	}
+	regions := connection.DiscoveryRegions()
+	if len(regions) == 0 {
+		regions = []string{connection.Region}
+	}
+	locations := make([]target.Location, 0, len(profiles)*len(regions))
+	for _, profile := range profiles {
+		for _, region := range regions {
+			locations = append(locations, target.Location{Profile: profile, Region: region})
+		}
+	}
+	return locations
+}
+
+func atLocation(connection input.ConnectionParameter, profile, region string) input.ConnectionParameter {
+	connection.Profile = profile
+	connection.Region = region
+	connection.DiscoverRegions = ""
+	connection.DiscoverProfiles = ""
+	return connection
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/wim-web/tnnl/internal/input"
+	"github.com/wim-web/tnnl/internal/listview"
+	"github.com/wim-web/tnnl/internal/session_manager"
+	"github.com/wim-web/tnnl/internal/target"
+)
+
+func TestExecHandlerDiscoversClustersAcrossRegions(t *testing.T) {
+	var events []string
+	ecsClient := newHandlerECS(&events)
+	ecsClient.listClustersOutput = &ecs.ListClustersOutput{ClusterArns: []string{handlerClusterARN}}
+	otherRegion := &handlerECS{listClustersOutput: &ecs.ListClustersOutput{
+		ClusterArns: []string{"arn:aws:ecs:us-east-1:210987654321:cluster/staging"},
+	}}
+	plugin := &handlerPlugin{events: &events}
+	deps := handlerDependencies(t, &events, ecsClient, &handlerSSM{events: &events}, plugin)
+	var (
+		mu          sync.Mutex
+		connections []input.ConnectionParameter
+	)
+	deps.loadConfig = func(_ context.Context, connection input.ConnectionParameter) (aws.Config, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		connections = append(connections, connection)
+		return aws.Config{Region: connection.Region}, nil
+	}
+	deps.newECS = func(cfg aws.Config) ecsAPI {
+		if cfg.Region == "us-east-1" {
+			return otherRegion
+		}
+		return ecsClient
+	}
+	var gotOptions session_manager.Options
+	deps.preflight = func(_ context.Context, options session_manager.Options) (session_manager.Plugin, error) {
+		gotOptions = options
+		return plugin, nil
+	}
+	var clusterLabels []string
+	deps.choose = func(title string, options []listview.Option) (string, bool, error) {
+		if strings.Contains(strings.ToLower(title), "cluster") {
+			for _, option := range options {
+				clusterLabels = append(clusterLabels, option.Label)
+			}
+			return handlerClusterARN, false, nil
+		}
+		return options[1].Value, false, nil
+	}
+	in := validExecHandlerInput()
+	in.Cluster = ""
+	in.ConnectionParameter = input.ConnectionParameter{Profile: "prod", DiscoverRegions: "us-east-1,ap-northeast-1"}
+
+	if err := execHandler(context.Background(), in, deps); err != nil {
+		t.Fatalf("execHandler() error = %v", err)
+	}
+	wantLabels := []string{"staging (210987654321/us-east-1)", "production (123456789012/ap-northeast-1)"}
+	if !reflect.DeepEqual(clusterLabels, wantLabels) {
+		t.Fatalf("cluster labels = %q, want %q", clusterLabels, wantLabels)
+	}
+	if len(connections) != 3 {
+		t.Fatalf("loadConfig calls = %d, want one per Region and one for the session", len(connections))
+	}
+	want := input.ConnectionParameter{Profile: "prod", Region: "ap-northeast-1"}
+	if connections[2] != want {
+		t.Fatalf("session connection = %#v, want %#v", connections[2], want)
+	}
+	if gotOptions.Profile != "prod" {
+		t.Fatalf("preflight profile = %q, want prod", gotOptions.Profile)
+	}
+	if got := aws.ToString(ecsClient.executeInput.Cluster); got != handlerClusterARN {
+		t.Fatalf("ExecuteCommand cluster = %q, want the chosen cluster", got)
+	}
+	if plugin.invocation.Region != "ap-northeast-1" {
+		t.Fatalf("plugin Region = %q, want the chosen cluster's Region", plugin.invocation.Region)
+	}
+}
+
+func TestDiscoverClusterKeepsExplicitCluster(t *testing.T) {
+	deps := dependencies{loadConfig: func(context.Context, input.ConnectionParameter) (aws.Config, error) {
+		t.Fatal("loadConfig called for an explicit cluster")
+		return aws.Config{}, nil
+	}}
+	connection := input.ConnectionParameter{DiscoverRegions: "us-east-1"}
+	got, cluster, quit, err := discoverCluster(context.Background(), deps, connection, "production", target.Selector{})
+	if err != nil || quit || cluster != "production" || got != connection {
+		t.Fatalf("discoverCluster() = %#v, %q, %v, %v; want inputs unchanged", got, cluster, quit, err)
+	}
+}
+
+func TestDiscoverClusterFindsSelectedTaskClusterWithoutAsking(t *testing.T) {
+	deps := dependencies{
+		loadConfig: func(_ context.Context, connection input.ConnectionParameter) (aws.Config, error) {
+			return aws.Config{Region: connection.Region}, nil
+		},
+		newECS: func(cfg aws.Config) ecsAPI {
+			return &handlerECS{listClustersOutput: &ecs.ListClustersOutput{
+				ClusterArns: []string{"arn:aws:ecs:" + cfg.Region + ":123456789012:cluster/production"},
+			}}
+		},
+		choose: func(title string, _ []listview.Option) (string, bool, error) {
+			t.Fatalf("chooser called with %q, want the selector to skip it", title)
+			return "", false, nil
+		},
+	}
+	connection := input.ConnectionParameter{Profile: "prod", DiscoverRegions: "us-east-1,ap-northeast-1"}
+
+	got, cluster, quit, err := discoverCluster(context.Background(), deps, connection, "", target.Selector{Task: handlerSecondTaskARN})
+	if err != nil || quit || cluster != handlerClusterARN {
+		t.Fatalf("discoverCluster() = %q, %v, %v; want the task ARN's cluster", cluster, quit, err)
+	}
+	if want := (input.ConnectionParameter{Profile: "prod", Region: "ap-northeast-1"}); got != want {
+		t.Fatalf("connection = %#v, want %#v", got, want)
+	}
+
+	_, _, _, err = discoverCluster(context.Background(), deps, connection, "", target.Selector{Container: "app"})
+	if err == nil || !strings.Contains(err.Error(), "--cluster") {
+		t.Fatalf("discoverCluster() error = %v, want a request for --cluster", err)
+	}
+}
+
+func TestDiscoveryLocationsCombineProfilesAndRegions(t *testing.T) {
+	got := discoveryLocations(input.ConnectionParameter{
+		Profile:          "ignored",
+		Region:           "eu-west-1",
+		DiscoverProfiles: "prod,stg",
+	})
+	want := []target.Location{{Profile: "prod", Region: "eu-west-1"}, {Profile: "stg", Region: "eu-west-1"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("discoveryLocations() = %#v, want %#v", got, want)
+	}
+
+	got = discoveryLocations(input.ConnectionParameter{Profile: "prod", DiscoverRegions: "us-east-1,ap-northeast-1"})
+	want = []target.Location{{Profile: "prod", Region: "us-east-1"}, {Profile: "prod", Region: "ap-northeast-1"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("discoveryLocations() = %#v, want %#v", got, want)
+	}
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/wim-web/tnnl/internal/event"
+	"github.com/wim-web/tnnl/internal/session_manager"
+	"github.com/wim-web/tnnl/internal/target"
+	"github.com/wim-web/tnnl/pkg/command"
+)
+
+// API calls a dry run stops short of.
+const (
+	executeCommandCall = "ecs:ExecuteCommand"
+	startSessionCall   = "ssm:StartSession"
+)
+
+// Placeholders for the session values the skipped API call would return.
+const (
+	plannedSessionID = "<session ID>"
+	plannedStreamURL = "<stream URL>"
+	// plannedLocalPort is an unset local port, picked when the session starts.
+	plannedLocalPort = "<free port>"
+)
+
+// dryRun describes the session a command would start on its target, from
+// the API call that opens it to the handoff to the session client. It is
+// emitted, or printed as text, in place of starting the session. PluginCommand is the session-manager-plugin command line with the
+// token redacted; PluginCredentials reports that the plugin would receive
+// the assumed role's credentials in its environment.
+type dryRun struct {
+	Command           string              `json:"command"`
+	Target            event.Target        `json:"target"`
+	Region            string              `json:"region"`
+	APICall           string              `json:"api_call"`
+	Document          string              `json:"document"`
+	Parameters        map[string][]string `json:"parameters"`
+	SessionClient     string              `json:"session_client"`
+	PluginCommand     []string            `json:"plugin_command,omitempty"`
+	PluginCredentials bool                `json:"plugin_credentials,omitempty"`
+}
+
+// execDryRun is the ExecuteCommand request exec would send for resolved.
+func execDryRun(resolved target.Resolved, region, remoteCommand string) dryRun {
+	return dryRun{
+		Command:  "exec",
+		Target:   event.NewTarget(resolved),
+		Region:   region,
+		APICall:  executeCommandCall,
+		Document: execDocument,
+		Parameters: map[string][]string{
+			"cluster":     {resolved.ECSCluster},
+			"task":        {resolved.TaskARN},
+			"container":   {resolved.ContainerName},
+			"command":     {remoteCommand},
+			"interactive": {"true"},
+		},
+	}
+}
+
+// forwardDryRun is the StartSession request of a forward on resolved.
+func forwardDryRun(resolved target.Resolved, region string, doc command.DocumentName, params map[string][]string) dryRun {
+	params = cloneParameters(params)
+	if strings.TrimSpace(firstParameter(params, "localPortNumber")) == "" {
+		params["localPortNumber"] = []string{plannedLocalPort}
+	}
+	return dryRun{
+		Command:    forwardCommandName(doc),
+		Target:     event.NewTarget(resolved),
+		Region:     region,
+		APICall:    startSessionCall,
+		Document:   string(doc),
+		Parameters: params,
+	}
+}
+
+// writeDryRun prints run with the handoff of its session to plugin, or
+// reports it as a dry_run event for --output json.
+func writeDryRun(deps dependencies, plugin session_manager.Plugin, run dryRun) error {
+	handoff, err := session_manager.Describe(plugin, session_manager.Invocation{
+		Response: session_manager.SessionResponse{
+			SessionID:  plannedSessionID,
+			StreamURL:  plannedStreamURL,
+			TokenValue: session_manager.RedactedToken,
+		},
+		Region: run.Region,
+		Target: run.Target.SSMTarget,
+	})
+	if err != nil {
+		return fmt.Errorf("describe the session client: %w", err)
+	}
+	run.SessionClient = handoff.Client
+	run.PluginCommand = handoff.Command
+	run.PluginCredentials = handoff.Credentials
+	if deps.events.Enabled() {
+		return deps.events.Emit(event.DryRun, run)
+	}
+
+	table := tabwriter.NewWriter(deps.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "command\t%s (dry run, no session started)\n", run.Command)
+	fmt.Fprintf(table, "cluster\t%s (%s)\n", run.Target.Cluster, run.Target.ClusterARN)
+	if run.Target.Service != "" {
+		fmt.Fprintf(table, "service\t%s\n", run.Target.Service)
+	}
+	fmt.Fprintf(table, "task\t%s (%s)\n", run.Target.TaskID, run.Target.TaskARN)
+	fmt.Fprintf(table, "container\t%s\n", run.Target.Container)
+	fmt.Fprintf(table, "runtime ID\t%s\n", run.Target.RuntimeID)
+	fmt.Fprintf(table, "SSM target\t%s\n", run.Target.SSMTarget)
+	fmt.Fprintf(table, "region\t%s\n", run.Region)
+	fmt.Fprintf(table, "API call\t%s\n", run.APICall)
+	fmt.Fprintf(table, "document\t%s\n", run.Document)
+	names := make([]string, 0, len(run.Parameters))
+	for name := range run.Parameters {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Fprintf(table, "parameter\t%s=%s\n", name, strings.Join(run.Parameters[name], ","))
+	}
+	switch {
+	case run.SessionClient == session_manager.ClientNative:
+		fmt.Fprintf(table, "session client\tnative (built-in data channel)\n")
+	case run.PluginCredentials:
+		fmt.Fprintf(table, "session client\t%s\n", shellCommand(run.PluginCommand))
+		fmt.Fprintf(table, "\twith the assumed role's credentials in the environment\n")
+	default:
+		fmt.Fprintf(table, "session client\t%s\n", shellCommand(run.PluginCommand))
+	}
+	return table.Flush()
+}
+
+var shellSafe = regexp.MustCompile(`^[\w@%+=:,./-]+$`)
+
+// shellCommand quotes args for a POSIX shell.
+func shellCommand(args []string) string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		if shellSafe.MatchString(arg) {
+			quoted = append(quoted, arg)
+			continue
+		}
+		quoted = append(quoted, "'"+strings.ReplaceAll(arg, "'", `'"'"'`)+"'")
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wim-web/tnnl/internal/event"
+	"github.com/wim-web/tnnl/internal/input"
+	"github.com/wim-web/tnnl/internal/recent"
+	"github.com/wim-web/tnnl/internal/session_manager"
+)
+
+func dryRunDependencies(t *testing.T, events *[]string, ecsClient *handlerECS, ssmClient *handlerSSM, stdout *bytes.Buffer) dependencies {
+	t.Helper()
+	deps := handlerDependencies(t, events, ecsClient, ssmClient, &handlerPlugin{events: events})
+	deps.preflight = func(context.Context, session_manager.Options) (session_manager.Plugin, error) {
+		appendEvent(events, "preflight")
+		return session_manager.NewNative(), nil
+	}
+	deps.availablePort = func() (int, error) {
+		t.Fatal("availablePort called by a dry run")
+		return 0, nil
+	}
+	deps.rememberTarget = func(choice recent.Choice) error {
+		t.Errorf("dry run remembered %#v", choice)
+		return nil
+	}
+	deps.stdout = stdout
+	return deps
+}
+
+func TestExecHandlerDryRunPrintsTheTargetWithoutExecuteCommand(t *testing.T) {
+	var events []string
+	ecsClient := newHandlerECS(&events)
+	ssmClient := &handlerSSM{events: &events}
+	var stdout bytes.Buffer
+	deps := dryRunDependencies(t, &events, ecsClient, ssmClient, &stdout)
+	in := validExecHandlerInput()
+	in.DryRun = true
+	in.Batch = true
+
+	if err := execHandler(context.Background(), in, deps); err != nil {
+		t.Fatalf("execHandler() error = %v", err)
+	}
+	if want := []string{"preflight", "load-config", "list-tasks", "describe-targets", "choose-task"}; !reflect.DeepEqual(events, want) {
+		t.Fatalf("events = %q, want preflight and resolution only", events)
+	}
+	if ecsClient.executeCalls != 0 || ssmClient.terminateCalls != 0 {
+		t.Fatalf("ExecuteCommand/TerminateSession calls = %d/%d, want none", ecsClient.executeCalls, ssmClient.terminateCalls)
+	}
+	for _, want := range []string{
+		"command         exec (dry run, no session started)\n",
+		"cluster         production (" + handlerClusterARN + ")\n",
+		"service         service-web\n",
+		"task            task-second (" + handlerSecondTaskARN + ")\n",
+		"SSM target      ecs:production_task-second_runtime-second\n",
+		"API call        ecs:ExecuteCommand\n",
+		"document        AmazonECS-ExecuteInteractiveCommand\n",
+		"parameter       command=/bin/sh\n",
+		"parameter       interactive=true\n",
+		"session client  native (built-in data channel)\n",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("stdout =\n%s\nwant it to contain %q", stdout.String(), want)
+		}
+	}
+}
+
+func TestRemotePortForwardHandlerDryRunReportsTheSessionParameters(t *testing.T) {
+	var events []string
+	ecsClient := newHandlerECS(&events)
+	ssmClient := &handlerSSM{events: &events, startOutput: validHandlerStartOutput()}
+	var stdout bytes.Buffer
+	deps := dryRunDependencies(t, &events, ecsClient, ssmClient, &stdout)
+	var reported strings.Builder
+	deps.events = event.NewEmitter(&reported, time.Now)
+	deps.listen = nil
+	in := input.RemotePortForwardInput{
+		EcsParameter:     input.EcsParameter{Cluster: handlerClusterARN, Service: "service-web"},
+		RemotePortNumber: "3306",
+		Host:             "db.internal",
+		DryRun:           true,
+	}
+
+	if err := remotePortForwardHandler(context.Background(), in, deps); err != nil {
+		t.Fatalf("remotePortForwardHandler() error = %v", err)
+	}
+	if ssmClient.startCalls != 0 {
+		t.Fatalf("StartSession calls = %d, want none", ssmClient.startCalls)
+	}
+	decoded := decodeEvents(t, reported.String())
+	if stdout.Len() != 0 || len(decoded) != 1 || decoded[0]["event"] != "dry_run" {
+		t.Fatalf("stdout = %q, events = %v; want one dry_run event", stdout.String(), decoded)
+	}
+	run := decoded[0]
+	if run["command"] != "remoteportforward" || run["api_call"] != "ssm:StartSession" ||
+		run["document"] != "AWS-StartPortForwardingSessionToRemoteHost" || run["session_client"] != "native" {
+		t.Fatalf("dry_run = %v", run)
+	}
+	wantParameters := map[string]any{
+		"host":            []any{"db.internal"},
+		"portNumber":      []any{"3306"},
+		"localPortNumber": []any{"<free port>"},
+	}
+	if !reflect.DeepEqual(run["parameters"], wantParameters) {
+		t.Fatalf("parameters = %v, want %v", run["parameters"], wantParameters)
+	}
+	if target := run["target"].(map[string]any); target["ssm_target"] != "ecs:production_task-second_runtime-second" {
+		t.Fatalf("target = %v", target)
+	}
+}
+
+func TestExecHandlerFanOutDryRunPrintsEveryTarget(t *testing.T) {
+	var events []string
+	ecsClient := newHandlerECS(&events)
+	ssmClient := &handlerSSM{events: &events}
+	var stdout bytes.Buffer
+	deps := dryRunDependencies(t, &events, ecsClient, ssmClient, &stdout)
+	in := validExecHandlerInput()
+	in.DryRun = true
+	in.All = true
+
+	if err := execHandler(context.Background(), in, deps); err != nil {
+		t.Fatalf("execHandler() error = %v", err)
+	}
+	if ecsClient.executeCalls != 0 {
+		t.Fatalf("ExecuteCommand calls = %d, want none", ecsClient.executeCalls)
+	}
+	for _, want := range []string{
+		"task            task-first (" + handlerFirstTaskARN + ")\n",
+		"task            task-second (" + handlerSecondTaskARN + ")\n",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("stdout =\n%s\nwant it to contain %q", stdout.String(), want)
+		}
+	}
+}
+
+func TestShellCommandQuotesArguments(t *testing.T) {
+	got := shellCommand([]string{"/usr/bin/session-manager-plugin", `{"TokenValue":"REDACTED"}`, "ap-northeast-1", "", "it's"})
+	want := `/usr/bin/session-manager-plugin '{"TokenValue":"REDACTED"}' ap-northeast-1 '' 'it'"'"'s'`
+	if got != want {
+		t.Fatalf("shellCommand() = %s, want %s", got, want)
+	}
+}
@@ -2,45 +2,97 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"time"
 
+	"github.com/wim-web/tnnl/internal/audit"
+	"github.com/wim-web/tnnl/internal/cast"
+	"github.com/wim-web/tnnl/internal/event"
 	"github.com/wim-web/tnnl/internal/input"
+	"github.com/wim-web/tnnl/internal/session_manager"
 	"github.com/wim-web/tnnl/internal/target"
 	"github.com/wim-web/tnnl/internal/view"
 	"github.com/wim-web/tnnl/pkg/command"
 )
 
 func ExecHandler(ctx context.Context, in input.ExecInput) error {
-	return execHandler(ctx, in, productionDependencies())
+	return execHandler(ctx, in, productionDependencies(ctx))
 }
 
 func execHandler(ctx context.Context, in input.ExecInput, deps dependencies) error {
-	plugin, err := deps.preflight(ctx)
+	// An interactive session writes its terminal to the stdout the events go to.
+	if deps.events.Enabled() && !in.Batch && !in.All && !in.DryRun {
+		return &input.InvalidError{Err: errors.New("--output json needs --batch or --all: an interactive session writes its terminal to standard output")}
+	}
+	connection, cluster, quit, err := discoverCluster(ctx, deps, in.ConnectionParameter, in.Cluster, targetSelector(in.EcsParameter))
+	if err != nil || quit {
+		return err
+	}
+	in.ConnectionParameter, in.Cluster = connection, cluster
+
+	options := sessionOptions(in.ConnectionParameter)
+	if in.Record != "" && options.Client == "" {
+		// Only the native client relays the terminal traffic it records.
+		options.Client = session_manager.ClientNative
+	}
+	plugin, err := deps.preflight(ctx, options)
 	if err != nil {
 		return err
 	}
 
-	cfg, err := deps.loadConfig(ctx)
+	cfg, err := deps.loadConfig(ctx, in.ConnectionParameter)
 	if err != nil {
 		return fmt.Errorf("load AWS configuration: %w", err)
 	}
+	plugin = withAssumedRole(plugin, in.ConnectionParameter, cfg)
 
 	ecsClient := deps.newECS(cfg)
-	resolved, quit, err := view.ResolveTarget(
-		ctx,
-		target.NewResolver(ecsClient),
-		deps.choose,
-		in.Cluster,
-		in.Service,
-		time.Duration(in.Wait)*time.Second,
-	)
+	if in.All {
+		return fanOutExec(ctx, in, cfg, ecsClient, deps.newSSM(cfg), plugin, deps)
+	}
+	resolved, quit, err := view.ResolveTarget(ctx, target.NewResolver(ecsClient), deps.choose, view.ResolveOptions{
+		Cluster:        in.Cluster,
+		Service:        in.Service,
+		Selector:       targetSelector(in.EcsParameter),
+		ShowIneligible: in.ShowIneligible,
+		MaxWait:        time.Duration(in.Wait) * time.Second,
+		History:        recentTargets(deps),
+		ChooseTable:    deps.chooseTable,
+	})
 	if err != nil {
 		return err
 	}
 	if quit {
 		return nil
 	}
+	if in.DryRun {
+		return writeDryRun(deps, plugin, execDryRun(resolved, cfg.Region, in.Cmd))
+	}
+	rememberTarget(deps, resolved)
+
+	var marker string
+	remoteCommand := in.Cmd
+	if in.Batch {
+		if marker, err = command.NewBatchMarker(); err != nil {
+			return err
+		}
+		remoteCommand = command.BatchCommand(in.Cmd, marker)
+	}
+
+	var record *recording
+	if in.Record != "" {
+		header := cast.Header{
+			Command: in.Cmd,
+			Title:   fmt.Sprintf("%s %s/%s", resolved.ClusterName, resolved.TaskID, resolved.ContainerName),
+			Env:     map[string]string{"TERM": os.Getenv("TERM")},
+		}
+		if plugin, record, err = startRecording(plugin, in.Record, header, deps.clock.Now); err != nil {
+			return err
+		}
+	}
 
 	ssmClient := deps.newSSM(cfg)
 	remote, err := command.StartExecSession(
@@ -52,12 +104,119 @@ func execHandler(ctx context.Context, in input.ExecInput, deps dependencies) err
 			TaskARN:       resolved.TaskARN,
 			ContainerName: resolved.ContainerName,
 		},
-		in.Cmd,
+		remoteCommand,
 		cfg.Region,
 	)
 	if err != nil {
+		if record != nil {
+			return errors.Join(err, record.discard())
+		}
 		return err
 	}
 
-	return remote.Run(ctx, plugin)
+	// A re-run does not overwrite the recording.
+	rerun := in
+	rerun.Record = ""
+	sessionAudit := newSessionAudit(deps, cfg, "exec", auditInput(deps, rerun, resolved, cfg.Region))
+	entry := audit.Entry{Document: execDocument, Remote: in.Cmd}
+	if in.Batch {
+		return sessionAudit.run(ctx, resolved, remote, entry, func() error {
+			stdout := batchStdout(deps, resolved)
+			err := runBatch(ctx, remote, plugin, marker, time.Duration(in.Timeout)*time.Second, nil, stdout, deps.stderr)
+			if closeErr := stdout.Close(); err == nil {
+				err = closeErr
+			}
+			return err
+		})
+	}
+	if record != nil {
+		runErr := sessionAudit.run(ctx, resolved, remote, entry, func() error {
+			return remote.Run(ctx, plugin)
+		})
+		if err := record.finish(); err != nil {
+			return errors.Join(runErr, err)
+		}
+		fmt.Fprintf(deps.stderr, "session recorded to %s; play it back with tnnl replay %s\n", in.Record, in.Record)
+		return runErr
+	}
+	return sessionAudit.run(ctx, resolved, remote, entry, func() error {
+		return remote.Run(ctx, plugin)
+	})
+}
+
+// batchStdout is where the standard output of a batch command on resolved
+// goes: deps.stdout, or output events for --output json.
+func batchStdout(deps dependencies, resolved target.Resolved) io.WriteCloser {
+	if deps.events.Enabled() {
+		return event.NewLines(deps.events, resolved.TaskID)
+	}
+	return nopCloser{deps.stdout}
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// runBatch runs a BatchCommand session with its output split onto stdout and
+// stderr, and returns an *command.ExitError for any status but zero. input,
+// when set, is sent to the command; it is closed if it stops being read.
+func runBatch(
+	ctx context.Context,
+	remote command.RemoteSession,
+	plugin session_manager.Plugin,
+	marker string,
+	timeout time.Duration,
+	input io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+) error {
+	runCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// Standard input stays open after input: the session is not ended early
+	// by an end of file.
+	stdin, keepOpen, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("create batch input: %w", err)
+	}
+	defer stdin.Close()
+	defer keepOpen.Close()
+	if input != nil {
+		go func() {
+			if _, err := io.Copy(keepOpen, input); err != nil {
+				if closer, ok := input.(io.Closer); ok {
+					closer.Close()
+				}
+			}
+		}()
+	}
+
+	output := command.NewBatchOutput(marker, stdout, stderr)
+	runErr := remote.Run(runCtx, session_manager.WithStreams(plugin, stdin, output, stderr))
+	closeErr := output.Close()
+	if errors.Is(runCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		return &command.ExitError{Code: 124, Err: fmt.Errorf("%w after %s", command.ErrTimedOut, timeout)}
+	}
+	if runErr != nil {
+		return runErr
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+	code, ok := output.ExitCode()
+	if !ok {
+		return errors.New("remote command ended without an exit status")
+	}
+	if code != 0 {
+		return &command.ExitError{Code: code}
+	}
+	return nil
 }
@@ -1,19 +1,28 @@
 package handler
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ecs"
 	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
 	"github.com/wim-web/tnnl/internal/input"
 	"github.com/wim-web/tnnl/internal/listview"
 	"github.com/wim-web/tnnl/internal/session_manager"
+	"github.com/wim-web/tnnl/pkg/command"
 )
 
 const (
@@ -51,6 +60,8 @@ type handlerECS struct {
 
 	listClustersOutput *ecs.ListClustersOutput
 	listClustersErr    error
+	listServicesOutput *ecs.ListServicesOutput
+	services           []ecstypes.Service
 	listTasksOutput    *ecs.ListTasksOutput
 	listTasksErr       error
 	resolveOutput      *ecs.DescribeTasksOutput
@@ -59,6 +70,8 @@ type handlerECS struct {
 	executeErr         error
 	refreshOutput      *ecs.DescribeTasksOutput
 	refreshErr         error
+	clusters           []ecstypes.Cluster
+	taskDefinition     *ecstypes.TaskDefinition
 
 	listClustersCalls int
 	listClustersCtx   context.Context
@@ -82,6 +95,29 @@ func (f *handlerECS) ListClusters(ctx context.Context, in *ecs.ListClustersInput
 	return f.listClustersOutput, f.listClustersErr
 }
 
+func (f *handlerECS) ListServices(context.Context, *ecs.ListServicesInput, ...func(*ecs.Options)) (*ecs.ListServicesOutput, error) {
+	appendEvent(f.events, "list-services")
+	if f.listServicesOutput == nil {
+		return &ecs.ListServicesOutput{}, nil
+	}
+	return f.listServicesOutput, nil
+}
+
+func (f *handlerECS) DescribeServices(context.Context, *ecs.DescribeServicesInput, ...func(*ecs.Options)) (*ecs.DescribeServicesOutput, error) {
+	appendEvent(f.events, "describe-services")
+	return &ecs.DescribeServicesOutput{Services: f.services}, nil
+}
+
+func (f *handlerECS) DescribeClusters(context.Context, *ecs.DescribeClustersInput, ...func(*ecs.Options)) (*ecs.DescribeClustersOutput, error) {
+	appendEvent(f.events, "describe-clusters")
+	return &ecs.DescribeClustersOutput{Clusters: f.clusters}, nil
+}
+
+func (f *handlerECS) DescribeTaskDefinition(context.Context, *ecs.DescribeTaskDefinitionInput, ...func(*ecs.Options)) (*ecs.DescribeTaskDefinitionOutput, error) {
+	appendEvent(f.events, "describe-task-definition")
+	return &ecs.DescribeTaskDefinitionOutput{TaskDefinition: f.taskDefinition}, nil
+}
+
 func (f *handlerECS) ListTasks(ctx context.Context, in *ecs.ListTasksInput, _ ...func(*ecs.Options)) (*ecs.ListTasksOutput, error) {
 	f.listTasksCalls++
 	f.listTasksCtx = ctx
@@ -123,6 +159,24 @@ type handlerSSM struct {
 	terminateCtx   context.Context
 	terminateInput *ssm.TerminateSessionInput
 	onTerminate    func(context.Context)
+	terminated     []string
+	sessions       []ssmtypes.Session
+	describeInputs []*ssm.DescribeSessionsInput
+}
+
+// DescribeSessions returns one page per session.
+func (f *handlerSSM) DescribeSessions(_ context.Context, in *ssm.DescribeSessionsInput, _ ...func(*ssm.Options)) (*ssm.DescribeSessionsOutput, error) {
+	f.describeInputs = append(f.describeInputs, in)
+	appendEvent(f.events, "describe-sessions")
+	page := len(f.describeInputs) - 1
+	if page >= len(f.sessions) {
+		return &ssm.DescribeSessionsOutput{}, nil
+	}
+	output := &ssm.DescribeSessionsOutput{Sessions: f.sessions[page : page+1]}
+	if page+1 < len(f.sessions) {
+		output.NextToken = aws.String(fmt.Sprintf("page-%d", page+1))
+	}
+	return output, nil
 }
 
 func (f *handlerSSM) StartSession(ctx context.Context, in *ssm.StartSessionInput, _ ...func(*ssm.Options)) (*ssm.StartSessionOutput, error) {
@@ -137,6 +191,7 @@ func (f *handlerSSM) TerminateSession(ctx context.Context, in *ssm.TerminateSess
 	f.terminateCalls++
 	f.terminateCtx = ctx
 	f.terminateInput = in
+	f.terminated = append(f.terminated, aws.ToString(in.SessionId))
 	appendEvent(f.events, "terminate-session")
 	if f.onTerminate != nil {
 		f.onTerminate(ctx)
@@ -148,11 +203,11 @@ func TestExecHandlerPreflightFailureStopsBeforeAWS(t *testing.T) {
 	preflightErr := errors.New("preflight sentinel")
 	var events []string
 	deps := dependencies{
-		preflight: func(context.Context) (session_manager.Plugin, error) {
+		preflight: func(context.Context, session_manager.Options) (session_manager.Plugin, error) {
 			appendEvent(&events, "preflight")
 			return nil, preflightErr
 		},
-		loadConfig: func(context.Context) (aws.Config, error) {
+		loadConfig: func(context.Context, input.ConnectionParameter) (aws.Config, error) {
 			t.Fatal("loadConfig called after preflight failure")
 			return aws.Config{}, nil
 		},
@@ -188,7 +243,7 @@ func TestExecHandlerSelectsExactSecondReplicaAndRunsInOrder(t *testing.T) {
 	}
 	wantEvents := []string{
 		"preflight", "load-config", "list-tasks", "describe-targets", "choose-task",
-		"execute-command", "describe-refresh", "plugin-run",
+		"execute-command", "describe-refresh", "plugin-run", "terminate-session",
 	}
 	if !reflect.DeepEqual(events, wantEvents) {
 		t.Fatalf("events = %#v, want %#v", events, wantEvents)
@@ -211,8 +266,8 @@ func TestExecHandlerSelectsExactSecondReplicaAndRunsInOrder(t *testing.T) {
 		t.Fatalf("plugin region = %q, want %q", plugin.invocation.Region, handlerRegion)
 	}
 	assertOriginalContext(t, ctx, ecsClient, plugin)
-	if ssmClient.terminateCalls != 0 {
-		t.Fatalf("TerminateSession calls = %d, want 0", ssmClient.terminateCalls)
+	if ssmClient.terminateCalls != 1 || aws.ToString(ssmClient.terminateInput.SessionId) != plugin.invocation.Response.SessionID {
+		t.Fatalf("TerminateSession calls = %d, want the session terminated once", ssmClient.terminateCalls)
 	}
 }
 
@@ -291,11 +346,11 @@ func TestExecHandlerPreservesConfigAndAPIErrors(t *testing.T) {
 		configErr := errors.New("config sentinel")
 		var events []string
 		deps := dependencies{
-			preflight: func(context.Context) (session_manager.Plugin, error) {
+			preflight: func(context.Context, session_manager.Options) (session_manager.Plugin, error) {
 				appendEvent(&events, "preflight")
 				return &handlerPlugin{}, nil
 			},
-			loadConfig: func(context.Context) (aws.Config, error) {
+			loadConfig: func(context.Context, input.ConnectionParameter) (aws.Config, error) {
 				appendEvent(&events, "load-config")
 				return aws.Config{}, configErr
 			},
@@ -336,14 +391,14 @@ func handlerDependencies(
 ) dependencies {
 	t.Helper()
 	return dependencies{
-		preflight: func(ctx context.Context) (session_manager.Plugin, error) {
+		preflight: func(ctx context.Context, _ session_manager.Options) (session_manager.Plugin, error) {
 			appendEvent(events, "preflight")
 			if ctx == nil {
 				t.Fatal("preflight received nil context")
 			}
 			return plugin, nil
 		},
-		loadConfig: func(ctx context.Context) (aws.Config, error) {
+		loadConfig: func(ctx context.Context, _ input.ConnectionParameter) (aws.Config, error) {
 			appendEvent(events, "load-config")
 			if ctx == nil {
 				t.Fatal("loadConfig received nil context")
@@ -438,3 +493,215 @@ func appendEvent(events *[]string, event string) {
 		*events = append(*events, event)
 	}
 }
+
+func TestExecHandlerPassesSessionClientToPreflight(t *testing.T) {
+	var events []string
+	ecsClient := newHandlerECS(&events)
+	plugin := &handlerPlugin{events: &events}
+	deps := handlerDependencies(t, &events, ecsClient, &handlerSSM{events: &events}, plugin)
+	var got session_manager.Options
+	deps.preflight = func(_ context.Context, options session_manager.Options) (session_manager.Plugin, error) {
+		got = options
+		return plugin, nil
+	}
+	in := validExecHandlerInput()
+	in.SessionClient = session_manager.ClientNative
+
+	if err := execHandler(context.Background(), in, deps); err != nil {
+		t.Fatalf("execHandler() error = %v", err)
+	}
+	if got.Client != session_manager.ClientNative {
+		t.Fatalf("preflight options = %#v, want native client", got)
+	}
+}
+
+func TestExecHandlerPassesAWSConnectionToConfigAndPlugin(t *testing.T) {
+	var events []string
+	ecsClient := newHandlerECS(&events)
+	plugin := &handlerPlugin{events: &events}
+	deps := handlerDependencies(t, &events, ecsClient, &handlerSSM{events: &events}, plugin)
+	var gotOptions session_manager.Options
+	deps.preflight = func(_ context.Context, options session_manager.Options) (session_manager.Plugin, error) {
+		gotOptions = options
+		return plugin, nil
+	}
+	var gotConnection input.ConnectionParameter
+	deps.loadConfig = func(_ context.Context, connection input.ConnectionParameter) (aws.Config, error) {
+		gotConnection = connection
+		return aws.Config{Region: connection.Region}, nil
+	}
+	in := validExecHandlerInput()
+	in.ConnectionParameter = input.ConnectionParameter{Profile: "prod", Region: "us-east-1", EndpointURL: "http://localhost:4566"}
+
+	if err := execHandler(context.Background(), in, deps); err != nil {
+		t.Fatalf("execHandler() error = %v", err)
+	}
+	if gotConnection != in.ConnectionParameter {
+		t.Fatalf("loadConfig connection = %#v, want %#v", gotConnection, in.ConnectionParameter)
+	}
+	wantOptions := session_manager.Options{Profile: "prod", Endpoint: "http://localhost:4566"}
+	if gotOptions != wantOptions {
+		t.Fatalf("preflight options = %#v, want %#v", gotOptions, wantOptions)
+	}
+	if plugin.invocation.Region != "us-east-1" {
+		t.Fatalf("plugin Region = %q, want us-east-1", plugin.invocation.Region)
+	}
+}
+
+func TestLoadAWSConfigAppliesConnection(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config")
+	if err := os.WriteFile(configPath, []byte("[profile prod]\nregion = eu-west-1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_CONFIG_FILE", configPath)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_REGION", "ap-northeast-1")
+
+	cfg, err := loadAWSConfig(context.Background(), input.ConnectionParameter{Profile: "prod"}, nil)
+	if err != nil {
+		t.Fatalf("loadAWSConfig() error = %v", err)
+	}
+	if cfg.Region != "ap-northeast-1" {
+		t.Fatalf("Region = %q, want AWS_REGION to win over the profile Region", cfg.Region)
+	}
+
+	cfg, err = loadAWSConfig(context.Background(), input.ConnectionParameter{
+		Profile:     "prod",
+		Region:      "us-east-1",
+		EndpointURL: "http://localhost:4566",
+	}, nil)
+	if err != nil {
+		t.Fatalf("loadAWSConfig() error = %v", err)
+	}
+	if cfg.Region != "us-east-1" || aws.ToString(cfg.BaseEndpoint) != "http://localhost:4566" {
+		t.Fatalf("config Region/endpoint = %q/%q, want explicit values", cfg.Region, aws.ToString(cfg.BaseEndpoint))
+	}
+
+	if _, err := loadAWSConfig(context.Background(), input.ConnectionParameter{Profile: "missing"}, nil); err == nil {
+		t.Fatal("loadAWSConfig() with an unknown profile succeeded")
+	}
+}
+
+func TestExecHandlerSelectsTaskWithoutChooser(t *testing.T) {
+	var events []string
+	ecsClient := newHandlerECS(&events)
+	plugin := &handlerPlugin{events: &events}
+	deps := handlerDependencies(t, &events, ecsClient, &handlerSSM{events: &events}, plugin)
+	deps.choose = func(title string, _ []listview.Option) (string, bool, error) {
+		t.Fatalf("chooser called with %q, want task selector", title)
+		return "", false, nil
+	}
+	in := validExecHandlerInput()
+	in.Task = "task-second"
+	in.Container = handlerContainer
+
+	if err := execHandler(context.Background(), in, deps); err != nil {
+		t.Fatalf("execHandler() error = %v", err)
+	}
+	if got := aws.ToString(ecsClient.executeInput.Task); got != handlerSecondTaskARN {
+		t.Fatalf("ExecuteCommand task = %q, want %q", got, handlerSecondTaskARN)
+	}
+}
+
+// shellPlugin stands in for a terminal session by running the ExecuteCommand
+// command with a local shell, stderr merged into stdout as a pty would.
+type shellPlugin struct {
+	ecsClient *handlerECS
+	stdin     io.Reader
+	stdout    io.Writer
+}
+
+func (p *shellPlugin) Run(ctx context.Context, _ session_manager.Invocation) error {
+	cmd := osexec.CommandContext(ctx, "/bin/sh", "-c", aws.ToString(p.ecsClient.executeInput.Command))
+	cmd.Stdout, cmd.Stderr = p.stdout, p.stdout
+	cmd.WaitDelay = 100 * time.Millisecond
+	if p.stdin != nil {
+		reader, writer, err := os.Pipe()
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		go func() {
+			_, _ = io.Copy(writer, &terminalInput{r: p.stdin})
+			writer.Close()
+		}()
+		cmd.Stdin = reader
+	}
+	return cmd.Run()
+}
+
+// terminalInput ends at ^D as a terminal in canonical mode does.
+type terminalInput struct {
+	r    io.Reader
+	done bool
+}
+
+func (t *terminalInput) Read(p []byte) (int, error) {
+	if t.done {
+		return 0, io.EOF
+	}
+	n, err := t.r.Read(p)
+	if i := bytes.IndexByte(p[:n], 0x04); i >= 0 {
+		t.done = true
+		return i, nil
+	}
+	return n, err
+}
+
+func (p *shellPlugin) WithStreams(stdin io.Reader, stdout, _ io.Writer) session_manager.Plugin {
+	return &shellPlugin{ecsClient: p.ecsClient, stdin: stdin, stdout: stdout}
+}
+
+func TestExecHandlerBatchSplitsOutputAndReturnsExitStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		command    string
+		timeout    int
+		wantStdout string
+		wantStderr string
+		wantCode   int
+	}{
+		{name: "success", command: "echo out; echo err >&2", wantStdout: "out\n", wantStderr: "err\n"},
+		{name: "failure", command: "printf partial; exit 3", wantStdout: "partial", wantCode: 3},
+		{name: "timeout", command: "echo started; sleep 5", timeout: 1, wantStdout: "started\n", wantCode: 124},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []string
+			ecsClient := newHandlerECS(&events)
+			ssmClient := &handlerSSM{events: &events}
+			deps := handlerDependencies(t, &events, ecsClient, ssmClient, &handlerPlugin{events: &events})
+			deps.preflight = func(context.Context, session_manager.Options) (session_manager.Plugin, error) {
+				return &shellPlugin{ecsClient: ecsClient}, nil
+			}
+			var stdout, stderr strings.Builder
+			deps.stdout, deps.stderr = &stdout, &stderr
+			in := validExecHandlerInput()
+			in.Task, in.Container = "task-second", handlerContainer
+			in.Cmd, in.Batch, in.Timeout = tt.command, true, tt.timeout
+
+			err := execHandler(context.Background(), in, deps)
+			if tt.wantCode == 0 {
+				if err != nil {
+					t.Fatalf("execHandler() error = %v", err)
+				}
+			} else {
+				var exitErr *command.ExitError
+				if !errors.As(err, &exitErr) || exitErr.Code != tt.wantCode {
+					t.Fatalf("execHandler() error = %v, want exit status %d", err, tt.wantCode)
+				}
+			}
+			if stdout.String() != tt.wantStdout || stderr.String() != tt.wantStderr {
+				t.Fatalf("output = (%q, %q), want (%q, %q)", stdout.String(), stderr.String(), tt.wantStdout, tt.wantStderr)
+			}
+			if ssmClient.terminateCalls != 1 {
+				t.Fatalf("TerminateSession calls = %d, want the session terminated", ssmClient.terminateCalls)
+			}
+			if !ecsClient.executeInput.Interactive {
+				t.Fatal("ExecuteCommand Interactive = false, want the terminal session ECS requires")
+			}
+		})
+	}
+}
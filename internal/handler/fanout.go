@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/wim-web/tnnl/internal/audit"
+	"github.com/wim-web/tnnl/internal/event"
+	"github.com/wim-web/tnnl/internal/input"
+	"github.com/wim-web/tnnl/internal/session_manager"
+	"github.com/wim-web/tnnl/internal/target"
+	"github.com/wim-web/tnnl/internal/view"
+	"github.com/wim-web/tnnl/pkg/command"
+)
+
+// defaultFanOutParallel bounds concurrent sessions when the input leaves
+// parallel at zero.
+const defaultFanOutParallel = 4
+
+// fanOutResult is how the command ended on one task: an exit status, or err
+// when no status was read.
+type fanOutResult struct {
+	target target.Resolved
+	code   int
+	err    error
+}
+
+// fanOutExec runs in.Cmd in batch mode on every matching eligible task,
+// prefixing each output line with the task ID, and ends with a per-task
+// summary on stderr. For --output json the output lines are output events
+// that carry the task ID instead.
+func fanOutExec(
+	ctx context.Context,
+	in input.ExecInput,
+	cfg aws.Config,
+	ecsClient ecsAPI,
+	ssmClient ssmAPI,
+	plugin session_manager.Plugin,
+	deps dependencies,
+) error {
+	targets, quit, err := view.ResolveTargets(ctx, target.NewResolver(ecsClient), deps.choose, recentTargets(deps), in.Cluster, in.Service, targetSelector(in.EcsParameter))
+	if err != nil || quit {
+		return err
+	}
+	if in.DryRun {
+		for _, resolved := range targets {
+			if err := writeDryRun(deps, plugin, execDryRun(resolved, cfg.Region, in.Cmd)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	rememberTarget(deps, targets[0])
+
+	parallel := in.Parallel
+	if parallel == 0 {
+		parallel = defaultFanOutParallel
+	}
+	stdout, stderr := command.NewSharedOutput(deps.stdout), command.NewSharedOutput(deps.stderr)
+	timeout := time.Duration(in.Timeout) * time.Second
+	// Every task runs the same container, so any target pins the re-run.
+	sessionAudit := newSessionAudit(deps, cfg, "exec", auditInput(deps, in, targets[0], cfg.Region))
+
+	results := make([]fanOutResult, len(targets))
+	slots := make(chan struct{}, parallel)
+	// ExecuteCommand is throttled per account, so sessions start one at a
+	// time and only the commands run in parallel.
+	var starting sync.Mutex
+	var wg sync.WaitGroup
+	for i, resolved := range targets {
+		results[i].target = resolved
+		wg.Go(func() {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				results[i].err = ctx.Err()
+				return
+			}
+			defer func() { <-slots }()
+
+			prefix := "[" + resolved.TaskID + "] "
+			var taskStdout io.WriteCloser = stdout.Prefixed(prefix)
+			if deps.events.Enabled() {
+				taskStdout = event.NewLines(deps.events, resolved.TaskID)
+			}
+			taskStderr := stderr.Prefixed(prefix)
+			err := runFanOutTask(ctx, &starting, sessionAudit, in.Cmd, cfg.Region, resolved, ecsClient, ssmClient, plugin, timeout, taskStdout, taskStderr)
+			results[i].err = errors.Join(err, taskStdout.Close(), taskStderr.Close())
+			var exitErr *command.ExitError
+			if errors.As(results[i].err, &exitErr) && exitErr.Err == nil {
+				results[i].code, results[i].err = exitErr.Code, nil
+			}
+		})
+	}
+	wg.Wait()
+
+	return writeFanOutSummary(deps.stderr, results)
+}
+
+func runFanOutTask(
+	ctx context.Context,
+	starting *sync.Mutex,
+	sessionAudit *sessionAudit,
+	cmd string,
+	region string,
+	resolved target.Resolved,
+	ecsClient ecsAPI,
+	ssmClient ssmAPI,
+	plugin session_manager.Plugin,
+	timeout time.Duration,
+	stdout io.Writer,
+	stderr io.Writer,
+) error {
+	marker, err := command.NewBatchMarker()
+	if err != nil {
+		return err
+	}
+	starting.Lock()
+	remote, err := command.StartExecSession(
+		ctx,
+		ecsClient,
+		ssmClient,
+		command.ExecTarget{
+			Cluster:       resolved.ECSCluster,
+			TaskARN:       resolved.TaskARN,
+			ContainerName: resolved.ContainerName,
+		},
+		command.BatchCommand(cmd, marker),
+		region,
+	)
+	starting.Unlock()
+	if err != nil {
+		return err
+	}
+	entry := audit.Entry{Document: execDocument, Remote: cmd}
+	return sessionAudit.run(ctx, resolved, remote, entry, func() error {
+		return runBatch(ctx, remote, plugin, marker, timeout, nil, stdout, stderr)
+	})
+}
+
+// writeFanOutSummary lists how each task ended and returns an
+// *command.ExitError with status 1 when any task did not exit 0.
+func writeFanOutSummary(w io.Writer, results []fanOutResult) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "TASK\tCONTAINER\tRESULT")
+	failed := 0
+	for _, result := range results {
+		outcome := fmt.Sprintf("exit %d", result.code)
+		if result.err != nil {
+			outcome = "error: " + result.err.Error()
+		}
+		if result.err != nil || result.code != 0 {
+			failed++
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\n", result.target.TaskID, result.target.ContainerName, outcome)
+	}
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("write exec summary: %w", err)
+	}
+	if failed > 0 {
+		return &command.ExitError{Code: 1, Err: fmt.Errorf("command failed on %d of %d tasks", failed, len(results))}
+	}
+	return nil
+}
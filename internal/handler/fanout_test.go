@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	osexec "os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/wim-web/tnnl/internal/session_manager"
+	"github.com/wim-web/tnnl/pkg/command"
+)
+
+// fanOutECS answers ExecuteCommand for whichever task was asked for and
+// remembers each task's command for fanOutPlugin.
+type fanOutECS struct {
+	*handlerECS
+
+	mu       sync.Mutex
+	commands map[string]string
+}
+
+func (f *fanOutECS) ExecuteCommand(_ context.Context, in *ecs.ExecuteCommandInput, _ ...func(*ecs.Options)) (*ecs.ExecuteCommandOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := aws.ToString(in.Task)[strings.LastIndex(aws.ToString(in.Task), "/")+1:]
+	f.commands["runtime-"+strings.TrimPrefix(id, "task-")] = aws.ToString(in.Command)
+	output := *f.executeOutput
+	output.TaskArn = in.Task
+	return &output, nil
+}
+
+func (f *fanOutECS) DescribeTasks(ctx context.Context, in *ecs.DescribeTasksInput, opts ...func(*ecs.Options)) (*ecs.DescribeTasksOutput, error) {
+	if f.describeCalls == 0 {
+		return f.handlerECS.DescribeTasks(ctx, in, opts...)
+	}
+	task := in.Tasks[0]
+	runtime := "runtime-" + strings.TrimPrefix(task[strings.LastIndex(task, "/")+1:], "task-")
+	return &ecs.DescribeTasksOutput{Tasks: []ecstypes.Task{readyHandlerTask(task, runtime)}}, nil
+}
+
+// fanOutSSM terminates the sessions of concurrent tasks.
+type fanOutSSM struct {
+	*handlerSSM
+
+	mu sync.Mutex
+}
+
+func (f *fanOutSSM) TerminateSession(ctx context.Context, in *ssm.TerminateSessionInput, opts ...func(*ssm.Options)) (*ssm.TerminateSessionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.handlerSSM.TerminateSession(ctx, in, opts...)
+}
+
+// fanOutPlugin runs each session's command with a local shell, stderr merged
+// into stdout as a pty would, and tracks how many run at once.
+type fanOutPlugin struct {
+	ecsClient *fanOutECS
+	stdin     io.Reader
+	stdout    io.Writer
+	running   *int
+	peak      *int
+	mu        *sync.Mutex
+}
+
+func (p *fanOutPlugin) Run(ctx context.Context, invocation session_manager.Invocation) error {
+	p.mu.Lock()
+	*p.running++
+	*p.peak = max(*p.peak, *p.running)
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		*p.running--
+		p.mu.Unlock()
+	}()
+
+	p.ecsClient.mu.Lock()
+	script := p.ecsClient.commands[invocation.Target[strings.LastIndex(invocation.Target, "_")+1:]]
+	p.ecsClient.mu.Unlock()
+	cmd := osexec.CommandContext(ctx, "/bin/sh", "-c", script)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = p.stdin, p.stdout, p.stdout
+	return cmd.Run()
+}
+
+func (p *fanOutPlugin) WithStreams(stdin io.Reader, stdout, _ io.Writer) session_manager.Plugin {
+	copied := *p
+	copied.stdin, copied.stdout = stdin, stdout
+	return &copied
+}
+
+func TestExecHandlerAllRunsOnEveryTaskAndSummarises(t *testing.T) {
+	var events []string
+	ecsClient := &fanOutECS{handlerECS: newHandlerECS(&events), commands: make(map[string]string)}
+	var running, peak int
+	plugin := &fanOutPlugin{ecsClient: ecsClient, running: &running, peak: &peak, mu: &sync.Mutex{}}
+	ssmClient := &fanOutSSM{handlerSSM: &handlerSSM{events: &events}}
+	deps := handlerDependencies(t, &events, ecsClient.handlerECS, ssmClient.handlerSSM, &handlerPlugin{events: &events})
+	deps.newSSM = func(aws.Config) ssmAPI { return ssmClient }
+	deps.newECS = func(aws.Config) ecsAPI { return ecsClient }
+	deps.preflight = func(context.Context, session_manager.Options) (session_manager.Plugin, error) {
+		return plugin, nil
+	}
+	var stdout, stderr strings.Builder
+	deps.stdout, deps.stderr = &stdout, &stderr
+	in := validExecHandlerInput()
+	in.Cmd = "echo out; echo warn >&2; sleep 0.1"
+	in.All, in.Parallel = true, 1
+
+	err := execHandler(context.Background(), in, deps)
+	if err != nil {
+		t.Fatalf("execHandler() error = %v", err)
+	}
+	for _, want := range []string{"[task-first] out\n", "[task-second] out\n"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("stdout = %q, want %q", stdout.String(), want)
+		}
+	}
+	for _, want := range []string{"[task-first] warn\n", "[task-second] warn\n", "TASK", "task-first   app        exit 0", "task-second  app        exit 0"} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("stderr = %q, want %q", stderr.String(), want)
+		}
+	}
+	if peak != 1 {
+		t.Fatalf("peak concurrent sessions = %d, want the parallel limit 1", peak)
+	}
+}
+
+func TestExecHandlerAllReportsFailedTasks(t *testing.T) {
+	var events []string
+	ecsClient := &fanOutECS{handlerECS: newHandlerECS(&events), commands: make(map[string]string)}
+	var running, peak int
+	plugin := &fanOutPlugin{ecsClient: ecsClient, running: &running, peak: &peak, mu: &sync.Mutex{}}
+	ssmClient := &fanOutSSM{handlerSSM: &handlerSSM{events: &events}}
+	deps := handlerDependencies(t, &events, ecsClient.handlerECS, ssmClient.handlerSSM, &handlerPlugin{events: &events})
+	deps.newSSM = func(aws.Config) ssmAPI { return ssmClient }
+	deps.newECS = func(aws.Config) ecsAPI { return ecsClient }
+	deps.preflight = func(context.Context, session_manager.Options) (session_manager.Plugin, error) {
+		return plugin, nil
+	}
+	var stderr strings.Builder
+	deps.stdout, deps.stderr = io.Discard, &stderr
+	in := validExecHandlerInput()
+	in.Cmd = "exit 5"
+	in.All = true
+
+	err := execHandler(context.Background(), in, deps)
+	var exitErr *command.ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 1 || !strings.Contains(err.Error(), "command failed on 2 of 2 tasks") {
+		t.Fatalf("execHandler() error = %v, want exit status 1 for the failed tasks", err)
+	}
+	if !strings.Contains(stderr.String(), "task-first   app        exit 5") {
+		t.Fatalf("summary = %q, want the remote exit status", stderr.String())
+	}
+}
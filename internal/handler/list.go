@@ -0,0 +1,387 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/wim-web/tnnl/internal/event"
+	"github.com/wim-web/tnnl/internal/input"
+	"github.com/wim-web/tnnl/internal/target"
+)
+
+func ListClustersHandler(ctx context.Context, in input.ListInput) error {
+	return listClustersHandler(ctx, in, productionDependencies(ctx))
+}
+
+func ListServicesHandler(ctx context.Context, in input.ListInput) error {
+	return listServicesHandler(ctx, in, productionDependencies(ctx))
+}
+
+func ListTasksHandler(ctx context.Context, in input.ListInput) error {
+	return listTasksHandler(ctx, in, productionDependencies(ctx))
+}
+
+func ListContainersHandler(ctx context.Context, in input.ListInput) error {
+	return listContainersHandler(ctx, in, productionDependencies(ctx))
+}
+
+// listScope is one cluster tnnl ls reads, with a resolver in its account and
+// Region.
+type listScope struct {
+	cluster  target.DiscoveredCluster
+	resolver *target.Resolver
+}
+
+// listScopes returns the cluster of the input, or every cluster of every
+// discovery location when none is given. The AWS configuration is loaded
+// once per location so that an MFA code is asked for at most once each.
+func listScopes(ctx context.Context, deps dependencies, in input.ListInput) ([]listScope, error) {
+	if in.Cluster != "" {
+		name, err := target.ClusterName(in.Cluster)
+		if err != nil {
+			return nil, err
+		}
+		cfg, err := deps.loadConfig(ctx, in.ConnectionParameter)
+		if err != nil {
+			return nil, fmt.Errorf("load AWS configuration: %w", err)
+		}
+		cluster := target.DiscoveredCluster{ARN: in.Cluster, Name: name, Region: cfg.Region}
+		return []listScope{{cluster: cluster, resolver: target.NewResolver(deps.newECS(cfg))}}, nil
+	}
+
+	var mu sync.Mutex
+	resolvers := make(map[target.Location]*target.Resolver)
+	clusters, err := target.DiscoverClusters(ctx, discoveryLocations(in.ConnectionParameter), func(ctx context.Context, location target.Location) ([]string, error) {
+		cfg, err := deps.loadConfig(ctx, atLocation(in.ConnectionParameter, location.Profile, location.Region))
+		if err != nil {
+			return nil, fmt.Errorf("load AWS configuration: %w", err)
+		}
+		resolver := target.NewResolver(deps.newECS(cfg))
+		mu.Lock()
+		resolvers[location] = resolver
+		mu.Unlock()
+		return resolver.Clusters(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	scopes := make([]listScope, 0, len(clusters))
+	for _, cluster := range clusters {
+		scopes = append(scopes, listScope{cluster: cluster, resolver: resolvers[cluster.Location]})
+	}
+	return scopes, nil
+}
+
+// clusterEvent names a cluster tnnl ls clusters found and where it is: the
+// account, the Region, and the profile it was discovered with.
+type clusterEvent struct {
+	Cluster    string `json:"cluster"`
+	ClusterARN string `json:"cluster_arn"`
+	Account    string `json:"account"`
+	Region     string `json:"region"`
+	Profile    string `json:"profile,omitempty"`
+}
+
+// listClustersHandler prints the clusters of the account and Region, or of
+// every discovery location, with the account and Region of each.
+func listClustersHandler(ctx context.Context, in input.ListInput, deps dependencies) error {
+	scopes, err := listScopes(ctx, deps, in)
+	if err != nil {
+		return err
+	}
+	if deps.events.Enabled() {
+		for _, scope := range scopes {
+			err := deps.events.Emit(event.Cluster, clusterEvent{
+				Cluster:    scope.cluster.Name,
+				ClusterARN: scope.cluster.ARN,
+				Account:    scope.cluster.Account,
+				Region:     scope.cluster.Region,
+				Profile:    scope.cluster.Location.Profile,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	table := tabwriter.NewWriter(deps.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "CLUSTER\tACCOUNT\tREGION\tPROFILE\tARN")
+	for _, scope := range scopes {
+		fmt.Fprintf(
+			table,
+			"%s\t%s\t%s\t%s\t%s\n",
+			scope.cluster.Name,
+			listCell(scope.cluster.Account),
+			listCell(scope.cluster.Region),
+			listCell(scope.cluster.Location.Profile),
+			scope.cluster.ARN,
+		)
+	}
+	return table.Flush()
+}
+
+// serviceEvent carries the task counts and rollout status of a service tnnl
+// ls services found, and whether it starts tasks with execute command.
+type serviceEvent struct {
+	Cluster        string `json:"cluster"`
+	Service        string `json:"service"`
+	ServiceARN     string `json:"service_arn"`
+	RunningCount   int32  `json:"running_count"`
+	DesiredCount   int32  `json:"desired_count"`
+	Status         string `json:"status"`
+	ExecuteCommand bool   `json:"execute_command"`
+}
+
+// listServicesHandler prints the services of the clusters with their task
+// counts, rollout, and whether they start tasks with execute command.
+func listServicesHandler(ctx context.Context, in input.ListInput, deps dependencies) error {
+	scopes, err := listScopes(ctx, deps, in)
+	if err != nil {
+		return err
+	}
+	table := tabwriter.NewWriter(deps.stdout, 0, 0, 2, ' ', 0)
+	if !deps.events.Enabled() {
+		fmt.Fprintln(table, "CLUSTER\tSERVICE\tTASKS\tSTATUS\tEXECUTE COMMAND")
+	}
+	for _, scope := range scopes {
+		services, err := scope.resolver.Services(ctx, scope.cluster.ARN)
+		if err != nil {
+			return err
+		}
+		for _, service := range services {
+			if deps.events.Enabled() {
+				err := deps.events.Emit(event.Service, serviceEvent{
+					Cluster:        scope.cluster.Name,
+					Service:        aws.ToString(service.ServiceName),
+					ServiceARN:     aws.ToString(service.ServiceArn),
+					RunningCount:   service.RunningCount,
+					DesiredCount:   service.DesiredCount,
+					Status:         target.DeploymentStatus(service),
+					ExecuteCommand: service.EnableExecuteCommand,
+				})
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			fmt.Fprintf(
+				table,
+				"%s\t%s\t%d/%d\t%s\t%s\n",
+				scope.cluster.Name,
+				aws.ToString(service.ServiceName),
+				service.RunningCount,
+				service.DesiredCount,
+				target.DeploymentStatus(service),
+				enabledLabel(service.EnableExecuteCommand),
+			)
+		}
+	}
+	if deps.events.Enabled() {
+		return nil
+	}
+	return table.Flush()
+}
+
+// taskEvent carries a running task tnnl ls tasks found with its group,
+// task definition, and whether a session can reach it. Reason explains an
+// ineligible task.
+type taskEvent struct {
+	Cluster        string     `json:"cluster"`
+	TaskARN        string     `json:"task_arn"`
+	TaskID         string     `json:"task_id"`
+	Group          string     `json:"group"`
+	TaskDefinition string     `json:"task_definition"`
+	Status         string     `json:"status"`
+	Started        *time.Time `json:"started,omitempty"`
+	Eligible       bool       `json:"eligible"`
+	Reason         string     `json:"reason,omitempty"`
+}
+
+// listTasksHandler prints every task of the clusters that is meant to be
+// running, eligible or not, with the reason an ineligible task cannot be
+// used.
+func listTasksHandler(ctx context.Context, in input.ListInput, deps dependencies) error {
+	scopes, err := listScopes(ctx, deps, in)
+	if err != nil {
+		return err
+	}
+	table := tabwriter.NewWriter(deps.stdout, 0, 0, 2, ' ', 0)
+	if !deps.events.Enabled() {
+		fmt.Fprintln(table, "CLUSTER\tTASK\tGROUP\tTASK DEFINITION\tSTATUS\tELIGIBLE\tREASON")
+	}
+	for _, scope := range scopes {
+		diagnoses, err := scope.resolver.DiagnoseTasks(ctx, scope.cluster.ARN, in.Service)
+		if err != nil {
+			return err
+		}
+		for _, diagnosis := range diagnoses {
+			task := diagnosis.Task
+			row := taskEvent{
+				Cluster:        scope.cluster.Name,
+				TaskARN:        aws.ToString(task.TaskArn),
+				TaskID:         listTaskID(task),
+				Group:          aws.ToString(task.Group),
+				TaskDefinition: taskDefinition(task),
+				Status:         aws.ToString(task.LastStatus),
+				Started:        task.StartedAt,
+				Eligible:       diagnosis.Eligible(),
+				Reason:         diagnosis.Summary(),
+			}
+			if deps.events.Enabled() {
+				if err := deps.events.Emit(event.Task, row); err != nil {
+					return err
+				}
+				continue
+			}
+			fmt.Fprintf(
+				table,
+				"%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				row.Cluster,
+				listCell(row.TaskID),
+				listCell(row.Group),
+				listCell(row.TaskDefinition),
+				listCell(row.Status),
+				eligibleLabel(row.Eligible),
+				listCell(row.Reason),
+			)
+		}
+	}
+	if deps.events.Enabled() {
+		return nil
+	}
+	return table.Flush()
+}
+
+// containerEvent carries a container tnnl ls containers found with its task
+// and whether a session can reach it. SSMTarget is the Session Manager target of an eligible
+// container.
+type containerEvent struct {
+	Cluster   string `json:"cluster"`
+	TaskARN   string `json:"task_arn"`
+	TaskID    string `json:"task_id"`
+	Container string `json:"container"`
+	RuntimeID string `json:"runtime_id"`
+	Status    string `json:"status"`
+	Eligible  bool   `json:"eligible"`
+	Reason    string `json:"reason,omitempty"`
+	SSMTarget string `json:"ssm_target,omitempty"`
+}
+
+// listContainersHandler prints the containers of every task the clusters
+// run, or of one task, with the reason an ineligible container cannot be
+// used. A container of an ineligible task is ineligible with the task's
+// reason.
+func listContainersHandler(ctx context.Context, in input.ListInput, deps dependencies) error {
+	// The input holds a task ID or a task ARN.
+	taskID := in.Task
+	if id, err := target.TaskID(in.Task); err == nil {
+		taskID = id
+	}
+	scopes, err := listScopes(ctx, deps, in)
+	if err != nil {
+		return err
+	}
+	table := tabwriter.NewWriter(deps.stdout, 0, 0, 2, ' ', 0)
+	if !deps.events.Enabled() {
+		fmt.Fprintln(table, "CLUSTER\tTASK\tCONTAINER\tRUNTIME ID\tSTATUS\tELIGIBLE\tREASON")
+	}
+	for _, scope := range scopes {
+		diagnoses, err := scope.resolver.DiagnoseTasks(ctx, scope.cluster.ARN, in.Service)
+		if err != nil {
+			return err
+		}
+		for _, diagnosis := range diagnoses {
+			id := listTaskID(diagnosis.Task)
+			if taskID != "" && id != taskID {
+				continue
+			}
+			for _, container := range diagnosis.Containers {
+				row := containerEvent{
+					Cluster:   scope.cluster.Name,
+					TaskARN:   aws.ToString(diagnosis.Task.TaskArn),
+					TaskID:    id,
+					Container: aws.ToString(container.Container.Name),
+					RuntimeID: aws.ToString(container.Container.RuntimeId),
+					Status:    aws.ToString(container.Container.LastStatus),
+				}
+				switch {
+				case len(diagnosis.Reasons) > 0:
+					row.Reason = diagnosis.Reasons[0].String()
+				case len(container.Reasons) > 0:
+					row.Reason = container.Reasons[0].String()
+				default:
+					row.Eligible = true
+					row.SSMTarget = target.Resolved{
+						ClusterName: scope.cluster.Name,
+						TaskID:      id,
+						RuntimeID:   row.RuntimeID,
+					}.SSMTarget()
+				}
+				if deps.events.Enabled() {
+					if err := deps.events.Emit(event.Container, row); err != nil {
+						return err
+					}
+					continue
+				}
+				fmt.Fprintf(
+					table,
+					"%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+					row.Cluster,
+					listCell(row.TaskID),
+					listCell(row.Container),
+					listCell(row.RuntimeID),
+					listCell(row.Status),
+					eligibleLabel(row.Eligible),
+					listCell(row.Reason),
+				)
+			}
+		}
+	}
+	if deps.events.Enabled() {
+		return nil
+	}
+	return table.Flush()
+}
+
+// listTaskID is the task ID of task, or its whole ARN when that does not
+// parse.
+func listTaskID(task types.Task) string {
+	id, err := target.TaskID(aws.ToString(task.TaskArn))
+	if err != nil {
+		return aws.ToString(task.TaskArn)
+	}
+	return id
+}
+
+// taskDefinition is the family:revision of the task's task definition.
+func taskDefinition(task types.Task) string {
+	definition := aws.ToString(task.TaskDefinitionArn)
+	return definition[strings.LastIndex(definition, "/")+1:]
+}
+
+func listCell(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "-"
+	}
+	return value
+}
+
+func eligibleLabel(eligible bool) string {
+	if eligible {
+		return "yes"
+	}
+	return "no"
+}
+
+func enabledLabel(enabled bool) string {
+	if enabled {
+		return "enabled"
+	}
+	return "disabled"
+}
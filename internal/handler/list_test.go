@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/wim-web/tnnl/internal/event"
+	"github.com/wim-web/tnnl/internal/input"
+)
+
+func listDependencies(t *testing.T, events *[]string, ecsClient *handlerECS, stdout *bytes.Buffer) dependencies {
+	t.Helper()
+	deps := handlerDependencies(t, events, ecsClient, &handlerSSM{events: events}, &handlerPlugin{events: events})
+	deps.stdout = stdout
+	return deps
+}
+
+// listECS returns a cluster with an eligible task and one whose task was
+// started without execute command.
+func listECS(events *[]string) *handlerECS {
+	ecsClient := newHandlerECS(events)
+	disabled := readyHandlerTask(handlerSecondTaskARN, "runtime-second")
+	disabled.EnableExecuteCommand = false
+	disabled.TaskDefinitionArn = aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/web:7")
+	ecsClient.resolveOutput = &ecs.DescribeTasksOutput{Tasks: []ecstypes.Task{
+		readyHandlerTask(handlerFirstTaskARN, "runtime-first"),
+		disabled,
+	}}
+	return ecsClient
+}
+
+func TestListClustersHandlerListsEveryDiscoveryLocation(t *testing.T) {
+	var events []string
+	ecsClient := newHandlerECS(&events)
+	ecsClient.listClustersOutput = &ecs.ListClustersOutput{ClusterArns: []string{handlerClusterARN}}
+	otherRegion := &handlerECS{listClustersOutput: &ecs.ListClustersOutput{
+		ClusterArns: []string{"arn:aws:ecs:us-east-1:210987654321:cluster/staging"},
+	}}
+	var stdout bytes.Buffer
+	deps := listDependencies(t, &events, ecsClient, &stdout)
+	// Every discovery location loads its configuration concurrently.
+	var loads atomic.Int32
+	deps.loadConfig = func(_ context.Context, connection input.ConnectionParameter) (aws.Config, error) {
+		loads.Add(1)
+		return aws.Config{Region: connection.Region}, nil
+	}
+	deps.newECS = func(cfg aws.Config) ecsAPI {
+		if cfg.Region == "us-east-1" {
+			return otherRegion
+		}
+		return ecsClient
+	}
+	in := input.ListInput{ConnectionParameter: input.ConnectionParameter{Profile: "prod", DiscoverRegions: "us-east-1,ap-northeast-1"}}
+
+	if err := listClustersHandler(context.Background(), in, deps); err != nil {
+		t.Fatalf("listClustersHandler() error = %v", err)
+	}
+	want := []string{
+		"CLUSTER     ACCOUNT       REGION          PROFILE  ARN",
+		"staging     210987654321  us-east-1       prod     arn:aws:ecs:us-east-1:210987654321:cluster/staging",
+		"production  123456789012  ap-northeast-1  prod     " + handlerClusterARN,
+	}
+	if got := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("stdout =\n%s\nwant\n%s", stdout.String(), strings.Join(want, "\n"))
+	}
+	if got := loads.Load(); got != 2 {
+		t.Fatalf("loadConfig calls = %d, want one per Region", got)
+	}
+}
+
+func TestListServicesHandlerReportsExecuteCommand(t *testing.T) {
+	var events []string
+	ecsClient := newHandlerECS(&events)
+	ecsClient.listServicesOutput = &ecs.ListServicesOutput{ServiceArns: []string{"arn:aws:ecs:ap-northeast-1:123456789012:service/production/web"}}
+	ecsClient.services = []ecstypes.Service{{
+		ServiceName:          aws.String("web"),
+		ServiceArn:           aws.String("arn:aws:ecs:ap-northeast-1:123456789012:service/production/web"),
+		RunningCount:         2,
+		DesiredCount:         3,
+		EnableExecuteCommand: true,
+		Deployments:          []ecstypes.Deployment{{Status: aws.String("PRIMARY"), RolloutState: ecstypes.DeploymentRolloutStateInProgress}},
+	}}
+	var stdout bytes.Buffer
+	deps := listDependencies(t, &events, ecsClient, &stdout)
+	in := input.ListInput{Cluster: handlerClusterARN}
+
+	if err := listServicesHandler(context.Background(), in, deps); err != nil {
+		t.Fatalf("listServicesHandler() error = %v", err)
+	}
+	if got := stdout.String(); !strings.Contains(got, "production  web      2/3    deploying  enabled") {
+		t.Fatalf("stdout = %q, want the service row", got)
+	}
+
+	stdout.Reset()
+	var reported strings.Builder
+	deps.events = event.NewEmitter(&reported, time.Now)
+	if err := listServicesHandler(context.Background(), in, deps); err != nil {
+		t.Fatalf("listServicesHandler() error = %v", err)
+	}
+	decoded := decodeEvents(t, reported.String())
+	if stdout.Len() != 0 || len(decoded) != 1 {
+		t.Fatalf("stdout = %q, events = %v; want one service event only", stdout.String(), decoded)
+	}
+	if service := decoded[0]; service["event"] != "service" || service["service"] != "web" || service["cluster"] != "production" ||
+		service["running_count"] != float64(2) || service["execute_command"] != true {
+		t.Fatalf("service event = %v", service)
+	}
+}
+
+func TestListTasksHandlerShowsIneligibleTasksWithTheReason(t *testing.T) {
+	var events []string
+	ecsClient := listECS(&events)
+	var stdout bytes.Buffer
+	deps := listDependencies(t, &events, ecsClient, &stdout)
+
+	err := listTasksHandler(context.Background(), input.ListInput{Cluster: "production", Service: "web"}, deps)
+	if err != nil {
+		t.Fatalf("listTasksHandler() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "CLUSTER ") {
+		t.Fatalf("stdout =\n%s\nwant a header and two tasks", stdout.String())
+	}
+	if fields := strings.Fields(lines[1]); fields[1] != "task-first" || fields[5] != "yes" || fields[6] != "-" {
+		t.Fatalf("eligible task row = %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "task-second  service:service-web  web:7") ||
+		!strings.Contains(lines[2], "no        execute command is not enabled for the task (fix: deployment)") {
+		t.Fatalf("ineligible task row = %q", lines[2])
+	}
+	if got := aws.ToString(ecsClient.listTasksInput.ServiceName); got != "web" {
+		t.Fatalf("ListTasks service = %q, want web", got)
+	}
+}
+
+func TestListContainersHandlerReportsContainerEvents(t *testing.T) {
+	var events []string
+	ecsClient := listECS(&events)
+	var stdout bytes.Buffer
+	deps := listDependencies(t, &events, ecsClient, &stdout)
+	var reported strings.Builder
+	deps.events = event.NewEmitter(&reported, time.Now)
+
+	for _, task := range []string{"", "task-second", handlerSecondTaskARN} {
+		reported.Reset()
+		ecsClient.describeCalls = 0
+		err := listContainersHandler(context.Background(), input.ListInput{Cluster: handlerClusterARN, Task: task}, deps)
+		if err != nil {
+			t.Fatalf("listContainersHandler(%q) error = %v", task, err)
+		}
+		decoded := decodeEvents(t, reported.String())
+		if task == "" {
+			if len(decoded) != 2 {
+				t.Fatalf("events = %v, want one per container", decoded)
+			}
+			first := decoded[0]
+			if first["event"] != "container" || first["eligible"] != true || first["ssm_target"] != "ecs:production_task-first_runtime-first" {
+				t.Fatalf("eligible container event = %v", first)
+			}
+			continue
+		}
+		if len(decoded) != 1 {
+			t.Fatalf("task %q events = %v, want the second task's container", task, decoded)
+		}
+		second := decoded[0]
+		if second["task_id"] != "task-second" || second["container"] != handlerContainer || second["eligible"] != false ||
+			second["ssm_target"] != nil || !strings.Contains(second["reason"].(string), "execute command is not enabled") {
+			t.Fatalf("task %q container event = %v", task, second)
+		}
+	}
+	if stdout.Len() != 0 {
+		t.Fatalf("stdout = %q, want only events", stdout.String())
+	}
+}
@@ -0,0 +1,249 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/wim-web/tnnl/internal/event"
+	"github.com/wim-web/tnnl/internal/input"
+	"github.com/wim-web/tnnl/internal/session_manager"
+	"github.com/wim-web/tnnl/internal/target"
+	"github.com/wim-web/tnnl/internal/view"
+	"github.com/wim-web/tnnl/pkg/command"
+)
+
+const maxLocalPortAttempts = 8
+
+type plannedForward struct {
+	forward  input.ForwardParameter
+	resolved target.Resolved
+	plugin   session_manager.Plugin
+	remote   command.RemoteSession
+}
+
+func MultiPortforwardHandler(ctx context.Context, in input.MultiPortForwardInput) error {
+	return multiPortForwardHandler(ctx, in, productionDependencies(ctx))
+}
+
+// multiPortForwardHandler resolves every forward, starts all sessions, and
+// runs them until one ends or ctx is canceled. Every started session is
+// terminated before it returns.
+func multiPortForwardHandler(ctx context.Context, in input.MultiPortForwardInput, deps dependencies) error {
+	plugin, err := deps.preflight(ctx, sessionOptions(in.ConnectionParameter))
+	if err != nil {
+		return err
+	}
+
+	cfg, err := deps.loadConfig(ctx, in.ConnectionParameter)
+	if err != nil {
+		return fmt.Errorf("load AWS configuration: %w", err)
+	}
+	plugin = forwardPlugin(deps, withAssumedRole(plugin, in.ConnectionParameter, cfg))
+
+	ecsClient := deps.newECS(cfg)
+	resolver := target.NewResolver(ecsClient)
+	targets := make(map[input.EcsParameter]target.Resolved)
+	usedPorts := make(map[string]bool)
+	for _, forward := range in.Forwards {
+		if forward.LocalPortNumber != "" {
+			usedPorts[forward.LocalPortNumber] = true
+		}
+	}
+
+	forwards := make([]plannedForward, 0, len(in.Forwards))
+	for _, forward := range in.Forwards {
+		resolved, ok := targets[forward.EcsParameter]
+		if !ok {
+			var quit bool
+			resolved, quit, err = view.ResolveTarget(ctx, resolver, deps.choose, view.ResolveOptions{
+				Cluster:        forward.Cluster,
+				Service:        forward.Service,
+				Selector:       targetSelector(forward.EcsParameter),
+				ShowIneligible: forward.ShowIneligible,
+				History:        recentTargets(deps),
+				ChooseTable:    deps.chooseTable,
+			})
+			if err != nil {
+				return fmt.Errorf("forward %q: %w", forward.Name, err)
+			}
+			if quit {
+				return nil
+			}
+			rememberTarget(deps, resolved)
+			targets[forward.EcsParameter] = resolved
+		}
+
+		forwardPlugin, localPort, release, err := bindLocalPort(deps, plugin, forward.LocalPortNumber, func() (string, error) {
+			return allocateLocalPort(deps.availablePort, usedPorts)
+		})
+		if err != nil {
+			return fmt.Errorf("forward %q: %w", forward.Name, err)
+		}
+		defer release()
+		forward.LocalPortNumber = localPort
+		forwards = append(forwards, plannedForward{forward: forward, resolved: resolved, plugin: forwardPlugin})
+	}
+
+	ssmClient := deps.newSSM(cfg)
+	for i := range forwards {
+		doc, params := forwardDocument(forwards[i].forward)
+		remote, err := command.StartPortForwardSession(
+			ctx,
+			ssmClient,
+			command.PortTarget{SSMTarget: forwards[i].resolved.SSMTarget()},
+			cfg.Region,
+			doc,
+			params,
+		)
+		if err != nil {
+			return errors.Join(
+				fmt.Errorf("forward %q: %w", forwards[i].forward.Name, err),
+				terminateForwards(ctx, forwards[:i]),
+			)
+		}
+		forwards[i].remote = remote
+	}
+
+	if err := writeForwards(deps, forwards); err != nil {
+		return errors.Join(err, terminateForwards(ctx, forwards))
+	}
+
+	rerun := in
+	rerun.Forwards = make([]input.ForwardParameter, len(in.Forwards))
+	for i, planned := range forwards {
+		rerun.Forwards[i] = in.Forwards[i]
+		rerun.Forwards[i].Cluster = planned.resolved.ECSCluster
+		rerun.Forwards[i].Service = planned.resolved.Service
+		rerun.Forwards[i].Container = planned.resolved.ContainerName
+	}
+	sessionAudit := newSessionAudit(deps, cfg, "multiportforward", auditInput(deps, rerun, target.Resolved{}, cfg.Region))
+	return runForwards(ctx, forwards, sessionAudit)
+}
+
+func allocateLocalPort(availablePort func() (int, error), used map[string]bool) (string, error) {
+	for range maxLocalPortAttempts {
+		allocated, err := availablePort()
+		if err != nil {
+			return "", fmt.Errorf("allocate local port: %w", err)
+		}
+		if allocated < 1 || allocated > 65535 {
+			return "", fmt.Errorf("allocate local port: returned invalid port %d", allocated)
+		}
+		port := strconv.Itoa(allocated)
+		if !used[port] {
+			used[port] = true
+			return port, nil
+		}
+	}
+	return "", fmt.Errorf("allocate local port: no unused port after %d attempts", maxLocalPortAttempts)
+}
+
+func forwardDocument(forward input.ForwardParameter) (command.DocumentName, map[string][]string) {
+	params := map[string][]string{
+		"portNumber":      {forward.TargetPortNumber},
+		"localPortNumber": {forward.LocalPortNumber},
+	}
+	if forward.Host == "" {
+		return command.PORT_FORWARD_DOCUMENT_NAME, params
+	}
+	params["host"] = []string{forward.Host}
+	return command.REMOTE_PORT_FORWARD_DOCUMENT_NAME, params
+}
+
+// forwardEvent names one forward of the input with its ports, the task it
+// reaches, and the session that carries it. One is emitted per forward once
+// every session has started.
+type forwardEvent struct {
+	Name       string       `json:"name"`
+	SessionID  string       `json:"session_id"`
+	LocalPort  string       `json:"local_port"`
+	RemotePort string       `json:"remote_port"`
+	Host       string       `json:"host,omitempty"`
+	Target     event.Target `json:"target"`
+}
+
+// writeForwards lists the started forwards as a table, or as forward events
+// for --output json.
+func writeForwards(deps dependencies, forwards []plannedForward) error {
+	if !deps.events.Enabled() {
+		return writeForwardTable(deps.stdout, forwards)
+	}
+	for _, planned := range forwards {
+		err := deps.events.Emit(event.Forward, forwardEvent{
+			Name:       planned.forward.Name,
+			SessionID:  planned.remote.ID,
+			LocalPort:  planned.forward.LocalPortNumber,
+			RemotePort: planned.forward.TargetPortNumber,
+			Host:       planned.forward.Host,
+			Target:     event.NewTarget(planned.resolved),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeForwardTable(w io.Writer, forwards []plannedForward) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "NAME\tLOCAL\tREMOTE\tTASK\tCONTAINER\tSESSION")
+	for _, planned := range forwards {
+		remote := planned.forward.TargetPortNumber
+		if planned.forward.Host != "" {
+			remote = planned.forward.Host + ":" + remote
+		}
+		fmt.Fprintf(
+			table,
+			"%s\tlocalhost:%s\t%s\t%s\t%s\t%s\n",
+			planned.forward.Name,
+			planned.forward.LocalPortNumber,
+			remote,
+			planned.resolved.TaskID,
+			planned.resolved.ContainerName,
+			planned.remote.ID,
+		)
+	}
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("write port forward table: %w", err)
+	}
+	return nil
+}
+
+func runForwards(ctx context.Context, forwards []plannedForward, sessionAudit *sessionAudit) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make([]error, len(forwards))
+	var wg sync.WaitGroup
+	for i, planned := range forwards {
+		wg.Go(func() {
+			// The first forward to end stops the others.
+			defer cancel()
+			doc, params := forwardDocument(planned.forward)
+			err := sessionAudit.run(runCtx, planned.resolved, planned.remote, forwardAuditEntry(doc, params), func() error {
+				return planned.remote.Run(runCtx, planned.plugin)
+			})
+			// A forward stopped by another one ending has nothing to report.
+			if err != nil && (ctx.Err() != nil || !errors.Is(err, context.Canceled)) {
+				errs[i] = fmt.Errorf("forward %q: %w", planned.forward.Name, err)
+			}
+		})
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func terminateForwards(ctx context.Context, forwards []plannedForward) error {
+	errs := make([]error, 0, len(forwards))
+	for _, planned := range forwards {
+		if err := planned.remote.Terminate(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("forward %q: %w", planned.forward.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,278 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/wim-web/tnnl/internal/event"
+	"github.com/wim-web/tnnl/internal/input"
+	"github.com/wim-web/tnnl/internal/listview"
+	"github.com/wim-web/tnnl/internal/session_manager"
+	"github.com/wim-web/tnnl/pkg/command"
+)
+
+type multiSSM struct {
+	mu         sync.Mutex
+	starts     []*ssm.StartSessionInput
+	startErrAt int
+	terminated []string
+}
+
+func (f *multiSSM) DescribeSessions(context.Context, *ssm.DescribeSessionsInput, ...func(*ssm.Options)) (*ssm.DescribeSessionsOutput, error) {
+	return &ssm.DescribeSessionsOutput{}, nil
+}
+
+func (f *multiSSM) StartSession(_ context.Context, in *ssm.StartSessionInput, _ ...func(*ssm.Options)) (*ssm.StartSessionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.starts = append(f.starts, in)
+	if len(f.starts) == f.startErrAt {
+		return nil, errors.New("start sentinel")
+	}
+	id := fmt.Sprintf("session-%d", len(f.starts))
+	return &ssm.StartSessionOutput{
+		SessionId:  aws.String(id),
+		StreamUrl:  aws.String("wss://" + id),
+		TokenValue: aws.String("token-" + id),
+	}, nil
+}
+
+func (f *multiSSM) TerminateSession(_ context.Context, in *ssm.TerminateSessionInput, _ ...func(*ssm.Options)) (*ssm.TerminateSessionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.terminated = append(f.terminated, aws.ToString(in.SessionId))
+	return &ssm.TerminateSessionOutput{}, nil
+}
+
+func (f *multiSSM) terminatedIDs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ids := slices.Clone(f.terminated)
+	slices.Sort(ids)
+	return ids
+}
+
+// multiPlugin blocks every session until ctx ends, except that failSession
+// fails and endSession ends cleanly once all sessions are running.
+type multiPlugin struct {
+	failSession string
+	endSession  string
+	running     sync.WaitGroup
+}
+
+func (p *multiPlugin) Run(ctx context.Context, invocation session_manager.Invocation) error {
+	p.running.Done()
+	switch invocation.Response.SessionID {
+	case p.failSession:
+		p.running.Wait()
+		return errors.New("plugin sentinel")
+	case p.endSession:
+		p.running.Wait()
+		return nil
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func multiDependencies(t *testing.T, ssmClient *multiSSM, plugin *multiPlugin, stdout *bytes.Buffer) (dependencies, *int) {
+	t.Helper()
+	var events []string
+	deps := handlerDependencies(t, &events, newHandlerECS(&events), nil, nil)
+	deps.preflight = func(context.Context, session_manager.Options) (session_manager.Plugin, error) {
+		return plugin, nil
+	}
+	deps.newSSM = func(aws.Config) ssmAPI { return ssmClient }
+	chooseCalls := 0
+	choose := deps.choose
+	deps.choose = func(title string, options []listview.Option) (string, bool, error) {
+		chooseCalls++
+		return choose(title, options)
+	}
+	nextPort := 49152
+	deps.availablePort = func() (int, error) {
+		nextPort++
+		return nextPort, nil
+	}
+	deps.stdout = stdout
+	return deps, &chooseCalls
+}
+
+func validMultiHandlerInput() input.MultiPortForwardInput {
+	ecsParam := input.EcsParameter{Cluster: handlerClusterARN, Service: "service-web"}
+	return input.MultiPortForwardInput{Forwards: []input.ForwardParameter{
+		{Name: "app", EcsParameter: ecsParam, TargetPortNumber: "8080", LocalPortNumber: "18080"},
+		{Name: "db", EcsParameter: ecsParam, TargetPortNumber: "5432", Host: "db.internal"},
+	}}
+}
+
+func TestMultiPortForwardHandlerStartsAllAndTerminatesAllWhenOneFails(t *testing.T) {
+	ssmClient := &multiSSM{}
+	plugin := &multiPlugin{failSession: "session-1"}
+	plugin.running.Add(2)
+	var stdout bytes.Buffer
+	deps, chooseCalls := multiDependencies(t, ssmClient, plugin, &stdout)
+
+	err := multiPortForwardHandler(context.Background(), validMultiHandlerInput(), deps)
+	if err == nil || !strings.Contains(err.Error(), `forward "app"`) || !strings.Contains(err.Error(), "plugin sentinel") {
+		t.Fatalf("multiPortForwardHandler() error = %v, want app plugin failure", err)
+	}
+	if strings.Contains(err.Error(), `forward "db"`) {
+		t.Fatalf("multiPortForwardHandler() error = %v, want db stopped without an error of its own", err)
+	}
+	if *chooseCalls != 1 {
+		t.Fatalf("chooser calls = %d, want one shared target resolution", *chooseCalls)
+	}
+	if got, want := ssmClient.terminatedIDs(), []string{"session-1", "session-2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("terminated sessions = %v, want %v", got, want)
+	}
+
+	if len(ssmClient.starts) != 2 {
+		t.Fatalf("StartSession calls = %d, want 2", len(ssmClient.starts))
+	}
+	if got := aws.ToString(ssmClient.starts[0].DocumentName); got != string(command.PORT_FORWARD_DOCUMENT_NAME) {
+		t.Fatalf("first document = %q", got)
+	}
+	wantRemote := map[string][]string{
+		"portNumber":      {"5432"},
+		"localPortNumber": {"49153"},
+		"host":            {"db.internal"},
+	}
+	if got := aws.ToString(ssmClient.starts[1].DocumentName); got != string(command.REMOTE_PORT_FORWARD_DOCUMENT_NAME) {
+		t.Fatalf("second document = %q", got)
+	}
+	if !reflect.DeepEqual(ssmClient.starts[1].Parameters, wantRemote) {
+		t.Fatalf("second parameters = %#v, want %#v", ssmClient.starts[1].Parameters, wantRemote)
+	}
+
+	table := stdout.String()
+	for _, want := range []string{
+		"NAME", "LOCAL", "REMOTE",
+		"app   localhost:18080  8080",
+		"db    localhost:49153  db.internal:5432",
+		"task-second", "session-2",
+	} {
+		if !strings.Contains(table, want) {
+			t.Errorf("endpoint table = %q, want it to contain %q", table, want)
+		}
+	}
+}
+
+func TestMultiPortForwardHandlerForwardEndingCleanlyStopsOthersWithoutError(t *testing.T) {
+	ssmClient := &multiSSM{}
+	plugin := &multiPlugin{endSession: "session-1"}
+	plugin.running.Add(2)
+	deps, _ := multiDependencies(t, ssmClient, plugin, &bytes.Buffer{})
+
+	if err := multiPortForwardHandler(context.Background(), validMultiHandlerInput(), deps); err != nil {
+		t.Fatalf("multiPortForwardHandler() error = %v, want nil", err)
+	}
+	if got, want := ssmClient.terminatedIDs(), []string{"session-1", "session-2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("terminated sessions = %v, want %v", got, want)
+	}
+}
+
+func TestMultiPortForwardHandlerCancellationTerminatesAll(t *testing.T) {
+	ssmClient := &multiSSM{}
+	plugin := &multiPlugin{}
+	plugin.running.Add(2)
+	deps, _ := multiDependencies(t, ssmClient, plugin, &bytes.Buffer{})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		plugin.running.Wait()
+		cancel()
+	}()
+
+	result := make(chan error, 1)
+	go func() { result <- multiPortForwardHandler(ctx, validMultiHandlerInput(), deps) }()
+
+	select {
+	case err := <-result:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("multiPortForwardHandler() error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("multiPortForwardHandler() did not return after cancellation")
+	}
+	if got, want := ssmClient.terminatedIDs(), []string{"session-1", "session-2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("terminated sessions = %v, want %v", got, want)
+	}
+}
+
+func TestMultiPortForwardHandlerStartFailureTerminatesStartedSessions(t *testing.T) {
+	ssmClient := &multiSSM{startErrAt: 2}
+	plugin := &multiPlugin{}
+	var stdout bytes.Buffer
+	deps, _ := multiDependencies(t, ssmClient, plugin, &stdout)
+
+	err := multiPortForwardHandler(context.Background(), validMultiHandlerInput(), deps)
+	if err == nil || !strings.Contains(err.Error(), `forward "db"`) || !strings.Contains(err.Error(), "start sentinel") {
+		t.Fatalf("multiPortForwardHandler() error = %v, want db start failure", err)
+	}
+	if got, want := ssmClient.terminatedIDs(), []string{"session-1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("terminated sessions = %v, want %v", got, want)
+	}
+	if stdout.Len() != 0 {
+		t.Fatalf("endpoint table = %q, want nothing before all sessions start", stdout.String())
+	}
+}
+
+func TestAllocateLocalPortSkipsPortsInUse(t *testing.T) {
+	ports := []int{18080, 18080, 18081}
+	used := map[string]bool{"18080": true}
+	got, err := allocateLocalPort(func() (int, error) {
+		port := ports[0]
+		ports = ports[1:]
+		return port, nil
+	}, used)
+	if err != nil {
+		t.Fatalf("allocateLocalPort() error = %v", err)
+	}
+	if got != "18081" || !used["18081"] {
+		t.Fatalf("allocateLocalPort() = %q, used = %v; want 18081 marked used", got, used)
+	}
+}
+
+func TestMultiPortForwardHandlerReportsForwardEvents(t *testing.T) {
+	ssmClient := &multiSSM{}
+	plugin := &multiPlugin{failSession: "session-2"}
+	plugin.running.Add(2)
+	var stdout bytes.Buffer
+	deps, _ := multiDependencies(t, ssmClient, plugin, &stdout)
+	var reported strings.Builder
+	deps.clock = &reconnectClock{}
+	deps.events = event.NewEmitter(&reported, time.Now)
+
+	if err := multiPortForwardHandler(context.Background(), validMultiHandlerInput(), deps); err == nil {
+		t.Fatal("multiPortForwardHandler() error = nil, want the db plugin failure")
+	}
+	if stdout.Len() != 0 {
+		t.Fatalf("stdout = %q, want only events", stdout.String())
+	}
+	var forwards []map[string]any
+	for _, fields := range decodeEvents(t, reported.String()) {
+		if fields["event"] == "forward" {
+			forwards = append(forwards, fields)
+		}
+	}
+	if len(forwards) != 2 {
+		t.Fatalf("forward events = %v, want two", forwards)
+	}
+	if app := forwards[0]; app["name"] != "app" || app["local_port"] != "18080" || app["remote_port"] != "8080" || app["session_id"] != "session-1" {
+		t.Fatalf("app forward = %v", app)
+	}
+	db := forwards[1]
+	target := db["target"].(map[string]any)
+	if db["name"] != "db" || db["local_port"] != "49153" || db["host"] != "db.internal" || target["task_id"] != "task-second" {
+		t.Fatalf("db forward = %v", db)
+	}
+}
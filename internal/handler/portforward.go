@@ -6,14 +6,16 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/wim-web/tnnl/internal/audit"
 	"github.com/wim-web/tnnl/internal/input"
+	"github.com/wim-web/tnnl/internal/session_manager"
 	"github.com/wim-web/tnnl/internal/target"
 	"github.com/wim-web/tnnl/internal/view"
 	"github.com/wim-web/tnnl/pkg/command"
 )
 
 func PortforwardHandler(ctx context.Context, in input.PortForwardInput) error {
-	return portForwardHandler(ctx, in, productionDependencies())
+	return portForwardHandler(ctx, in, productionDependencies(ctx))
 }
 
 func portForwardHandler(ctx context.Context, in input.PortForwardInput, deps dependencies) error {
@@ -21,11 +23,19 @@ func portForwardHandler(ctx context.Context, in input.PortForwardInput, deps dep
 		"portNumber":      {in.TargetPortNumber},
 		"localPortNumber": {in.LocalPortNumber},
 	}
-	return portforwardHandler(ctx, command.PORT_FORWARD_DOCUMENT_NAME, params, in.EcsParameter, deps)
+	return portforwardHandler(ctx, portForwardOptions{
+		input:      in,
+		doc:        command.PORT_FORWARD_DOCUMENT_NAME,
+		parameters: params,
+		ecs:        in.EcsParameter,
+		connection: in.ConnectionParameter,
+		reconnect:  in.ReconnectParameter,
+		dryRun:     in.DryRun,
+	}, deps)
 }
 
 func RemotePortforwardHandler(ctx context.Context, in input.RemotePortForwardInput) error {
-	return remotePortForwardHandler(ctx, in, productionDependencies())
+	return remotePortForwardHandler(ctx, in, productionDependencies(ctx))
 }
 
 func remotePortForwardHandler(ctx context.Context, in input.RemotePortForwardInput, deps dependencies) error {
@@ -34,56 +44,88 @@ func remotePortForwardHandler(ctx context.Context, in input.RemotePortForwardInp
 		"localPortNumber": {in.LocalPortNumber},
 		"host":            {in.Host},
 	}
-	return portforwardHandler(ctx, command.REMOTE_PORT_FORWARD_DOCUMENT_NAME, params, in.EcsParameter, deps)
+	return portforwardHandler(ctx, portForwardOptions{
+		input:      in,
+		doc:        command.REMOTE_PORT_FORWARD_DOCUMENT_NAME,
+		parameters: params,
+		ecs:        in.EcsParameter,
+		connection: in.ConnectionParameter,
+		reconnect:  in.ReconnectParameter,
+		dryRun:     in.DryRun,
+	}, deps)
 }
 
-func portforwardHandler(
-	ctx context.Context,
-	doc command.DocumentName,
-	parameters map[string][]string,
-	ecsParam input.EcsParameter,
-	deps dependencies,
-) error {
-	plugin, err := deps.preflight(ctx)
+// portForwardOptions are the values of a PortForwardInput or
+// RemotePortForwardInput that portforwardHandler needs. input is the whole
+// input, which the audit log records for a re-run.
+type portForwardOptions struct {
+	input      any
+	doc        command.DocumentName
+	parameters map[string][]string
+	ecs        input.EcsParameter
+	connection input.ConnectionParameter
+	reconnect  input.ReconnectParameter
+	dryRun     bool
+}
+
+// portforwardHandler runs the forward options describes. A dry run stops
+// before the local port is bound.
+func portforwardHandler(ctx context.Context, options portForwardOptions, deps dependencies) error {
+	doc, ecsParam, connection := options.doc, options.ecs, options.connection
+	connection, cluster, quit, err := discoverCluster(ctx, deps, connection, ecsParam.Cluster, targetSelector(ecsParam))
+	if err != nil || quit {
+		return err
+	}
+	ecsParam.Cluster = cluster
+
+	plugin, err := deps.preflight(ctx, sessionOptions(connection))
 	if err != nil {
 		return err
 	}
 
-	cfg, err := deps.loadConfig(ctx)
+	cfg, err := deps.loadConfig(ctx, connection)
 	if err != nil {
 		return fmt.Errorf("load AWS configuration: %w", err)
 	}
+	plugin = forwardPlugin(deps, withAssumedRole(plugin, connection, cfg))
 
 	ecsClient := deps.newECS(cfg)
-	resolved, quit, err := view.ResolveTarget(
-		ctx,
-		target.NewResolver(ecsClient),
-		deps.choose,
-		ecsParam.Cluster,
-		ecsParam.Service,
-		0,
-	)
+	resolver := target.NewResolver(ecsClient)
+	resolved, quit, err := view.ResolveTarget(ctx, resolver, deps.choose, view.ResolveOptions{
+		Cluster:        ecsParam.Cluster,
+		Service:        ecsParam.Service,
+		Selector:       targetSelector(ecsParam),
+		ShowIneligible: ecsParam.ShowIneligible,
+		History:        recentTargets(deps),
+		ChooseTable:    deps.chooseTable,
+	})
 	if err != nil {
 		return err
 	}
 	if quit {
 		return nil
 	}
+	if options.dryRun {
+		return writeDryRun(deps, plugin, forwardDryRun(resolved, cfg.Region, doc, options.parameters))
+	}
+	rememberTarget(deps, resolved)
 
-	params := cloneParameters(parameters)
-	localPort := firstParameter(params, "localPortNumber")
-	if strings.TrimSpace(localPort) == "" {
-		allocated, err := deps.availablePort()
-		if err != nil {
-			return fmt.Errorf("allocate local port: %w", err)
-		}
-		if allocated < 1 || allocated > 65535 {
-			return fmt.Errorf("allocate local port: returned invalid port %d", allocated)
-		}
-		params["localPortNumber"] = []string{strconv.Itoa(allocated)}
+	params := cloneParameters(options.parameters)
+	plugin, localPort, release, err := bindLocalPort(deps, plugin, firstParameter(params, "localPortNumber"), func() (string, error) {
+		return allocateLocalPort(deps.availablePort, map[string]bool{})
+	})
+	if err != nil {
+		return err
 	}
+	defer release()
+	params["localPortNumber"] = []string{localPort}
 
 	ssmClient := deps.newSSM(cfg)
+	sessionAudit := newSessionAudit(deps, cfg, forwardCommandName(doc), auditInput(deps, options.input, resolved, cfg.Region))
+	if options.reconnect.Reconnect {
+		supervisor := newForwardSupervisor(deps, ssmClient, resolver, plugin, sessionAudit, cfg.Region, doc, params, ecsParam, options.reconnect)
+		return supervisor.run(ctx, resolved)
+	}
 	remote, err := command.StartPortForwardSession(
 		ctx,
 		ssmClient,
@@ -96,7 +138,56 @@ func portforwardHandler(
 		return err
 	}
 
-	return remote.Run(ctx, plugin)
+	return sessionAudit.run(ctx, resolved, remote, forwardAuditEntry(doc, params), func() error {
+		return remote.Run(ctx, plugin)
+	})
+}
+
+// forwardCommandName is the tnnl command that runs doc.
+func forwardCommandName(doc command.DocumentName) string {
+	if doc == command.REMOTE_PORT_FORWARD_DOCUMENT_NAME {
+		return "remoteportforward"
+	}
+	return "portforward"
+}
+
+func forwardAuditEntry(doc command.DocumentName, params map[string][]string) audit.Entry {
+	return audit.Entry{
+		Document:   string(doc),
+		LocalPort:  firstParameter(params, "localPortNumber"),
+		RemotePort: firstParameter(params, "portNumber"),
+		Host:       firstParameter(params, "host"),
+	}
+}
+
+// bindLocalPort decides the local port of a forward. Session clients that
+// accept a caller-owned listener get one bound here and kept for the whole
+// command, so the port cannot be taken before or between sessions. Other
+// clients bind the port themselves, so an unset port is only picked here.
+func bindLocalPort(
+	deps dependencies,
+	plugin session_manager.Plugin,
+	localPort string,
+	allocate func() (string, error),
+) (session_manager.Plugin, string, func(), error) {
+	listenerPlugin, ok := plugin.(session_manager.ListenerPlugin)
+	if !ok {
+		if strings.TrimSpace(localPort) != "" {
+			return plugin, localPort, func() {}, nil
+		}
+		allocated, err := allocate()
+		if err != nil {
+			return nil, "", nil, err
+		}
+		return plugin, allocated, func() {}, nil
+	}
+
+	listener, err := deps.listen(localPort)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	release := func() { _ = listener.Close() }
+	return session_manager.ServeListener(listenerPlugin, listener.Session), strconv.Itoa(listener.Port()), release, nil
 }
 
 func cloneParameters(parameters map[string][]string) map[string][]string {
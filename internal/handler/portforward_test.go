@@ -3,7 +3,9 @@ package handler
 import (
 	"context"
 	"errors"
+	"net"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 	"testing"
@@ -14,17 +16,18 @@ import (
 	"github.com/wim-web/tnnl/internal/listview"
 	"github.com/wim-web/tnnl/internal/session_manager"
 	"github.com/wim-web/tnnl/pkg/command"
+	"github.com/wim-web/tnnl/pkg/port"
 )
 
 func TestPortForwardHandlerPreflightFailureStopsBeforeAWS(t *testing.T) {
 	preflightErr := errors.New("preflight sentinel")
 	var events []string
 	deps := dependencies{
-		preflight: func(context.Context) (session_manager.Plugin, error) {
+		preflight: func(context.Context, session_manager.Options) (session_manager.Plugin, error) {
 			appendEvent(&events, "preflight")
 			return nil, preflightErr
 		},
-		loadConfig: func(context.Context) (aws.Config, error) {
+		loadConfig: func(context.Context, input.ConnectionParameter) (aws.Config, error) {
 			t.Fatal("loadConfig called after preflight failure")
 			return aws.Config{}, nil
 		},
@@ -66,7 +69,7 @@ func TestPortForwardHandlerSelectsSecondReplicaAndAllocatesLocalPort(t *testing.
 	}
 	wantEvents := []string{
 		"preflight", "load-config", "list-tasks", "describe-targets", "choose-task",
-		"available-port", "start-session", "plugin-run",
+		"available-port", "start-session", "plugin-run", "terminate-session",
 	}
 	if !reflect.DeepEqual(events, wantEvents) {
 		t.Fatalf("events = %#v, want %#v", events, wantEvents)
@@ -250,3 +253,50 @@ func validHandlerStartOutput() *ssm.StartSessionOutput {
 		TokenValue: aws.String("handler-token"),
 	}
 }
+
+type handlerListenerPlugin struct {
+	handlerPlugin
+	listenerAddr string
+}
+
+func (p *handlerListenerPlugin) RunListener(ctx context.Context, invocation session_manager.Invocation, listener net.Listener) error {
+	p.listenerAddr = listener.Addr().String()
+	return p.Run(ctx, invocation)
+}
+
+func TestPortForwardHandlerOwnsListenerForListenerClients(t *testing.T) {
+	var events []string
+	ecsClient := newHandlerECS(&events)
+	ssmClient := &handlerSSM{events: &events, startOutput: validHandlerStartOutput()}
+	plugin := &handlerListenerPlugin{handlerPlugin: handlerPlugin{events: &events}}
+	deps := handlerDependencies(t, &events, ecsClient, ssmClient, nil)
+	deps.preflight = func(context.Context, session_manager.Options) (session_manager.Plugin, error) {
+		return plugin, nil
+	}
+	var listened []string
+	deps.listen = func(localPort string) (*port.Listener, error) {
+		listened = append(listened, localPort)
+		appendEvent(&events, "listen")
+		return port.Listen(localPort)
+	}
+
+	if err := portForwardHandler(context.Background(), validPortHandlerInput(""), deps); err != nil {
+		t.Fatalf("portForwardHandler() error = %v", err)
+	}
+	if !reflect.DeepEqual(listened, []string{""}) {
+		t.Fatalf("listen calls = %q, want one kernel-chosen port", listened)
+	}
+	localPort := firstParameter(ssmClient.startInput.Parameters, "localPortNumber")
+	if want := net.JoinHostPort("127.0.0.1", localPort); plugin.listenerAddr != want {
+		t.Fatalf("plugin listener = %q, want %q from localPortNumber", plugin.listenerAddr, want)
+	}
+	if i, j := slices.Index(events, "listen"), slices.Index(events, "start-session"); i < 0 || i > j {
+		t.Fatalf("events = %#v, want listen before start-session", events)
+	}
+
+	released, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", localPort))
+	if err != nil {
+		t.Fatalf("local port %s was not released after the handler returned: %v", localPort, err)
+	}
+	_ = released.Close()
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/wim-web/tnnl/internal/recent"
+	"github.com/wim-web/tnnl/internal/target"
+)
+
+func loadRecentTargets() (recent.History, error) {
+	path, err := recent.Path()
+	if err != nil {
+		return nil, err
+	}
+	return recent.Load(path)
+}
+
+func rememberRecentTarget(choice recent.Choice) error {
+	path, err := recent.Path()
+	if err != nil {
+		return err
+	}
+	return recent.Remember(path, choice)
+}
+
+// recentTargets returns the targets chosen lately for the pickers to offer
+// first. A history that cannot be read is reported and left out.
+func recentTargets(deps dependencies) recent.History {
+	if deps.loadRecent == nil {
+		return nil
+	}
+	history, err := deps.loadRecent()
+	if err != nil {
+		fmt.Fprintf(deps.stderr, "warning: %v\n", err)
+		return nil
+	}
+	return history
+}
+
+// rememberTarget records resolved as the latest choice. Failing to record
+// it is reported but does not fail the command.
+func rememberTarget(deps dependencies, resolved target.Resolved) {
+	if deps.rememberTarget == nil {
+		return
+	}
+	if err := deps.rememberTarget(recent.NewChoice(resolved, deps.clock.Now())); err != nil {
+		fmt.Fprintf(deps.stderr, "warning: %v\n", err)
+	}
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wim-web/tnnl/internal/recent"
+)
+
+func TestExecHandlerRemembersTheResolvedTarget(t *testing.T) {
+	var events []string
+	ecsClient := newHandlerECS(&events)
+	deps := handlerDependencies(t, &events, ecsClient, &handlerSSM{events: &events}, &handlerPlugin{events: &events})
+	var stderr strings.Builder
+	deps.stderr = &stderr
+	deps.clock = &reconnectClock{now: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}
+	deps.loadRecent = func() (recent.History, error) {
+		return nil, errors.New("decode recent targets file: unexpected end of JSON input")
+	}
+	var remembered []recent.Choice
+	deps.rememberTarget = func(choice recent.Choice) error {
+		remembered = append(remembered, choice)
+		return nil
+	}
+	in := validExecHandlerInput()
+
+	if err := execHandler(context.Background(), in, deps); err != nil {
+		t.Fatalf("execHandler() error = %v", err)
+	}
+	if want := "warning: decode recent targets file: unexpected end of JSON input\n"; stderr.String() != want {
+		t.Fatalf("stderr = %q, want %q", stderr.String(), want)
+	}
+	want := []recent.Choice{{
+		Time:      time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Account:   "123456789012",
+		Region:    handlerRegion,
+		Cluster:   handlerClusterARN,
+		Service:   "service-web",
+		Container: handlerContainer,
+	}}
+	if !reflect.DeepEqual(remembered, want) {
+		t.Fatalf("remembered = %#v, want %#v", remembered, want)
+	}
+}
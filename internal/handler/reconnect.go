@@ -0,0 +1,243 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/wim-web/tnnl/internal/event"
+	"github.com/wim-web/tnnl/internal/input"
+	"github.com/wim-web/tnnl/internal/session_manager"
+	"github.com/wim-web/tnnl/internal/target"
+	"github.com/wim-web/tnnl/pkg/command"
+)
+
+const (
+	defaultReconnectAttempts = 5
+	defaultReconnectWait     = 2 * time.Minute
+	reconnectInitialBackoff  = time.Second
+	reconnectMaxBackoff      = 30 * time.Second
+	// A session that stayed up this long resets the consecutive attempt count.
+	reconnectResetAfter = time.Minute
+)
+
+var errSessionEnded = errors.New("session ended")
+
+// forwardSupervisor keeps one port forward alive across task replacement by
+// starting a fresh session on the same local port whenever the current one
+// ends.
+type forwardSupervisor struct {
+	ssm      ssmAPI
+	resolver *target.Resolver
+	plugin   session_manager.Plugin
+	audit    *sessionAudit
+	clock    target.Clock
+	stderr   io.Writer
+	events   *event.Emitter
+
+	region   string
+	doc      command.DocumentName
+	params   map[string][]string
+	ecsParam input.EcsParameter
+	attempts int
+	wait     time.Duration
+}
+
+func newForwardSupervisor(
+	deps dependencies,
+	ssmClient ssmAPI,
+	resolver *target.Resolver,
+	plugin session_manager.Plugin,
+	sessionAudit *sessionAudit,
+	region string,
+	doc command.DocumentName,
+	params map[string][]string,
+	ecsParam input.EcsParameter,
+	reconnect input.ReconnectParameter,
+) forwardSupervisor {
+	attempts := reconnect.ReconnectAttempts
+	if attempts == 0 {
+		attempts = defaultReconnectAttempts
+	}
+	wait := time.Duration(reconnect.ReconnectWait) * time.Second
+	if wait == 0 {
+		wait = defaultReconnectWait
+	}
+	return forwardSupervisor{
+		ssm:      ssmClient,
+		resolver: resolver,
+		plugin:   plugin,
+		audit:    sessionAudit,
+		clock:    deps.clock,
+		stderr:   deps.stderr,
+		events:   deps.events,
+		region:   region,
+		doc:      doc,
+		params:   params,
+		ecsParam: ecsParam,
+		attempts: attempts,
+		wait:     wait,
+	}
+}
+
+func (s forwardSupervisor) run(ctx context.Context, resolved target.Resolved) error {
+	failures := 0
+	for {
+		startedAt := s.clock.Now()
+		err := s.runSession(ctx, resolved)
+		if ctx.Err() != nil {
+			return err
+		}
+		if s.clock.Now().Sub(startedAt) >= reconnectResetAfter {
+			failures = 0
+		}
+
+		for {
+			failures++
+			if failures > s.attempts {
+				return fmt.Errorf("port forward stopped after %d reconnect attempts: %w", s.attempts, err)
+			}
+			delay := reconnectBackoff(failures)
+			fmt.Fprintf(
+				s.stderr,
+				"Port forward to task %s ended: %v. Reconnecting in %s (attempt %d/%d).\n",
+				resolved.TaskID, err, delay, failures, s.attempts,
+			)
+			emit(s.events, s.stderr, event.Reconnecting, reconnectingEvent{
+				TaskID:      resolved.TaskID,
+				Reason:      err.Error(),
+				Delay:       delay.Seconds(),
+				Attempt:     failures,
+				MaxAttempts: s.attempts,
+			})
+			if sleepErr := s.clock.Sleep(ctx, delay); sleepErr != nil {
+				return errors.Join(err, fmt.Errorf("reconnect port forward: %w", sleepErr))
+			}
+
+			next, resolveErr := s.reselect(ctx, resolved)
+			if resolveErr == nil {
+				resolved = next
+				break
+			}
+			if ctx.Err() != nil {
+				return resolveErr
+			}
+			err = resolveErr
+		}
+		fmt.Fprintf(
+			s.stderr,
+			"Reconnecting port forward to task %s container %s on local port %s.\n",
+			resolved.TaskID, resolved.ContainerName, firstParameter(s.params, "localPortNumber"),
+		)
+	}
+}
+
+// reconnectingEvent says why the forward to a task ended and when the next
+// attempt follows. It is emitted before each wait between reconnects.
+type reconnectingEvent struct {
+	TaskID      string  `json:"task_id"`
+	Reason      string  `json:"reason"`
+	Delay       float64 `json:"delay_seconds"`
+	Attempt     int     `json:"attempt"`
+	MaxAttempts int     `json:"max_attempts"`
+}
+
+// runSession starts and runs one session. It returns errSessionEnded when the
+// plugin exits cleanly so the caller always has a reason to report.
+func (s forwardSupervisor) runSession(ctx context.Context, resolved target.Resolved) error {
+	remote, err := command.StartPortForwardSession(
+		ctx,
+		s.ssm,
+		command.PortTarget{SSMTarget: resolved.SSMTarget()},
+		s.region,
+		s.doc,
+		s.params,
+	)
+	if err != nil {
+		return err
+	}
+	err = s.audit.run(ctx, resolved, remote, forwardAuditEntry(s.doc, s.params), func() error {
+		return remote.Run(ctx, s.plugin)
+	})
+	if err != nil {
+		return err
+	}
+	return errSessionEnded
+}
+
+// reselect waits for an eligible task in the same cluster and service. It
+// keeps the previous task when it is still eligible and otherwise follows the
+// newest replacement running the same container. Outside a service, a
+// replacement runs the family of the previous task, or shares its group when
+// the family is unknown, so that an unrelated task with a container of the
+// same name is never picked up.
+func (s forwardSupervisor) reselect(ctx context.Context, previous target.Resolved) (target.Resolved, error) {
+	tasks, err := s.resolver.WaitForEligibleTasks(ctx, previous.ECSCluster, previous.Service, s.wait, s.clock)
+	if err != nil {
+		return target.Resolved{}, fmt.Errorf("resolve replacement ECS task: %w", err)
+	}
+
+	selector := target.Selector{Task: previous.TaskID, Container: previous.ContainerName}
+	task, err := selector.SelectTask(tasks)
+	if err != nil {
+		selector = target.Selector{
+			Container: previous.ContainerName,
+			Family:    s.ecsParam.Family,
+			Strategy:  s.ecsParam.Strategy,
+		}
+		if selector.Strategy == "" {
+			selector.Strategy = target.StrategyNewest
+		}
+		if previous.Service == "" && selector.Family == "" {
+			tasks = sameWorkload(previous.Task, tasks)
+		}
+		task, err = selector.SelectTask(tasks)
+		if err != nil {
+			return target.Resolved{}, fmt.Errorf("select replacement ECS task: %w", err)
+		}
+	}
+	container, err := selector.SelectContainer(target.EligibleContainers(task))
+	if err != nil {
+		return target.Resolved{}, fmt.Errorf("select replacement ECS container: %w", err)
+	}
+	next, err := target.NewResolved(previous.ECSCluster, task, container)
+	if err != nil {
+		return target.Resolved{}, err
+	}
+	next.Service = previous.Service
+	return next, nil
+}
+
+// sameWorkload returns the tasks running the task definition family of
+// previous, or in its group when its task definition is unknown. It returns
+// none when previous has neither.
+func sameWorkload(previous ecstypes.Task, tasks []ecstypes.Task) []ecstypes.Task {
+	same := func(task ecstypes.Task) bool { return false }
+	if family, err := target.TaskDefinitionFamily(aws.ToString(previous.TaskDefinitionArn)); err == nil {
+		same = func(task ecstypes.Task) bool {
+			candidate, err := target.TaskDefinitionFamily(aws.ToString(task.TaskDefinitionArn))
+			return err == nil && candidate == family
+		}
+	} else if group := aws.ToString(previous.Group); group != "" {
+		same = func(task ecstypes.Task) bool { return aws.ToString(task.Group) == group }
+	}
+	var matched []ecstypes.Task
+	for _, task := range tasks {
+		if same(task) {
+			matched = append(matched, task)
+		}
+	}
+	return matched
+}
+
+func reconnectBackoff(attempt int) time.Duration {
+	delay := reconnectInitialBackoff
+	for i := 1; i < attempt && delay < reconnectMaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, reconnectMaxBackoff)
+}
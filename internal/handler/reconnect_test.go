@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/wim-web/tnnl/internal/event"
+	"github.com/wim-web/tnnl/internal/input"
+	"github.com/wim-web/tnnl/internal/session_manager"
+	"github.com/wim-web/tnnl/internal/target"
+)
+
+const handlerThirdTaskARN = "arn:aws:ecs:ap-northeast-1:123456789012:task/production/task-third"
+
+type reconnectClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *reconnectClock) Now() time.Time {
+	return c.now
+}
+
+func (c *reconnectClock) Sleep(ctx context.Context, delay time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.sleeps = append(c.sleeps, delay)
+	c.now = c.now.Add(delay)
+	return nil
+}
+
+type recordingSSM struct {
+	handlerSSM
+	starts []*ssm.StartSessionInput
+}
+
+func (f *recordingSSM) StartSession(ctx context.Context, in *ssm.StartSessionInput, opts ...func(*ssm.Options)) (*ssm.StartSessionOutput, error) {
+	f.starts = append(f.starts, in)
+	return f.handlerSSM.StartSession(ctx, in, opts...)
+}
+
+func TestPortForwardHandlerReconnectsToReplacementTaskOnSameLocalPort(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var events []string
+	ecsClient := newHandlerECS(&events)
+	replacement := readyHandlerTask(handlerThirdTaskARN, "runtime-third")
+	replacement.StartedAt = aws.Time(time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC))
+	older := readyHandlerTask(handlerFirstTaskARN, "runtime-first")
+	older.StartedAt = aws.Time(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	ecsClient.refreshOutput = &ecs.DescribeTasksOutput{Tasks: []ecstypes.Task{older, replacement}}
+	ssmClient := &recordingSSM{handlerSSM: handlerSSM{events: &events, startOutput: validHandlerStartOutput()}}
+	plugin := &handlerPlugin{events: &events, run: func(pluginCtx context.Context, invocation session_manager.Invocation) error {
+		if strings.Contains(invocation.Target, "task-third") {
+			cancel()
+			<-pluginCtx.Done()
+			return pluginCtx.Err()
+		}
+		return nil
+	}}
+	clock := &reconnectClock{now: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}
+	var stderr bytes.Buffer
+	deps := handlerDependencies(t, &events, ecsClient, &ssmClient.handlerSSM, plugin)
+	deps.newSSM = func(aws.Config) ssmAPI { return ssmClient }
+	deps.clock = clock
+	deps.stderr = &stderr
+	deps.availablePort = func() (int, error) { return 49152, nil }
+	var reported strings.Builder
+	deps.events = event.NewEmitter(&reported, clock.Now)
+	in := validPortHandlerInput("")
+	in.Reconnect = true
+
+	err := portForwardHandler(ctx, in, deps)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("portForwardHandler() error = %v, want context.Canceled", err)
+	}
+	decoded := decodeEvents(t, reported.String())
+	wantEvents := []string{"target_resolved", "session_started", "session_ended", "reconnecting", "target_resolved", "session_started", "session_ended"}
+	if got := eventNames(decoded); !reflect.DeepEqual(got, wantEvents) {
+		t.Fatalf("events = %v, want %v", got, wantEvents)
+	}
+	if reconnecting := decoded[3]; reconnecting["task_id"] != "task-second" || reconnecting["attempt"] != 1.0 ||
+		reconnecting["max_attempts"] != 5.0 || reconnecting["delay_seconds"] != 1.0 || reconnecting["reason"] != "session ended" {
+		t.Fatalf("reconnecting = %v", reconnecting)
+	}
+	if ended := decoded[6]["error"].(map[string]any); ended["code"] != "error" || decoded[5]["local_port"] != "49152" {
+		t.Fatalf("reconnected session = %v, %v", decoded[5], decoded[6])
+	}
+	if len(ssmClient.starts) != 2 {
+		t.Fatalf("StartSession calls = %d, want initial and reconnected session", len(ssmClient.starts))
+	}
+	targets := []string{aws.ToString(ssmClient.starts[0].Target), aws.ToString(ssmClient.starts[1].Target)}
+	wantTargets := []string{"ecs:production_task-second_runtime-second", "ecs:production_task-third_runtime-third"}
+	if !reflect.DeepEqual(targets, wantTargets) {
+		t.Fatalf("StartSession targets = %v, want %v", targets, wantTargets)
+	}
+	for i, start := range ssmClient.starts {
+		if got := start.Parameters["localPortNumber"]; !reflect.DeepEqual(got, []string{"49152"}) {
+			t.Fatalf("StartSession[%d] localPortNumber = %v, want the same allocated port", i, got)
+		}
+	}
+	if !reflect.DeepEqual(clock.sleeps, []time.Duration{time.Second}) {
+		t.Fatalf("backoff sleeps = %v, want [1s]", clock.sleeps)
+	}
+	if got := aws.ToString(ecsClient.listTasksInput.ServiceName); got != "service-web" {
+		t.Fatalf("replacement lookup service = %q, want service-web", got)
+	}
+	for _, want := range []string{"task-second ended: session ended", "attempt 1/5", "task task-third container app on local port 49152"} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("stderr = %q, want it to contain %q", stderr.String(), want)
+		}
+	}
+}
+
+func TestPortForwardHandlerReconnectStopsAfterAttemptCap(t *testing.T) {
+	var events []string
+	ecsClient := newHandlerECS(&events)
+	ecsClient.refreshOutput = ecsClient.resolveOutput
+	pluginErr := errors.New("plugin sentinel")
+	plugin := &handlerPlugin{events: &events, run: func(context.Context, session_manager.Invocation) error {
+		return pluginErr
+	}}
+	ssmClient := &recordingSSM{handlerSSM: handlerSSM{events: &events, startOutput: validHandlerStartOutput()}}
+	clock := &reconnectClock{}
+	deps := handlerDependencies(t, &events, ecsClient, &ssmClient.handlerSSM, plugin)
+	deps.newSSM = func(aws.Config) ssmAPI { return ssmClient }
+	deps.clock = clock
+	deps.stderr = &bytes.Buffer{}
+	in := validPortHandlerInput("6000")
+	in.ReconnectParameter = input.ReconnectParameter{Reconnect: true, ReconnectAttempts: 3}
+
+	err := portForwardHandler(context.Background(), in, deps)
+	if !errors.Is(err, pluginErr) || !strings.Contains(err.Error(), "stopped after 3 reconnect attempts") {
+		t.Fatalf("portForwardHandler() error = %v, want attempt cap wrapping plugin error", err)
+	}
+	if len(ssmClient.starts) != 4 {
+		t.Fatalf("StartSession calls = %d, want initial plus 3 reconnects", len(ssmClient.starts))
+	}
+	if want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}; !reflect.DeepEqual(clock.sleeps, want) {
+		t.Fatalf("backoff sleeps = %v, want %v", clock.sleeps, want)
+	}
+	if got := aws.ToString(ssmClient.starts[3].Target); got != "ecs:production_task-second_runtime-second" {
+		t.Fatalf("reconnect target = %q, want the still-eligible previous task", got)
+	}
+}
+
+func TestReconnectBackoffIsCapped(t *testing.T) {
+	tests := map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 5: 16 * time.Second, 6: 30 * time.Second, 40: 30 * time.Second}
+	for attempt, want := range tests {
+		if got := reconnectBackoff(attempt); got != want {
+			t.Errorf("reconnectBackoff(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+}
+
+func TestReselectOutsideServiceKeepsPreviousTaskFamily(t *testing.T) {
+	var events []string
+	ecsClient := newHandlerECS(&events)
+	// Both tasks run a container named app; only the older one runs the
+	// family of the task that ended.
+	replacement := readyHandlerTask(handlerFirstTaskARN, "runtime-first")
+	replacement.Group = aws.String("family:web")
+	replacement.TaskDefinitionArn = aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/web:8")
+	replacement.StartedAt = aws.Time(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	unrelated := readyHandlerTask(handlerSecondTaskARN, "runtime-second")
+	unrelated.Group = aws.String("family:batch")
+	unrelated.TaskDefinitionArn = aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/batch:3")
+	unrelated.StartedAt = aws.Time(time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC))
+	ecsClient.resolveOutput = &ecs.DescribeTasksOutput{Tasks: []ecstypes.Task{replacement, unrelated}}
+	ended := readyHandlerTask(handlerThirdTaskARN, "runtime-third")
+	ended.Group = aws.String("family:web")
+	ended.TaskDefinitionArn = aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/web:7")
+	previous, err := target.NewResolved(handlerClusterARN, ended, ended.Containers[0])
+	if err != nil {
+		t.Fatalf("NewResolved() error = %v", err)
+	}
+	supervisor := forwardSupervisor{resolver: target.NewResolver(ecsClient), clock: &reconnectClock{}}
+
+	next, err := supervisor.reselect(context.Background(), previous)
+	if err != nil {
+		t.Fatalf("reselect() error = %v", err)
+	}
+	if next.TaskARN != handlerFirstTaskARN {
+		t.Fatalf("reselect() task = %s, want the replacement in family web", next.TaskARN)
+	}
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/wim-web/tnnl/internal/cast"
+	"github.com/wim-web/tnnl/internal/session_manager"
+)
+
+// recording is a cast file an interactive session is being recorded to.
+type recording struct {
+	file     *os.File
+	recorder *cast.Recorder
+}
+
+// startRecording creates the cast file at path, refusing to overwrite one,
+// and returns plugin set up to record to it. The file is private to the user
+// since a session may show secrets.
+func startRecording(
+	plugin session_manager.Plugin,
+	path string,
+	header cast.Header,
+	now func() time.Time,
+) (session_manager.Plugin, *recording, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create session recording: %w", err)
+	}
+	r := &recording{file: file, recorder: cast.NewRecorder(file, header, now)}
+	recording, ok := session_manager.WithRecorder(plugin, r.recorder)
+	if !ok {
+		return nil, nil, errors.Join(
+			fmt.Errorf("record a session: the session client cannot record; use --session-client %s", session_manager.ClientNative),
+			r.discard(),
+		)
+	}
+	return recording, r, nil
+}
+
+// finish completes the cast file.
+func (r *recording) finish() error {
+	if err := errors.Join(r.recorder.Close(), r.file.Close()); err != nil {
+		return fmt.Errorf("write session recording %s: %w", r.file.Name(), err)
+	}
+	return nil
+}
+
+// discard removes the cast file of a session that never started.
+func (r *recording) discard() error {
+	return errors.Join(r.file.Close(), os.Remove(r.file.Name()))
+}
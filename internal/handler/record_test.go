@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wim-web/tnnl/internal/session_manager"
+)
+
+// recordingPlugin is a session client that shows output to its recorder.
+type recordingPlugin struct {
+	handlerPlugin
+	recorder session_manager.Recorder
+}
+
+func (p *recordingPlugin) WithRecorder(recorder session_manager.Recorder) session_manager.Plugin {
+	configured := *p
+	configured.recorder = recorder
+	configured.run = func(context.Context, session_manager.Invocation) error {
+		recorder.Resize(100, 30)
+		recorder.Output([]byte("$ "))
+		recorder.Input([]byte("exit\r"))
+		return nil
+	}
+	return &configured
+}
+
+func TestExecHandlerRecordsSessionWithNativeClient(t *testing.T) {
+	var events []string
+	ecsClient := newHandlerECS(&events)
+	deps := handlerDependencies(t, &events, ecsClient, &handlerSSM{events: &events}, &handlerPlugin{events: &events})
+	var gotOptions session_manager.Options
+	deps.preflight = func(_ context.Context, options session_manager.Options) (session_manager.Plugin, error) {
+		gotOptions = options
+		return &recordingPlugin{handlerPlugin: handlerPlugin{events: &events}}, nil
+	}
+	deps.clock = &reconnectClock{now: time.Unix(1700000000, 0)}
+	var stderr strings.Builder
+	deps.stderr = &stderr
+	in := validExecHandlerInput()
+	in.Record = filepath.Join(t.TempDir(), "session.cast")
+
+	if err := execHandler(context.Background(), in, deps); err != nil {
+		t.Fatalf("execHandler() error = %v", err)
+	}
+	if gotOptions.Client != session_manager.ClientNative {
+		t.Fatalf("session client = %q, want %q for a recording", gotOptions.Client, session_manager.ClientNative)
+	}
+	recorded, err := os.ReadFile(in.Record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"version":2,"width":100,"height":30,"timestamp":1700000000,"command":"/bin/sh","title":"production task-second/app","env":{"TERM":"` + os.Getenv("TERM") + `"}}
+[0.000000,"o","$ "]
+[0.000000,"i","exit\r"]
+`
+	if string(recorded) != want {
+		t.Fatalf("cast =\n%s\nwant\n%s", recorded, want)
+	}
+	if info, err := os.Stat(in.Record); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("cast file mode = %v, %v, want 0600", info.Mode(), err)
+	}
+	if !strings.Contains(stderr.String(), "tnnl replay "+in.Record) {
+		t.Fatalf("stderr = %q, want the replay command", stderr.String())
+	}
+}
+
+func TestExecHandlerRecordingFailuresStartNoSession(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing bool
+		wantErr  string
+	}{
+		{name: "existing file", existing: true, wantErr: "create session recording"},
+		{name: "client cannot record", wantErr: "the session client cannot record"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []string
+			ecsClient := newHandlerECS(&events)
+			deps := handlerDependencies(t, &events, ecsClient, &handlerSSM{events: &events}, &handlerPlugin{events: &events})
+			deps.clock = &reconnectClock{}
+			in := validExecHandlerInput()
+			in.Record = filepath.Join(t.TempDir(), "session.cast")
+			if tt.existing {
+				if err := os.WriteFile(in.Record, []byte("keep"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := execHandler(context.Background(), in, deps)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("execHandler() error = %v, want %q", err, tt.wantErr)
+			}
+			if ecsClient.executeCalls != 0 {
+				t.Fatalf("ExecuteCommand calls = %d, want 0", ecsClient.executeCalls)
+			}
+			recorded, err := os.ReadFile(in.Record)
+			if tt.existing && string(recorded) != "keep" {
+				t.Fatalf("existing file = %q, %v, want it untouched", recorded, err)
+			}
+			if !tt.existing && !errors.Is(err, os.ErrNotExist) {
+				t.Fatalf("cast file stat error = %v, want it removed", err)
+			}
+		})
+	}
+}
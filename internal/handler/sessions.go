@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/wim-web/tnnl/internal/event"
+	"github.com/wim-web/tnnl/internal/input"
+	"github.com/wim-web/tnnl/internal/target"
+	"github.com/wim-web/tnnl/pkg/command"
+)
+
+func SessionsListHandler(ctx context.Context, in input.SessionsInput) error {
+	return sessionsListHandler(ctx, in, productionDependencies(ctx))
+}
+
+func SessionsTerminateHandler(ctx context.Context, in input.SessionsInput) error {
+	return sessionsTerminateHandler(ctx, in, productionDependencies(ctx))
+}
+
+// sessionsListHandler prints the caller's active Session Manager sessions on
+// ECS tasks, including those a terminal that died left behind.
+func sessionsListHandler(ctx context.Context, in input.SessionsInput, deps dependencies) error {
+	cfg, err := deps.loadConfig(ctx, in.ConnectionParameter)
+	if err != nil {
+		return fmt.Errorf("load AWS configuration: %w", err)
+	}
+	caller, sessions, err := callerSessions(ctx, deps, cfg, deps.newSSM(cfg))
+	if err != nil {
+		return err
+	}
+	if deps.events.Enabled() {
+		return emitSessions(deps.events, sessions)
+	}
+	if len(sessions) == 0 {
+		_, err := fmt.Fprintf(deps.stdout, "no active ECS sessions for %s\n", caller)
+		return err
+	}
+	return writeSessions(deps.stdout, sessions)
+}
+
+// sessionsTerminateHandler terminates the sessions named in the input, or
+// every active ECS session of the caller. Each failure is reported; the
+// remaining sessions are still terminated.
+func sessionsTerminateHandler(ctx context.Context, in input.SessionsInput, deps dependencies) error {
+	cfg, err := deps.loadConfig(ctx, in.ConnectionParameter)
+	if err != nil {
+		return fmt.Errorf("load AWS configuration: %w", err)
+	}
+	ssmClient := deps.newSSM(cfg)
+	ids := in.SessionIDs
+	if in.AllMine {
+		caller, sessions, err := callerSessions(ctx, deps, cfg, ssmClient)
+		if err != nil {
+			return err
+		}
+		if len(sessions) == 0 && !deps.events.Enabled() {
+			_, err := fmt.Fprintf(deps.stdout, "no active ECS sessions for %s\n", caller)
+			return err
+		}
+		ids = nil
+		for _, session := range sessions {
+			ids = append(ids, aws.ToString(session.SessionId))
+		}
+	}
+
+	var errs []error
+	for _, id := range ids {
+		if err := command.TerminateSession(ctx, ssmClient, id); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if deps.events.Enabled() {
+			err = deps.events.Emit(event.SessionTerminated, struct {
+				SessionID string `json:"session_id"`
+			}{SessionID: id})
+		} else {
+			_, err = fmt.Fprintf(deps.stdout, "terminated %s\n", id)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// callerSessions returns the ARN of the caller and its active ECS sessions.
+func callerSessions(ctx context.Context, deps dependencies, cfg aws.Config, ssmClient ssmAPI) (string, []ssmtypes.Session, error) {
+	identity, err := deps.newSTS(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", nil, fmt.Errorf("look up the caller identity: %w", err)
+	}
+	caller := aws.ToString(identity.Arn)
+	sessions, err := command.ListSessions(ctx, ssmClient, caller)
+	if err != nil {
+		return "", nil, err
+	}
+	return caller, sessions, nil
+}
+
+// sessionEvent carries an active session of the caller with the task and
+// container it reaches, as tnnl sessions lists them.
+type sessionEvent struct {
+	SessionID string     `json:"session_id"`
+	Started   *time.Time `json:"started,omitempty"`
+	Cluster   string     `json:"cluster"`
+	TaskID    string     `json:"task_id"`
+	RuntimeID string     `json:"runtime_id"`
+	SSMTarget string     `json:"ssm_target"`
+	Document  string     `json:"document"`
+}
+
+func emitSessions(events *event.Emitter, sessions []ssmtypes.Session) error {
+	for _, session := range sessions {
+		sessionTarget, _ := target.ParseSSMTarget(aws.ToString(session.Target))
+		err := events.Emit(event.Session, sessionEvent{
+			SessionID: aws.ToString(session.SessionId),
+			Started:   session.StartDate,
+			Cluster:   sessionTarget.ClusterName,
+			TaskID:    sessionTarget.TaskID,
+			RuntimeID: sessionTarget.RuntimeID,
+			SSMTarget: aws.ToString(session.Target),
+			Document:  aws.ToString(session.DocumentName),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeSessions(w io.Writer, sessions []ssmtypes.Session) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "SESSION ID\tSTARTED\tCLUSTER\tTASK\tRUNTIME ID\tDOCUMENT")
+	for _, session := range sessions {
+		// A target that does not parse is shown whole in place of the cluster.
+		sessionTarget, err := target.ParseSSMTarget(aws.ToString(session.Target))
+		if err != nil {
+			sessionTarget = target.SessionTarget{ClusterName: aws.ToString(session.Target)}
+		}
+		var started string
+		if session.StartDate != nil {
+			started = session.StartDate.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(
+			table,
+			"%s\t%s\t%s\t%s\t%s\t%s\n",
+			aws.ToString(session.SessionId),
+			started,
+			sessionTarget.ClusterName,
+			sessionTarget.TaskID,
+			sessionTarget.RuntimeID,
+			aws.ToString(session.DocumentName),
+		)
+	}
+	return table.Flush()
+}
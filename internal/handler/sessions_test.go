@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/wim-web/tnnl/internal/event"
+	"github.com/wim-web/tnnl/internal/input"
+)
+
+func sessionsDependencies(t *testing.T, events *[]string, ssmClient *handlerSSM, stdout *bytes.Buffer) dependencies {
+	t.Helper()
+	deps := handlerDependencies(t, events, newHandlerECS(events), ssmClient, &handlerPlugin{events: events})
+	deps.newSTS = func(aws.Config) stsAPI { return &handlerSTS{} }
+	deps.stdout = stdout
+	return deps
+}
+
+func activeSessions() []ssmtypes.Session {
+	return []ssmtypes.Session{
+		{
+			SessionId:    aws.String("alice-1"),
+			Target:       aws.String("ecs:web_prod_0123456789abcdef0_0123456789abcdef0-1111111111"),
+			DocumentName: aws.String(execDocument),
+			StartDate:    aws.Time(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)),
+		},
+		{
+			SessionId:    aws.String("alice-2"),
+			Target:       aws.String("ecs:staging_fedcba9876543210f_runtime"),
+			DocumentName: aws.String("AWS-StartPortForwardingSession"),
+		},
+	}
+}
+
+func TestSessionsListHandlerPrintsCallerSessions(t *testing.T) {
+	var events []string
+	ssmClient := &handlerSSM{events: &events, sessions: activeSessions()}
+	var stdout bytes.Buffer
+
+	err := sessionsListHandler(context.Background(), input.SessionsInput{}, sessionsDependencies(t, &events, ssmClient, &stdout))
+	if err != nil {
+		t.Fatalf("sessionsListHandler() error = %v", err)
+	}
+	if owner := aws.ToString(ssmClient.describeInputs[0].Filters[0].Value); owner != handlerCallerARN {
+		t.Fatalf("sessions filtered to %q, want %q", owner, handlerCallerARN)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "SESSION ID") {
+		t.Fatalf("stdout = %q, want a header and two sessions", stdout.String())
+	}
+	for i, want := range [][]string{
+		{"alice-1", "web_prod", "0123456789abcdef0", "0123456789abcdef0-1111111111", execDocument},
+		{"alice-2", "staging", "fedcba9876543210f", "runtime", "AWS-StartPortForwardingSession"},
+	} {
+		fields := strings.Fields(lines[i+1])
+		if got := append(fields[:1:1], fields[len(fields)-4:]...); !reflect.DeepEqual(got, want) {
+			t.Errorf("session row = %q, want %v", lines[i+1], want)
+		}
+	}
+}
+
+func TestSessionsListHandlerReportsNoSessions(t *testing.T) {
+	var events []string
+	var stdout bytes.Buffer
+	deps := sessionsDependencies(t, &events, &handlerSSM{events: &events}, &stdout)
+
+	if err := sessionsListHandler(context.Background(), input.SessionsInput{}, deps); err != nil {
+		t.Fatalf("sessionsListHandler() error = %v", err)
+	}
+	if want := "no active ECS sessions for " + handlerCallerARN + "\n"; stdout.String() != want {
+		t.Fatalf("stdout = %q, want %q", stdout.String(), want)
+	}
+}
+
+func TestSessionsTerminateHandlerTerminatesAllMine(t *testing.T) {
+	var events []string
+	ssmClient := &handlerSSM{events: &events, sessions: activeSessions()}
+	var stdout bytes.Buffer
+
+	err := sessionsTerminateHandler(context.Background(), input.SessionsInput{AllMine: true}, sessionsDependencies(t, &events, ssmClient, &stdout))
+	if err != nil {
+		t.Fatalf("sessionsTerminateHandler() error = %v", err)
+	}
+	if want := []string{"alice-1", "alice-2"}; !reflect.DeepEqual(ssmClient.terminated, want) {
+		t.Fatalf("terminated = %v, want %v", ssmClient.terminated, want)
+	}
+	if want := "terminated alice-1\nterminated alice-2\n"; stdout.String() != want {
+		t.Fatalf("stdout = %q, want %q", stdout.String(), want)
+	}
+}
+
+func TestSessionsTerminateHandlerContinuesAfterFailure(t *testing.T) {
+	var events []string
+	ssmClient := &handlerSSM{events: &events, terminateErr: errors.New("access denied")}
+	var stdout bytes.Buffer
+	deps := sessionsDependencies(t, &events, ssmClient, &stdout)
+
+	err := sessionsTerminateHandler(context.Background(), input.SessionsInput{SessionIDs: []string{"bob-1", "bob-2"}}, deps)
+	if err == nil || strings.Count(err.Error(), "access denied") != 2 {
+		t.Fatalf("sessionsTerminateHandler() error = %v, want both failures", err)
+	}
+	if want := []string{"bob-1", "bob-2"}; !reflect.DeepEqual(ssmClient.terminated, want) {
+		t.Fatalf("terminated = %v, want %v", ssmClient.terminated, want)
+	}
+	if len(ssmClient.describeInputs) != 0 {
+		t.Fatalf("DescribeSessions called %d times, want none", len(ssmClient.describeInputs))
+	}
+}
+
+func TestSessionsHandlersReportEvents(t *testing.T) {
+	var events []string
+	ssmClient := &handlerSSM{events: &events, sessions: activeSessions()}
+	var stdout bytes.Buffer
+	var reported strings.Builder
+	deps := sessionsDependencies(t, &events, ssmClient, &stdout)
+	deps.events = event.NewEmitter(&reported, time.Now)
+
+	if err := sessionsListHandler(context.Background(), input.SessionsInput{}, deps); err != nil {
+		t.Fatalf("sessionsListHandler() error = %v", err)
+	}
+	if err := sessionsTerminateHandler(context.Background(), input.SessionsInput{SessionIDs: []string{"alice-2"}}, deps); err != nil {
+		t.Fatalf("sessionsTerminateHandler() error = %v", err)
+	}
+	if stdout.Len() != 0 {
+		t.Fatalf("stdout = %q, want only events", stdout.String())
+	}
+	decoded := decodeEvents(t, reported.String())
+	if got, want := eventNames(decoded), []string{"session", "session", "session_terminated"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	first := decoded[0]
+	if first["session_id"] != "alice-1" || first["cluster"] != "web_prod" || first["task_id"] != "0123456789abcdef0" ||
+		first["runtime_id"] != "0123456789abcdef0-1111111111" || first["started"] != "2026-01-02T03:04:05Z" {
+		t.Fatalf("session event = %v", first)
+	}
+	if _, ok := decoded[1]["started"]; ok {
+		t.Fatalf("session event = %v, want no start time", decoded[1])
+	}
+	if decoded[2]["session_id"] != "alice-2" {
+		t.Fatalf("session_terminated = %v", decoded[2])
+	}
+}
@@ -1,7 +1,9 @@
 package input
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -54,20 +56,66 @@ func ReadInputFile(v any, path string) error {
 	}
 	defer file.Close()
 
-	decoder := json.NewDecoder(file)
+	if err := decodeInput(v, file); err != nil {
+		return fmt.Errorf("decode input file %q: %w", path, err)
+	}
+	return nil
+}
+
+// Source fills an input value before explicit overrides are applied.
+type Source func(v any) error
+
+// FileSource reads input JSON from path. An empty path yields a nil Source,
+// which leaves the defaults in place.
+func FileSource(path string) Source {
+	if path == "" {
+		return nil
+	}
+	return func(v any) error { return ReadInputFile(v, path) }
+}
+
+// SavedSource decodes the input JSON stored for the saved target name, with
+// the same strictness as an input file. Non-empty AWS settings in connection
+// replace the decoded ones.
+func SavedSource(name string, data []byte, connection ConnectionParameter) Source {
+	return func(v any) error {
+		if len(bytes.TrimSpace(data)) > 0 {
+			if err := decodeInput(v, bytes.NewReader(data)); err != nil {
+				return fmt.Errorf("decode saved target %q input: %w", name, err)
+			}
+		}
+		holder, ok := v.(interface{ connectionParameter() *ConnectionParameter })
+		if !ok {
+			return nil
+		}
+		decoded := holder.connectionParameter()
+		if connection.Profile != "" {
+			decoded.Profile = connection.Profile
+		}
+		if connection.Region != "" {
+			decoded.Region = connection.Region
+		}
+		if connection.EndpointURL != "" {
+			decoded.EndpointURL = connection.EndpointURL
+		}
+		return nil
+	}
+}
+
+func decodeInput(v any, r io.Reader) error {
+	decoder := json.NewDecoder(r)
 	decoder.DisallowUnknownFields()
 	if err := decoder.Decode(v); err != nil {
-		return fmt.Errorf("decode input file %q: %w", path, err)
+		return err
 	}
 
 	var extra any
-	err = decoder.Decode(&extra)
+	err := decoder.Decode(&extra)
 	if err == nil {
-		return fmt.Errorf("decode input file %q: expected exactly one JSON document", path)
+		return errors.New("expected exactly one JSON document")
 	}
 	if err != io.EOF {
-		return fmt.Errorf("decode input file %q: %w", path, err)
+		return err
 	}
-
 	return nil
 }
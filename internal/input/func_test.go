@@ -119,3 +119,23 @@ func writeFixture(t *testing.T, path string, content []byte) {
 		t.Fatalf("write fixture: %v", err)
 	}
 }
+
+func TestSavedSourceDecodesStrictly(t *testing.T) {
+	var got ExecInput
+	if err := SavedSource("web", []byte(`{"cluster":"cluster","command":"bash","region":"us-east-1"}`), ConnectionParameter{Profile: "prod"})(&got); err != nil {
+		t.Fatalf("SavedSource() error = %v", err)
+	}
+	if got.Cluster != "cluster" || got.Cmd != "bash" || got.Profile != "prod" || got.Region != "us-east-1" {
+		t.Fatalf("SavedSource() decoded %#v", got)
+	}
+
+	err := SavedSource("web", []byte(`{"commnad":"bash"}`), ConnectionParameter{})(&got)
+	if err == nil || !strings.Contains(err.Error(), `saved target "web"`) || !strings.Contains(err.Error(), `unknown field "commnad"`) {
+		t.Fatalf("SavedSource() error = %v, want strict decode error naming the target", err)
+	}
+
+	defaults := ExecInput{Cmd: "sh"}
+	if err := SavedSource("web", nil, ConnectionParameter{})(&defaults); err != nil || defaults.Cmd != "sh" {
+		t.Fatalf("SavedSource(empty) = %#v, %v; want defaults kept", defaults, err)
+	}
+}
@@ -1,41 +1,260 @@
 package input
 
+import "strings"
+
 type EcsParameter struct {
-	Cluster string `json:"cluster"`
-	Service string `json:"service"`
+	Cluster        string `json:"cluster"`
+	Service        string `json:"service"`
+	Task           string `json:"task"`
+	Container      string `json:"container"`
+	Family         string `json:"family"`
+	Strategy       string `json:"strategy"`
+	ShowIneligible bool   `json:"show_ineligible"`
+}
+
+type EcsOverrides struct {
+	Cluster        *string
+	Service        *string
+	Task           *string
+	Container      *string
+	Family         *string
+	Strategy       *string
+	ShowIneligible *bool
+}
+
+// ConnectionParameter selects the session client and the AWS account and
+// Region a command talks to. Empty values fall back to the AWS SDK default
+// configuration chain. A RoleARN is assumed on top of those credentials.
+// DiscoverRegions and DiscoverProfiles are comma-separated lists that widen
+// the cluster picker to every combination of them.
+type ConnectionParameter struct {
+	SessionClient    string `json:"session_client"`
+	Profile          string `json:"profile"`
+	Region           string `json:"region"`
+	EndpointURL      string `json:"endpoint_url"`
+	RoleARN          string `json:"role_arn"`
+	ExternalID       string `json:"external_id"`
+	RoleSessionName  string `json:"role_session_name"`
+	MFASerial        string `json:"mfa_serial"`
+	DiscoverRegions  string `json:"discover_regions"`
+	DiscoverProfiles string `json:"discover_profiles"`
+}
+
+type ConnectionOverrides struct {
+	SessionClient    *string
+	Profile          *string
+	Region           *string
+	EndpointURL      *string
+	RoleARN          *string
+	ExternalID       *string
+	RoleSessionName  *string
+	MFASerial        *string
+	DiscoverRegions  *string
+	DiscoverProfiles *string
+}
+
+// connectionParameter lets sources reach the ConnectionParameter embedded in
+// any command input.
+func (c *ConnectionParameter) connectionParameter() *ConnectionParameter {
+	return c
+}
+
+// Discovers reports whether clusters are discovered across regions or
+// profiles instead of listed in the configured one.
+func (c ConnectionParameter) Discovers() bool {
+	return c.DiscoverRegions != "" || c.DiscoverProfiles != ""
+}
+
+// DiscoveryRegions returns the entries of DiscoverRegions.
+func (c ConnectionParameter) DiscoveryRegions() []string {
+	return splitList(c.DiscoverRegions)
+}
+
+// DiscoveryProfiles returns the entries of DiscoverProfiles.
+func (c ConnectionParameter) DiscoveryProfiles() []string {
+	return splitList(c.DiscoverProfiles)
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+type ReconnectParameter struct {
+	Reconnect         bool `json:"reconnect"`
+	ReconnectAttempts int  `json:"reconnect_attempts"`
+	ReconnectWait     int  `json:"reconnect_wait"`
+}
+
+type ReconnectOverrides struct {
+	Reconnect *bool
+	Attempts  *int
+	Wait      *int
 }
 
 type ExecInput struct {
 	EcsParameter
+	ConnectionParameter
 	Cmd  string `json:"command"`
 	Wait int    `json:"wait"`
+	// Batch runs Cmd without a terminal and exits with its status. Timeout, in
+	// seconds, bounds a batch run; zero means no limit.
+	Batch   bool `json:"batch"`
+	Timeout int  `json:"timeout"`
+	// All runs Cmd in batch mode on every matching eligible task, at most
+	// Parallel at a time; zero uses the default.
+	All      bool `json:"all"`
+	Parallel int  `json:"parallel"`
+	// Record is the path of an asciinema cast file the interactive session
+	// is recorded to.
+	Record string `json:"record"`
+	// DryRun prints the resolved target and session instead of starting it.
+	// It is a flag only and never read from input JSON.
+	DryRun bool `json:"-"`
 }
 
 type ExecOverrides struct {
-	Command *string
-	Wait    *int
+	Ecs        EcsOverrides
+	Connection ConnectionOverrides
+	Command    *string
+	Wait       *int
+	Batch      *bool
+	Timeout    *int
+	All        *bool
+	Parallel   *int
+	Record     *string
+	DryRun     *bool
+}
+
+// CheckInput selects the task tnnl check inspects.
+type CheckInput struct {
+	EcsParameter
+	ConnectionParameter
+}
+
+type CheckOverrides struct {
+	Ecs        EcsOverrides
+	Connection ConnectionOverrides
+}
+
+// CopyInput describes a tnnl cp transfer. Exactly one of Source and
+// Destination is a container path, written with a leading colon.
+type CopyInput struct {
+	EcsParameter
+	ConnectionParameter
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+}
+
+type CopyOverrides struct {
+	Ecs         EcsOverrides
+	Connection  ConnectionOverrides
+	Source      *string
+	Destination *string
+}
+
+// ContainerPath returns value without its leading colon when it names a path
+// in the container.
+func ContainerPath(value string) (string, bool) {
+	return strings.CutPrefix(value, ":")
+}
+
+// Upload reports whether the copy goes from the local machine to the
+// container.
+func (v CopyInput) Upload() bool {
+	_, remote := ContainerPath(v.Destination)
+	return remote
+}
+
+// SessionsInput selects the account and Region whose Session Manager sessions
+// tnnl sessions lists or terminates. SessionIDs and AllMine pick the sessions
+// to terminate.
+type SessionsInput struct {
+	ConnectionParameter
+	SessionIDs []string
+	AllMine    bool
+}
+
+type SessionsOverrides struct {
+	Connection ConnectionOverrides
+	SessionIDs []string
+	AllMine    *bool
+}
+
+// ListInput selects what tnnl ls lists. An empty Cluster lists every cluster
+// of the account and Region, or of every discovery location; Service and Task
+// narrow the tasks and containers of Cluster.
+type ListInput struct {
+	ConnectionParameter
+	Cluster string
+	Service string
+	Task    string
+}
+
+type ListOverrides struct {
+	Connection ConnectionOverrides
+	Cluster    *string
+	Service    *string
+	Task       *string
 }
 
 type PortForwardInput struct {
 	EcsParameter
+	ConnectionParameter
+	ReconnectParameter
 	TargetPortNumber string `json:"target_port_number"`
 	LocalPortNumber  string `json:"local_port_number"`
+	// DryRun is ExecInput.DryRun for the forward.
+	DryRun bool `json:"-"`
 }
 
 type PortForwardOverrides struct {
+	Ecs        EcsOverrides
+	Connection ConnectionOverrides
+	Reconnect  ReconnectOverrides
 	TargetPort *string
 	LocalPort  *string
+	DryRun     *bool
 }
 
 type RemotePortForwardInput struct {
 	EcsParameter
+	ConnectionParameter
+	ReconnectParameter
 	RemotePortNumber string `json:"remote_port_number"`
 	LocalPortNumber  string `json:"local_port_number"`
 	Host             string `json:"host"`
+	// DryRun is ExecInput.DryRun for the forward.
+	DryRun bool `json:"-"`
 }
 
 type RemotePortForwardOverrides struct {
+	Ecs        EcsOverrides
+	Connection ConnectionOverrides
+	Reconnect  ReconnectOverrides
 	RemotePort *string
 	LocalPort  *string
 	Host       *string
+	DryRun     *bool
+}
+
+// ForwardParameter is one forward in a MultiPortForwardInput. A non-empty Host
+// forwards through the container to that remote host.
+type ForwardParameter struct {
+	Name string `json:"name"`
+	EcsParameter
+	TargetPortNumber string `json:"target_port_number"`
+	LocalPortNumber  string `json:"local_port_number"`
+	Host             string `json:"host"`
+}
+
+type MultiPortForwardInput struct {
+	ConnectionParameter
+	Forwards []ForwardParameter `json:"forwards"`
+}
+
+type MultiPortForwardOverrides struct {
+	Connection ConnectionOverrides
 }
@@ -1,81 +1,361 @@
 package input
 
-import "strings"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 func ResolveExec(path string, overrides ExecOverrides) (ExecInput, error) {
+	return ResolveExecFrom(FileSource(path), overrides)
+}
+
+// ResolveExecFrom is ResolveExec with input read from source.
+func ResolveExecFrom(source Source, overrides ExecOverrides) (ExecInput, error) {
 	resolved := ExecInput{Cmd: "sh", Wait: 0}
-	if path != "" {
-		if err := ReadInputFile(&resolved, path); err != nil {
+	if source != nil {
+		if err := source(&resolved); err != nil {
 			return ExecInput{}, err
 		}
 	}
+	applyECS(&resolved.EcsParameter, overrides.Ecs)
+	applyConnection(&resolved.ConnectionParameter, overrides.Connection)
 	if overrides.Command != nil {
 		resolved.Cmd = *overrides.Command
 	}
 	if overrides.Wait != nil {
 		resolved.Wait = *overrides.Wait
 	}
+	if overrides.Batch != nil {
+		resolved.Batch = *overrides.Batch
+	}
+	if overrides.Timeout != nil {
+		resolved.Timeout = *overrides.Timeout
+	}
+	if overrides.All != nil {
+		resolved.All = *overrides.All
+	}
+	if overrides.Parallel != nil {
+		resolved.Parallel = *overrides.Parallel
+	}
+	if overrides.Record != nil {
+		resolved.Record = *overrides.Record
+	}
+	if overrides.DryRun != nil {
+		resolved.DryRun = *overrides.DryRun
+	}
 	normalizeExec(&resolved)
 	if err := ValidateExec(resolved); err != nil {
-		return ExecInput{}, err
+		return ExecInput{}, &InvalidError{Err: err}
+	}
+	return resolved, nil
+}
+
+func ResolveCheck(path string, overrides CheckOverrides) (CheckInput, error) {
+	return ResolveCheckFrom(FileSource(path), overrides)
+}
+
+// ResolveCheckFrom is ResolveCheck with input read from source.
+func ResolveCheckFrom(source Source, overrides CheckOverrides) (CheckInput, error) {
+	var resolved CheckInput
+	if source != nil {
+		if err := source(&resolved); err != nil {
+			return CheckInput{}, err
+		}
+	}
+	applyECS(&resolved.EcsParameter, overrides.Ecs)
+	applyConnection(&resolved.ConnectionParameter, overrides.Connection)
+	normalizeECS(&resolved.EcsParameter)
+	normalizeConnection(&resolved.ConnectionParameter)
+	if err := ValidateCheck(resolved); err != nil {
+		return CheckInput{}, &InvalidError{Err: err}
+	}
+	return resolved, nil
+}
+
+func ResolveCopy(path string, overrides CopyOverrides) (CopyInput, error) {
+	return ResolveCopyFrom(FileSource(path), overrides)
+}
+
+// ResolveCopyFrom is ResolveCopy with input read from source.
+func ResolveCopyFrom(source Source, overrides CopyOverrides) (CopyInput, error) {
+	var resolved CopyInput
+	if source != nil {
+		if err := source(&resolved); err != nil {
+			return CopyInput{}, err
+		}
+	}
+	applyECS(&resolved.EcsParameter, overrides.Ecs)
+	applyConnection(&resolved.ConnectionParameter, overrides.Connection)
+	if overrides.Source != nil {
+		resolved.Source = *overrides.Source
+	}
+	if overrides.Destination != nil {
+		resolved.Destination = *overrides.Destination
+	}
+	normalizeECS(&resolved.EcsParameter)
+	normalizeConnection(&resolved.ConnectionParameter)
+	resolved.Source = strings.TrimSpace(resolved.Source)
+	resolved.Destination = strings.TrimSpace(resolved.Destination)
+	if err := ValidateCopy(resolved); err != nil {
+		return CopyInput{}, &InvalidError{Err: err}
+	}
+	return resolved, nil
+}
+
+// ResolveSessions resolves the input of tnnl sessions, which has no input
+// file.
+func ResolveSessions(overrides SessionsOverrides) (SessionsInput, error) {
+	var resolved SessionsInput
+	applyConnection(&resolved.ConnectionParameter, overrides.Connection)
+	for _, id := range overrides.SessionIDs {
+		resolved.SessionIDs = append(resolved.SessionIDs, strings.TrimSpace(id))
+	}
+	if overrides.AllMine != nil {
+		resolved.AllMine = *overrides.AllMine
+	}
+	normalizeConnection(&resolved.ConnectionParameter)
+	if err := ValidateSessions(resolved); err != nil {
+		return SessionsInput{}, &InvalidError{Err: err}
+	}
+	return resolved, nil
+}
+
+// ResolveList resolves the input of tnnl ls, which has no input file.
+func ResolveList(overrides ListOverrides) (ListInput, error) {
+	var resolved ListInput
+	applyConnection(&resolved.ConnectionParameter, overrides.Connection)
+	for _, field := range []struct {
+		value  *string
+		target *string
+	}{
+		{overrides.Cluster, &resolved.Cluster},
+		{overrides.Service, &resolved.Service},
+		{overrides.Task, &resolved.Task},
+	} {
+		if field.value != nil {
+			*field.target = strings.TrimSpace(*field.value)
+		}
+	}
+	normalizeConnection(&resolved.ConnectionParameter)
+	if err := ValidateList(resolved); err != nil {
+		return ListInput{}, &InvalidError{Err: err}
 	}
 	return resolved, nil
 }
 
 func ResolvePortForward(path string, overrides PortForwardOverrides) (PortForwardInput, error) {
+	return ResolvePortForwardFrom(FileSource(path), overrides)
+}
+
+// ResolvePortForwardFrom is ResolvePortForward with input read from source.
+func ResolvePortForwardFrom(source Source, overrides PortForwardOverrides) (PortForwardInput, error) {
 	var resolved PortForwardInput
-	if path != "" {
-		if err := ReadInputFile(&resolved, path); err != nil {
+	if source != nil {
+		if err := source(&resolved); err != nil {
 			return PortForwardInput{}, err
 		}
 	}
+	applyECS(&resolved.EcsParameter, overrides.Ecs)
+	applyConnection(&resolved.ConnectionParameter, overrides.Connection)
+	applyReconnect(&resolved.ReconnectParameter, overrides.Reconnect)
 	if overrides.TargetPort != nil {
 		resolved.TargetPortNumber = *overrides.TargetPort
 	}
 	if overrides.LocalPort != nil {
 		resolved.LocalPortNumber = *overrides.LocalPort
 	}
+	if overrides.DryRun != nil {
+		resolved.DryRun = *overrides.DryRun
+	}
 	normalizeECS(&resolved.EcsParameter)
+	normalizeConnection(&resolved.ConnectionParameter)
 	resolved.TargetPortNumber = strings.TrimSpace(resolved.TargetPortNumber)
 	resolved.LocalPortNumber = strings.TrimSpace(resolved.LocalPortNumber)
 	if err := ValidatePortForward(resolved); err != nil {
-		return PortForwardInput{}, err
+		return PortForwardInput{}, &InvalidError{Err: err}
 	}
 	return resolved, nil
 }
 
 func ResolveRemotePortForward(path string, overrides RemotePortForwardOverrides) (RemotePortForwardInput, error) {
+	return ResolveRemotePortForwardFrom(FileSource(path), overrides)
+}
+
+// ResolveRemotePortForwardFrom is ResolveRemotePortForward with input read from source.
+func ResolveRemotePortForwardFrom(source Source, overrides RemotePortForwardOverrides) (RemotePortForwardInput, error) {
 	var resolved RemotePortForwardInput
-	if path != "" {
-		if err := ReadInputFile(&resolved, path); err != nil {
+	if source != nil {
+		if err := source(&resolved); err != nil {
 			return RemotePortForwardInput{}, err
 		}
 	}
+	applyECS(&resolved.EcsParameter, overrides.Ecs)
+	applyConnection(&resolved.ConnectionParameter, overrides.Connection)
+	applyReconnect(&resolved.ReconnectParameter, overrides.Reconnect)
 	if overrides.RemotePort != nil {
 		resolved.RemotePortNumber = *overrides.RemotePort
 	}
 	if overrides.LocalPort != nil {
 		resolved.LocalPortNumber = *overrides.LocalPort
 	}
+	if overrides.DryRun != nil {
+		resolved.DryRun = *overrides.DryRun
+	}
 	if overrides.Host != nil {
 		resolved.Host = *overrides.Host
 	}
 	normalizeECS(&resolved.EcsParameter)
+	normalizeConnection(&resolved.ConnectionParameter)
 	resolved.RemotePortNumber = strings.TrimSpace(resolved.RemotePortNumber)
 	resolved.LocalPortNumber = strings.TrimSpace(resolved.LocalPortNumber)
 	resolved.Host = strings.TrimSpace(resolved.Host)
 	if err := ValidateRemotePortForward(resolved); err != nil {
-		return RemotePortForwardInput{}, err
+		return RemotePortForwardInput{}, &InvalidError{Err: err}
 	}
 	return resolved, nil
 }
 
+func applyECS(value *EcsParameter, overrides EcsOverrides) {
+	if overrides.Cluster != nil {
+		value.Cluster = *overrides.Cluster
+	}
+	if overrides.Service != nil {
+		value.Service = *overrides.Service
+	}
+	if overrides.Task != nil {
+		value.Task = *overrides.Task
+	}
+	if overrides.Container != nil {
+		value.Container = *overrides.Container
+	}
+	if overrides.Family != nil {
+		value.Family = *overrides.Family
+	}
+	if overrides.Strategy != nil {
+		value.Strategy = *overrides.Strategy
+	}
+	if overrides.ShowIneligible != nil {
+		value.ShowIneligible = *overrides.ShowIneligible
+	}
+}
+
 func normalizeECS(value *EcsParameter) {
 	value.Cluster = strings.TrimSpace(value.Cluster)
 	value.Service = strings.TrimSpace(value.Service)
+	value.Task = strings.TrimSpace(value.Task)
+	value.Container = strings.TrimSpace(value.Container)
+	value.Family = strings.TrimSpace(value.Family)
+	value.Strategy = strings.ToLower(strings.TrimSpace(value.Strategy))
+}
+
+func applyConnection(value *ConnectionParameter, overrides ConnectionOverrides) {
+	if overrides.SessionClient != nil {
+		value.SessionClient = *overrides.SessionClient
+	}
+	if overrides.Profile != nil {
+		value.Profile = *overrides.Profile
+	}
+	if overrides.Region != nil {
+		value.Region = *overrides.Region
+	}
+	if overrides.EndpointURL != nil {
+		value.EndpointURL = *overrides.EndpointURL
+	}
+	if overrides.RoleARN != nil {
+		value.RoleARN = *overrides.RoleARN
+	}
+	if overrides.ExternalID != nil {
+		value.ExternalID = *overrides.ExternalID
+	}
+	if overrides.RoleSessionName != nil {
+		value.RoleSessionName = *overrides.RoleSessionName
+	}
+	if overrides.MFASerial != nil {
+		value.MFASerial = *overrides.MFASerial
+	}
+	if overrides.DiscoverRegions != nil {
+		value.DiscoverRegions = *overrides.DiscoverRegions
+	}
+	if overrides.DiscoverProfiles != nil {
+		value.DiscoverProfiles = *overrides.DiscoverProfiles
+	}
+}
+
+func applyReconnect(value *ReconnectParameter, overrides ReconnectOverrides) {
+	if overrides.Reconnect != nil {
+		value.Reconnect = *overrides.Reconnect
+	}
+	if overrides.Attempts != nil {
+		value.ReconnectAttempts = *overrides.Attempts
+	}
+	if overrides.Wait != nil {
+		value.ReconnectWait = *overrides.Wait
+	}
+}
+
+func normalizeConnection(value *ConnectionParameter) {
+	value.SessionClient = strings.ToLower(strings.TrimSpace(value.SessionClient))
+	value.Profile = strings.TrimSpace(value.Profile)
+	value.Region = strings.TrimSpace(value.Region)
+	value.EndpointURL = strings.TrimSpace(value.EndpointURL)
+	value.RoleARN = strings.TrimSpace(value.RoleARN)
+	value.ExternalID = strings.TrimSpace(value.ExternalID)
+	value.RoleSessionName = strings.TrimSpace(value.RoleSessionName)
+	value.MFASerial = strings.TrimSpace(value.MFASerial)
+	value.DiscoverRegions = normalizeList(value.DiscoverRegions)
+	value.DiscoverProfiles = normalizeList(value.DiscoverProfiles)
+}
+
+// normalizeList trims every entry of a comma-separated list.
+func normalizeList(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ""
+	}
+	entries := strings.Split(value, ",")
+	for i, entry := range entries {
+		entries[i] = strings.TrimSpace(entry)
+	}
+	return strings.Join(entries, ",")
 }
 
 func normalizeExec(value *ExecInput) {
 	normalizeECS(&value.EcsParameter)
+	normalizeConnection(&value.ConnectionParameter)
 	value.Cmd = strings.TrimSpace(value.Cmd)
+	value.Record = strings.TrimSpace(value.Record)
+}
+
+func ResolveMultiPortForward(path string, overrides MultiPortForwardOverrides) (MultiPortForwardInput, error) {
+	return ResolveMultiPortForwardFrom(FileSource(path), overrides)
+}
+
+// ResolveMultiPortForwardFrom is ResolveMultiPortForward with input read from
+// source, which is required.
+func ResolveMultiPortForwardFrom(source Source, overrides MultiPortForwardOverrides) (MultiPortForwardInput, error) {
+	var resolved MultiPortForwardInput
+	if source == nil {
+		return MultiPortForwardInput{}, &InvalidError{Err: errors.New("input file is required for multiple port forwards")}
+	}
+	if err := source(&resolved); err != nil {
+		return MultiPortForwardInput{}, err
+	}
+	applyConnection(&resolved.ConnectionParameter, overrides.Connection)
+	normalizeConnection(&resolved.ConnectionParameter)
+	for i := range resolved.Forwards {
+		forward := &resolved.Forwards[i]
+		normalizeECS(&forward.EcsParameter)
+		forward.Name = strings.TrimSpace(forward.Name)
+		if forward.Name == "" {
+			forward.Name = fmt.Sprintf("forward-%d", i+1)
+		}
+		forward.TargetPortNumber = strings.TrimSpace(forward.TargetPortNumber)
+		forward.LocalPortNumber = strings.TrimSpace(forward.LocalPortNumber)
+		forward.Host = strings.TrimSpace(forward.Host)
+	}
+	if err := ValidateMultiPortForward(resolved); err != nil {
+		return MultiPortForwardInput{}, &InvalidError{Err: err}
+	}
+	return resolved, nil
 }
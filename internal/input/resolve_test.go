@@ -1,6 +1,7 @@
 package input
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -28,6 +29,34 @@ func TestResolveExecPrecedence(t *testing.T) {
 	}
 }
 
+func TestResolveExecAppliesBatchOverrides(t *testing.T) {
+	path := writeResolveFixture(t, "exec.json", `{"command":"rake db:migrate","batch":false,"timeout":0}`)
+	batch := true
+	timeout := 600
+
+	got, err := ResolveExec(path, ExecOverrides{Batch: &batch, Timeout: &timeout})
+	if err != nil {
+		t.Fatalf("ResolveExec() error = %v", err)
+	}
+	if !got.Batch || got.Timeout != 600 || got.Cmd != "rake db:migrate" {
+		t.Fatalf("ResolveExec() = %#v, want batch with 600s timeout", got)
+	}
+}
+
+func TestResolveExecRecordFlagOverridesFile(t *testing.T) {
+	path := writeResolveFixture(t, "exec.json", `{"record":" file.cast "}`)
+	record := " flag.cast "
+
+	got, err := ResolveExec(path, ExecOverrides{})
+	if err != nil || got.Record != "file.cast" {
+		t.Fatalf("ResolveExec() = %#v, %v, want record file.cast", got, err)
+	}
+	got, err = ResolveExec(path, ExecOverrides{Record: &record})
+	if err != nil || got.Record != "flag.cast" {
+		t.Fatalf("ResolveExec() = %#v, %v, want record flag.cast", got, err)
+	}
+}
+
 func TestResolveExecUsesDefaultWithoutFileOrOverride(t *testing.T) {
 	got, err := ResolveExec("", ExecOverrides{})
 	if err != nil {
@@ -75,6 +104,67 @@ func TestResolveExecPropagatesStrictInputFileError(t *testing.T) {
 	}
 }
 
+func TestResolveCheckAppliesOverridesAndRejectsUnknownFields(t *testing.T) {
+	path := writeResolveFixture(t, "check.json", `{"cluster":" production ","service":"web","task":"task-file"}`)
+	cluster, task := " staging ", "task-flag"
+
+	got, err := ResolveCheck(path, CheckOverrides{Ecs: EcsOverrides{Cluster: &cluster, Task: &task}})
+	if err != nil {
+		t.Fatalf("ResolveCheck() error = %v", err)
+	}
+	want := CheckInput{EcsParameter: EcsParameter{Cluster: "staging", Service: "web", Task: "task-flag"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ResolveCheck() = %#v, want %#v", got, want)
+	}
+
+	path = writeResolveFixture(t, "check.json", `{"cluster":"production","command":"sh"}`)
+	if _, err := ResolveCheck(path, CheckOverrides{}); err == nil || !strings.Contains(err.Error(), "unknown field") {
+		t.Fatalf("ResolveCheck() error = %v, want unknown field", err)
+	}
+}
+
+func TestResolveCopyAppliesArgumentsOverFile(t *testing.T) {
+	path := writeResolveFixture(t, "copy.json", `{"cluster":"production","source":":/tmp/old","destination":"old"}`)
+	source, destination := " :/tmp/heap.hprof ", "./heap.hprof"
+
+	got, err := ResolveCopy(path, CopyOverrides{Source: &source, Destination: &destination})
+	if err != nil {
+		t.Fatalf("ResolveCopy() error = %v", err)
+	}
+	want := CopyInput{EcsParameter: EcsParameter{Cluster: "production"}, Source: ":/tmp/heap.hprof", Destination: "./heap.hprof"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ResolveCopy() = %#v, want %#v", got, want)
+	}
+	if got.Upload() {
+		t.Fatal("Upload() = true, want a download")
+	}
+}
+
+func TestResolveSessionsNormalizesAndValidates(t *testing.T) {
+	region, allMine := " ap-northeast-1 ", true
+
+	got, err := ResolveSessions(SessionsOverrides{
+		Connection: ConnectionOverrides{Region: &region},
+		SessionIDs: []string{" alice-1 ", "alice-2"},
+	})
+	want := SessionsInput{ConnectionParameter: ConnectionParameter{Region: "ap-northeast-1"}, SessionIDs: []string{"alice-1", "alice-2"}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("ResolveSessions() = %#v, %v, want %#v", got, err, want)
+	}
+
+	discover := "us-east-1,us-west-2"
+	_, err = ResolveSessions(SessionsOverrides{
+		Connection: ConnectionOverrides{DiscoverRegions: &discover},
+		SessionIDs: []string{"alice-1", " "},
+		AllMine:    &allMine,
+	})
+	for _, want := range []string{"cluster discovery is not supported", "mutually exclusive", "must not be empty"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ResolveSessions() error = %v, want it to contain %q", err, want)
+		}
+	}
+}
+
 func TestResolvePortForwardAppliesExplicitOverridesAndNormalizes(t *testing.T) {
 	path := writeResolveFixture(t, "port.json", `{
 		"cluster":" cluster ",
@@ -264,3 +354,248 @@ func writeResolveFixture(t *testing.T, name, content string) string {
 	}
 	return path
 }
+
+func TestResolveSessionClientPrecedence(t *testing.T) {
+	path := writeResolveFixture(t, "exec.json", `{"command":"sh","session_client":" Plugin "}`)
+
+	got, err := ResolveExec(path, ExecOverrides{})
+	if err != nil {
+		t.Fatalf("ResolveExec() error = %v", err)
+	}
+	if got.SessionClient != "plugin" {
+		t.Fatalf("file session client = %q, want normalized plugin", got.SessionClient)
+	}
+
+	native := "native"
+	got, err = ResolveExec(path, ExecOverrides{Connection: ConnectionOverrides{SessionClient: &native}})
+	if err != nil {
+		t.Fatalf("ResolveExec() error = %v", err)
+	}
+	if got.SessionClient != "native" {
+		t.Fatalf("overridden session client = %q, want native", got.SessionClient)
+	}
+}
+
+func TestResolveAWSConnectionPrecedence(t *testing.T) {
+	path := writeResolveFixture(t, "exec.json", `{"profile":" dev ","region":"ap-northeast-1","endpoint_url":"http://localhost:4566"}`)
+
+	got, err := ResolveExec(path, ExecOverrides{})
+	if err != nil {
+		t.Fatalf("ResolveExec() error = %v", err)
+	}
+	want := ConnectionParameter{Profile: "dev", Region: "ap-northeast-1", EndpointURL: "http://localhost:4566"}
+	if got.ConnectionParameter != want {
+		t.Fatalf("file connection = %#v, want %#v", got.ConnectionParameter, want)
+	}
+
+	profile, region, endpoint := "prod", "us-east-1", ""
+	got, err = ResolveExec(path, ExecOverrides{Connection: ConnectionOverrides{Profile: &profile, Region: &region, EndpointURL: &endpoint}})
+	if err != nil {
+		t.Fatalf("ResolveExec() error = %v", err)
+	}
+	want = ConnectionParameter{Profile: "prod", Region: "us-east-1"}
+	if got.ConnectionParameter != want {
+		t.Fatalf("overridden connection = %#v, want %#v", got.ConnectionParameter, want)
+	}
+}
+
+func TestResolveDiscoveryLists(t *testing.T) {
+	path := writeResolveFixture(t, "exec.json", `{"discover_regions":" ap-northeast-1 , us-east-1 ","discover_profiles":"prod"}`)
+
+	got, err := ResolveExec(path, ExecOverrides{})
+	if err != nil {
+		t.Fatalf("ResolveExec() error = %v", err)
+	}
+	if got.DiscoverRegions != "ap-northeast-1,us-east-1" || got.DiscoverProfiles != "prod" {
+		t.Fatalf("discovery = %q, %q; want normalized lists", got.DiscoverRegions, got.DiscoverProfiles)
+	}
+	if regions := got.DiscoveryRegions(); len(regions) != 2 || regions[1] != "us-east-1" {
+		t.Fatalf("DiscoveryRegions() = %q", regions)
+	}
+
+	profiles := "stg, dev"
+	got, err = ResolveExec(path, ExecOverrides{Connection: ConnectionOverrides{DiscoverProfiles: &profiles}})
+	if err != nil {
+		t.Fatalf("ResolveExec() error = %v", err)
+	}
+	if got.DiscoverProfiles != "stg,dev" || !got.Discovers() {
+		t.Fatalf("overridden profiles = %q, want stg,dev", got.DiscoverProfiles)
+	}
+
+	for regions, want := range map[string]string{
+		"us-east-1,,eu-west-1": "must not contain empty entries",
+		"us-east-1, us-east-1": `must not repeat "us-east-1"`,
+		"us-east-1,Tokyo":      `invalid entry: "Tokyo"`,
+	} {
+		_, err := ResolveExec("", ExecOverrides{Connection: ConnectionOverrides{DiscoverRegions: &regions}})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ResolveExec(discover regions %q) error = %v, want %q", regions, err, want)
+		}
+	}
+}
+
+func TestResolveRejectsInvalidEndpointURL(t *testing.T) {
+	for _, endpoint := range []string{"localhost:4566", "ftp://example.com", "https://"} {
+		_, err := ResolveExec("", ExecOverrides{Connection: ConnectionOverrides{EndpointURL: &endpoint}})
+		if err == nil || !strings.Contains(err.Error(), "endpoint URL must be an absolute http or https URL") {
+			t.Errorf("ResolveExec(endpoint %q) error = %v, want endpoint validation", endpoint, err)
+		}
+	}
+}
+
+func TestResolvePortForwardsRejectUnknownSessionClient(t *testing.T) {
+	client := "telnet"
+	targetPort := "80"
+	host := "db.internal"
+
+	_, err := ResolvePortForward("", PortForwardOverrides{
+		Connection: ConnectionOverrides{SessionClient: &client},
+		TargetPort: &targetPort,
+	})
+	if err == nil || !strings.Contains(err.Error(), `session client must be "plugin" or "native"`) {
+		t.Fatalf("ResolvePortForward() error = %v, want session client validation", err)
+	}
+
+	_, err = ResolveRemotePortForward("", RemotePortForwardOverrides{
+		Connection: ConnectionOverrides{SessionClient: &client},
+		RemotePort: &targetPort,
+		Host:       &host,
+	})
+	if err == nil || !strings.Contains(err.Error(), `session client must be "plugin" or "native"`) {
+		t.Fatalf("ResolveRemotePortForward() error = %v, want session client validation", err)
+	}
+}
+
+func TestResolvePortForwardSelectorPrecedence(t *testing.T) {
+	path := writeResolveFixture(t, "portforward.json", `{
+		"task":" task-file ",
+		"container":" app ",
+		"family":" web ",
+		"strategy":" First ",
+		"target_port_number":"80"
+	}`)
+
+	task := "task-flag"
+	strategy := "NEWEST"
+	got, err := ResolvePortForward(path, PortForwardOverrides{
+		Ecs: EcsOverrides{Task: &task, Strategy: &strategy},
+	})
+	if err != nil {
+		t.Fatalf("ResolvePortForward() error = %v", err)
+	}
+	want := EcsParameter{Task: "task-flag", Container: "app", Family: "web", Strategy: "newest"}
+	if got.EcsParameter != want {
+		t.Fatalf("ResolvePortForward() EcsParameter = %#v, want %#v", got.EcsParameter, want)
+	}
+}
+
+func TestResolveMultiPortForwardNormalizesForwards(t *testing.T) {
+	path := writeResolveFixture(t, "multi.json", `{
+		"session_client":"plugin",
+		"forwards":[
+			{"name":" app ","cluster":" production ","service":"web","target_port_number":" 8080 ","local_port_number":"18080"},
+			{"cluster":"production","family":"api","strategy":" First ","target_port_number":"5432","host":" db.internal "}
+		]
+	}`)
+	native := "native"
+
+	got, err := ResolveMultiPortForward(path, MultiPortForwardOverrides{Connection: ConnectionOverrides{SessionClient: &native}})
+	if err != nil {
+		t.Fatalf("ResolveMultiPortForward() error = %v", err)
+	}
+	want := MultiPortForwardInput{
+		ConnectionParameter: ConnectionParameter{SessionClient: "native"},
+		Forwards: []ForwardParameter{
+			{
+				Name:             "app",
+				EcsParameter:     EcsParameter{Cluster: "production", Service: "web"},
+				TargetPortNumber: "8080",
+				LocalPortNumber:  "18080",
+			},
+			{
+				Name:             "forward-2",
+				EcsParameter:     EcsParameter{Cluster: "production", Family: "api", Strategy: "first"},
+				TargetPortNumber: "5432",
+				Host:             "db.internal",
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ResolveMultiPortForward() = %#v, want %#v", got, want)
+	}
+}
+
+func TestResolveMultiPortForwardRequiresInputFile(t *testing.T) {
+	_, err := ResolveMultiPortForward("", MultiPortForwardOverrides{})
+	if err == nil || !strings.Contains(err.Error(), "input file is required") {
+		t.Fatalf("ResolveMultiPortForward() error = %v, want input file requirement", err)
+	}
+}
+
+func TestResolveRemotePortForwardReconnectPrecedence(t *testing.T) {
+	path := writeResolveFixture(t, "remote.json", `{
+		"remote_port_number":"5432",
+		"host":"db.internal",
+		"reconnect":true,
+		"reconnect_attempts":3,
+		"reconnect_wait":30
+	}`)
+	attempts := 10
+
+	got, err := ResolveRemotePortForward(path, RemotePortForwardOverrides{Reconnect: ReconnectOverrides{Attempts: &attempts}})
+	if err != nil {
+		t.Fatalf("ResolveRemotePortForward() error = %v", err)
+	}
+	want := ReconnectParameter{Reconnect: true, ReconnectAttempts: 10, ReconnectWait: 30}
+	if got.ReconnectParameter != want {
+		t.Fatalf("ReconnectParameter = %#v, want %#v", got.ReconnectParameter, want)
+	}
+
+	negative := -1
+	targetPort := "80"
+	_, err = ResolvePortForward("", PortForwardOverrides{
+		TargetPort: &targetPort,
+		Reconnect:  ReconnectOverrides{Attempts: &negative, Wait: &negative},
+	})
+	for _, want := range []string{"reconnect attempts must be non-negative", "reconnect wait must be non-negative"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ResolvePortForward() error = %v, want substring %q", err, want)
+		}
+	}
+}
+
+func TestResolveMarksOnlyValidationErrorsInvalid(t *testing.T) {
+	var invalid *InvalidError
+	strategy := "sideways"
+	_, err := ResolveExecFrom(nil, ExecOverrides{Ecs: EcsOverrides{Strategy: &strategy}})
+	if !errors.As(err, &invalid) {
+		t.Fatalf("ResolveExecFrom() error = %v, want *InvalidError", err)
+	}
+
+	path := writeResolveFixture(t, "exec.json", `{"commnad":"bash"}`)
+	if _, err := ResolveExec(path, ExecOverrides{}); err == nil || errors.As(err, &invalid) {
+		t.Fatalf("ResolveExec() error = %v, want a read error that is not *InvalidError", err)
+	}
+}
+
+func TestResolveListNormalizesAndValidates(t *testing.T) {
+	cluster, service, task := " production ", " web ", "0123456789abcdef0"
+
+	got, err := ResolveList(ListOverrides{Cluster: &cluster, Service: &service, Task: &task})
+	want := ListInput{Cluster: "production", Service: "web", Task: "0123456789abcdef0"}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("ResolveList() = %#v, %v, want %#v", got, err, want)
+	}
+
+	badTask := "task/production/a/b"
+	_, err = ResolveList(ListOverrides{Service: &service, Task: &badTask})
+	for _, want := range []string{"service and task require a cluster", "task must be a task ID or task ARN"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ResolveList() error = %v, want it to contain %q", err, want)
+		}
+	}
+	var invalid *InvalidError
+	if !errors.As(err, &invalid) {
+		t.Errorf("ResolveList() error = %T, want *InvalidError", err)
+	}
+}
@@ -3,8 +3,15 @@ package input
 import (
 	"errors"
 	"fmt"
+	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+
+	"github.com/wim-web/tnnl/internal/session_manager"
+	"github.com/wim-web/tnnl/internal/target"
 )
 
 func validatePort(name, value string, required bool) error {
@@ -34,19 +41,215 @@ func validatePort(name, value string, required bool) error {
 	return nil
 }
 
-func ValidateExec(v ExecInput) error {
+func validateConnection(v ConnectionParameter) error {
+	var errs []error
+	switch v.SessionClient {
+	case "", session_manager.ClientPlugin, session_manager.ClientNative:
+	default:
+		errs = append(errs, fmt.Errorf(
+			"session client must be %q or %q: %q",
+			session_manager.ClientPlugin,
+			session_manager.ClientNative,
+			v.SessionClient,
+		))
+	}
+	if v.EndpointURL != "" {
+		endpoint, err := url.Parse(v.EndpointURL)
+		if err != nil || (endpoint.Scheme != "https" && endpoint.Scheme != "http") || endpoint.Host == "" {
+			errs = append(errs, fmt.Errorf("endpoint URL must be an absolute http or https URL: %q", v.EndpointURL))
+		}
+	}
+	errs = append(errs,
+		validateList("discover regions", v.DiscoveryRegions(), regionPattern),
+		validateList("discover profiles", v.DiscoveryProfiles(), nil),
+	)
+	return errors.Join(append(errs, validateRole(v))...)
+}
+
+var regionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-\d+$`)
+
+func validateList(name string, entries []string, pattern *regexp.Regexp) error {
 	var errs []error
+	seen := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		switch _, duplicate := seen[entry]; {
+		case entry == "":
+			errs = append(errs, fmt.Errorf("%s must not contain empty entries", name))
+		case duplicate:
+			errs = append(errs, fmt.Errorf("%s must not repeat %q", name, entry))
+		case pattern != nil && !pattern.MatchString(entry):
+			errs = append(errs, fmt.Errorf("%s has an invalid entry: %q", name, entry))
+		}
+		seen[entry] = struct{}{}
+	}
+	return errors.Join(errs...)
+}
+
+var (
+	roleSessionNamePattern = regexp.MustCompile(`^[\w+=,.@-]{2,64}$`)
+	mfaSerialPattern       = regexp.MustCompile(`^[\w+=/:,.@-]{9,256}$`)
+)
+
+func validateRole(v ConnectionParameter) error {
+	if v.RoleARN == "" {
+		var errs []error
+		for _, field := range []struct{ name, value string }{
+			{"external ID", v.ExternalID},
+			{"role session name", v.RoleSessionName},
+			{"MFA serial", v.MFASerial},
+		} {
+			if field.value != "" {
+				errs = append(errs, fmt.Errorf("%s requires a role ARN", field.name))
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	var errs []error
+	if role, err := arn.Parse(v.RoleARN); err != nil || role.Service != "iam" || !strings.HasPrefix(role.Resource, "role/") {
+		errs = append(errs, fmt.Errorf("role ARN must be an IAM role ARN: %q", v.RoleARN))
+	}
+	if v.RoleSessionName != "" && !roleSessionNamePattern.MatchString(v.RoleSessionName) {
+		errs = append(errs, fmt.Errorf("role session name must be 2-64 characters of letters, digits, and +=,.@_-: %q", v.RoleSessionName))
+	}
+	if v.MFASerial != "" && !mfaSerialPattern.MatchString(v.MFASerial) {
+		errs = append(errs, fmt.Errorf("MFA serial must be an MFA device ARN or serial number: %q", v.MFASerial))
+	}
+	return errors.Join(errs...)
+}
+
+func validateECS(v EcsParameter) error {
+	var errs []error
+	switch v.Strategy {
+	case "", target.StrategyFirst, target.StrategyRandom, target.StrategyNewest:
+	default:
+		errs = append(errs, fmt.Errorf(
+			"strategy must be %q, %q, or %q: %q",
+			target.StrategyFirst,
+			target.StrategyRandom,
+			target.StrategyNewest,
+			v.Strategy,
+		))
+	}
+	if strings.HasPrefix(v.Task, "arn:") {
+		if _, err := target.TaskID(v.Task); err != nil {
+			errs = append(errs, fmt.Errorf("task: %w", err))
+		}
+	} else if strings.Contains(v.Task, "/") {
+		errs = append(errs, fmt.Errorf("task must be a task ID or task ARN: %q", v.Task))
+	}
+	return errors.Join(errs...)
+}
+
+func validateReconnect(v ReconnectParameter) error {
+	var errs []error
+	if v.ReconnectAttempts < 0 {
+		errs = append(errs, errors.New("reconnect attempts must be non-negative"))
+	}
+	if v.ReconnectWait < 0 {
+		errs = append(errs, errors.New("reconnect wait must be non-negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// InvalidError reports resolved input that failed validation, as opposed to
+// input that could not be read.
+type InvalidError struct {
+	Err error
+}
+
+func (e *InvalidError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InvalidError) Unwrap() error {
+	return e.Err
+}
+
+func ValidateExec(v ExecInput) error {
+	errs := []error{validateECS(v.EcsParameter), validateConnection(v.ConnectionParameter)}
 	if strings.TrimSpace(v.Cmd) == "" {
 		errs = append(errs, errors.New("command is required"))
 	}
 	if v.Wait < 0 {
 		errs = append(errs, errors.New("wait must be non-negative"))
 	}
+	if v.Timeout < 0 {
+		errs = append(errs, errors.New("timeout must be non-negative"))
+	}
+	if v.Timeout > 0 && !v.Batch && !v.All {
+		errs = append(errs, errors.New("timeout requires batch mode"))
+	}
+	if v.Parallel < 0 {
+		errs = append(errs, errors.New("parallel must be non-negative"))
+	}
+	if v.Parallel > 0 && !v.All {
+		errs = append(errs, errors.New("parallel requires all"))
+	}
+	if v.Record != "" {
+		if v.Batch || v.All {
+			errs = append(errs, errors.New("record requires an interactive session"))
+		}
+		if v.SessionClient == session_manager.ClientPlugin {
+			errs = append(errs, fmt.Errorf("record requires the %q session client; %s drives the terminal itself", session_manager.ClientNative, session_manager.CommandName))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func ValidateCheck(v CheckInput) error {
+	return errors.Join(validateECS(v.EcsParameter), validateConnection(v.ConnectionParameter))
+}
+
+func ValidateCopy(v CopyInput) error {
+	errs := []error{validateECS(v.EcsParameter), validateConnection(v.ConnectionParameter)}
+	if v.Source == "" || v.Destination == "" {
+		errs = append(errs, errors.New("source and destination are required"))
+		return errors.Join(errs...)
+	}
+	sourcePath, remoteSource := ContainerPath(v.Source)
+	destinationPath, remoteDestination := ContainerPath(v.Destination)
+	switch {
+	case remoteSource == remoteDestination:
+		errs = append(errs, errors.New("exactly one of source and destination must be a container path starting with ':'"))
+	case remoteSource && strings.TrimSpace(sourcePath) == "":
+		errs = append(errs, errors.New("source container path is empty"))
+	case remoteDestination && strings.TrimSpace(destinationPath) == "":
+		errs = append(errs, errors.New("destination container path is empty"))
+	}
+	return errors.Join(errs...)
+}
+
+func ValidateSessions(v SessionsInput) error {
+	errs := []error{validateConnection(v.ConnectionParameter)}
+	if v.Discovers() {
+		errs = append(errs, errors.New("cluster discovery is not supported for sessions; set the profile and region"))
+	}
+	if v.AllMine && len(v.SessionIDs) > 0 {
+		errs = append(errs, errors.New("session IDs and all mine are mutually exclusive"))
+	}
+	for _, id := range v.SessionIDs {
+		if id == "" {
+			errs = append(errs, errors.New("session IDs must not be empty"))
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func ValidateList(v ListInput) error {
+	errs := []error{validateConnection(v.ConnectionParameter), validateECS(EcsParameter{Task: v.Task})}
+	if v.Cluster == "" && (v.Service != "" || v.Task != "") {
+		errs = append(errs, errors.New("service and task require a cluster"))
+	}
 	return errors.Join(errs...)
 }
 
 func ValidatePortForward(v PortForwardInput) error {
 	return errors.Join(
+		validateECS(v.EcsParameter),
+		validateConnection(v.ConnectionParameter),
+		validateReconnect(v.ReconnectParameter),
 		validatePort("target port", v.TargetPortNumber, true),
 		validatePort("local port", v.LocalPortNumber, false),
 	)
@@ -58,8 +261,46 @@ func ValidateRemotePortForward(v RemotePortForwardInput) error {
 		hostErr = errors.New("host is required")
 	}
 	return errors.Join(
+		validateECS(v.EcsParameter),
+		validateConnection(v.ConnectionParameter),
+		validateReconnect(v.ReconnectParameter),
 		validatePort("remote port", v.RemotePortNumber, true),
 		validatePort("local port", v.LocalPortNumber, false),
 		hostErr,
 	)
 }
+
+func ValidateMultiPortForward(v MultiPortForwardInput) error {
+	errs := []error{validateConnection(v.ConnectionParameter)}
+	if v.Discovers() {
+		errs = append(errs, errors.New("cluster discovery is not supported for multiple port forwards; set each forward's cluster and the region"))
+	}
+	if len(v.Forwards) == 0 {
+		errs = append(errs, errors.New("at least one forward is required"))
+	}
+	names := make(map[string]int, len(v.Forwards))
+	localPorts := make(map[string]int, len(v.Forwards))
+	for i, forward := range v.Forwards {
+		forwardErr := errors.Join(
+			validateECS(forward.EcsParameter),
+			validatePort("target port", forward.TargetPortNumber, true),
+			validatePort("local port", forward.LocalPortNumber, false),
+		)
+		if first, ok := names[forward.Name]; ok {
+			forwardErr = errors.Join(forwardErr, fmt.Errorf("name %q is already used by forwards[%d]", forward.Name, first))
+		} else {
+			names[forward.Name] = i
+		}
+		if forward.LocalPortNumber != "" {
+			if first, ok := localPorts[forward.LocalPortNumber]; ok {
+				forwardErr = errors.Join(forwardErr, fmt.Errorf("local port %s is already used by forwards[%d]", forward.LocalPortNumber, first))
+			} else {
+				localPorts[forward.LocalPortNumber] = i
+			}
+		}
+		if forwardErr != nil {
+			errs = append(errs, fmt.Errorf("forwards[%d] %q: %w", i, forward.Name, forwardErr))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -124,6 +124,71 @@ func TestValidateExecRejectsNegativeWaitAndBlankCommand(t *testing.T) {
 	}
 }
 
+func TestValidateExecBatchOptions(t *testing.T) {
+	tests := []struct {
+		name  string
+		input ExecInput
+		want  string
+	}{
+		{name: "negative", input: ExecInput{Cmd: "sh", Batch: true, Timeout: -1}, want: "timeout must be non-negative"},
+		{name: "without batch", input: ExecInput{Cmd: "sh", Timeout: 30}, want: "timeout requires batch mode"},
+		{name: "batch", input: ExecInput{Cmd: "sh", Batch: true, Timeout: 30}},
+		{name: "all", input: ExecInput{Cmd: "sh", All: true, Timeout: 30, Parallel: 8}},
+		{name: "negative parallel", input: ExecInput{Cmd: "sh", All: true, Parallel: -1}, want: "parallel must be non-negative"},
+		{name: "parallel without all", input: ExecInput{Cmd: "sh", Parallel: 2}, want: "parallel requires all"},
+		{name: "record", input: ExecInput{Cmd: "sh", Record: "session.cast"}},
+		{name: "record in batch mode", input: ExecInput{Cmd: "sh", Batch: true, Record: "session.cast"}, want: "record requires an interactive session"},
+		{
+			name:  "record with plugin",
+			input: ExecInput{ConnectionParameter: ConnectionParameter{SessionClient: "plugin"}, Cmd: "sh", Record: "session.cast"},
+			want:  `record requires the "native" session client`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateExec(tt.input)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("ValidateExec() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("ValidateExec() error = %v, want substring %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCopy(t *testing.T) {
+	tests := []struct {
+		name  string
+		input CopyInput
+		want  string
+	}{
+		{name: "upload", input: CopyInput{Source: "dump", Destination: ":/tmp/"}},
+		{name: "download", input: CopyInput{Source: ":/tmp/dump", Destination: "."}},
+		{name: "missing", input: CopyInput{Source: "dump"}, want: "source and destination are required"},
+		{name: "both local", input: CopyInput{Source: "a", Destination: "b"}, want: "exactly one of source and destination"},
+		{name: "both remote", input: CopyInput{Source: ":a", Destination: ":b"}, want: "exactly one of source and destination"},
+		{name: "empty remote", input: CopyInput{Source: ":", Destination: "b"}, want: "source container path is empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCopy(tt.input)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("ValidateCopy() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("ValidateCopy() error = %v, want substring %q", err, tt.want)
+			}
+		})
+	}
+}
+
 func TestValidateExecAcceptsCommandAndNonNegativeWait(t *testing.T) {
 	if err := ValidateExec(ExecInput{Cmd: " sh ", Wait: 0}); err != nil {
 		t.Fatalf("ValidateExec() error = %v, want nil", err)
@@ -185,3 +250,122 @@ func TestValidateRemotePortForward(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateExecSelectors(t *testing.T) {
+	valid := []EcsParameter{
+		{Task: "0123456789abcdef", Container: "app"},
+		{Task: "arn:aws:ecs:us-east-1:123456789012:task/production/0123456789abcdef"},
+		{Family: "web", Strategy: "newest"},
+		{Strategy: "random"},
+	}
+	for _, ecs := range valid {
+		if err := ValidateExec(ExecInput{EcsParameter: ecs, Cmd: "sh"}); err != nil {
+			t.Errorf("ValidateExec(%#v) error = %v, want nil", ecs, err)
+		}
+	}
+
+	err := ValidateExec(ExecInput{
+		EcsParameter: EcsParameter{Task: "production/0123", Strategy: "oldest"},
+		Cmd:          "sh",
+	})
+	if err == nil {
+		t.Fatal("ValidateExec() error = nil, want selector errors")
+	}
+	for _, want := range []string{`strategy must be "first", "random", or "newest": "oldest"`, "task must be a task ID or task ARN"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("ValidateExec() error = %q, want substring %q", err, want)
+		}
+	}
+
+	err = ValidatePortForward(PortForwardInput{
+		EcsParameter:     EcsParameter{Task: "arn:aws:ecs:us-east-1:123456789012:cluster/production"},
+		TargetPortNumber: "80",
+	})
+	if err == nil || !strings.Contains(err.Error(), "task:") {
+		t.Fatalf("ValidatePortForward() error = %v, want task ARN error", err)
+	}
+}
+
+func TestValidateMultiPortForward(t *testing.T) {
+	if err := ValidateMultiPortForward(MultiPortForwardInput{}); err == nil || !strings.Contains(err.Error(), "at least one forward is required") {
+		t.Fatalf("ValidateMultiPortForward(empty) error = %v, want forward requirement", err)
+	}
+	discovering := MultiPortForwardInput{
+		ConnectionParameter: ConnectionParameter{DiscoverRegions: "us-east-1"},
+		Forwards:            []ForwardParameter{{Name: "app", TargetPortNumber: "8080"}},
+	}
+	if err := ValidateMultiPortForward(discovering); err == nil || !strings.Contains(err.Error(), "cluster discovery is not supported") {
+		t.Fatalf("ValidateMultiPortForward(discovery) error = %v, want discovery rejection", err)
+	}
+
+	err := ValidateMultiPortForward(MultiPortForwardInput{Forwards: []ForwardParameter{
+		{Name: "app", TargetPortNumber: "8080", LocalPortNumber: "18080"},
+		{Name: "app", TargetPortNumber: "9090", LocalPortNumber: "18080"},
+		{Name: "db", LocalPortNumber: "0", EcsParameter: EcsParameter{Strategy: "oldest"}},
+	}})
+	if err == nil {
+		t.Fatal("ValidateMultiPortForward() error = nil, want joined errors")
+	}
+	for _, want := range []string{
+		`forwards[1] "app": name "app" is already used by forwards[0]`,
+		"local port 18080 is already used by forwards[0]",
+		`forwards[2] "db": strategy must be`,
+		"target port is required",
+		"local port must be between 1 and 65535",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("ValidateMultiPortForward() error = %q, want substring %q", err, want)
+		}
+	}
+}
+
+func TestValidateConnectionRole(t *testing.T) {
+	const role = "arn:aws:iam::123456789012:role/ecs-operator"
+	tests := []struct {
+		name       string
+		connection ConnectionParameter
+		wantErr    []string
+	}{
+		{name: "no role"},
+		{
+			name: "full role",
+			connection: ConnectionParameter{
+				RoleARN:         role,
+				ExternalID:      "shared-secret",
+				RoleSessionName: "alice@example.com",
+				MFASerial:       "arn:aws:iam::111111111111:mfa/alice",
+			},
+		},
+		{
+			name:       "role options without role",
+			connection: ConnectionParameter{ExternalID: "x", MFASerial: "GAHT12345678"},
+			wantErr:    []string{"external ID requires a role ARN", "MFA serial requires a role ARN"},
+		},
+		{
+			name:       "not a role ARN",
+			connection: ConnectionParameter{RoleARN: "arn:aws:iam::123456789012:user/alice"},
+			wantErr:    []string{"role ARN must be an IAM role ARN"},
+		},
+		{
+			name:       "invalid session name and serial",
+			connection: ConnectionParameter{RoleARN: role, RoleSessionName: "a b", MFASerial: "short"},
+			wantErr:    []string{"role session name must be", "MFA serial must be"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateConnection(tt.connection)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("validateConnection() error = %v", err)
+				}
+				return
+			}
+			for _, want := range tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), want) {
+					t.Errorf("validateConnection() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
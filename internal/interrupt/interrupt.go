@@ -0,0 +1,73 @@
+// Package interrupt ends a command's work when the process receives a
+// termination signal, and keeps the signal so sessions can pass it on to the
+// session client and report why they ended.
+package interrupt
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Signals are the signals Notify traps.
+var Signals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP}
+
+var signalNames = map[os.Signal]string{
+	os.Interrupt:    "SIGINT",
+	syscall.SIGTERM: "SIGTERM",
+	syscall.SIGHUP:  "SIGHUP",
+}
+
+// Error is the cancellation cause of a context ended by a signal.
+type Error struct {
+	Signal os.Signal
+}
+
+func (e *Error) Error() string {
+	name, ok := signalNames[e.Signal]
+	if !ok {
+		name = e.Signal.String()
+	}
+	return "received " + name
+}
+
+// ExitCode is the status a shell reports for a process the signal ended.
+func (e *Error) ExitCode() int {
+	if number, ok := e.Signal.(syscall.Signal); ok {
+		return 128 + int(number)
+	}
+	return 1
+}
+
+// Notify returns a copy of parent that is canceled with an *Error cause when
+// the process receives one of Signals. Only the first signal is trapped, so
+// a second one ends the process even when cleanup hangs. stop releases the
+// signals and cancels the context.
+func Notify(parent context.Context) (ctx context.Context, stop context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	received := make(chan os.Signal, 1)
+	signal.Notify(received, Signals...)
+	go func() {
+		select {
+		case sig := <-received:
+			signal.Stop(received)
+			cancel(&Error{Signal: sig})
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(received)
+		cancel(context.Canceled)
+	}
+}
+
+// Cause returns the signal that ended ctx, if one did.
+func Cause(ctx context.Context) (*Error, bool) {
+	var caught *Error
+	if errors.As(context.Cause(ctx), &caught) {
+		return caught, true
+	}
+	return nil, false
+}
@@ -0,0 +1,33 @@
+package interrupt
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCauseReachesDerivedContexts(t *testing.T) {
+	parent, cancel := context.WithCancelCause(context.Background())
+	child, cancelChild := context.WithTimeout(parent, time.Hour)
+	defer cancelChild()
+	cancel(&Error{Signal: syscall.SIGTERM})
+
+	caught, ok := Cause(child)
+	if !ok || caught.Signal != syscall.SIGTERM || caught.ExitCode() != 143 {
+		t.Fatalf("Cause() = %v, %t, want SIGTERM", caught, ok)
+	}
+	if !errors.Is(child.Err(), context.Canceled) {
+		t.Fatalf("Err() = %v, want context.Canceled", child.Err())
+	}
+}
+
+func TestCauseIgnoresOtherCancellation(t *testing.T) {
+	ctx, stop := Notify(context.Background())
+	stop()
+
+	if caught, ok := Cause(ctx); ok {
+		t.Fatalf("Cause() = %v, want none after stop", caught)
+	}
+}
@@ -0,0 +1,32 @@
+//go:build unix
+
+package interrupt
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestNotifyCancelsWithTheSignal(t *testing.T) {
+	ctx, stop := Notify(context.Background())
+	defer stop()
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("context was not canceled by SIGHUP")
+	}
+	caught, ok := Cause(ctx)
+	if !ok || caught.Signal != syscall.SIGHUP {
+		t.Fatalf("Cause() = %v, %t, want SIGHUP", caught, ok)
+	}
+	if caught.Error() != "received SIGHUP" || caught.ExitCode() != 129 {
+		t.Fatalf("error = %q, exit code %d, want received SIGHUP and 129", caught.Error(), caught.ExitCode())
+	}
+}
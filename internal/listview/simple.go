@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 
+	"charm.land/bubbles/v2/key"
 	"charm.land/bubbles/v2/list"
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
@@ -14,13 +15,23 @@ var (
 	listWidth         = 20
 	itemStyle         = lipgloss.NewStyle().PaddingLeft(4)
 	selectedItemStyle = lipgloss.NewStyle().PaddingLeft(2).Foreground(lipgloss.Color("170"))
+	disabledStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
 )
 
+// Option is one choice. A non-empty Disabled greys the option out with that
+// reason and makes it unselectable. Last marks the option chosen last time:
+// the cursor starts on it and r moves back to it.
 type Option struct {
-	Label string
-	Value string
+	Label    string
+	Value    string
+	Disabled string
+	Last     bool
 }
 
+const lastLabel = " (last)"
+
+var lastKey = key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "last choice"))
+
 type NoItemsError struct {
 	Title string
 }
@@ -42,6 +53,9 @@ func RenderOptions(title string, options []Option) (string, bool, error) {
 	listModel := list.New(items, itemDelegate{}, listWidth, listHeight)
 	listModel.Title = title
 	m := model{list: listModel}
+	if m.selectLast() {
+		m.list.AdditionalShortHelpKeys = func() []key.Binding { return []key.Binding{lastKey} }
+	}
 
 	p := tea.NewProgram(m)
 
@@ -77,6 +91,12 @@ func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list
 	}
 
 	str := fmt.Sprintf("%d. %s", index+1, i.Label)
+	if i.Last {
+		str += lastLabel
+	}
+	if i.Disabled != "" {
+		str = disabledStyle.Render(str + " — " + i.Disabled)
+	}
 
 	fn := itemStyle.Render
 	if index == m.Index() {
@@ -113,9 +133,17 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.quitting = true
 			return m, tea.Quit
 
+		case "r":
+			if m.list.FilterState() == list.Filtering {
+				break
+			}
+			m.list.ResetFilter()
+			m.selectLast()
+			return m, nil
+
 		case "enter":
 			i, ok := m.list.SelectedItem().(item)
-			if !ok {
+			if !ok || i.Disabled != "" {
 				return m, nil
 			}
 			m.choice = i.Value
@@ -128,6 +156,18 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// selectLast moves the cursor to the option marked Last and reports whether
+// there is one.
+func (m *model) selectLast() bool {
+	for index, listItem := range m.list.Items() {
+		if i, ok := listItem.(item); ok && i.Last {
+			m.list.Select(index)
+			return true
+		}
+	}
+	return false
+}
+
 func (m model) View() tea.View {
 	v := tea.NewView("\n" + m.list.View())
 	v.AltScreen = true
@@ -66,3 +66,45 @@ func TestModelEnterSelectsValueWhenLabelsAreDuplicated(t *testing.T) {
 		t.Fatalf("Update() command message type = %T, want tea.QuitMsg", cmdMsg)
 	}
 }
+
+func TestModelEnterIgnoresDisabledOption(t *testing.T) {
+	items := []list.Item{
+		item{Option: Option{Label: "app", Value: "app"}},
+		item{Option: Option{Label: "sidecar", Value: "sidecar", Disabled: "container is STOPPED"}},
+	}
+	listModel := list.New(items, itemDelegate{}, listWidth, listHeight)
+	listModel.Select(1)
+
+	var enter tea.KeyMsg = tea.KeyPressMsg{Code: tea.KeyEnter}
+	updated, cmd := (model{list: listModel}).Update(enter)
+	got, ok := updated.(model)
+	if !ok {
+		t.Fatalf("Update() model type = %T, want listview.model", updated)
+	}
+	if got.choice != "" || cmd != nil {
+		t.Fatalf("Update() choice = %q, cmd = %v; want disabled option ignored", got.choice, cmd)
+	}
+}
+
+func TestModelRMovesTheCursorBackToTheLastChoice(t *testing.T) {
+	items := []list.Item{
+		item{Option: Option{Label: "production", Value: "production"}},
+		item{Option: Option{Label: "staging", Value: "staging", Last: true}},
+		item{Option: Option{Label: "sandbox", Value: "sandbox"}},
+	}
+	m := model{list: list.New(items, itemDelegate{}, listWidth, listHeight)}
+	if !m.selectLast() || m.list.Index() != 1 {
+		t.Fatalf("selectLast() index = %d, want the last choice", m.list.Index())
+	}
+	m.list.Select(2)
+
+	var r tea.KeyMsg = tea.KeyPressMsg{Code: 'r', Text: "r"}
+	updated, _ := m.Update(r)
+	got, ok := updated.(model)
+	if !ok {
+		t.Fatalf("Update() model type = %T, want listview.model", updated)
+	}
+	if got.list.Index() != 1 || got.choice != "" {
+		t.Fatalf("Update(r) index = %d, choice = %q; want the cursor on the last choice", got.list.Index(), got.choice)
+	}
+}
@@ -0,0 +1,271 @@
+package listview
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"charm.land/bubbles/v2/table"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+)
+
+const defaultRefreshInterval = 5 * time.Second
+
+var (
+	tableHeight = 14
+	footerStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	errorStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("203"))
+)
+
+// Row is one table row. Keys holds a sortable form of each cell; a missing
+// or empty key sorts by the cell itself. A non-empty Disabled greys the row
+// out with that reason and makes it unselectable.
+type Row struct {
+	Value    string
+	Cells    []string
+	Keys     []string
+	Disabled string
+}
+
+// Table is a picker laid out in columns. When Refresh is set, it runs every
+// RefreshInterval while the picker is open and its rows replace the shown
+// ones, keeping the cursor on the same value.
+type Table struct {
+	Title           string
+	Columns         []string
+	Rows            []Row
+	Refresh         func() ([]Row, error)
+	RefreshInterval time.Duration
+}
+
+// RenderTable presents t and returns the value of the selected row.
+func RenderTable(t Table) (string, bool, error) {
+	if len(t.Rows) == 0 {
+		return "", false, &NoItemsError{Title: t.Title}
+	}
+
+	p := tea.NewProgram(newTableModel(t, time.Now))
+	mi, err := p.Run()
+	if err != nil {
+		return "", false, err
+	}
+	m, ok := mi.(tableModel)
+	if !ok {
+		return "", false, fmt.Errorf("unexpected model type %T", mi)
+	}
+	return m.choice, m.quitting, nil
+}
+
+type refreshTickMsg struct{}
+
+type refreshedMsg struct {
+	rows []Row
+	err  error
+}
+
+type tableModel struct {
+	spec       Table
+	now        func() time.Time
+	rows       []Row
+	table      table.Model
+	sortColumn int
+	descending bool
+	refreshed  time.Time
+	refreshErr error
+	choice     string
+	quitting   bool
+}
+
+func newTableModel(t Table, now func() time.Time) tableModel {
+	if t.RefreshInterval <= 0 {
+		t.RefreshInterval = defaultRefreshInterval
+	}
+	m := tableModel{
+		spec:       t,
+		now:        now,
+		sortColumn: -1,
+		table: table.New(
+			table.WithFocused(true),
+			table.WithHeight(tableHeight),
+		),
+	}
+	m.setRows(t.Rows)
+	return m
+}
+
+func (m tableModel) Init() tea.Cmd {
+	return m.scheduleRefresh()
+}
+
+func (m tableModel) scheduleRefresh() tea.Cmd {
+	if m.spec.Refresh == nil {
+		return nil
+	}
+	return tea.Tick(m.spec.RefreshInterval, func(time.Time) tea.Msg {
+		return refreshTickMsg{}
+	})
+}
+
+func (m tableModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.table.SetWidth(msg.Width)
+		return m, nil
+
+	case refreshTickMsg:
+		refresh := m.spec.Refresh
+		return m, func() tea.Msg {
+			rows, err := refresh()
+			return refreshedMsg{rows: rows, err: err}
+		}
+
+	case refreshedMsg:
+		m.refreshErr = msg.err
+		if msg.err == nil {
+			m.refreshed = m.now()
+			m.setRows(msg.rows)
+		}
+		return m, m.scheduleRefresh()
+
+	case tea.KeyMsg:
+		switch keypress := msg.String(); keypress {
+		case "ctrl+c", "q":
+			m.quitting = true
+			return m, tea.Quit
+
+		case "enter":
+			if row, ok := m.selected(); ok && row.Disabled == "" {
+				m.choice = row.Value
+				return m, tea.Quit
+			}
+			return m, nil
+
+		case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+			column := int(keypress[0] - '1')
+			if column >= len(m.spec.Columns) {
+				return m, nil
+			}
+			if column == m.sortColumn {
+				m.descending = !m.descending
+			} else {
+				m.sortColumn, m.descending = column, false
+			}
+			m.setRows(m.rows)
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+func (m tableModel) View() tea.View {
+	v := tea.NewView(m.spec.Title + "\n\n" + m.table.View() + "\n" + m.footer())
+	v.AltScreen = true
+	return v
+}
+
+func (m tableModel) footer() string {
+	parts := []string{"enter select", "q quit", fmt.Sprintf("1-%d sort", min(len(m.spec.Columns), 9))}
+	if m.sortColumn >= 0 {
+		direction := "asc"
+		if m.descending {
+			direction = "desc"
+		}
+		parts = append(parts, fmt.Sprintf("sorted by %s %s", m.spec.Columns[m.sortColumn], direction))
+	}
+	if m.spec.Refresh != nil && !m.refreshed.IsZero() {
+		parts = append(parts, "refreshed "+m.refreshed.Format(time.TimeOnly))
+	}
+	footer := footerStyle.Render(strings.Join(parts, " · "))
+	if m.refreshErr != nil {
+		footer += "\n" + errorStyle.Render("refresh failed: "+m.refreshErr.Error())
+	}
+	return footer
+}
+
+func (m tableModel) selected() (Row, bool) {
+	cursor := m.table.Cursor()
+	if cursor < 0 || cursor >= len(m.rows) {
+		return Row{}, false
+	}
+	return m.rows[cursor], true
+}
+
+// setRows sorts rows and shows them, keeping the cursor on the previously
+// selected value when it is still present.
+func (m *tableModel) setRows(rows []Row) {
+	previous, hadSelection := m.selected()
+	rows = slices.Clone(rows)
+	if m.sortColumn >= 0 {
+		column, descending := m.sortColumn, m.descending
+		slices.SortStableFunc(rows, func(a, b Row) int {
+			order := cmp.Compare(sortKey(a, column), sortKey(b, column))
+			if descending {
+				return -order
+			}
+			return order
+		})
+	}
+	m.rows = rows
+
+	titles, cells := tableCells(m.spec.Columns, rows)
+	m.table.SetColumns(fitColumns(titles, cells))
+	m.table.SetRows(cells)
+	if hadSelection {
+		if i := slices.IndexFunc(rows, func(row Row) bool { return row.Value == previous.Value }); i >= 0 {
+			m.table.SetCursor(i)
+		}
+	}
+}
+
+func sortKey(row Row, column int) string {
+	if column < len(row.Keys) && row.Keys[column] != "" {
+		return row.Keys[column]
+	}
+	if column < len(row.Cells) {
+		return row.Cells[column]
+	}
+	return ""
+}
+
+// tableCells lays out rows for display. Disabled rows are greyed out and
+// explained in a trailing column that appears only when a row needs it.
+func tableCells(titles []string, rows []Row) ([]string, []table.Row) {
+	withReason := slices.ContainsFunc(rows, func(row Row) bool { return row.Disabled != "" })
+	if withReason {
+		titles = append(slices.Clone(titles), "DISABLED")
+	}
+	cells := make([]table.Row, 0, len(rows))
+	for _, row := range rows {
+		line := slices.Clone(row.Cells)
+		if withReason {
+			line = append(line, row.Disabled)
+		}
+		if row.Disabled != "" {
+			for i, cell := range line {
+				line[i] = disabledStyle.Render(cell)
+			}
+		}
+		cells = append(cells, line)
+	}
+	return titles, cells
+}
+
+func fitColumns(titles []string, rows []table.Row) []table.Column {
+	columns := make([]table.Column, len(titles))
+	for i, title := range titles {
+		width := lipgloss.Width(title)
+		for _, row := range rows {
+			if i < len(row) {
+				width = max(width, lipgloss.Width(row[i]))
+			}
+		}
+		columns[i] = table.Column{Title: title, Width: width}
+	}
+	return columns
+}
@@ -0,0 +1,164 @@
+package listview
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"charm.land/bubbles/v2/table"
+	tea "charm.land/bubbletea/v2"
+)
+
+func tableTestRows() []Row {
+	return []Row{
+		{Value: "b", Cells: []string{"b", "5m"}, Keys: []string{"", "2024-01-01T00:05:00Z"}},
+		{Value: "a", Cells: []string{"a", "1h"}, Keys: []string{"", "2024-01-01T00:00:00Z"}},
+		{Value: "c", Cells: []string{"c", "1m"}, Keys: []string{"", "2024-01-01T00:09:00Z"}},
+	}
+}
+
+func pressKey(t *testing.T, m tableModel, msg tea.Msg) (tableModel, tea.Cmd) {
+	t.Helper()
+	updated, cmd := m.Update(msg)
+	got, ok := updated.(tableModel)
+	if !ok {
+		t.Fatalf("Update() model type = %T, want listview.tableModel", updated)
+	}
+	return got, cmd
+}
+
+func rowValues(m tableModel) []string {
+	values := make([]string, 0, len(m.rows))
+	for _, row := range m.rows {
+		values = append(values, row.Value)
+	}
+	return values
+}
+
+func TestTableModelSortsByColumnKeyAndReverses(t *testing.T) {
+	m := newTableModel(Table{Title: "Tasks", Columns: []string{"TASK", "STARTED"}, Rows: tableTestRows()}, time.Now)
+	if got := rowValues(m); !reflect.DeepEqual(got, []string{"b", "a", "c"}) {
+		t.Fatalf("initial rows = %v, want input order", got)
+	}
+
+	m, _ = pressKey(t, m, tea.KeyPressMsg{Code: '2', Text: "2"})
+	if got := rowValues(m); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Fatalf("rows sorted by STARTED = %v, want by key", got)
+	}
+	m, _ = pressKey(t, m, tea.KeyPressMsg{Code: '2', Text: "2"})
+	if got := rowValues(m); !reflect.DeepEqual(got, []string{"c", "b", "a"}) {
+		t.Fatalf("rows reversed = %v", got)
+	}
+	if !strings.Contains(m.footer(), "sorted by STARTED desc") {
+		t.Fatalf("footer = %q, want sort state", m.footer())
+	}
+	m, _ = pressKey(t, m, tea.KeyPressMsg{Code: '1', Text: "1"})
+	if got := rowValues(m); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Fatalf("rows sorted by TASK cells = %v", got)
+	}
+	m, _ = pressKey(t, m, tea.KeyPressMsg{Code: '9', Text: "9"})
+	if m.sortColumn != 0 {
+		t.Fatalf("sort column after unknown column = %d, want unchanged", m.sortColumn)
+	}
+}
+
+func TestTableModelRefreshKeepsCursorOnSameValue(t *testing.T) {
+	refreshes := 0
+	refreshed := []Row{
+		{Value: "d", Cells: []string{"d", "now"}},
+		{Value: "c", Cells: []string{"c", "2m"}},
+	}
+	now := time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC)
+	m := newTableModel(Table{
+		Columns: []string{"TASK", "STARTED"},
+		Rows:    tableTestRows(),
+		Refresh: func() ([]Row, error) {
+			refreshes++
+			return refreshed, nil
+		},
+	}, func() time.Time { return now })
+	if m.Init() == nil {
+		t.Fatal("Init() = nil, want a refresh tick")
+	}
+	m.table.SetCursor(2)
+
+	m, cmd := pressKey(t, m, refreshTickMsg{})
+	if cmd == nil {
+		t.Fatal("refresh tick returned no command")
+	}
+	m, next := pressKey(t, m, cmd())
+	if refreshes != 1 || next == nil {
+		t.Fatalf("refreshes = %d, next tick = %v; want one refresh and another tick", refreshes, next)
+	}
+	if got := rowValues(m); !reflect.DeepEqual(got, []string{"d", "c"}) {
+		t.Fatalf("rows after refresh = %v", got)
+	}
+	if row, _ := m.selected(); row.Value != "c" || m.table.SelectedRow()[1] != "2m" {
+		t.Fatalf("selected after refresh = %#v, want updated row c", row)
+	}
+	if !strings.Contains(m.footer(), "refreshed 12:30:00") {
+		t.Fatalf("footer = %q, want refresh time", m.footer())
+	}
+}
+
+func TestTableModelRefreshErrorKeepsRows(t *testing.T) {
+	m := newTableModel(Table{Columns: []string{"TASK", "STARTED"}, Rows: tableTestRows(), Refresh: func() ([]Row, error) {
+		return nil, errors.New("throttled")
+	}}, time.Now)
+
+	m, _ = pressKey(t, m, refreshedMsg{err: errors.New("throttled")})
+	if len(m.rows) != 3 {
+		t.Fatalf("rows after failed refresh = %v, want previous rows", rowValues(m))
+	}
+	if !strings.Contains(m.footer(), "refresh failed: throttled") {
+		t.Fatalf("footer = %q, want refresh error", m.footer())
+	}
+}
+
+func TestTableModelEnterAndQuit(t *testing.T) {
+	m := newTableModel(Table{Columns: []string{"TASK", "STARTED"}, Rows: tableTestRows()}, time.Now)
+	if m.Init() != nil {
+		t.Fatal("Init() without Refresh returned a command")
+	}
+	m.table.SetCursor(1)
+	selected, cmd := pressKey(t, m, tea.KeyPressMsg{Code: tea.KeyEnter})
+	if selected.choice != "a" || cmd == nil {
+		t.Fatalf("enter choice = %q, cmd = %v; want a and quit", selected.choice, cmd)
+	}
+	quit, _ := pressKey(t, m, tea.KeyPressMsg{Code: 'q', Text: "q"})
+	if !quit.quitting || quit.choice != "" {
+		t.Fatalf("q quitting = %t, choice = %q", quit.quitting, quit.choice)
+	}
+}
+
+func TestTableModelIgnoresEnterOnDisabledRow(t *testing.T) {
+	rows := []Row{
+		{Value: "ready", Cells: []string{"ready", "1m"}},
+		{Value: "stopped", Cells: []string{"stopped", "2m"}, Disabled: "task is STOPPED"},
+	}
+	m := newTableModel(Table{Columns: []string{"TASK", "STARTED"}, Rows: rows}, time.Now)
+	if columns := m.table.Columns(); len(columns) != 3 || columns[2].Title != "DISABLED" {
+		t.Fatalf("columns = %#v, want a trailing DISABLED column", columns)
+	}
+	m.table.SetCursor(1)
+	selected, cmd := pressKey(t, m, tea.KeyPressMsg{Code: tea.KeyEnter})
+	if selected.choice != "" || cmd != nil {
+		t.Fatalf("enter on disabled row choice = %q, cmd = %v; want nothing", selected.choice, cmd)
+	}
+}
+
+func TestRenderTableReturnsNoItemsError(t *testing.T) {
+	var noItemsErr *NoItemsError
+	if _, _, err := RenderTable(Table{Title: "Tasks"}); !errors.As(err, &noItemsErr) {
+		t.Fatalf("RenderTable() error = %v, want *NoItemsError", err)
+	}
+}
+
+func TestFitColumnsUsesWidestCell(t *testing.T) {
+	columns := fitColumns([]string{"TASK", "AZ"}, []table.Row{{"abc", "ap-northeast-1a"}})
+	if columns[0].Width != 4 || columns[1].Width != len("ap-northeast-1a") {
+		t.Fatalf("fitColumns() = %#v", columns)
+	}
+}
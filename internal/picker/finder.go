@@ -0,0 +1,65 @@
+package picker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/wim-web/tnnl/internal/listview"
+)
+
+// Environment variables that give a finder the title and the column header
+// of the choice, for example fzf --prompt "$TNNL_PICKER_TITLE> ".
+const (
+	TitleEnvName  = "TNNL_PICKER_TITLE"
+	HeaderEnvName = "TNNL_PICKER_HEADER"
+)
+
+// runFinder pipes the numbered options through the finder command line, run
+// by sh, and reads the option number of the line it prints. The finder draws
+// on the terminal itself. Printing nothing, or exiting 1 or 130 as fzf and
+// peco do when cancelled, quits.
+func runFinder(ctx context.Context, command string, stderr io.Writer, title, header string, options []listview.Option) (string, bool, error) {
+	if _, err := exec.LookPath(strings.Fields(command)[0]); err != nil {
+		return "", false, fmt.Errorf(
+			"picker %q is not %s, %s, %s, or %s, nor a command on PATH: %w",
+			command, BackendAuto, BackendTUI, BackendPrompt, BackendNone, err,
+		)
+	}
+	lines := numbered(header, options)
+	if header != "" {
+		header, lines = lines[0], lines[1:]
+	}
+
+	finder := exec.CommandContext(ctx, "sh", "-c", command)
+	finder.Stdin = strings.NewReader(strings.Join(lines, "\n") + "\n")
+	finder.Stderr = stderr
+	finder.Env = append(os.Environ(), TitleEnvName+"="+title, HeaderEnvName+"="+header)
+	out, err := finder.Output()
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		return "", false, context.Cause(ctx)
+	case errors.As(err, &exitErr) && (exitErr.ExitCode() == 1 || exitErr.ExitCode() == 130):
+		return "", true, nil
+	case err != nil:
+		return "", false, fmt.Errorf("run picker %q: %w", command, err)
+	}
+
+	chosen, _, _ := strings.Cut(string(out), "\n")
+	if strings.TrimSpace(chosen) == "" {
+		return "", true, nil
+	}
+	i, ok := optionNumber(chosen, options)
+	if !ok {
+		return "", false, fmt.Errorf("picker %q printed %q, which is not one of the options", command, chosen)
+	}
+	if options[i].Disabled != "" {
+		return "", false, fmt.Errorf("%s: %s cannot be selected: %s", title, options[i].Label, options[i].Disabled)
+	}
+	return options[i].Value, false, nil
+}
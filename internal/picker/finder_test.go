@@ -0,0 +1,74 @@
+package picker
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/wim-web/tnnl/internal/listview"
+)
+
+func TestRunFinderReturnsTheValueOfThePrintedLine(t *testing.T) {
+	got, quit, err := runFinder(context.Background(), "grep worker", io.Discard, "Select an ECS task", "", testOptions)
+	if err != nil || quit || got != "arn:task-c" {
+		t.Fatalf("runFinder() = %q, %v, %v; want arn:task-c", got, quit, err)
+	}
+}
+
+func TestRunFinderPassesTheTitleAndHeader(t *testing.T) {
+	var stderr strings.Builder
+	p := Picker{ChooseTable: func(tbl listview.Table) (string, bool, error) {
+		header, options := tableOptions(tbl)
+		return runFinder(context.Background(), `echo "$TNNL_PICKER_TITLE|$TNNL_PICKER_HEADER" >&2; tail -n 1`, &stderr, tbl.Title, header, options)
+	}}
+
+	got, _, err := p.ChooseTable(listview.Table{
+		Title:   "Select an ECS task",
+		Columns: []string{"TASK", "AZ"},
+		Rows: []listview.Row{
+			{Value: "arn:task-a", Cells: []string{"task-a", "1a"}},
+			{Value: "arn:task-b", Cells: []string{"task-b", "1c"}},
+		},
+	})
+	if err != nil || got != "arn:task-b" {
+		t.Fatalf("ChooseTable() = %q, %v; want arn:task-b", got, err)
+	}
+	if want := "Select an ECS task|   TASK    AZ\n"; stderr.String() != want {
+		t.Fatalf("finder environment = %q, want %q", stderr.String(), want)
+	}
+}
+
+func TestRunFinderQuitsWhenCancelled(t *testing.T) {
+	for name, command := range map[string]string{
+		"no match": "grep nothing",
+		"escape":   "sh -c 'exit 130'",
+		"empty":    "true",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, quit, err := runFinder(context.Background(), command, io.Discard, "Select", "", testOptions)
+			if err != nil || !quit {
+				t.Fatalf("runFinder() quit = %v, error = %v; want quit", quit, err)
+			}
+		})
+	}
+}
+
+func TestRunFinderRejectsWhatIsNotAnOption(t *testing.T) {
+	for name, tt := range map[string]struct {
+		command string
+		want    string
+	}{
+		"missing command": {command: "fzf-that-is-not-installed", want: `picker "fzf-that-is-not-installed" is not auto, tui, prompt, or none, nor a command on PATH`},
+		"failure":         {command: "sh -c 'exit 2'", want: `run picker "sh -c 'exit 2'": exit status 2`},
+		"foreign line":    {command: "echo chosen", want: `picker "echo chosen" printed "chosen", which is not one of the options`},
+		"disabled":        {command: "sed -n 2p", want: "Select: web task-b cannot be selected: wait: task is PENDING"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := runFinder(context.Background(), tt.command, io.Discard, "Select", "", testOptions)
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Fatalf("runFinder() error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package picker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wim-web/tnnl/internal/listview"
+	"github.com/wim-web/tnnl/internal/target"
+)
+
+// NotInteractiveError reports a choice that could not be asked for because
+// the picker is none. It matches target.ErrAmbiguous: more than one option
+// was left and nothing chose between them.
+type NotInteractiveError struct {
+	Title string
+	// Lines shows the options as the prompt numbers them.
+	Lines []string
+}
+
+func (e *NotInteractiveError) Error() string {
+	return fmt.Sprintf(
+		"%s: no terminal to choose on; narrow the choice with flags or input JSON, or pass --picker prompt or a finder command such as fzf. The options are:\n  %s",
+		e.Title, strings.Join(e.Lines, "\n  "),
+	)
+}
+
+func (e *NotInteractiveError) Unwrap() error {
+	return target.ErrAmbiguous
+}
+
+// refuse is the none backend.
+func refuse(title, header string, options []listview.Option) (string, bool, error) {
+	return "", false, &NotInteractiveError{Title: title, Lines: numbered(header, options)}
+}
@@ -0,0 +1,168 @@
+// Package picker asks the user to choose among options. The backend is the
+// built-in list and table, a numbered prompt for dumb terminals, an external
+// fuzzy finder such as fzf or peco, or none, which fails with the options
+// when there is no one to ask.
+package picker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/wim-web/tnnl/internal/listview"
+)
+
+// Backends selected by --picker. Any other value is the command line of an
+// external finder that reads one option per line on standard input and
+// prints the chosen line.
+const (
+	// BackendAuto is tui when standard input and output are terminals and
+	// none otherwise.
+	BackendAuto   = "auto"
+	BackendTUI    = "tui"
+	BackendPrompt = "prompt"
+	BackendNone   = "none"
+)
+
+// EnvName is the environment variable that selects the backend when
+// --picker is not set.
+const EnvName = "TNNL_PICKER"
+
+// Picker holds the two ways of asking: a list of options, and a table of
+// rows. Both return the value of the choice, or true when the user quit.
+type Picker struct {
+	Choose      func(string, []listview.Option) (string, bool, error)
+	ChooseTable func(listview.Table) (string, bool, error)
+}
+
+// New returns the picker of backend, an empty one being auto. The prompt and
+// finders give up when ctx ends.
+func New(ctx context.Context, backend string) Picker {
+	return newPicker(ctx, backend, stdTerminal)
+}
+
+func newPicker(ctx context.Context, backend string, terminal func() bool) Picker {
+	backend = strings.TrimSpace(backend)
+	if backend == "" || backend == BackendAuto {
+		backend = BackendNone
+		if terminal() {
+			backend = BackendTUI
+		}
+	}
+	var ask asker
+	switch backend {
+	case BackendTUI:
+		return Picker{Choose: listview.RenderOptions, ChooseTable: listview.RenderTable}
+	case BackendPrompt:
+		ask = func(title, header string, options []listview.Option) (string, bool, error) {
+			return promptOnTerminal(ctx, title, header, options)
+		}
+	case BackendNone:
+		ask = refuse
+	default:
+		ask = func(title, header string, options []listview.Option) (string, bool, error) {
+			return runFinder(ctx, backend, os.Stderr, title, header, options)
+		}
+	}
+	return Picker{
+		Choose: func(title string, options []listview.Option) (string, bool, error) {
+			if len(options) == 0 {
+				return "", false, &listview.NoItemsError{Title: title}
+			}
+			return ask(title, "", options)
+		},
+		ChooseTable: func(t listview.Table) (string, bool, error) {
+			if len(t.Rows) == 0 {
+				return "", false, &listview.NoItemsError{Title: t.Title}
+			}
+			header, options := tableOptions(t)
+			return ask(t.Title, header, options)
+		},
+	}
+}
+
+// asker asks once. header, when set, names the columns of the labels.
+type asker func(title, header string, options []listview.Option) (string, bool, error)
+
+func stdTerminal() bool {
+	return term.IsTerminal(os.Stdin.Fd()) && term.IsTerminal(os.Stdout.Fd())
+}
+
+type contextKey struct{}
+
+// NewContext returns ctx carrying p.
+func NewContext(ctx context.Context, p Picker) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the picker ctx carries, the auto picker when it
+// carries none.
+func FromContext(ctx context.Context) Picker {
+	if p, ok := ctx.Value(contextKey{}).(Picker); ok {
+		return p
+	}
+	return New(ctx, BackendAuto)
+}
+
+// tableOptions lays the rows of t out in aligned columns and returns the
+// column header with one option per row. A table is read once; it is not
+// refreshed outside the tui.
+func tableOptions(t listview.Table) (string, []listview.Option) {
+	var laidOut strings.Builder
+	w := tabwriter.NewWriter(&laidOut, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(t.Columns, "\t"))
+	for _, row := range t.Rows {
+		fmt.Fprintln(w, strings.Join(row.Cells, "\t"))
+	}
+	w.Flush()
+	lines := strings.Split(strings.TrimSuffix(laidOut.String(), "\n"), "\n")
+	options := make([]listview.Option, 0, len(t.Rows))
+	for i, row := range t.Rows {
+		options = append(options, listview.Option{
+			Label:    strings.TrimRight(lines[i+1], " "),
+			Value:    row.Value,
+			Disabled: row.Disabled,
+		})
+	}
+	return strings.TrimRight(lines[0], " "), options
+}
+
+// numbered returns the lines that show options, each led by its number as
+// in the tui, with header indented above them when it is set. The last
+// choice is marked, and a disabled option ends with its reason.
+func numbered(header string, options []listview.Option) []string {
+	width := len(strconv.Itoa(len(options)))
+	lines := make([]string, 0, len(options)+1)
+	if header != "" {
+		lines = append(lines, strings.Repeat(" ", width+2)+header)
+	}
+	for i, option := range options {
+		line := fmt.Sprintf("%*d. %s", width, i+1, option.Label)
+		if option.Last {
+			line += " (last)"
+		}
+		if option.Disabled != "" {
+			line += " — " + option.Disabled
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// optionNumber parses the option number that leads a line, as typed at the
+// prompt or printed by a finder, and returns its index in options.
+func optionNumber(line string, options []listview.Option) (int, bool) {
+	line = strings.TrimSpace(line)
+	if end := strings.IndexByte(line, '.'); end >= 0 {
+		line = line[:end]
+	}
+	number, err := strconv.Atoi(line)
+	if err != nil || number < 1 || number > len(options) {
+		return 0, false
+	}
+	return number - 1, true
+}
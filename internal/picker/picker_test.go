@@ -0,0 +1,93 @@
+package picker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/wim-web/tnnl/internal/listview"
+	"github.com/wim-web/tnnl/internal/target"
+)
+
+var testOptions = []listview.Option{
+	{Label: "web task-a", Value: "arn:task-a"},
+	{Label: "web task-b", Value: "arn:task-b", Disabled: "wait: task is PENDING"},
+	{Label: "worker task-c", Value: "arn:task-c"},
+}
+
+func TestNewAutoFallsBackToNoneWithoutATerminal(t *testing.T) {
+	p := newPicker(context.Background(), "", func() bool { return false })
+
+	_, _, err := p.Choose("Select an ECS task", testOptions)
+	var refused *NotInteractiveError
+	if !errors.As(err, &refused) || !errors.Is(err, target.ErrAmbiguous) {
+		t.Fatalf("Choose() error = %v, want *NotInteractiveError matching target.ErrAmbiguous", err)
+	}
+	want := "Select an ECS task: no terminal to choose on; narrow the choice with flags or input JSON, " +
+		"or pass --picker prompt or a finder command such as fzf. The options are:\n" +
+		"  1. web task-a\n  2. web task-b — wait: task is PENDING\n  3. worker task-c"
+	if err.Error() != want {
+		t.Fatalf("Choose() error =\n%s\nwant\n%s", err, want)
+	}
+}
+
+func TestNewAutoUsesTheTUIOnATerminal(t *testing.T) {
+	p := newPicker(context.Background(), BackendAuto, func() bool { return true })
+
+	// The tui reports an empty list before it draws anything.
+	_, _, err := p.Choose("Select an ECS task", nil)
+	var noItems *listview.NoItemsError
+	if !errors.As(err, &noItems) {
+		t.Fatalf("Choose() error = %v, want *listview.NoItemsError", err)
+	}
+}
+
+func TestNoneListsTheRowsOfATable(t *testing.T) {
+	p := newPicker(context.Background(), BackendNone, func() bool { return true })
+
+	_, _, err := p.ChooseTable(listview.Table{
+		Title:   "Select an ECS task",
+		Columns: []string{"TASK", "REVISION"},
+		Rows: []listview.Row{
+			{Value: "arn:task-a", Cells: []string{"task-a", "web:12"}},
+			{Value: "arn:task-bb", Cells: []string{"task-bb", "web:3"}},
+		},
+	})
+	if err == nil || !strings.HasSuffix(err.Error(), "The options are:\n     TASK     REVISION\n  1. task-a   web:12\n  2. task-bb  web:3") {
+		t.Fatalf("ChooseTable() error = %v, want the rows in columns", err)
+	}
+}
+
+func TestNumberedAlignsWideNumbers(t *testing.T) {
+	options := make([]listview.Option, 10)
+	for i := range options {
+		options[i] = listview.Option{Label: "task"}
+	}
+
+	lines := numbered("TASK", options)
+	if lines[0] != "    TASK" || lines[1] != " 1. task" || lines[10] != "10. task" {
+		t.Fatalf("numbered() = %q", lines)
+	}
+	for line, want := range map[string]int{" 1. task": 0, "10": 9, "10. task": 9} {
+		if got, ok := optionNumber(line, options); !ok || got != want {
+			t.Errorf("optionNumber(%q) = %d, %v; want %d", line, got, ok, want)
+		}
+	}
+	for _, line := range []string{"", "0", "11", "task"} {
+		if _, ok := optionNumber(line, options); ok {
+			t.Errorf("optionNumber(%q) is a number, want none", line)
+		}
+	}
+}
+
+func TestFromContextReturnsThePickerItCarries(t *testing.T) {
+	ctx := NewContext(context.Background(), Picker{
+		Choose: func(string, []listview.Option) (string, bool, error) { return "carried", false, nil },
+	})
+
+	got, _, _ := FromContext(ctx).Choose("", testOptions)
+	if got != "carried" {
+		t.Fatalf("FromContext().Choose() = %q, want the carried picker", got)
+	}
+}
@@ -0,0 +1,86 @@
+package picker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/wim-web/tnnl/internal/listview"
+)
+
+// promptOnTerminal prompts on the controlling terminal, falling back to
+// stdin and stderr when there is none, so standard output stays free for
+// --output json.
+func promptOnTerminal(ctx context.Context, title, header string, options []listview.Option) (string, bool, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return prompt(ctx, os.Stdin, os.Stderr, title, header, options)
+	}
+	defer tty.Close()
+	return prompt(ctx, tty, tty, title, header, options)
+}
+
+// prompt lists options by number and reads the number of the choice until
+// one can be selected. An empty answer takes the last choice when one is
+// marked; q or the end of input quits.
+func prompt(ctx context.Context, r io.Reader, w io.Writer, title, header string, options []listview.Option) (string, bool, error) {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	fmt.Fprintln(w, title)
+	for _, line := range numbered(header, options) {
+		fmt.Fprintln(w, line)
+	}
+	last := slices.IndexFunc(options, func(option listview.Option) bool { return option.Last })
+	ask := fmt.Sprintf("Number (1-%d, q to quit): ", len(options))
+	if last >= 0 {
+		ask = fmt.Sprintf("Number (1-%d, Enter for %d, q to quit): ", len(options), last+1)
+	}
+	for {
+		fmt.Fprint(w, ask)
+		var (
+			line string
+			ok   bool
+		)
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(w)
+			return "", false, context.Cause(ctx)
+		case line, ok = <-lines:
+		}
+		if !ok {
+			fmt.Fprintln(w)
+			return "", true, nil
+		}
+		answer := strings.TrimSpace(line)
+		if answer == "q" {
+			return "", true, nil
+		}
+		i, valid := optionNumber(answer, options)
+		if answer == "" && last >= 0 {
+			i, valid = last, true
+		}
+		switch {
+		case !valid:
+			fmt.Fprintf(w, "%q is not a number from 1 to %d\n", answer, len(options))
+		case options[i].Disabled != "":
+			fmt.Fprintf(w, "%d cannot be selected: %s\n", i+1, options[i].Disabled)
+		default:
+			return options[i].Value, false, nil
+		}
+	}
+}
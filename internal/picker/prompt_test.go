@@ -0,0 +1,67 @@
+package picker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/wim-web/tnnl/internal/listview"
+)
+
+func TestPromptAsksAgainUntilAnOptionCanBeSelected(t *testing.T) {
+	var w strings.Builder
+	got, quit, err := prompt(context.Background(), strings.NewReader("x\n2\n4\n 3 \n"), &w, "Select an ECS task", "", testOptions)
+	if err != nil || quit || got != "arn:task-c" {
+		t.Fatalf("prompt() = %q, %v, %v; want arn:task-c", got, quit, err)
+	}
+	want := "Select an ECS task\n" +
+		"1. web task-a\n2. web task-b — wait: task is PENDING\n3. worker task-c\n" +
+		"Number (1-3, q to quit): \"x\" is not a number from 1 to 3\n" +
+		"Number (1-3, q to quit): 2 cannot be selected: wait: task is PENDING\n" +
+		"Number (1-3, q to quit): \"4\" is not a number from 1 to 3\n" +
+		"Number (1-3, q to quit): "
+	if w.String() != want {
+		t.Fatalf("prompt output =\n%s\nwant\n%s", w.String(), want)
+	}
+}
+
+func TestPromptQuitsOnQAndEndOfInput(t *testing.T) {
+	for name, answers := range map[string]string{"q": "q\n", "end of input": ""} {
+		t.Run(name, func(t *testing.T) {
+			_, quit, err := prompt(context.Background(), strings.NewReader(answers), io.Discard, "Select", "", testOptions)
+			if err != nil || !quit {
+				t.Fatalf("prompt() quit = %v, error = %v; want quit", quit, err)
+			}
+		})
+	}
+}
+
+func TestPromptEndsWithItsContext(t *testing.T) {
+	cause := errors.New("received SIGINT")
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(cause)
+	blocked, _ := io.Pipe()
+
+	_, _, err := prompt(ctx, blocked, io.Discard, "Select", "", testOptions)
+	if !errors.Is(err, cause) {
+		t.Fatalf("prompt() error = %v, want the cause of the context", err)
+	}
+}
+
+func TestPromptTakesTheLastChoiceOnEnter(t *testing.T) {
+	options := []listview.Option{
+		{Label: "staging", Value: "arn:staging", Last: true},
+		{Label: "production", Value: "arn:production"},
+	}
+	var w strings.Builder
+
+	got, quit, err := prompt(context.Background(), strings.NewReader("\n"), &w, "Select an ECS cluster", "", options)
+	if err != nil || quit || got != "arn:staging" {
+		t.Fatalf("prompt() = %q, %v, %v; want the last choice", got, quit, err)
+	}
+	if want := "Select an ECS cluster\n1. staging (last)\n2. production\nNumber (1-2, Enter for 1, q to quit): "; w.String() != want {
+		t.Fatalf("prompt output = %q, want %q", w.String(), want)
+	}
+}
@@ -0,0 +1,180 @@
+// Package recent keeps the targets chosen lately, so the pickers can offer
+// the last cluster, service, and container first.
+package recent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/wim-web/tnnl/internal/target"
+)
+
+const (
+	appName  = "tnnl"
+	fileName = "recent.json"
+	// limit is how many choices the history keeps.
+	limit = 20
+)
+
+// Choice is one target chosen by a command. Cluster is the cluster ARN,
+// which carries the account and Region the choice belongs to; Account and
+// Region repeat them for reading the file. Service is empty for a target
+// outside any service.
+type Choice struct {
+	Time      time.Time `json:"time"`
+	Account   string    `json:"account"`
+	Region    string    `json:"region"`
+	Cluster   string    `json:"cluster"`
+	Service   string    `json:"service,omitempty"`
+	Container string    `json:"container"`
+}
+
+// NewChoice returns the choice of resolved, made at now.
+func NewChoice(resolved target.Resolved, now time.Time) Choice {
+	cluster := aws.ToString(resolved.Task.ClusterArn)
+	if cluster == "" {
+		cluster = resolved.ECSCluster
+	}
+	choice := Choice{
+		Time:      now.UTC(),
+		Cluster:   cluster,
+		Service:   resolved.Service,
+		Container: resolved.ContainerName,
+	}
+	if parsed, err := arn.Parse(resolved.TaskARN); err == nil {
+		choice.Account, choice.Region = parsed.AccountID, parsed.Region
+	}
+	return choice
+}
+
+// History is the choices made lately, most recent first.
+type History []Choice
+
+// Add returns h with choice first, in place of an earlier choice of the same
+// target.
+func (h History) Add(choice Choice) History {
+	added := History{choice}
+	for _, earlier := range h {
+		if len(added) == limit {
+			break
+		}
+		if earlier.Cluster == choice.Cluster && earlier.Service == choice.Service && earlier.Container == choice.Container {
+			continue
+		}
+		added = append(added, earlier)
+	}
+	return added
+}
+
+// Clusters returns the clusters chosen lately, most recent first.
+func (h History) Clusters() []string {
+	return h.values(func(Choice) bool { return true }, func(c Choice) string { return c.Cluster })
+}
+
+// Services returns the services chosen lately in the cluster of ARN
+// cluster, most recent first. An empty service stands for the tasks of every
+// service. A bare cluster name matches no choice, as clusters of that name
+// in other accounts and Regions would.
+func (h History) Services(cluster string) []string {
+	return h.values(
+		func(c Choice) bool { return c.Cluster == cluster },
+		func(c Choice) string { return c.Service },
+	)
+}
+
+// Containers returns the containers chosen lately in the cluster of ARN
+// cluster and service, most recent first.
+func (h History) Containers(cluster, service string) []string {
+	return h.values(
+		func(c Choice) bool { return c.Cluster == cluster && c.Service == service },
+		func(c Choice) string { return c.Container },
+	)
+}
+
+func (h History) values(match func(Choice) bool, value func(Choice) string) []string {
+	var values []string
+	seen := map[string]bool{}
+	for _, choice := range h {
+		if !match(choice) || seen[value(choice)] {
+			continue
+		}
+		seen[value(choice)] = true
+		values = append(values, value(choice))
+	}
+	return values
+}
+
+type file struct {
+	Choices History `json:"choices"`
+}
+
+// Path returns $XDG_STATE_HOME/tnnl/recent.json, falling back to
+// ~/.local/state when XDG_STATE_HOME is unset.
+func Path() (string, error) {
+	return path(os.Getenv, os.UserHomeDir)
+}
+
+func path(getenv func(string) string, home func() (string, error)) (string, error) {
+	if dir := getenv("XDG_STATE_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, appName, fileName), nil
+	}
+	dir, err := home()
+	if err != nil {
+		return "", fmt.Errorf("locate recent targets file: %w", err)
+	}
+	return filepath.Join(dir, ".local", "state", appName, fileName), nil
+}
+
+// Load reads the history at path. A missing file has no choices.
+func Load(path string) (History, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read recent targets file: %w", err)
+	}
+	var loaded file
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return nil, fmt.Errorf("decode recent targets file %s: %w", path, err)
+	}
+	return loaded.Choices, nil
+}
+
+// Remember adds choice to the history at path, creating it readable only
+// by the user. The file is replaced whole, so a concurrent tnnl process never
+// reads half of it; of two processes remembering at once, one choice wins.
+func Remember(path string, choice Choice) error {
+	history, err := Load(path)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(file{Choices: history.Add(choice)}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode recent targets: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create recent targets directory: %w", err)
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), fileName+".*")
+	if err != nil {
+		return fmt.Errorf("write recent targets file: %w", err)
+	}
+	_, writeErr := temp.Write(append(data, '\n'))
+	if err := errors.Join(writeErr, temp.Close()); err != nil {
+		os.Remove(temp.Name())
+		return fmt.Errorf("write recent targets file %s: %w", path, err)
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		os.Remove(temp.Name())
+		return fmt.Errorf("write recent targets file %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package recent
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/wim-web/tnnl/internal/target"
+)
+
+const (
+	productionARN = "arn:aws:ecs:ap-northeast-1:123456789012:cluster/production"
+	stagingARN    = "arn:aws:ecs:ap-northeast-1:123456789012:cluster/staging"
+)
+
+func TestPathUsesXDGStateHomeOrHome(t *testing.T) {
+	tests := []struct {
+		name  string
+		state string
+		want  string
+	}{
+		{name: "XDG_STATE_HOME", state: "/state", want: "/state/tnnl/recent.json"},
+		{name: "relative XDG_STATE_HOME", state: "state", want: "/home/user/.local/state/tnnl/recent.json"},
+		{name: "unset", want: "/home/user/.local/state/tnnl/recent.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := path(func(string) string { return tt.state }, func() (string, error) { return "/home/user", nil })
+			if err != nil || got != tt.want {
+				t.Fatalf("path() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+	if _, err := path(func(string) string { return "" }, func() (string, error) { return "", errors.New("no home") }); err == nil {
+		t.Fatal("path() error = nil, want home directory error")
+	}
+}
+
+func TestNewChoiceTakesTheAccountAndRegionOfTheTask(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.FixedZone("JST", 9*60*60))
+	resolved := target.Resolved{
+		ECSCluster:    "production",
+		Task:          types.Task{ClusterArn: aws.String(productionARN)},
+		TaskARN:       "arn:aws:ecs:ap-northeast-1:123456789012:task/production/abc",
+		Service:       "web",
+		ContainerName: "app",
+	}
+
+	got := NewChoice(resolved, now)
+	want := Choice{Time: now.UTC(), Account: "123456789012", Region: "ap-northeast-1", Cluster: productionARN, Service: "web", Container: "app"}
+	if got != want {
+		t.Fatalf("NewChoice() = %#v, want %#v", got, want)
+	}
+}
+
+func TestHistoryOrdersChoicesWithinTheirCluster(t *testing.T) {
+	history := History{}.
+		Add(Choice{Cluster: productionARN, Service: "web", Container: "app"}).
+		Add(Choice{Cluster: stagingARN, Service: "worker", Container: "app"}).
+		Add(Choice{Cluster: productionARN, Service: "", Container: "debug"}).
+		Add(Choice{Cluster: productionARN, Service: "web", Container: "sidecar"}).
+		Add(Choice{Cluster: productionARN, Service: "web", Container: "app"})
+
+	if got, want := history.Clusters(), []string{productionARN, stagingARN}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Clusters() = %q, want %q", got, want)
+	}
+	if got, want := history.Services(productionARN), []string{"web", ""}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Services() = %q, want %q", got, want)
+	}
+	if got := history.Services("production"); got != nil {
+		t.Fatalf("Services(bare name) = %q, want none", got)
+	}
+	if got, want := history.Containers(productionARN, "web"), []string{"app", "sidecar"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Containers() = %q, want %q", got, want)
+	}
+	if got := history.Services("arn:aws:ecs:us-east-1:123456789012:cluster/production"); got != nil {
+		t.Fatalf("Services(other Region) = %q, want none", got)
+	}
+	if len(history) != 4 {
+		t.Fatalf("history has %d choices, want the repeated one once", len(history))
+	}
+}
+
+func TestHistoryKeepsTheLatestChoices(t *testing.T) {
+	var history History
+	for i := range limit + 5 {
+		history = history.Add(Choice{Cluster: productionARN, Container: string(rune('a' + i))})
+	}
+
+	if len(history) != limit || history[0].Container != string(rune('a'+limit+4)) {
+		t.Fatalf("history = %d choices starting at %q, want the latest %d", len(history), history[0].Container, limit)
+	}
+}
+
+func TestRememberAndLoadRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "recent.json")
+	if history, err := Load(path); err != nil || history != nil {
+		t.Fatalf("Load(missing) = %#v, %v, want no choices", history, err)
+	}
+	first := Choice{Time: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), Account: "123456789012", Region: "ap-northeast-1", Cluster: productionARN, Service: "web", Container: "app"}
+	second := Choice{Time: first.Time.Add(time.Hour), Cluster: stagingARN, Container: "app"}
+	for _, choice := range []Choice{first, second} {
+		if err := Remember(path, choice); err != nil {
+			t.Fatalf("Remember() error = %v", err)
+		}
+	}
+
+	history, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(history, History{second, first}) {
+		t.Fatalf("Load() = %#v, want the latest choice first", history)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("recent targets file mode = %v, %v, want 0600", info.Mode(), err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Fatalf("state directory has %d files, want no temporary files left", len(entries))
+	}
+}
+
+func TestLoadRejectsAMalformedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recent.json")
+	if err := os.WriteFile(path, []byte("not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Fatal("Load() error = nil, want decode error")
+	}
+	if err := Remember(path, Choice{Cluster: productionARN}); err == nil {
+		t.Fatal("Remember() error = nil, want the file left alone")
+	}
+}
@@ -0,0 +1,95 @@
+// Package savedtarget reads the user config file of named targets that
+// tnnl run replays.
+package savedtarget
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	appName  = "tnnl"
+	fileName = "targets.json"
+)
+
+// Target is one named entry. Input holds the same JSON that the command's
+// make-input-file generates.
+type Target struct {
+	Command    string          `json:"command"`
+	AWSProfile string          `json:"aws_profile"`
+	Region     string          `json:"region"`
+	Input      json.RawMessage `json:"input"`
+}
+
+// File is the decoded config file.
+type File struct {
+	Targets map[string]Target `json:"targets"`
+}
+
+// Path returns $XDG_CONFIG_HOME/tnnl/targets.json, falling back to
+// ~/.config when XDG_CONFIG_HOME is unset.
+func Path() (string, error) {
+	return path(os.Getenv, os.UserHomeDir)
+}
+
+func path(getenv func(string) string, home func() (string, error)) (string, error) {
+	if dir := getenv("XDG_CONFIG_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, appName, fileName), nil
+	}
+	dir, err := home()
+	if err != nil {
+		return "", fmt.Errorf("locate saved targets file: %w", err)
+	}
+	return filepath.Join(dir, ".config", appName, fileName), nil
+}
+
+// Load reads the config file at path. A missing file has no targets.
+func Load(path string) (File, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return File{}, nil
+	}
+	if err != nil {
+		return File{}, fmt.Errorf("open saved targets file %q: %w", path, err)
+	}
+	defer file.Close()
+
+	var loaded File
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&loaded); err != nil && err != io.EOF {
+		return File{}, fmt.Errorf("decode saved targets file %q: %w", path, err)
+	}
+	for name := range loaded.Targets {
+		if strings.TrimSpace(name) == "" {
+			return File{}, fmt.Errorf("decode saved targets file %q: target name must not be empty", path)
+		}
+	}
+	return loaded, nil
+}
+
+// Lookup returns the target saved as name.
+func (f File) Lookup(name string) (Target, error) {
+	target, ok := f.Targets[name]
+	if !ok {
+		return Target{}, fmt.Errorf("saved target %q not found", name)
+	}
+	return target, nil
+}
+
+// Names returns the saved target names in sorted order.
+func (f File) Names() []string {
+	names := make([]string, 0, len(f.Targets))
+	for name := range f.Targets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,105 @@
+package savedtarget
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPathPrefersAbsoluteXDGConfigHome(t *testing.T) {
+	tests := []struct {
+		name string
+		xdg  string
+		want string
+	}{
+		{name: "xdg", xdg: "/xdg", want: "/xdg/tnnl/targets.json"},
+		{name: "unset", xdg: "", want: "/home/user/.config/tnnl/targets.json"},
+		{name: "relative ignored", xdg: "relative", want: "/home/user/.config/tnnl/targets.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := path(
+				func(string) string { return tt.xdg },
+				func() (string, error) { return "/home/user", nil },
+			)
+			if err != nil {
+				t.Fatalf("path() error = %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("path() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathPreservesHomeError(t *testing.T) {
+	wantErr := errors.New("home sentinel")
+	_, err := path(func(string) string { return "" }, func() (string, error) { return "", wantErr })
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("path() error = %v, want error wrapping %v", err, wantErr)
+	}
+}
+
+func TestLoadReadsTargets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "targets.json")
+	content := `{"targets": {
+		"web": {"command": "portforward", "input": {"target_port_number": "8080"}},
+		"api-db": {"command": "remoteportforward", "aws_profile": "prod", "region": "ap-northeast-1", "input": {"host": "db.internal"}}
+	}}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got, want := loaded.Names(), []string{"api-db", "web"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Names() = %v, want %v", got, want)
+	}
+	target, err := loaded.Lookup("api-db")
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if target.Command != "remoteportforward" || target.AWSProfile != "prod" || target.Region != "ap-northeast-1" {
+		t.Fatalf("Lookup() = %#v", target)
+	}
+	if string(target.Input) != `{"host": "db.internal"}` {
+		t.Fatalf("Lookup() input = %s", target.Input)
+	}
+	if _, err := loaded.Lookup("missing"); err == nil || !strings.Contains(err.Error(), `"missing" not found`) {
+		t.Fatalf("Lookup(missing) error = %v", err)
+	}
+}
+
+func TestLoadMissingFileHasNoTargets(t *testing.T) {
+	loaded, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(loaded.Names()) != 0 {
+		t.Fatalf("Names() = %v, want none", loaded.Names())
+	}
+}
+
+func TestLoadRejectsInvalidFiles(t *testing.T) {
+	tests := map[string]string{
+		"unknown field": `{"targets": {"web": {"comand": "exec"}}}`,
+		"empty name":    `{"targets": {" ": {"command": "exec"}}}`,
+		"not JSON":      `targets`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "targets.json")
+			if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "decode saved targets file") {
+				t.Fatalf("Load() error = %v, want decode error", err)
+			}
+		})
+	}
+}
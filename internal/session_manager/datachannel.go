@@ -0,0 +1,354 @@
+package session_manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	openDataChannelSchemaVersion = "1.0"
+	// Clients from 1.1.70 on multiplex port sessions when the agent can.
+	nativeClientVersion = "1.2.0.0"
+
+	dataChannelResendInterval = time.Second
+	dataChannelResendAttempts = 30
+)
+
+var errDataChannelClosed = errors.New("data channel closed")
+
+type openDataChannelInput struct {
+	MessageSchemaVersion string `json:"MessageSchemaVersion"`
+	RequestID            string `json:"RequestId"`
+	TokenValue           string `json:"TokenValue"`
+	ClientID             string `json:"ClientId"`
+	ClientVersion        string `json:"ClientVersion"`
+}
+
+type acknowledgeContent struct {
+	MessageType         string `json:"AcknowledgedMessageType"`
+	MessageID           string `json:"AcknowledgedMessageId"`
+	SequenceNumber      int64  `json:"AcknowledgedMessageSequenceNumber"`
+	IsSequentialMessage bool   `json:"IsSequentialMessage"`
+}
+
+type channelClosedContent struct {
+	SessionID string `json:"SessionId"`
+	Output    string `json:"Output"`
+}
+
+type pendingMessage struct {
+	message  clientMessage
+	sentAt   time.Time
+	attempts int
+}
+
+// dataChannel carries sequenced, acknowledged stream messages between tnnl
+// and the SSM agent over the session WebSocket.
+type dataChannel struct {
+	conn           *websocket.Conn
+	resendInterval time.Duration
+
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	nextSeq  int64
+	unacked  map[int64]*pendingMessage
+	paused   bool
+	resumed  chan struct{}
+	closed   bool
+	closeErr error
+
+	expectedSeq int64
+	incoming    map[int64]clientMessage
+
+	messages chan clientMessage
+	done     chan struct{}
+}
+
+func openDataChannel(
+	ctx context.Context,
+	dialer *websocket.Dialer,
+	response SessionResponse,
+	clientVersion string,
+	resendInterval time.Duration,
+) (*dataChannel, error) {
+	conn, httpResponse, err := dialer.DialContext(ctx, response.StreamURL, nil)
+	if err != nil {
+		if httpResponse != nil {
+			return nil, fmt.Errorf("open data channel for session %s: status %d: %w", response.SessionID, httpResponse.StatusCode, err)
+		}
+		return nil, fmt.Errorf("open data channel for session %s: %w", response.SessionID, err)
+	}
+
+	open, err := json.Marshal(openDataChannelInput{
+		MessageSchemaVersion: openDataChannelSchemaVersion,
+		RequestID:            newMessageID().String(),
+		TokenValue:           response.TokenValue,
+		ClientID:             newMessageID().String(),
+		ClientVersion:        clientVersion,
+	})
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("encode open data channel request: %w", err)
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, open); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("send open data channel request for session %s: %w", response.SessionID, err)
+	}
+
+	c := &dataChannel{
+		conn:           conn,
+		resendInterval: resendInterval,
+		unacked:        make(map[int64]*pendingMessage),
+		resumed:        make(chan struct{}),
+		incoming:       make(map[int64]clientMessage),
+		messages:       make(chan clientMessage),
+		done:           make(chan struct{}),
+	}
+	close(c.resumed)
+	go c.readLoop()
+	go c.resendLoop()
+	return c, nil
+}
+
+// send writes one sequenced input_stream_data message and keeps it until the
+// agent acknowledges it.
+func (c *dataChannel) send(kind payloadType, payload []byte) error {
+	c.mu.Lock()
+	for c.paused && !c.closed {
+		resumed := c.resumed
+		c.mu.Unlock()
+		select {
+		case <-resumed:
+		case <-c.done:
+		}
+		c.mu.Lock()
+	}
+	if c.closed {
+		err := c.closeErr
+		c.mu.Unlock()
+		return err
+	}
+
+	message := clientMessage{
+		MessageType:    messageTypeInputStreamData,
+		SchemaVersion:  messageSchemaVersion,
+		CreatedDate:    uint64(time.Now().UnixMilli()),
+		SequenceNumber: c.nextSeq,
+		MessageID:      newMessageID(),
+		PayloadType:    kind,
+		Payload:        payload,
+	}
+	c.unacked[message.SequenceNumber] = &pendingMessage{message: message, sentAt: time.Now(), attempts: 1}
+	c.nextSeq++
+	c.mu.Unlock()
+
+	return c.write(message)
+}
+
+func (c *dataChannel) write(message clientMessage) error {
+	frame, err := message.marshal()
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := c.conn.WriteMessage(websocket.BinaryMessage, frame); err != nil {
+		return fmt.Errorf("write %s message %d: %w", message.MessageType, message.SequenceNumber, err)
+	}
+	return nil
+}
+
+func (c *dataChannel) acknowledge(message clientMessage) error {
+	content, err := json.Marshal(acknowledgeContent{
+		MessageType:         message.MessageType,
+		MessageID:           message.MessageID.String(),
+		SequenceNumber:      message.SequenceNumber,
+		IsSequentialMessage: true,
+	})
+	if err != nil {
+		return fmt.Errorf("encode acknowledgement: %w", err)
+	}
+	return c.write(clientMessage{
+		MessageType:   messageTypeAcknowledge,
+		SchemaVersion: messageSchemaVersion,
+		CreatedDate:   uint64(time.Now().UnixMilli()),
+		Flags:         acknowledgeFlags,
+		MessageID:     newMessageID(),
+		Payload:       content,
+	})
+}
+
+func (c *dataChannel) readLoop() {
+	defer close(c.messages)
+
+	for {
+		kind, frame, err := c.conn.ReadMessage()
+		if err != nil {
+			c.fail(fmt.Errorf("read data channel: %w", err))
+			return
+		}
+		if kind != websocket.BinaryMessage {
+			continue
+		}
+		message, err := unmarshalClientMessage(frame)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+
+		switch message.MessageType {
+		case messageTypeAcknowledge:
+			var ack acknowledgeContent
+			if err := json.Unmarshal(message.Payload, &ack); err != nil {
+				c.fail(fmt.Errorf("%w: decode acknowledgement: %v", errInvalidMessage, err))
+				return
+			}
+			c.mu.Lock()
+			delete(c.unacked, ack.SequenceNumber)
+			c.mu.Unlock()
+		case messageTypeOutputStreamData:
+			// A lost acknowledgement only makes the agent resend; a broken
+			// connection surfaces on the next read instead.
+			_ = c.acknowledge(message)
+			if !c.deliverInOrder(message) {
+				return
+			}
+		case messageTypeChannelClosed:
+			if !c.deliver(message) {
+				return
+			}
+		case messageTypePausePublication:
+			c.setPaused(true)
+		case messageTypeStartPublication:
+			c.setPaused(false)
+		}
+	}
+}
+
+func (c *dataChannel) deliverInOrder(message clientMessage) bool {
+	switch {
+	case message.SequenceNumber < c.expectedSeq:
+		return true
+	case message.SequenceNumber > c.expectedSeq:
+		c.incoming[message.SequenceNumber] = message
+		return true
+	}
+
+	if !c.deliver(message) {
+		return false
+	}
+	c.expectedSeq++
+	for {
+		next, ok := c.incoming[c.expectedSeq]
+		if !ok {
+			return true
+		}
+		delete(c.incoming, c.expectedSeq)
+		if !c.deliver(next) {
+			return false
+		}
+		c.expectedSeq++
+	}
+}
+
+func (c *dataChannel) deliver(message clientMessage) bool {
+	select {
+	case c.messages <- message:
+		return true
+	case <-c.done:
+		return false
+	}
+}
+
+func (c *dataChannel) setPaused(paused bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.paused == paused {
+		return
+	}
+	c.paused = paused
+	if paused {
+		c.resumed = make(chan struct{})
+		return
+	}
+	close(c.resumed)
+}
+
+func (c *dataChannel) resendLoop() {
+	ticker := time.NewTicker(c.resendInterval / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case now := <-ticker.C:
+			var resend []clientMessage
+			c.mu.Lock()
+			for seq, pending := range c.unacked {
+				if now.Sub(pending.sentAt) < c.resendInterval {
+					continue
+				}
+				if pending.attempts >= dataChannelResendAttempts {
+					c.mu.Unlock()
+					c.fail(fmt.Errorf("agent did not acknowledge input message %d after %d attempts", seq, pending.attempts))
+					return
+				}
+				pending.sentAt = now
+				pending.attempts++
+				resend = append(resend, pending.message)
+			}
+			c.mu.Unlock()
+
+			for _, message := range resend {
+				if err := c.write(message); err != nil {
+					c.fail(err)
+					return
+				}
+			}
+		}
+	}
+}
+
+// err reports why the channel stopped, or nil after a clean close.
+func (c *dataChannel) err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if errors.Is(c.closeErr, errDataChannelClosed) {
+		return nil
+	}
+	return c.closeErr
+}
+
+func (c *dataChannel) fail(err error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	c.closeErr = err
+	c.mu.Unlock()
+
+	close(c.done)
+	_ = c.conn.Close()
+}
+
+func (c *dataChannel) close() {
+	c.writeMu.Lock()
+	_ = c.conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		time.Now().Add(time.Second),
+	)
+	c.writeMu.Unlock()
+	c.fail(errDataChannelClosed)
+}
@@ -0,0 +1,154 @@
+package session_manager
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Message types exchanged over the Session Manager data channel.
+const (
+	messageTypeInputStreamData  = "input_stream_data"
+	messageTypeOutputStreamData = "output_stream_data"
+	messageTypeAcknowledge      = "acknowledge"
+	messageTypeChannelClosed    = "channel_closed"
+	messageTypeStartPublication = "start_publication"
+	messageTypePausePublication = "pause_publication"
+)
+
+type payloadType uint32
+
+const (
+	payloadTypeOutput            payloadType = 1
+	payloadTypeError             payloadType = 2
+	payloadTypeSize              payloadType = 3
+	payloadTypeParameter         payloadType = 4
+	payloadTypeHandshakeRequest  payloadType = 5
+	payloadTypeHandshakeResponse payloadType = 6
+	payloadTypeHandshakeComplete payloadType = 7
+	payloadTypeFlag              payloadType = 10
+	payloadTypeStdErr            payloadType = 11
+	payloadTypeExitCode          payloadType = 12
+)
+
+type portFlag uint32
+
+const (
+	flagDisconnectToPort   portFlag = 1
+	flagTerminateSession   portFlag = 2
+	flagConnectToPortError portFlag = 3
+)
+
+const (
+	messageSchemaVersion = 1
+	acknowledgeFlags     = 3
+
+	messageTypeLength   = 32
+	headerLengthOffset  = 0
+	messageTypeOffset   = 4
+	schemaVersionOffset = 36
+	createdDateOffset   = 40
+	sequenceOffset      = 48
+	flagsOffset         = 56
+	messageIDOffset     = 64
+	payloadDigestOffset = 80
+	payloadTypeOffset   = 112
+	payloadLengthOffset = 116
+	payloadOffset       = 120
+)
+
+var errInvalidMessage = errors.New("invalid data channel message")
+
+type messageID [16]byte
+
+func newMessageID() messageID {
+	var id messageID
+	_, _ = rand.Read(id[:])
+	id[6] = (id[6] & 0x0f) | 0x40
+	id[8] = (id[8] & 0x3f) | 0x80
+	return id
+}
+
+func (id messageID) String() string {
+	encoded := hex.EncodeToString(id[:])
+	return strings.Join([]string{
+		encoded[0:8], encoded[8:12], encoded[12:16], encoded[16:20], encoded[20:32],
+	}, "-")
+}
+
+// clientMessage is one binary frame on the data channel.
+type clientMessage struct {
+	MessageType    string
+	SchemaVersion  uint32
+	CreatedDate    uint64
+	SequenceNumber int64
+	Flags          uint64
+	MessageID      messageID
+	PayloadType    payloadType
+	Payload        []byte
+}
+
+func (m clientMessage) marshal() ([]byte, error) {
+	if len(m.MessageType) > messageTypeLength {
+		return nil, fmt.Errorf("%w: message type %q is longer than %d bytes", errInvalidMessage, m.MessageType, messageTypeLength)
+	}
+
+	buf := make([]byte, payloadOffset+len(m.Payload))
+	binary.BigEndian.PutUint32(buf[headerLengthOffset:], payloadLengthOffset)
+	copy(buf[messageTypeOffset:schemaVersionOffset], bytes.Repeat([]byte{' '}, messageTypeLength))
+	copy(buf[messageTypeOffset:], m.MessageType)
+	binary.BigEndian.PutUint32(buf[schemaVersionOffset:], m.SchemaVersion)
+	binary.BigEndian.PutUint64(buf[createdDateOffset:], m.CreatedDate)
+	binary.BigEndian.PutUint64(buf[sequenceOffset:], uint64(m.SequenceNumber))
+	binary.BigEndian.PutUint64(buf[flagsOffset:], m.Flags)
+	// Session Manager writes the least significant half of the UUID first.
+	copy(buf[messageIDOffset:], m.MessageID[8:])
+	copy(buf[messageIDOffset+8:], m.MessageID[:8])
+	digest := sha256.Sum256(m.Payload)
+	copy(buf[payloadDigestOffset:], digest[:])
+	binary.BigEndian.PutUint32(buf[payloadTypeOffset:], uint32(m.PayloadType))
+	binary.BigEndian.PutUint32(buf[payloadLengthOffset:], uint32(len(m.Payload)))
+	copy(buf[payloadOffset:], m.Payload)
+	return buf, nil
+}
+
+func unmarshalClientMessage(data []byte) (clientMessage, error) {
+	if len(data) < payloadOffset {
+		return clientMessage{}, fmt.Errorf("%w: %d bytes is shorter than the %d byte header", errInvalidMessage, len(data), payloadOffset)
+	}
+
+	headerLength := binary.BigEndian.Uint32(data[headerLengthOffset:])
+	if headerLength < payloadTypeOffset+4 || uint64(headerLength)+4 > uint64(len(data)) {
+		return clientMessage{}, fmt.Errorf("%w: header length %d is out of range", errInvalidMessage, headerLength)
+	}
+	payloadLength := binary.BigEndian.Uint32(data[headerLength:])
+	start := uint64(headerLength) + 4
+	if start+uint64(payloadLength) > uint64(len(data)) {
+		return clientMessage{}, fmt.Errorf("%w: payload length %d exceeds frame", errInvalidMessage, payloadLength)
+	}
+
+	m := clientMessage{
+		MessageType:    strings.TrimRight(string(data[messageTypeOffset:schemaVersionOffset]), " \x00"),
+		SchemaVersion:  binary.BigEndian.Uint32(data[schemaVersionOffset:]),
+		CreatedDate:    binary.BigEndian.Uint64(data[createdDateOffset:]),
+		SequenceNumber: int64(binary.BigEndian.Uint64(data[sequenceOffset:])),
+		Flags:          binary.BigEndian.Uint64(data[flagsOffset:]),
+		PayloadType:    payloadType(binary.BigEndian.Uint32(data[payloadTypeOffset:])),
+		Payload:        append([]byte(nil), data[start:start+uint64(payloadLength)]...),
+	}
+	copy(m.MessageID[8:], data[messageIDOffset:messageIDOffset+8])
+	copy(m.MessageID[:8], data[messageIDOffset+8:payloadDigestOffset])
+
+	if payloadLength > 0 {
+		digest := sha256.Sum256(m.Payload)
+		if !bytes.Equal(digest[:], data[payloadDigestOffset:payloadTypeOffset]) {
+			return clientMessage{}, fmt.Errorf("%w: payload digest mismatch for %s %d", errInvalidMessage, m.MessageType, m.SequenceNumber)
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,94 @@
+package session_manager
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestClientMessageRoundTrip(t *testing.T) {
+	want := clientMessage{
+		MessageType:    messageTypeInputStreamData,
+		SchemaVersion:  messageSchemaVersion,
+		CreatedDate:    1700000000123,
+		SequenceNumber: 42,
+		Flags:          1,
+		MessageID:      newMessageID(),
+		PayloadType:    payloadTypeOutput,
+		Payload:        []byte("ls -la\n"),
+	}
+
+	frame, err := want.marshal()
+	if err != nil {
+		t.Fatalf("marshal() error = %v", err)
+	}
+	if got := binary.BigEndian.Uint32(frame[headerLengthOffset:]); got != payloadLengthOffset {
+		t.Fatalf("header length = %d, want %d", got, payloadLengthOffset)
+	}
+	if got := frame[messageTypeOffset:schemaVersionOffset]; !bytes.HasSuffix(got, []byte("  ")) {
+		t.Fatalf("message type field = %q, want space padding", got)
+	}
+	if !bytes.Equal(frame[messageIDOffset:messageIDOffset+8], want.MessageID[8:]) {
+		t.Fatal("message ID does not start with its least significant half")
+	}
+
+	got, err := unmarshalClientMessage(frame)
+	if err != nil {
+		t.Fatalf("unmarshalClientMessage() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("round trip = %#v, want %#v", got, want)
+	}
+}
+
+func TestUnmarshalClientMessageRejectsInvalidFrames(t *testing.T) {
+	valid, err := clientMessage{MessageType: messageTypeOutputStreamData, Payload: []byte("hello")}.marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		frame func() []byte
+	}{
+		{name: "short header", frame: func() []byte { return valid[:payloadOffset-1] }},
+		{name: "payload beyond frame", frame: func() []byte { return valid[:len(valid)-1] }},
+		{name: "digest mismatch", frame: func() []byte {
+			frame := append([]byte(nil), valid...)
+			frame[len(frame)-1] ^= 0xff
+			return frame
+		}},
+		{name: "header length out of range", frame: func() []byte {
+			frame := append([]byte(nil), valid...)
+			binary.BigEndian.PutUint32(frame[headerLengthOffset:], 4096)
+			return frame
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := unmarshalClientMessage(tt.frame()); !errors.Is(err, errInvalidMessage) {
+				t.Fatalf("unmarshalClientMessage() error = %v, want errInvalidMessage", err)
+			}
+		})
+	}
+}
+
+func TestClientMessageRejectsLongMessageType(t *testing.T) {
+	message := clientMessage{MessageType: string(bytes.Repeat([]byte("x"), messageTypeLength+1))}
+	if _, err := message.marshal(); !errors.Is(err, errInvalidMessage) {
+		t.Fatalf("marshal() error = %v, want errInvalidMessage", err)
+	}
+}
+
+func TestMessageIDStringIsVersion4UUID(t *testing.T) {
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	for range 32 {
+		if id := newMessageID().String(); !pattern.MatchString(id) {
+			t.Fatalf("newMessageID().String() = %q, want version 4 UUID", id)
+		}
+	}
+}
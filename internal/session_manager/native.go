@@ -0,0 +1,778 @@
+package session_manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/gorilla/websocket"
+	"github.com/xtaci/smux"
+)
+
+const (
+	sessionTypeStandardStream         = "Standard_Stream"
+	sessionTypeInteractiveCommands    = "InteractiveCommands"
+	sessionTypeNonInteractiveCommands = "NonInteractiveCommands"
+	sessionTypePort                   = "Port"
+
+	portTypeLocalForwarding = "LocalPortForwarding"
+	// Agents newer than these versions multiplex port sessions with smux and
+	// no longer expect smux keepalives.
+	muxAgentVersion                   = "3.0.196.0"
+	smuxKeepAliveDisabledAgentVersion = "3.1.1511.0"
+
+	actionTypeKMSEncryption = "KMSEncryption"
+	actionTypeSessionType   = "SessionType"
+
+	actionStatusSuccess     = 1
+	actionStatusFailed      = 2
+	actionStatusUnsupported = 3
+
+	terminalSizePollInterval = 500 * time.Millisecond
+	streamReadBufferSize     = 1024
+)
+
+// errSessionTerminated ends Run without error when the agent terminates the
+// session.
+var errSessionTerminated = errors.New("session terminated by the agent")
+
+type handshakeRequest struct {
+	AgentVersion           string                  `json:"AgentVersion"`
+	RequestedClientActions []requestedClientAction `json:"RequestedClientActions"`
+}
+
+type requestedClientAction struct {
+	ActionType       string          `json:"ActionType"`
+	ActionParameters json.RawMessage `json:"ActionParameters"`
+}
+
+type sessionTypeRequest struct {
+	SessionType string          `json:"SessionType"`
+	Properties  json.RawMessage `json:"Properties"`
+}
+
+type processedClientAction struct {
+	ActionType   string `json:"ActionType"`
+	ActionStatus int    `json:"ActionStatus"`
+	Error        string `json:"Error,omitempty"`
+}
+
+type handshakeResponse struct {
+	ClientVersion          string                  `json:"ClientVersion"`
+	ProcessedClientActions []processedClientAction `json:"ProcessedClientActions"`
+	Errors                 []string                `json:"Errors"`
+}
+
+type handshakeComplete struct {
+	CustomerMessage string `json:"CustomerMessage"`
+}
+
+type portParameters struct {
+	PortNumber      string `json:"portNumber"`
+	LocalPortNumber string `json:"localPortNumber"`
+	Type            string `json:"type"`
+}
+
+type terminalSize struct {
+	Cols uint32 `json:"cols"`
+	Rows uint32 `json:"rows"`
+}
+
+// terminal is the local terminal boundary used by interactive sessions.
+type terminal interface {
+	IsTerminal() bool
+	MakeRaw() (func() error, error)
+	Size() (int, int, error)
+}
+
+type stdinTerminal struct{}
+
+func (stdinTerminal) IsTerminal() bool {
+	return term.IsTerminal(os.Stdin.Fd())
+}
+
+func (stdinTerminal) MakeRaw() (func() error, error) {
+	state, err := term.MakeRaw(os.Stdin.Fd())
+	if err != nil {
+		return nil, err
+	}
+	return func() error { return term.Restore(os.Stdin.Fd(), state) }, nil
+}
+
+func (stdinTerminal) Size() (int, int, error) {
+	return term.GetSize(os.Stdout.Fd())
+}
+
+// noTerminal stands in for standard input that is not a terminal.
+type noTerminal struct{}
+
+func (noTerminal) IsTerminal() bool { return false }
+
+func (noTerminal) MakeRaw() (func() error, error) {
+	return nil, errors.New("standard input is not a terminal")
+}
+
+func (noTerminal) Size() (int, int, error) {
+	return 0, 0, errors.New("standard input is not a terminal")
+}
+
+// Native attaches to Session Manager sessions by speaking the data channel
+// protocol directly, without session-manager-plugin.
+type Native struct {
+	dialer           *websocket.Dialer
+	stdin            io.Reader
+	stdout           io.Writer
+	stderr           io.Writer
+	terminal         terminal
+	recorder         Recorder
+	listen           func(string, string) (net.Listener, error)
+	sizePollInterval time.Duration
+	resendInterval   time.Duration
+}
+
+// NewNative returns a Native client bound to the process standard streams.
+func NewNative() *Native {
+	return &Native{
+		dialer:           websocket.DefaultDialer,
+		stdin:            os.Stdin,
+		stdout:           os.Stdout,
+		stderr:           os.Stderr,
+		terminal:         stdinTerminal{},
+		listen:           net.Listen,
+		sizePollInterval: terminalSizePollInterval,
+		resendInterval:   dataChannelResendInterval,
+	}
+}
+
+// WithStreams returns a copy of n that relays the given streams. The input is
+// not treated as a terminal, so it is neither put in raw mode nor sized.
+func (n *Native) WithStreams(stdin io.Reader, stdout, stderr io.Writer) Plugin {
+	configured := *n
+	configured.stdin, configured.stdout, configured.stderr = stdin, stdout, stderr
+	configured.terminal = noTerminal{}
+	return &configured
+}
+
+// WithRecorder returns a copy of n that copies the terminal traffic of
+// interactive sessions to recorder.
+func (n *Native) WithRecorder(recorder Recorder) Plugin {
+	configured := *n
+	configured.recorder = recorder
+	return &configured
+}
+
+// nativeSession handles stream payloads for one negotiated session type.
+type nativeSession interface {
+	start(context.Context, chan<- error) error
+	output(payloadType, []byte) error
+	close()
+}
+
+func (n *Native) Run(ctx context.Context, invocation Invocation) error {
+	return n.run(ctx, invocation, nil)
+}
+
+// RunListener runs the session like Run but serves a Port session on
+// listener instead of binding the requested local port. The session closes
+// listener when it ends.
+func (n *Native) RunListener(ctx context.Context, invocation Invocation, listener net.Listener) error {
+	return n.run(ctx, invocation, listener)
+}
+
+func (n *Native) run(ctx context.Context, invocation Invocation, listener net.Listener) error {
+	if err := invocation.Response.validate(); err != nil {
+		return err
+	}
+	sessionID := invocation.Response.SessionID
+
+	channel, err := openDataChannel(ctx, n.dialer, invocation.Response, nativeClientVersion, n.resendInterval)
+	if err != nil {
+		return err
+	}
+	defer channel.close()
+
+	sessionCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	sessionErrs := make(chan error, 4)
+
+	var (
+		session nativeSession
+		started bool
+	)
+	defer func() {
+		if session != nil {
+			session.close()
+		}
+	}()
+	startSession := func(s nativeSession) error {
+		session = s
+		if started {
+			return nil
+		}
+		started = true
+		if err := s.start(sessionCtx, sessionErrs); err != nil {
+			return fmt.Errorf("start native session %s: %w", sessionID, err)
+		}
+		return nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("run native session %s: %w", sessionID, ctx.Err())
+		case err := <-sessionErrs:
+			return fmt.Errorf("run native session %s: %w", sessionID, err)
+		case message, ok := <-channel.messages:
+			if !ok {
+				if err := channel.err(); err != nil {
+					return fmt.Errorf("run native session %s: %w", sessionID, err)
+				}
+				return nil
+			}
+
+			if message.MessageType == messageTypeChannelClosed {
+				var closed channelClosedContent
+				if err := json.Unmarshal(message.Payload, &closed); err == nil && closed.Output != "" {
+					fmt.Fprintf(n.stderr, "\n\nSessionId: %s : %s\n\n", sessionID, closed.Output)
+				}
+				return nil
+			}
+
+			switch message.PayloadType {
+			case payloadTypeHandshakeRequest:
+				negotiated, err := n.handshake(channel, message.Payload, listener)
+				if err != nil {
+					return fmt.Errorf("handshake for session %s: %w", sessionID, err)
+				}
+				session = negotiated
+			case payloadTypeHandshakeComplete:
+				var complete handshakeComplete
+				if err := json.Unmarshal(message.Payload, &complete); err == nil && complete.CustomerMessage != "" {
+					fmt.Fprintln(n.stderr, complete.CustomerMessage)
+				}
+				if session == nil {
+					session = n.newShellSession(channel, true)
+				}
+				if err := startSession(session); err != nil {
+					return err
+				}
+			case payloadTypeOutput, payloadTypeStdErr, payloadTypeFlag:
+				if session == nil {
+					// Agents without a handshake start streaming a shell directly.
+					session = n.newShellSession(channel, true)
+				}
+				if err := startSession(session); err != nil {
+					return err
+				}
+				if err := session.output(message.PayloadType, message.Payload); err != nil {
+					if errors.Is(err, errSessionTerminated) {
+						return nil
+					}
+					return fmt.Errorf("run native session %s: %w", sessionID, err)
+				}
+			}
+		}
+	}
+}
+
+func (n *Native) handshake(channel *dataChannel, payload []byte, listener net.Listener) (nativeSession, error) {
+	var request handshakeRequest
+	if err := json.Unmarshal(payload, &request); err != nil {
+		return nil, fmt.Errorf("%w: decode handshake request: %v", errInvalidMessage, err)
+	}
+
+	var (
+		session   nativeSession
+		processed []processedClientAction
+		failures  []error
+	)
+	for _, action := range request.RequestedClientActions {
+		switch action.ActionType {
+		case actionTypeSessionType:
+			var requested sessionTypeRequest
+			if err := json.Unmarshal(action.ActionParameters, &requested); err != nil {
+				failures = append(failures, fmt.Errorf("decode session type: %w", err))
+				processed = append(processed, failedAction(action.ActionType, actionStatusFailed, err.Error()))
+				continue
+			}
+			negotiated, err := n.sessionFor(channel, requested, request.AgentVersion, listener)
+			if err != nil {
+				failures = append(failures, err)
+				processed = append(processed, failedAction(action.ActionType, actionStatusUnsupported, err.Error()))
+				continue
+			}
+			session = negotiated
+			processed = append(processed, processedClientAction{ActionType: action.ActionType, ActionStatus: actionStatusSuccess})
+		case actionTypeKMSEncryption:
+			err := errors.New("KMS encryption is not supported by the native session client; use --session-client plugin")
+			failures = append(failures, err)
+			processed = append(processed, failedAction(action.ActionType, actionStatusUnsupported, err.Error()))
+		default:
+			err := fmt.Errorf("client action %q is not supported by the native session client", action.ActionType)
+			failures = append(failures, err)
+			processed = append(processed, failedAction(action.ActionType, actionStatusUnsupported, err.Error()))
+		}
+	}
+
+	response := handshakeResponse{
+		ClientVersion:          nativeClientVersion,
+		ProcessedClientActions: processed,
+		Errors:                 []string{},
+	}
+	for _, failure := range failures {
+		response.Errors = append(response.Errors, failure.Error())
+	}
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		return nil, fmt.Errorf("encode handshake response: %w", err)
+	}
+	if err := channel.send(payloadTypeHandshakeResponse, encoded); err != nil {
+		return nil, fmt.Errorf("send handshake response: %w", err)
+	}
+	if err := errors.Join(failures...); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func failedAction(actionType string, status int, message string) processedClientAction {
+	return processedClientAction{ActionType: actionType, ActionStatus: status, Error: message}
+}
+
+func (n *Native) sessionFor(channel *dataChannel, requested sessionTypeRequest, agentVersion string, listener net.Listener) (nativeSession, error) {
+	switch requested.SessionType {
+	case sessionTypeStandardStream, sessionTypeInteractiveCommands:
+		return n.newShellSession(channel, true), nil
+	case sessionTypeNonInteractiveCommands:
+		return n.newShellSession(channel, false), nil
+	case sessionTypePort:
+		var parameters portParameters
+		if len(requested.Properties) > 0 {
+			if err := json.Unmarshal(requested.Properties, &parameters); err != nil {
+				return nil, fmt.Errorf("decode port session properties: %w", err)
+			}
+		}
+		return &portSession{
+			native:       n,
+			channel:      channel,
+			parameters:   parameters,
+			agentVersion: agentVersion,
+			listener:     listener,
+		}, nil
+	default:
+		return nil, fmt.Errorf("session type %q is not supported by the native session client", requested.SessionType)
+	}
+}
+
+func (n *Native) newShellSession(channel *dataChannel, interactive bool) *shellSession {
+	return &shellSession{native: n, channel: channel, interactive: interactive}
+}
+
+// shellSession relays the local terminal to a remote shell or command.
+type shellSession struct {
+	native      *Native
+	channel     *dataChannel
+	interactive bool
+
+	startOnce sync.Once
+	restore   func() error
+}
+
+func (s *shellSession) start(ctx context.Context, errs chan<- error) error {
+	var startErr error
+	s.startOnce.Do(func() {
+		if s.interactive && s.native.terminal.IsTerminal() {
+			restore, err := s.native.terminal.MakeRaw()
+			if err != nil {
+				startErr = fmt.Errorf("set terminal raw mode: %w", err)
+				return
+			}
+			s.restore = restore
+			go s.watchSize(ctx)
+		}
+		go s.pumpInput(errs)
+	})
+	return startErr
+}
+
+func (s *shellSession) pumpInput(errs chan<- error) {
+	buf := make([]byte, streamReadBufferSize)
+	for {
+		n, err := s.native.stdin.Read(buf)
+		if n > 0 {
+			if s.native.recorder != nil {
+				s.native.recorder.Input(buf[:n])
+			}
+			if sendErr := s.channel.send(payloadTypeOutput, append([]byte(nil), buf[:n]...)); sendErr != nil {
+				reportSessionError(errs, sendErr)
+				return
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			reportSessionError(errs, fmt.Errorf("read standard input: %w", err))
+			return
+		}
+	}
+}
+
+func (s *shellSession) watchSize(ctx context.Context) {
+	ticker := time.NewTicker(s.native.sizePollInterval)
+	defer ticker.Stop()
+
+	var last terminalSize
+	for {
+		cols, rows, err := s.native.terminal.Size()
+		if err == nil && cols > 0 && rows > 0 {
+			current := terminalSize{Cols: uint32(cols), Rows: uint32(rows)}
+			if current != last {
+				encoded, _ := json.Marshal(current)
+				if s.channel.send(payloadTypeSize, encoded) != nil {
+					return
+				}
+				if s.native.recorder != nil {
+					s.native.recorder.Resize(cols, rows)
+				}
+				last = current
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *shellSession) output(kind payloadType, payload []byte) error {
+	writer := s.native.stdout
+	switch kind {
+	case payloadTypeStdErr:
+		writer = s.native.stderr
+	case payloadTypeFlag:
+		return nil
+	}
+	if _, err := writer.Write(payload); err != nil {
+		return fmt.Errorf("write session output: %w", err)
+	}
+	if s.native.recorder != nil {
+		s.native.recorder.Output(payload)
+	}
+	return nil
+}
+
+func (s *shellSession) close() {
+	if s.restore != nil {
+		_ = s.restore()
+		s.restore = nil
+	}
+}
+
+// portSession forwards local TCP connections over a Port session. Agents that
+// support multiplexing carry every connection as its own smux stream; older
+// agents relay one connection at a time.
+type portSession struct {
+	native       *Native
+	channel      *dataChannel
+	parameters   portParameters
+	agentVersion string
+
+	mu        sync.Mutex
+	listener  net.Listener
+	conn      *portConn
+	mux       *smux.Session
+	muxPipe   net.Conn
+	connCount int
+}
+
+// portConn is an accepted local connection with its transfer counters.
+type portConn struct {
+	net.Conn
+	id       int
+	openedAt time.Time
+	sent     atomic.Int64
+	received atomic.Int64
+}
+
+func (s *portSession) start(ctx context.Context, errs chan<- error) error {
+	if s.listener == nil {
+		localPort := strings.TrimSpace(s.parameters.LocalPortNumber)
+		if localPort == "" {
+			localPort = "0"
+		}
+		listener, err := s.native.listen("tcp", net.JoinHostPort("127.0.0.1", localPort))
+		if err != nil {
+			return fmt.Errorf("listen on local port %s: %w", localPort, err)
+		}
+		s.mu.Lock()
+		s.listener = listener
+		s.mu.Unlock()
+	}
+
+	if s.multiplexed() {
+		if err := s.startMux(errs); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(s.native.stderr, "Port %s opened. Waiting for connections...\n", portOf(s.listener.Addr()))
+	go s.accept(ctx, errs)
+	return nil
+}
+
+func (s *portSession) multiplexed() bool {
+	return s.parameters.Type == portTypeLocalForwarding && versionAfter(s.agentVersion, muxAgentVersion)
+}
+
+// startMux runs an smux client whose frames travel as stream data payloads.
+func (s *portSession) startMux(errs chan<- error) error {
+	local, remote := net.Pipe()
+	config := smux.DefaultConfig()
+	config.KeepAliveDisabled = versionAfter(s.agentVersion, smuxKeepAliveDisabledAgentVersion)
+	mux, err := smux.Client(local, config)
+	if err != nil {
+		_ = local.Close()
+		_ = remote.Close()
+		return fmt.Errorf("start port multiplexer: %w", err)
+	}
+
+	s.mu.Lock()
+	s.mux = mux
+	s.muxPipe = remote
+	s.mu.Unlock()
+	go s.pumpMux(remote, errs)
+	return nil
+}
+
+func (s *portSession) pumpMux(remote net.Conn, errs chan<- error) {
+	buf := make([]byte, streamReadBufferSize)
+	for {
+		n, err := remote.Read(buf)
+		if n > 0 {
+			if sendErr := s.channel.send(payloadTypeOutput, append([]byte(nil), buf[:n]...)); sendErr != nil {
+				reportSessionError(errs, sendErr)
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *portSession) accept(ctx context.Context, errs chan<- error) {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+				reportSessionError(errs, fmt.Errorf("accept local connection: %w", err))
+			}
+			return
+		}
+		accepted := s.opened(conn)
+
+		if s.mux != nil {
+			go s.serveStream(accepted)
+			continue
+		}
+
+		s.mu.Lock()
+		s.conn = accepted
+		s.mu.Unlock()
+
+		err = s.relay(accepted)
+
+		s.mu.Lock()
+		s.conn = nil
+		s.mu.Unlock()
+		_ = accepted.Close()
+		s.closed(accepted)
+
+		if err != nil {
+			reportSessionError(errs, err)
+			return
+		}
+	}
+}
+
+// serveStream copies one connection over its own stream until either side
+// closes.
+func (s *portSession) serveStream(conn *portConn) {
+	defer s.closed(conn)
+	stream, err := s.mux.OpenStream()
+	if err != nil {
+		_ = conn.Close()
+		fmt.Fprintf(s.native.stderr, "Connection %d could not open a stream: %v\n", conn.id, err)
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		n, _ := io.Copy(conn, stream)
+		conn.received.Add(n)
+		_ = conn.Close()
+	}()
+	n, _ := io.Copy(stream, conn)
+	conn.sent.Add(n)
+	_ = stream.Close()
+	<-done
+}
+
+func (s *portSession) relay(conn *portConn) error {
+	buf := make([]byte, streamReadBufferSize)
+	for {
+		n, err := conn.Read(buf)
+		if n > 0 {
+			conn.sent.Add(int64(n))
+			if sendErr := s.channel.send(payloadTypeOutput, append([]byte(nil), buf[:n]...)); sendErr != nil {
+				return sendErr
+			}
+		}
+		if err != nil {
+			return s.channel.send(payloadTypeFlag, encodePortFlag(flagDisconnectToPort))
+		}
+	}
+}
+
+func (s *portSession) output(kind payloadType, payload []byte) error {
+	if kind == payloadTypeFlag {
+		return s.flag(payload)
+	}
+	if kind != payloadTypeOutput {
+		return nil
+	}
+	s.mu.Lock()
+	conn, muxPipe := s.conn, s.muxPipe
+	s.mu.Unlock()
+	if muxPipe != nil {
+		if _, err := muxPipe.Write(payload); err != nil {
+			return fmt.Errorf("write port multiplexer: %w", err)
+		}
+		return nil
+	}
+	if conn == nil {
+		return nil
+	}
+	// A local client that disconnected mid-write is not a session failure.
+	n, _ := conn.Write(payload)
+	conn.received.Add(int64(n))
+	return nil
+}
+
+// flag acts on a flag from the agent. A failed dial of the remote port closes
+// the local connection waiting on it; a terminated session ends Run.
+func (s *portSession) flag(payload []byte) error {
+	flag, ok := decodePortFlag(payload)
+	if !ok {
+		return nil
+	}
+	switch flag {
+	case flagConnectToPortError:
+		s.mu.Lock()
+		conn := s.conn
+		s.mu.Unlock()
+		if conn != nil {
+			fmt.Fprintf(s.native.stderr, "Connection %d could not reach the remote port; check the SSM Agent logs.\n", conn.id)
+			_ = conn.Close()
+		}
+	case flagTerminateSession:
+		return errSessionTerminated
+	}
+	return nil
+}
+
+func (s *portSession) opened(conn net.Conn) *portConn {
+	s.mu.Lock()
+	s.connCount++
+	accepted := &portConn{Conn: conn, id: s.connCount, openedAt: time.Now()}
+	s.mu.Unlock()
+	fmt.Fprintf(s.native.stderr, "Connection %d from %s opened.\n", accepted.id, conn.RemoteAddr())
+	return accepted
+}
+
+func (s *portSession) closed(conn *portConn) {
+	fmt.Fprintf(
+		s.native.stderr,
+		"Connection %d closed after %s: %d bytes sent, %d bytes received.\n",
+		conn.id, time.Since(conn.openedAt).Round(time.Millisecond), conn.sent.Load(), conn.received.Load(),
+	)
+}
+
+func (s *portSession) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener != nil {
+		_ = s.listener.Close()
+	}
+	if s.mux != nil {
+		_ = s.mux.Close()
+	}
+	if s.muxPipe != nil {
+		_ = s.muxPipe.Close()
+	}
+	if s.conn != nil {
+		_ = s.conn.Close()
+	}
+}
+
+// versionAfter reports whether a dotted version is strictly newer than
+// threshold. Missing or non-numeric parts count as zero.
+func versionAfter(version, threshold string) bool {
+	if strings.TrimSpace(version) == "" {
+		return false
+	}
+	left, right := strings.Split(version, "."), strings.Split(threshold, ".")
+	for i := range max(len(left), len(right)) {
+		a, b := versionPart(left, i), versionPart(right, i)
+		if a != b {
+			return a > b
+		}
+	}
+	return false
+}
+
+func versionPart(parts []string, i int) int {
+	if i >= len(parts) {
+		return 0
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(parts[i]))
+	return n
+}
+
+func encodePortFlag(flag portFlag) []byte {
+	return []byte{byte(flag >> 24), byte(flag >> 16), byte(flag >> 8), byte(flag)}
+}
+
+func decodePortFlag(payload []byte) (portFlag, bool) {
+	if len(payload) != 4 {
+		return 0, false
+	}
+	return portFlag(payload[0])<<24 | portFlag(payload[1])<<16 | portFlag(payload[2])<<8 | portFlag(payload[3]), true
+}
+
+func portOf(addr net.Addr) string {
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		return fmt.Sprint(tcp.Port)
+	}
+	return addr.String()
+}
+
+func reportSessionError(errs chan<- error, err error) {
+	select {
+	case errs <- err:
+	default:
+	}
+}
@@ -0,0 +1,788 @@
+package session_manager
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/xtaci/smux"
+)
+
+const (
+	fakeAgentToken = "fake-token"
+	fakeAgentLimit = 5 * time.Second
+)
+
+// fakeAgent is a local WebSocket server that plays the SSM agent side of the
+// data channel for one connection.
+type fakeAgent struct {
+	t      *testing.T
+	server *httptest.Server
+	done   chan struct{}
+}
+
+type agentConn struct {
+	t    *testing.T
+	conn *websocket.Conn
+	open openDataChannelInput
+
+	writeMu sync.Mutex
+	seq     int64
+
+	dropFirstInput bool
+	inputs         chan clientMessage
+	skipped        []clientMessage
+	acks           chan acknowledgeContent
+}
+
+func newFakeAgent(t *testing.T, script func(*agentConn)) *fakeAgent {
+	t.Helper()
+
+	agent := &fakeAgent{t: t, done: make(chan struct{})}
+	upgrader := websocket.Upgrader{}
+	agent.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(agent.done)
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		kind, frame, err := conn.ReadMessage()
+		if err != nil || kind != websocket.TextMessage {
+			t.Errorf("read open data channel request: kind=%d err=%v", kind, err)
+			return
+		}
+		a := &agentConn{
+			t:      t,
+			conn:   conn,
+			inputs: make(chan clientMessage, 64),
+			acks:   make(chan acknowledgeContent, 64),
+		}
+		if err := json.Unmarshal(frame, &a.open); err != nil {
+			t.Errorf("decode open data channel request: %v", err)
+			return
+		}
+		go a.readLoop()
+		script(a)
+	}))
+	t.Cleanup(agent.server.Close)
+	return agent
+}
+
+func (a *fakeAgent) invocation() Invocation {
+	return Invocation{
+		Response: SessionResponse{
+			SessionID:  "session-native",
+			StreamURL:  "ws" + strings.TrimPrefix(a.server.URL, "http"),
+			TokenValue: fakeAgentToken,
+		},
+		Region: "ap-northeast-1",
+		Target: "ecs:cluster_task_runtime",
+	}
+}
+
+func (a *fakeAgent) wait() {
+	a.t.Helper()
+	select {
+	case <-a.done:
+	case <-time.After(fakeAgentLimit):
+		a.t.Fatal("fake agent did not finish")
+	}
+}
+
+func (a *agentConn) readLoop() {
+	dropped := false
+	for {
+		_, frame, err := a.conn.ReadMessage()
+		if err != nil {
+			close(a.inputs)
+			return
+		}
+		message, err := unmarshalClientMessage(frame)
+		if err != nil {
+			a.t.Errorf("agent received invalid frame: %v", err)
+			continue
+		}
+		switch message.MessageType {
+		case messageTypeAcknowledge:
+			var ack acknowledgeContent
+			if err := json.Unmarshal(message.Payload, &ack); err != nil {
+				a.t.Errorf("decode client acknowledgement: %v", err)
+			}
+			a.acks <- ack
+		case messageTypeInputStreamData:
+			if a.dropFirstInput && !dropped {
+				dropped = true
+				continue
+			}
+			a.ack(message)
+			a.inputs <- message
+		}
+	}
+}
+
+func (a *agentConn) ack(message clientMessage) {
+	content, _ := json.Marshal(acknowledgeContent{
+		MessageType:         message.MessageType,
+		MessageID:           message.MessageID.String(),
+		SequenceNumber:      message.SequenceNumber,
+		IsSequentialMessage: true,
+	})
+	a.write(clientMessage{MessageType: messageTypeAcknowledge, MessageID: newMessageID(), Flags: acknowledgeFlags, Payload: content})
+}
+
+func (a *agentConn) write(message clientMessage) {
+	frame, err := message.marshal()
+	if err != nil {
+		a.t.Errorf("marshal agent message: %v", err)
+		return
+	}
+	a.writeMu.Lock()
+	defer a.writeMu.Unlock()
+	if err := a.conn.WriteMessage(websocket.BinaryMessage, frame); err != nil {
+		a.t.Errorf("write agent message: %v", err)
+	}
+}
+
+func (a *agentConn) sendAt(seq int64, kind payloadType, payload []byte) {
+	a.write(clientMessage{
+		MessageType:    messageTypeOutputStreamData,
+		SchemaVersion:  messageSchemaVersion,
+		SequenceNumber: seq,
+		MessageID:      newMessageID(),
+		PayloadType:    kind,
+		Payload:        payload,
+	})
+}
+
+func (a *agentConn) send(kind payloadType, payload []byte) {
+	a.sendAt(a.seq, kind, payload)
+	a.seq++
+}
+
+func (a *agentConn) sendJSON(kind payloadType, value any) {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		a.t.Errorf("encode agent payload: %v", err)
+		return
+	}
+	a.send(kind, payload)
+}
+
+func (a *agentConn) handshake(sessionType string, properties any) handshakeResponse {
+	a.t.Helper()
+	return a.handshakeAs("3.3.0.0", sessionType, properties)
+}
+
+func (a *agentConn) handshakeAs(agentVersion, sessionType string, properties any) handshakeResponse {
+	a.t.Helper()
+	parameters, _ := json.Marshal(sessionTypeRequest{SessionType: sessionType, Properties: mustJSON(a.t, properties)})
+	a.sendJSON(payloadTypeHandshakeRequest, handshakeRequest{
+		AgentVersion: agentVersion,
+		RequestedClientActions: []requestedClientAction{{
+			ActionType:       actionTypeSessionType,
+			ActionParameters: parameters,
+		}},
+	})
+	var response handshakeResponse
+	if err := json.Unmarshal(a.next(payloadTypeHandshakeResponse).Payload, &response); err != nil {
+		a.t.Errorf("decode handshake response: %v", err)
+	}
+	a.sendJSON(payloadTypeHandshakeComplete, handshakeComplete{CustomerMessage: "welcome"})
+	return response
+}
+
+func (a *agentConn) next(kind payloadType) clientMessage {
+	a.t.Helper()
+	for i, message := range a.skipped {
+		if message.PayloadType == kind {
+			a.skipped = append(a.skipped[:i], a.skipped[i+1:]...)
+			return message
+		}
+	}
+	timeout := time.After(fakeAgentLimit)
+	for {
+		select {
+		case message, ok := <-a.inputs:
+			if !ok {
+				a.t.Errorf("client closed before sending payload type %d", kind)
+				return clientMessage{}
+			}
+			if message.PayloadType == kind {
+				return message
+			}
+			a.skipped = append(a.skipped, message)
+		case <-timeout:
+			a.t.Errorf("timed out waiting for payload type %d", kind)
+			return clientMessage{}
+		}
+	}
+}
+
+func (a *agentConn) closeChannel(output string) {
+	payload, _ := json.Marshal(channelClosedContent{SessionID: "session-native", Output: output})
+	a.write(clientMessage{MessageType: messageTypeChannelClosed, MessageID: newMessageID(), Payload: payload})
+}
+
+func mustJSON(t *testing.T, value any) json.RawMessage {
+	if value == nil {
+		return nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("encode JSON: %v", err)
+	}
+	return encoded
+}
+
+type fakeTerminal struct {
+	mu       sync.Mutex
+	terminal bool
+	raw      int
+	restored int
+	cols     int
+	rows     int
+}
+
+func (f *fakeTerminal) IsTerminal() bool { return f.terminal }
+
+func (f *fakeTerminal) MakeRaw() (func() error, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.raw++
+	return func() error {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.restored++
+		return nil
+	}, nil
+}
+
+func (f *fakeTerminal) Size() (int, int, error) { return f.cols, f.rows, nil }
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func newTestNative(stdin io.Reader, stdout, stderr io.Writer, term terminal) *Native {
+	return &Native{
+		dialer:           websocket.DefaultDialer,
+		stdin:            stdin,
+		stdout:           stdout,
+		stderr:           stderr,
+		terminal:         term,
+		listen:           net.Listen,
+		sizePollInterval: 10 * time.Millisecond,
+		resendInterval:   50 * time.Millisecond,
+	}
+}
+
+func TestNativeRunsInteractiveShellSession(t *testing.T) {
+	var gotOpen openDataChannelInput
+	var gotSize terminalSize
+	var gotInput string
+	var gotResponse handshakeResponse
+	agent := newFakeAgent(t, func(a *agentConn) {
+		gotOpen = a.open
+		gotResponse = a.handshake(sessionTypeStandardStream, nil)
+		if err := json.Unmarshal(a.next(payloadTypeSize).Payload, &gotSize); err != nil {
+			t.Errorf("decode size: %v", err)
+		}
+		gotInput = string(a.next(payloadTypeOutput).Payload)
+		a.send(payloadTypeOutput, []byte("hi\r\n"))
+		a.send(payloadTypeStdErr, []byte("warn"))
+		a.closeChannel("Exiting session")
+	})
+	term := &fakeTerminal{terminal: true, cols: 120, rows: 40}
+	var stdout, stderr syncBuffer
+	native := newTestNative(strings.NewReader("echo hi\n"), &stdout, &stderr, term)
+
+	if err := native.Run(context.Background(), agent.invocation()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	agent.wait()
+
+	if gotOpen.TokenValue != fakeAgentToken || gotOpen.MessageSchemaVersion != openDataChannelSchemaVersion || gotOpen.ClientID == "" {
+		t.Fatalf("open data channel request = %#v", gotOpen)
+	}
+	if len(gotResponse.ProcessedClientActions) != 1 || gotResponse.ProcessedClientActions[0].ActionStatus != actionStatusSuccess {
+		t.Fatalf("handshake response = %#v, want one successful action", gotResponse)
+	}
+	if gotSize != (terminalSize{Cols: 120, Rows: 40}) {
+		t.Fatalf("terminal size = %#v, want 120x40", gotSize)
+	}
+	if gotInput != "echo hi\n" {
+		t.Fatalf("agent input = %q, want stdin bytes", gotInput)
+	}
+	if got := stdout.String(); got != "hi\r\n" {
+		t.Fatalf("stdout = %q, want agent output", got)
+	}
+	for _, want := range []string{"warn", "welcome", "Exiting session"} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("stderr = %q, want %q", stderr.String(), want)
+		}
+	}
+	if term.raw != 1 || term.restored != 1 {
+		t.Fatalf("raw/restore calls = %d/%d, want 1/1", term.raw, term.restored)
+	}
+}
+
+type fakeRecorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *fakeRecorder) Input(p []byte)  { r.record("i " + string(p)) }
+func (r *fakeRecorder) Output(p []byte) { r.record("o " + string(p)) }
+func (r *fakeRecorder) Resize(cols, rows int) {
+	r.record(fmt.Sprintf("r %dx%d", cols, rows))
+}
+
+func (r *fakeRecorder) record(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func TestNativeRecordsInteractiveShellSession(t *testing.T) {
+	agent := newFakeAgent(t, func(a *agentConn) {
+		a.handshake(sessionTypeStandardStream, nil)
+		a.next(payloadTypeSize)
+		a.next(payloadTypeOutput)
+		a.send(payloadTypeOutput, []byte("hi\r\n"))
+		a.send(payloadTypeStdErr, []byte("warn"))
+		a.closeChannel("Exiting session")
+	})
+	recorder := &fakeRecorder{}
+	term := &fakeTerminal{terminal: true, cols: 120, rows: 40}
+	native := newTestNative(strings.NewReader("echo hi\n"), io.Discard, io.Discard, term).WithRecorder(recorder)
+
+	if err := native.Run(context.Background(), agent.invocation()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	agent.wait()
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	// Input and size are read concurrently, so only output order is fixed.
+	slices.Sort(recorder.events)
+	want := []string{"i echo hi\n", "o hi\r\n", "o warn", "r 120x40"}
+	if !reflect.DeepEqual(recorder.events, want) {
+		t.Fatalf("recorded events = %q, want %q", recorder.events, want)
+	}
+}
+
+func TestNativeDeliversOutputInSequenceOrder(t *testing.T) {
+	var acked []int64
+	agent := newFakeAgent(t, func(a *agentConn) {
+		a.sendAt(1, payloadTypeOutput, []byte("second"))
+		a.sendAt(0, payloadTypeOutput, []byte("first"))
+		a.sendAt(0, payloadTypeOutput, []byte("duplicate"))
+		for range 3 {
+			select {
+			case ack := <-a.acks:
+				acked = append(acked, ack.SequenceNumber)
+			case <-time.After(fakeAgentLimit):
+				t.Error("timed out waiting for acknowledgement")
+				return
+			}
+		}
+		a.closeChannel("")
+	})
+	var stdout syncBuffer
+	native := newTestNative(blockingReader{}, &stdout, io.Discard, &fakeTerminal{})
+
+	if err := native.Run(context.Background(), agent.invocation()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	agent.wait()
+
+	if got := stdout.String(); got != "firstsecond" {
+		t.Fatalf("stdout = %q, want in-order output without duplicates", got)
+	}
+	if len(acked) != 3 {
+		t.Fatalf("acknowledged sequence numbers = %v, want every received message", acked)
+	}
+}
+
+func TestNativeResendsUnacknowledgedInput(t *testing.T) {
+	var first clientMessage
+	agent := newFakeAgent(t, func(a *agentConn) {
+		a.dropFirstInput = true
+		a.send(payloadTypeOutput, []byte("$ "))
+		first = a.next(payloadTypeOutput)
+		a.closeChannel("")
+	})
+	native := newTestNative(strings.NewReader("x"), io.Discard, io.Discard, &fakeTerminal{})
+
+	if err := native.Run(context.Background(), agent.invocation()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	agent.wait()
+
+	if first.SequenceNumber != 0 || string(first.Payload) != "x" {
+		t.Fatalf("resent message = seq %d payload %q, want seq 0 payload x", first.SequenceNumber, first.Payload)
+	}
+}
+
+func TestNativeForwardsPortSessionConnectionsWithoutMultiplexing(t *testing.T) {
+	listening := make(chan string, 1)
+	var disconnect clientMessage
+	agent := newFakeAgent(t, func(a *agentConn) {
+		// Agents up to 3.0.196.0 relay a single connection without smux.
+		a.handshakeAs("3.0.196.0", sessionTypePort, portParameters{PortNumber: "80", LocalPortNumber: "0", Type: "LocalPortForwarding"})
+		if got := string(a.next(payloadTypeOutput).Payload); got != "ping" {
+			t.Errorf("agent received %q, want ping", got)
+		}
+		a.send(payloadTypeOutput, []byte("pong"))
+		disconnect = a.next(payloadTypeFlag)
+		a.closeChannel("")
+	})
+	native := newTestNative(blockingReader{}, io.Discard, io.Discard, &fakeTerminal{})
+	native.listen = func(network, address string) (net.Listener, error) {
+		if address != "127.0.0.1:0" {
+			t.Errorf("listen address = %q, want loopback local port from handshake", address)
+		}
+		listener, err := net.Listen(network, address)
+		if err == nil {
+			listening <- listener.Addr().String()
+		}
+		return listener, err
+	}
+
+	result := make(chan error, 1)
+	go func() { result <- native.Run(context.Background(), agent.invocation()) }()
+
+	var address string
+	select {
+	case address = <-listening:
+	case <-time.After(fakeAgentLimit):
+		t.Fatal("port session did not listen")
+	}
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatalf("dial local port: %v", err)
+	}
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatalf("write local connection: %v", err)
+	}
+	reply := make([]byte, 4)
+	if _, err := io.ReadFull(conn, reply); err != nil || string(reply) != "pong" {
+		t.Fatalf("local reply = %q, %v; want pong", reply, err)
+	}
+	_ = conn.Close()
+
+	if err := <-result; err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	agent.wait()
+	if got := binary.BigEndian.Uint32(disconnect.Payload); got != uint32(flagDisconnectToPort) {
+		t.Fatalf("flag = %d, want DisconnectToPort", got)
+	}
+}
+
+func TestNativeActsOnPortFlagsFromTheAgent(t *testing.T) {
+	listening := make(chan string, 1)
+	var disconnect clientMessage
+	agent := newFakeAgent(t, func(a *agentConn) {
+		a.handshakeAs("3.0.196.0", sessionTypePort, portParameters{PortNumber: "80", LocalPortNumber: "0", Type: "LocalPortForwarding"})
+		a.next(payloadTypeOutput)
+		a.send(payloadTypeFlag, encodePortFlag(flagConnectToPortError))
+		disconnect = a.next(payloadTypeFlag)
+		// The channel stays open: only the flag ends the session.
+		a.send(payloadTypeFlag, encodePortFlag(flagTerminateSession))
+		for range a.inputs {
+		}
+	})
+	var stderr syncBuffer
+	native := newTestNative(blockingReader{}, io.Discard, &stderr, &fakeTerminal{})
+	native.listen = func(network, address string) (net.Listener, error) {
+		listener, err := net.Listen(network, address)
+		if err == nil {
+			listening <- listener.Addr().String()
+		}
+		return listener, err
+	}
+
+	result := make(chan error, 1)
+	go func() { result <- native.Run(context.Background(), agent.invocation()) }()
+
+	var address string
+	select {
+	case address = <-listening:
+	case <-time.After(fakeAgentLimit):
+		t.Fatal("port session did not listen")
+	}
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatalf("dial local port: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatalf("write local connection: %v", err)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(fakeAgentLimit))
+	if n, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("local read after ConnectToPortError = %d, %v; want EOF", n, err)
+	}
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	case <-time.After(fakeAgentLimit):
+		t.Fatal("Run() did not return after TerminateSession")
+	}
+	agent.wait()
+	if got := binary.BigEndian.Uint32(disconnect.Payload); got != uint32(flagDisconnectToPort) {
+		t.Fatalf("flag = %d, want DisconnectToPort", got)
+	}
+	if !strings.Contains(stderr.String(), "could not reach the remote port") {
+		t.Fatalf("stderr = %q, want the failed remote port reported", stderr.String())
+	}
+}
+
+// serveMux plays the agent side of a multiplexed port session, echoing every
+// stream until the client closes it.
+func (a *agentConn) serveMux(streams int) {
+	agentEnd, clientEnd := net.Pipe()
+	defer agentEnd.Close()
+	go func() {
+		for message := range a.inputs {
+			if message.PayloadType == payloadTypeOutput {
+				_, _ = clientEnd.Write(message.Payload)
+			}
+		}
+		_ = clientEnd.Close()
+	}()
+	go func() {
+		buf := make([]byte, streamReadBufferSize)
+		for {
+			n, err := clientEnd.Read(buf)
+			if n > 0 {
+				a.send(payloadTypeOutput, append([]byte(nil), buf[:n]...))
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	config := smux.DefaultConfig()
+	config.KeepAliveDisabled = true
+	server, err := smux.Server(agentEnd, config)
+	if err != nil {
+		a.t.Errorf("start agent multiplexer: %v", err)
+		return
+	}
+	defer server.Close()
+	var echoes sync.WaitGroup
+	for range streams {
+		stream, err := server.AcceptStream()
+		if err != nil {
+			a.t.Errorf("accept agent stream: %v", err)
+			return
+		}
+		echoes.Go(func() {
+			_, _ = io.Copy(stream, stream)
+		})
+	}
+	echoes.Wait()
+}
+
+func TestNativeMultiplexesConnectionsOnOwnedListener(t *testing.T) {
+	agent := newFakeAgent(t, func(a *agentConn) {
+		a.handshakeAs("3.3.0.0", sessionTypePort, portParameters{PortNumber: "80", Type: "LocalPortForwarding"})
+		a.serveMux(2)
+		a.closeChannel("")
+	})
+	var stderr syncBuffer
+	native := newTestNative(blockingReader{}, io.Discard, &stderr, &fakeTerminal{})
+	native.listen = func(string, string) (net.Listener, error) {
+		t.Error("native client bound its own listener")
+		return nil, errors.New("unexpected listen")
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	result := make(chan error, 1)
+	go func() { result <- native.RunListener(context.Background(), agent.invocation(), listener) }()
+
+	// Both connections are open at once; each gets its own stream.
+	var conns []net.Conn
+	for range 2 {
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatalf("dial owned listener: %v", err)
+		}
+		conns = append(conns, conn)
+	}
+	for i, message := range []string{"first", "second"} {
+		if _, err := conns[i].Write([]byte(message)); err != nil {
+			t.Fatalf("write connection %d: %v", i, err)
+		}
+	}
+	for i, message := range []string{"first", "second"} {
+		reply := make([]byte, len(message))
+		if _, err := io.ReadFull(conns[i], reply); err != nil || string(reply) != message {
+			t.Fatalf("connection %d reply = %q, %v; want %q", i, reply, err, message)
+		}
+	}
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatalf("RunListener() error = %v", err)
+		}
+	case <-time.After(fakeAgentLimit):
+		t.Fatal("RunListener() did not return")
+	}
+	agent.wait()
+
+	deadline := time.Now().Add(fakeAgentLimit)
+	for _, want := range []string{"5 bytes sent, 5 bytes received", "6 bytes sent, 6 bytes received"} {
+		for !strings.Contains(stderr.String(), want) && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("stderr = %q, want it to contain %q", stderr.String(), want)
+		}
+	}
+	if _, err := listener.Accept(); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("listener Accept() after session error = %v, want net.ErrClosed", err)
+	}
+}
+
+func TestVersionAfter(t *testing.T) {
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{version: "3.0.197.0", want: true},
+		{version: "3.1.0.0", want: true},
+		{version: "3.0.196.0", want: false},
+		{version: "3.0.196", want: false},
+		{version: "2.3.1644.0", want: false},
+		{version: "", want: false},
+	}
+	for _, tt := range tests {
+		if got := versionAfter(tt.version, muxAgentVersion); got != tt.want {
+			t.Errorf("versionAfter(%q, %q) = %v, want %v", tt.version, muxAgentVersion, got, tt.want)
+		}
+	}
+}
+
+func TestNativeRejectsKMSEncryptedSessions(t *testing.T) {
+	var response handshakeResponse
+	agent := newFakeAgent(t, func(a *agentConn) {
+		a.sendJSON(payloadTypeHandshakeRequest, handshakeRequest{
+			RequestedClientActions: []requestedClientAction{{
+				ActionType:       actionTypeKMSEncryption,
+				ActionParameters: json.RawMessage(`{"KMSKeyId":"key"}`),
+			}},
+		})
+		if err := json.Unmarshal(a.next(payloadTypeHandshakeResponse).Payload, &response); err != nil {
+			t.Errorf("decode handshake response: %v", err)
+		}
+	})
+	native := newTestNative(blockingReader{}, io.Discard, io.Discard, &fakeTerminal{})
+
+	err := native.Run(context.Background(), agent.invocation())
+	if err == nil || !strings.Contains(err.Error(), "--session-client plugin") {
+		t.Fatalf("Run() error = %v, want KMS guidance", err)
+	}
+	agent.wait()
+	if len(response.ProcessedClientActions) != 1 || response.ProcessedClientActions[0].ActionStatus != actionStatusUnsupported {
+		t.Fatalf("handshake response = %#v, want unsupported KMS action", response)
+	}
+}
+
+func TestNativeReturnsContextCancellation(t *testing.T) {
+	release := make(chan struct{})
+	agent := newFakeAgent(t, func(a *agentConn) {
+		<-release
+	})
+	defer close(release)
+	ctx, cancel := context.WithCancel(context.Background())
+	native := newTestNative(blockingReader{}, io.Discard, io.Discard, &fakeTerminal{})
+
+	result := make(chan error, 1)
+	go func() { result <- native.Run(ctx, agent.invocation()) }()
+	cancel()
+
+	select {
+	case err := <-result:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Run() error = %v, want context.Canceled", err)
+		}
+	case <-time.After(fakeAgentLimit):
+		t.Fatal("Run() did not return after cancellation")
+	}
+}
+
+func TestNativeRejectsIncompleteResponseBeforeDialing(t *testing.T) {
+	native := newTestNative(blockingReader{}, io.Discard, io.Discard, &fakeTerminal{})
+	native.dialer = nil
+
+	err := native.Run(context.Background(), Invocation{Response: SessionResponse{SessionID: "s"}})
+	if err == nil || !strings.Contains(err.Error(), "missing id, stream URL, or token") {
+		t.Fatalf("Run() error = %v, want response validation", err)
+	}
+}
+
+func TestPreflightSelectsSessionClient(t *testing.T) {
+	plugin, err := Preflight(context.Background(), Options{Client: ClientNative})
+	if err != nil {
+		t.Fatalf("Preflight(native) error = %v", err)
+	}
+	if _, ok := plugin.(*Native); !ok {
+		t.Fatalf("Preflight(native) = %T, want *Native", plugin)
+	}
+
+	plugin, err = Preflight(context.Background(), Options{Client: "telnet"})
+	if err == nil || plugin != nil {
+		t.Fatalf("Preflight(telnet) = %v, %v; want nil plugin and error", plugin, err)
+	}
+}
+
+// blockingReader models an idle terminal that never produces input.
+type blockingReader struct{}
+
+func (blockingReader) Read([]byte) (int, error) {
+	select {}
+}
@@ -5,14 +5,25 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
+	"syscall"
 	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/wim-web/tnnl/internal/interrupt"
 )
 
 const CommandName = "session-manager-plugin"
 
+// pluginStopDelay is how long session-manager-plugin has to exit after it is
+// signaled to stop before it is killed.
+const pluginStopDelay = 5 * time.Second
+
 type SessionResponse struct {
 	SessionID  string `json:"SessionId"`
 	StreamURL  string `json:"StreamUrl"`
@@ -25,11 +36,18 @@ type Invocation struct {
 	Target   string
 }
 
+func (r SessionResponse) validate() error {
+	if strings.TrimSpace(r.SessionID) == "" ||
+		strings.TrimSpace(r.StreamURL) == "" ||
+		strings.TrimSpace(r.TokenValue) == "" {
+		return errors.New("session response is missing id, stream URL, or token")
+	}
+	return nil
+}
+
 func (i Invocation) arguments(profile, endpoint string) ([]string, error) {
-	if strings.TrimSpace(i.Response.SessionID) == "" ||
-		strings.TrimSpace(i.Response.StreamURL) == "" ||
-		strings.TrimSpace(i.Response.TokenValue) == "" {
-		return nil, errors.New("session response is missing id, stream URL, or token")
+	if err := i.Response.validate(); err != nil {
+		return nil, err
 	}
 	if strings.TrimSpace(i.Region) == "" {
 		return nil, errors.New("AWS region is required for session-manager-plugin")
@@ -63,10 +81,56 @@ type Plugin interface {
 	Run(context.Context, Invocation) error
 }
 
+// ListenerPlugin is a session client that can serve a Port session on a
+// local listener owned by the caller, so the port cannot be taken between
+// choosing it and the session starting.
+type ListenerPlugin interface {
+	Plugin
+	RunListener(context.Context, Invocation, net.Listener) error
+}
+
+// ServeListener returns a Plugin whose every Run serves a fresh listener from
+// next. Run closes the listener it was given when the session ends.
+func ServeListener(plugin ListenerPlugin, next func() net.Listener) Plugin {
+	return listenerPlugin{plugin: plugin, next: next}
+}
+
+type listenerPlugin struct {
+	plugin ListenerPlugin
+	next   func() net.Listener
+}
+
+func (p listenerPlugin) Run(ctx context.Context, invocation Invocation) error {
+	listener := p.next()
+	defer listener.Close()
+	return p.plugin.RunListener(ctx, invocation, listener)
+}
+
+// Session clients selectable through Options.Client.
+const (
+	ClientPlugin = "plugin"
+	ClientNative = "native"
+)
+
+// Options selects and configures the session client returned by Preflight.
+type Options struct {
+	// Client is ClientPlugin, ClientNative, or empty for ClientPlugin.
+	Client string
+	// Profile and Endpoint are passed to session-manager-plugin. Empty values
+	// fall back to AWS_PROFILE and AWS_ENDPOINT_URL_SSM/AWS_ENDPOINT_URL.
+	Profile  string
+	Endpoint string
+}
+
 type Runner struct {
-	path     string
-	profile  string
-	endpoint string
+	path        string
+	profile     string
+	endpoint    string
+	credentials aws.CredentialsProvider
+	// stdin, stdout, and stderr replace the process streams when set.
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
 }
 
 type command interface {
@@ -81,14 +145,28 @@ type dependencies struct {
 	preflightLimit time.Duration
 }
 
-func Preflight(ctx context.Context) (Plugin, error) {
-	return preflight(ctx, dependencies{
-		lookPath: exec.LookPath,
-		commandContext: func(ctx context.Context, name string, args ...string) command {
-			return exec.CommandContext(ctx, name, args...)
-		},
-		preflightLimit: 3 * time.Second,
-	})
+// Preflight returns the session client selected by options. The external
+// plugin is verified before use; the native client needs no local binary.
+func Preflight(ctx context.Context, options Options) (Plugin, error) {
+	switch options.Client {
+	case "", ClientPlugin:
+		runner, err := preflight(ctx, dependencies{
+			lookPath: exec.LookPath,
+			commandContext: func(ctx context.Context, name string, args ...string) command {
+				return exec.CommandContext(ctx, name, args...)
+			},
+			preflightLimit: 3 * time.Second,
+		})
+		if err != nil {
+			return nil, err
+		}
+		runner.apply(options)
+		return runner, nil
+	case ClientNative:
+		return NewNative(), nil
+	default:
+		return nil, fmt.Errorf("session client must be %q or %q: %q", ClientPlugin, ClientNative, options.Client)
+	}
 }
 
 func preflight(ctx context.Context, deps dependencies) (*Runner, error) {
@@ -129,6 +207,148 @@ func preflight(ctx context.Context, deps dependencies) (*Runner, error) {
 	}, nil
 }
 
+// apply lets explicit options take precedence over the environment.
+func (r *Runner) apply(options Options) {
+	if options.Profile != "" {
+		r.profile = options.Profile
+	}
+	if options.Endpoint != "" {
+		r.endpoint = options.Endpoint
+	}
+}
+
+// WithCredentials returns plugin set up to hand credentials to
+// session-manager-plugin in place of its profile. The native client makes no
+// AWS calls of its own and is returned unchanged.
+func WithCredentials(plugin Plugin, credentials aws.CredentialsProvider) Plugin {
+	runner, ok := plugin.(*Runner)
+	if !ok || credentials == nil {
+		return plugin
+	}
+	configured := *runner
+	configured.credentials = credentials
+	return &configured
+}
+
+// StreamPlugin is a session client that can be attached to streams other
+// than the process standard streams.
+type StreamPlugin interface {
+	Plugin
+	WithStreams(stdin io.Reader, stdout, stderr io.Writer) Plugin
+}
+
+// WithStreams returns plugin attached to the given streams in place of the
+// process standard streams. Clients that cannot be attached are returned
+// unchanged.
+func WithStreams(plugin Plugin, stdin io.Reader, stdout, stderr io.Writer) Plugin {
+	client, ok := plugin.(StreamPlugin)
+	if !ok {
+		return plugin
+	}
+	return client.WithStreams(stdin, stdout, stderr)
+}
+
+// Recorder receives a copy of the terminal traffic of an interactive session:
+// what is typed, what is shown, and each terminal size.
+type Recorder interface {
+	Input([]byte)
+	Output([]byte)
+	Resize(cols, rows int)
+}
+
+// RecordPlugin is a session client that can copy its terminal traffic to a
+// Recorder.
+type RecordPlugin interface {
+	Plugin
+	WithRecorder(Recorder) Plugin
+}
+
+// WithRecorder returns plugin set up to record its sessions, and false when
+// the client cannot record: session-manager-plugin drives the terminal
+// itself, so only the native client sees the traffic.
+func WithRecorder(plugin Plugin, recorder Recorder) (Plugin, bool) {
+	client, ok := plugin.(RecordPlugin)
+	if !ok {
+		return plugin, false
+	}
+	return client.WithRecorder(recorder), true
+}
+
+// WithStreams returns a copy of r that runs session-manager-plugin on the
+// given streams.
+func (r *Runner) WithStreams(stdin io.Reader, stdout, stderr io.Writer) Plugin {
+	configured := *r
+	configured.stdin, configured.stdout, configured.stderr = stdin, stdout, stderr
+	return &configured
+}
+
+// runProfile is the profile argument of the plugin: none when it signs with
+// credentials.
+func (r *Runner) runProfile() string {
+	if r.credentials != nil {
+		return ""
+	}
+	return r.profile
+}
+
+// RedactedToken stands in for the session token in a Handoff.
+const RedactedToken = "REDACTED"
+
+// Handoff is how a session client would take over a session, shown by a
+// dry run instead of running it.
+type Handoff struct {
+	// Client is ClientPlugin or ClientNative.
+	Client string
+	// Command is the session-manager-plugin command line with the token
+	// redacted; the native client runs no command.
+	Command []string
+	// Credentials reports that session-manager-plugin would receive
+	// credentials in its environment in place of a profile.
+	Credentials bool
+}
+
+// Describe returns the handoff of invocation to plugin without running it.
+func Describe(plugin Plugin, invocation Invocation) (Handoff, error) {
+	switch client := plugin.(type) {
+	case *Runner:
+		invocation.Response.TokenValue = RedactedToken
+		arguments, err := invocation.arguments(client.runProfile(), client.endpoint)
+		if err != nil {
+			return Handoff{}, err
+		}
+		return Handoff{
+			Client:      ClientPlugin,
+			Command:     append([]string{client.path}, arguments...),
+			Credentials: client.credentials != nil,
+		}, nil
+	case *Native:
+		return Handoff{Client: ClientNative}, nil
+	default:
+		return Handoff{}, fmt.Errorf("session client %T cannot be described", plugin)
+	}
+}
+
+// credentialEnvironment replaces any credential or profile settings in
+// environ so the plugin signs with credentials alone.
+func credentialEnvironment(environ []string, credentials aws.Credentials) []string {
+	replaced := []string{"AWS_PROFILE", "AWS_DEFAULT_PROFILE", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN"}
+	environment := make([]string, 0, len(environ)+3)
+	for _, entry := range environ {
+		name, _, _ := strings.Cut(entry, "=")
+		if !slices.Contains(replaced, name) {
+			environment = append(environment, entry)
+		}
+	}
+	environment = append(environment,
+		"AWS_ACCESS_KEY_ID="+credentials.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY="+credentials.SecretAccessKey,
+	)
+	if credentials.SessionToken != "" {
+		environment = append(environment, "AWS_SESSION_TOKEN="+credentials.SessionToken)
+	}
+	return environment
+}
+
 func firstEnvironment(names ...string) string {
 	for _, name := range names {
 		if value := os.Getenv(name); value != "" {
@@ -139,15 +359,39 @@ func firstEnvironment(names ...string) string {
 }
 
 func (r *Runner) Run(ctx context.Context, invocation Invocation) error {
-	arguments, err := invocation.arguments(r.profile, r.endpoint)
+	arguments, err := invocation.arguments(r.runProfile(), r.endpoint)
 	if err != nil {
 		return err
 	}
 
+	var environment []string
+	if r.credentials != nil {
+		credentials, err := r.credentials.Retrieve(ctx)
+		if err != nil {
+			return fmt.Errorf("retrieve credentials for %s: %w", CommandName, err)
+		}
+		environment = credentialEnvironment(os.Environ(), credentials)
+	}
+
 	cmd := exec.CommandContext(ctx, r.path, arguments...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Env = environment
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if r.stdin != nil {
+		cmd.Stdin = r.stdin
+	}
+	if r.stdout != nil {
+		cmd.Stdout = r.stdout
+	}
+	if r.stderr != nil {
+		cmd.Stderr = r.stderr
+	}
+	cmd.Cancel = func() error {
+		if err := cmd.Process.Signal(stopSignal(ctx)); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = pluginStopDelay
 	if err := cmd.Run(); err != nil {
 		runErr := fmt.Errorf("run %s: %w", CommandName, err)
 		if contextErr := ctx.Err(); contextErr != nil {
@@ -157,3 +401,13 @@ func (r *Runner) Run(ctx context.Context, invocation Invocation) error {
 	}
 	return nil
 }
+
+// stopSignal is the signal that asks session-manager-plugin to exit once ctx
+// ends. The plugin sends SIGINT to an interactive shell as a keystroke, so
+// it gets SIGTERM in its place; SIGHUP is passed on as it is.
+func stopSignal(ctx context.Context) os.Signal {
+	if caught, ok := interrupt.Cause(ctx); ok && caught.Signal == syscall.SIGHUP {
+		return syscall.SIGHUP
+	}
+	return syscall.SIGTERM
+}
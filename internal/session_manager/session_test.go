@@ -1,18 +1,26 @@
 package session_manager
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"os/exec"
+	"os/signal"
 	"reflect"
+	"slices"
 	"strings"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/wim-web/tnnl/internal/interrupt"
 )
 
 const (
@@ -23,6 +31,8 @@ const (
 	helperModeFailure   = "failure"
 	helperModeBlock     = "block"
 	helperModeMarkStart = "mark-start"
+	helperModeEnviron   = "environ"
+	helperModeSignal    = "signal"
 
 	helperSynchronizationLimit = 5 * time.Second
 	helperPollInterval         = 10 * time.Millisecond
@@ -38,6 +48,11 @@ func TestMain(m *testing.M) {
 }
 
 func runHelperProcess(mode string) {
+	// The signal helper traps signals before it reports that it started.
+	received := make(chan os.Signal, 1)
+	if mode == helperModeSignal {
+		signal.Notify(received, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	}
 	if startedFile := os.Getenv(helperStartedEnv); startedFile != "" {
 		if err := os.WriteFile(startedFile, []byte("started"), 0o600); err != nil {
 			fmt.Fprintln(os.Stderr, err)
@@ -73,6 +88,27 @@ func runHelperProcess(mode string) {
 		}
 	case helperModeMarkStart:
 		os.Exit(0)
+	case helperModeSignal:
+		sig := <-received
+		if err := os.WriteFile(os.Getenv(helperArgumentsEnv), []byte(sig.String()), 0o600); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		os.Exit(0)
+	case helperModeEnviron:
+		recorded, err := json.Marshal(struct {
+			Arguments []string
+			Environ   []string
+		}{os.Args[1:], os.Environ()})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		if err := os.WriteFile(os.Getenv(helperArgumentsEnv), recorded, 0o600); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		os.Exit(0)
 	default:
 		fmt.Fprintf(os.Stderr, "unknown helper mode %q", mode)
 		os.Exit(2)
@@ -461,6 +497,35 @@ func TestRunnerRunPreservesDeadline(t *testing.T) {
 	assertWrappedProcessError(t, err)
 }
 
+func TestRunnerRunPassesSignalsOnToThePlugin(t *testing.T) {
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		received os.Signal
+		want     syscall.Signal
+	}{
+		{received: os.Interrupt, want: syscall.SIGTERM},
+		{received: syscall.SIGTERM, want: syscall.SIGTERM},
+		{received: syscall.SIGHUP, want: syscall.SIGHUP},
+	} {
+		receivedFile := t.TempDir() + "/received"
+		t.Setenv(helperModeEnv, helperModeSignal)
+		t.Setenv(helperArgumentsEnv, receivedFile)
+		ctx, cancel := context.WithCancelCause(context.Background())
+
+		runRunnerAfterHelperStarts(t, executable, ctx, func() { cancel(&interrupt.Error{Signal: tt.received}) })
+		got, err := os.ReadFile(receivedFile)
+		if err != nil {
+			t.Fatalf("%v: plugin did not record a signal: %v", tt.received, err)
+		}
+		if string(got) != tt.want.String() {
+			t.Fatalf("%v: plugin received %q, want %q", tt.received, got, tt.want.String())
+		}
+	}
+}
+
 func assertWrappedProcessError(t *testing.T, err error) {
 	t.Helper()
 	if err == nil || !strings.Contains(err.Error(), "run "+CommandName) {
@@ -616,3 +681,221 @@ func readTestFile(t *testing.T, file *os.File) string {
 	}
 	return string(contents)
 }
+
+type recordingListenerPlugin struct {
+	listeners []net.Listener
+}
+
+func (*recordingListenerPlugin) Run(context.Context, Invocation) error {
+	return errors.New("Run called instead of RunListener")
+}
+
+func (p *recordingListenerPlugin) RunListener(_ context.Context, _ Invocation, listener net.Listener) error {
+	p.listeners = append(p.listeners, listener)
+	return nil
+}
+
+func TestServeListenerServesAndClosesFreshListenerPerRun(t *testing.T) {
+	plugin := &recordingListenerPlugin{}
+	var served []*net.TCPListener
+	next := func() net.Listener {
+		listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		if err != nil {
+			t.Fatalf("listen: %v", err)
+		}
+		served = append(served, listener)
+		return listener
+	}
+
+	runner := ServeListener(plugin, next)
+	for range 2 {
+		if err := runner.Run(context.Background(), Invocation{}); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	}
+	if len(plugin.listeners) != 2 || plugin.listeners[0] == plugin.listeners[1] {
+		t.Fatalf("RunListener listeners = %v, want a fresh listener per run", plugin.listeners)
+	}
+	for i, listener := range served {
+		if _, err := listener.Accept(); !errors.Is(err, net.ErrClosed) {
+			t.Fatalf("listener %d Accept() error = %v, want net.ErrClosed", i, err)
+		}
+	}
+}
+
+func TestRunnerOptionsOverrideEnvironment(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		want    Runner
+	}{
+		{name: "empty options keep environment", want: Runner{profile: "env-profile", endpoint: "https://env.example"}},
+		{
+			name:    "explicit options win",
+			options: Options{Profile: "prod", Endpoint: "https://ssm.example"},
+			want:    Runner{profile: "prod", endpoint: "https://ssm.example"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := Runner{profile: "env-profile", endpoint: "https://env.example"}
+			runner.apply(tt.options)
+			if runner != tt.want {
+				t.Fatalf("runner = %#v, want %#v", runner, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunnerWithCredentialsPassesThemInsteadOfProfile(t *testing.T) {
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(helperModeEnv, helperModeEnviron)
+	recordFile := t.TempDir() + "/environ.json"
+	t.Setenv(helperArgumentsEnv, recordFile)
+	t.Setenv("AWS_PROFILE", "hub")
+	t.Setenv("AWS_ACCESS_KEY_ID", "hub-key")
+
+	credentials := aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+		return aws.Credentials{AccessKeyID: "role-key", SecretAccessKey: "role-secret", SessionToken: "role-token"}, nil
+	})
+	plugin := WithCredentials(&Runner{path: executable, profile: "hub", endpoint: "https://ssm.example"}, credentials)
+	if err := plugin.Run(context.Background(), validInvocation()); err != nil {
+		t.Fatal(err)
+	}
+
+	recordedJSON, err := os.ReadFile(recordFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recorded struct {
+		Arguments []string
+		Environ   []string
+	}
+	if err := json.Unmarshal(recordedJSON, &recorded); err != nil {
+		t.Fatal(err)
+	}
+	if recorded.Arguments[3] != "" || recorded.Arguments[5] != "https://ssm.example" {
+		t.Fatalf("profile/endpoint arguments = %q/%q, want empty profile and kept endpoint", recorded.Arguments[3], recorded.Arguments[5])
+	}
+	for _, want := range []string{"AWS_ACCESS_KEY_ID=role-key", "AWS_SECRET_ACCESS_KEY=role-secret", "AWS_SESSION_TOKEN=role-token"} {
+		if !slices.Contains(recorded.Environ, want) {
+			t.Errorf("plugin environment is missing %s", want)
+		}
+	}
+	for _, unwanted := range []string{"AWS_PROFILE=hub", "AWS_ACCESS_KEY_ID=hub-key"} {
+		if slices.Contains(recorded.Environ, unwanted) {
+			t.Errorf("plugin environment kept %s", unwanted)
+		}
+	}
+}
+
+func TestWithCredentialsLeavesOtherClientsUnchanged(t *testing.T) {
+	native := NewNative()
+	credentials := aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+		return aws.Credentials{}, nil
+	})
+	if got := WithCredentials(native, credentials); got != Plugin(native) {
+		t.Fatalf("WithCredentials(native) = %#v, want the native client unchanged", got)
+	}
+	runner := &Runner{path: "plugin"}
+	if got := WithCredentials(runner, nil); got != Plugin(runner) {
+		t.Fatalf("WithCredentials(runner, nil) = %#v, want the runner unchanged", got)
+	}
+}
+
+func TestRunnerWithStreamsUsesThemInsteadOfProcessStreams(t *testing.T) {
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(helperModeEnv, helperModeSuccess)
+	t.Setenv(helperArgumentsEnv, t.TempDir()+"/arguments.json")
+
+	runner := &Runner{path: executable}
+	var stdout, stderr bytes.Buffer
+	plugin := WithStreams(runner, strings.NewReader("input"), &stdout, &stderr)
+	if err := plugin.Run(context.Background(), validInvocation()); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "stdout:input" || stderr.String() != "stderr" {
+		t.Fatalf("streams = (%q, %q), want (%q, %q)", stdout.String(), stderr.String(), "stdout:input", "stderr")
+	}
+	if runner.stdin != nil || runner.stdout != nil || runner.stderr != nil {
+		t.Fatalf("WithStreams() changed the original runner: %#v", runner)
+	}
+}
+
+func TestWithStreamsDetachesNativeFromTheTerminal(t *testing.T) {
+	native := NewNative()
+	var stdout bytes.Buffer
+	configured, ok := WithStreams(native, strings.NewReader(""), &stdout, io.Discard).(*Native)
+	if !ok {
+		t.Fatalf("WithStreams(native) is not a native client")
+	}
+	if configured == native || configured.stdout != &stdout || configured.terminal.IsTerminal() {
+		t.Fatalf("WithStreams(native) = %#v, want a detached copy", configured)
+	}
+	if native.stdout != os.Stdout {
+		t.Fatalf("WithStreams() changed the original native client")
+	}
+}
+
+func TestWithRecorderOnlyAttachesTheNativeClient(t *testing.T) {
+	recorder := &fakeRecorder{}
+	native := NewNative()
+	configured, ok := WithRecorder(native, recorder)
+	if client, isNative := configured.(*Native); !ok || !isNative || client == native || client.recorder != recorder {
+		t.Fatalf("WithRecorder(native) = %#v, %v, want a recording copy", configured, ok)
+	}
+	if native.recorder != nil {
+		t.Fatalf("WithRecorder() changed the original native client")
+	}
+
+	runner := &Runner{path: "/usr/bin/session-manager-plugin"}
+	if configured, ok := WithRecorder(runner, recorder); ok || configured != runner {
+		t.Fatalf("WithRecorder(runner) = %#v, %v, want the runner unchanged and false", configured, ok)
+	}
+}
+
+func TestDescribeRedactsTheTokenOfThePluginCommand(t *testing.T) {
+	credentials := aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+		return aws.Credentials{}, nil
+	})
+	runner := &Runner{path: "/usr/bin/session-manager-plugin", profile: "hub", endpoint: "https://ssm.example"}
+	for _, tt := range []struct {
+		plugin      Plugin
+		profile     string
+		credentials bool
+	}{
+		{plugin: runner, profile: "hub"},
+		{plugin: WithCredentials(runner, credentials), profile: "", credentials: true},
+	} {
+		got, err := Describe(tt.plugin, validInvocation())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Client != ClientPlugin || got.Credentials != tt.credentials || len(got.Command) != 7 {
+			t.Fatalf("Describe() = %#v", got)
+		}
+		if got.Command[0] != runner.path || got.Command[4] != tt.profile || got.Command[6] != "https://ssm.example" {
+			t.Fatalf("command = %q", got.Command)
+		}
+		var response map[string]string
+		if err := json.Unmarshal([]byte(got.Command[1]), &response); err != nil {
+			t.Fatalf("decode response argument: %v", err)
+		}
+		if response["TokenValue"] != RedactedToken || response["SessionId"] != validInvocation().Response.SessionID {
+			t.Fatalf("response = %#v, want the token redacted", response)
+		}
+	}
+
+	if got, err := Describe(NewNative(), validInvocation()); err != nil || !reflect.DeepEqual(got, Handoff{Client: ClientNative}) {
+		t.Fatalf("Describe(native) = %#v, %v", got, err)
+	}
+	if _, err := Describe(ServeListener(NewNative(), nil), validInvocation()); err == nil {
+		t.Fatal("Describe(listener plugin) error = nil, want an error")
+	}
+}
@@ -0,0 +1,92 @@
+package target
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Location is an AWS profile and Region that clusters are discovered in.
+// Empty values stand for the default configuration.
+type Location struct {
+	Profile string
+	Region  string
+}
+
+func (l Location) String() string {
+	profile, region := l.Profile, l.Region
+	if profile == "" {
+		profile = "default profile"
+	}
+	if region == "" {
+		region = "default Region"
+	}
+	return profile + "/" + region
+}
+
+// DiscoveredCluster is a cluster found in one Location. Account and Region
+// come from the cluster ARN.
+type DiscoveredCluster struct {
+	ARN      string
+	Name     string
+	Account  string
+	Region   string
+	Location Location
+}
+
+// DiscoverClusters lists the clusters of every location concurrently and
+// returns them in location order. A cluster visible from several locations
+// is reported once, for the first of them.
+func DiscoverClusters(
+	ctx context.Context,
+	locations []Location,
+	list func(context.Context, Location) ([]string, error),
+) ([]DiscoveredCluster, error) {
+	results := make([][]string, len(locations))
+	errs := make([]error, len(locations))
+	var wg sync.WaitGroup
+	for i, location := range locations {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			clusters, err := list(ctx, location)
+			if err != nil {
+				errs[i] = fmt.Errorf("discover ECS clusters in %s: %w", location, err)
+				return
+			}
+			results[i] = clusters
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	var discovered []DiscoveredCluster
+	seen := make(map[string]struct{})
+	for i, clusters := range results {
+		for _, cluster := range clusters {
+			if _, ok := seen[cluster]; ok {
+				continue
+			}
+			seen[cluster] = struct{}{}
+			name, err := ClusterName(cluster)
+			if err != nil {
+				return nil, err
+			}
+			parsed, err := parseECSARN(cluster)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cluster ARN %q: %w", cluster, err)
+			}
+			discovered = append(discovered, DiscoveredCluster{
+				ARN:      cluster,
+				Name:     name,
+				Account:  parsed.AccountID,
+				Region:   parsed.Region,
+				Location: locations[i],
+			})
+		}
+	}
+	return discovered, nil
+}
@@ -0,0 +1,80 @@
+package target
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDiscoverClustersFansOutAndTagsLocation(t *testing.T) {
+	locations := []Location{
+		{Profile: "prod", Region: "ap-northeast-1"},
+		{Profile: "prod", Region: "us-east-1"},
+		{Profile: "stg", Region: "ap-northeast-1"},
+	}
+	clusters := map[Location][]string{
+		locations[0]: {"arn:aws:ecs:ap-northeast-1:111111111111:cluster/web"},
+		locations[1]: {"arn:aws:ecs:us-east-1:111111111111:cluster/web", "arn:aws:ecs:us-east-1:111111111111:cluster/batch"},
+		// The same account reached through another profile is reported once.
+		locations[2]: {"arn:aws:ecs:ap-northeast-1:111111111111:cluster/web", "arn:aws:ecs:ap-northeast-1:222222222222:cluster/web"},
+	}
+	var (
+		mu      sync.Mutex
+		started int
+	)
+	allStarted := make(chan struct{})
+	list := func(ctx context.Context, location Location) ([]string, error) {
+		mu.Lock()
+		started++
+		if started == len(locations) {
+			close(allStarted)
+		}
+		mu.Unlock()
+		select {
+		case <-allStarted:
+		case <-time.After(5 * time.Second):
+			return nil, errors.New("locations were not listed concurrently")
+		}
+		return clusters[location], nil
+	}
+
+	got, err := DiscoverClusters(context.Background(), locations, list)
+	if err != nil {
+		t.Fatalf("DiscoverClusters() error = %v", err)
+	}
+	want := []DiscoveredCluster{
+		{ARN: "arn:aws:ecs:ap-northeast-1:111111111111:cluster/web", Name: "web", Account: "111111111111", Region: "ap-northeast-1", Location: locations[0]},
+		{ARN: "arn:aws:ecs:us-east-1:111111111111:cluster/web", Name: "web", Account: "111111111111", Region: "us-east-1", Location: locations[1]},
+		{ARN: "arn:aws:ecs:us-east-1:111111111111:cluster/batch", Name: "batch", Account: "111111111111", Region: "us-east-1", Location: locations[1]},
+		{ARN: "arn:aws:ecs:ap-northeast-1:222222222222:cluster/web", Name: "web", Account: "222222222222", Region: "ap-northeast-1", Location: locations[2]},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("DiscoverClusters() = %#v, want %#v", got, want)
+	}
+}
+
+func TestDiscoverClustersReportsFailedLocations(t *testing.T) {
+	wantErr := errors.New("access denied")
+	_, err := DiscoverClusters(context.Background(), []Location{{Region: "us-east-1"}, {Profile: "stg"}}, func(_ context.Context, location Location) ([]string, error) {
+		if location.Profile == "stg" {
+			return nil, wantErr
+		}
+		return []string{"arn:aws:ecs:us-east-1:111111111111:cluster/web"}, nil
+	})
+	if !errors.Is(err, wantErr) || !strings.Contains(err.Error(), "stg/default Region") {
+		t.Fatalf("DiscoverClusters() error = %v, want failed location wrapping %v", err, wantErr)
+	}
+}
+
+func TestDiscoverClustersRejectsShortNames(t *testing.T) {
+	_, err := DiscoverClusters(context.Background(), []Location{{}}, func(context.Context, Location) ([]string, error) {
+		return []string{"web"}, nil
+	})
+	if err == nil || !strings.Contains(err.Error(), `"web"`) {
+		t.Fatalf("DiscoverClusters() error = %v, want invalid cluster ARN", err)
+	}
+}
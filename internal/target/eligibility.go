@@ -1,6 +1,7 @@
 package target
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -9,38 +10,203 @@ import (
 
 const runningStatus = "RUNNING"
 
-// EligibleContainers returns the containers ready for ECS Exec or port forwarding.
-func EligibleContainers(task types.Task) []types.Container {
-	if !task.EnableExecuteCommand ||
-		aws.ToString(task.LastStatus) != runningStatus ||
-		strings.TrimSpace(aws.ToString(task.TaskArn)) == "" {
+// Remedy says where an ineligibility is fixed.
+type Remedy string
+
+const (
+	// RemedyWait means the task or container is still starting.
+	RemedyWait Remedy = "wait"
+	// RemedyDeployment means the service or run-task call must enable
+	// execute command and start new tasks.
+	RemedyDeployment Remedy = "deployment"
+	// RemedyTaskDefinition means the task definition or container image is
+	// at fault.
+	RemedyTaskDefinition Remedy = "task definition"
+	// RemedyIAM means the task role is missing the Session Manager
+	// permissions the agent needs.
+	RemedyIAM Remedy = "IAM role"
+)
+
+// Reason is one rule a task or container fails.
+type Reason struct {
+	Code    string
+	Message string
+	Remedy  Remedy
+}
+
+func (r Reason) String() string {
+	return fmt.Sprintf("%s (fix: %s)", r.Message, r.Remedy)
+}
+
+// ContainerDiagnosis is the eligibility of one container.
+type ContainerDiagnosis struct {
+	Container types.Container
+	Reasons   []Reason
+}
+
+// Eligible reports whether the container is ready.
+func (d ContainerDiagnosis) Eligible() bool {
+	return len(d.Reasons) == 0
+}
+
+// TaskDiagnosis is the eligibility of one task. Reasons holds the task-level
+// failures; each container is diagnosed separately.
+type TaskDiagnosis struct {
+	Task       types.Task
+	Reasons    []Reason
+	Containers []ContainerDiagnosis
+}
+
+// Eligible reports whether the task has at least one ready container.
+func (d TaskDiagnosis) Eligible() bool {
+	return len(d.EligibleContainers()) > 0
+}
+
+// EligibleContainers returns the ready containers of an eligible task.
+func (d TaskDiagnosis) EligibleContainers() []types.Container {
+	if len(d.Reasons) > 0 {
 		return nil
 	}
-
 	var eligible []types.Container
-	for _, container := range task.Containers {
-		if strings.TrimSpace(aws.ToString(container.Name)) == "" ||
-			aws.ToString(container.LastStatus) != runningStatus ||
-			strings.TrimSpace(aws.ToString(container.RuntimeId)) == "" ||
-			!hasRunningExecuteCommandAgent(container.ManagedAgents) {
-			continue
+	for _, container := range d.Containers {
+		if container.Eligible() {
+			eligible = append(eligible, container.Container)
 		}
-		eligible = append(eligible, container)
 	}
 	return eligible
 }
 
-// IsEligibleTask reports whether a task contains at least one ready container.
-func IsEligibleTask(task types.Task) bool {
-	return len(EligibleContainers(task)) > 0
+// Summary explains in one line why the task is not eligible. It is empty
+// for an eligible task.
+func (d TaskDiagnosis) Summary() string {
+	if len(d.Reasons) > 0 {
+		return d.Reasons[0].String()
+	}
+	if d.Eligible() {
+		return ""
+	}
+	if len(d.Containers) == 0 {
+		return "task has no containers (fix: " + string(RemedyTaskDefinition) + ")"
+	}
+	for _, container := range d.Containers {
+		if len(container.Reasons) > 0 {
+			return fmt.Sprintf("container %s: %s", containerLabel(container.Container), container.Reasons[0])
+		}
+	}
+	return ""
+}
+
+// DiagnoseTask applies the eligibility rules to task and every container.
+func DiagnoseTask(task types.Task) TaskDiagnosis {
+	diagnosis := TaskDiagnosis{Task: task}
+	if strings.TrimSpace(aws.ToString(task.TaskArn)) == "" {
+		diagnosis.Reasons = append(diagnosis.Reasons, Reason{
+			Code:    "missing-task-arn",
+			Message: "task ARN is empty",
+			Remedy:  RemedyWait,
+		})
+	}
+	if status := aws.ToString(task.LastStatus); status != runningStatus {
+		diagnosis.Reasons = append(diagnosis.Reasons, Reason{
+			Code:    "task-not-running",
+			Message: fmt.Sprintf("task is %s, not RUNNING", statusLabel(status)),
+			Remedy:  RemedyWait,
+		})
+	}
+	if !task.EnableExecuteCommand {
+		diagnosis.Reasons = append(diagnosis.Reasons, Reason{
+			Code:    "execute-command-disabled",
+			Message: "execute command is not enabled for the task",
+			Remedy:  RemedyDeployment,
+		})
+	}
+	for _, container := range task.Containers {
+		diagnosis.Containers = append(diagnosis.Containers, diagnoseContainer(container))
+	}
+	return diagnosis
+}
+
+func diagnoseContainer(container types.Container) ContainerDiagnosis {
+	diagnosis := ContainerDiagnosis{Container: container}
+	if strings.TrimSpace(aws.ToString(container.Name)) == "" {
+		diagnosis.Reasons = append(diagnosis.Reasons, Reason{
+			Code:    "missing-container-name",
+			Message: "container name is empty",
+			Remedy:  RemedyWait,
+		})
+	}
+	if status := aws.ToString(container.LastStatus); status != runningStatus {
+		diagnosis.Reasons = append(diagnosis.Reasons, Reason{
+			Code:    "container-not-running",
+			Message: fmt.Sprintf("container is %s, not RUNNING", statusLabel(status)),
+			Remedy:  RemedyWait,
+		})
+	}
+	if strings.TrimSpace(aws.ToString(container.RuntimeId)) == "" {
+		diagnosis.Reasons = append(diagnosis.Reasons, Reason{
+			Code:    "missing-runtime-id",
+			Message: "container has no runtime ID yet",
+			Remedy:  RemedyWait,
+		})
+	}
+	if reason, ok := agentReason(container.ManagedAgents); ok {
+		diagnosis.Reasons = append(diagnosis.Reasons, reason)
+	}
+	return diagnosis
 }
 
-func hasRunningExecuteCommandAgent(agents []types.ManagedAgent) bool {
+// agentReason explains a missing or not running ExecuteCommandAgent. A
+// pending agent is still starting; one that stopped usually could not reach
+// Session Manager with the task role.
+func agentReason(agents []types.ManagedAgent) (Reason, bool) {
 	for _, agent := range agents {
-		if agent.Name == types.ManagedAgentNameExecuteCommandAgent &&
-			aws.ToString(agent.LastStatus) == runningStatus {
-			return true
+		if agent.Name != types.ManagedAgentNameExecuteCommandAgent {
+			continue
+		}
+		switch status := aws.ToString(agent.LastStatus); status {
+		case runningStatus:
+			return Reason{}, false
+		case "PENDING", "":
+			return Reason{
+				Code:    "agent-pending",
+				Message: fmt.Sprintf("ExecuteCommandAgent is %s", statusLabel(status)),
+				Remedy:  RemedyWait,
+			}, true
+		default:
+			return Reason{
+				Code:    "agent-not-running",
+				Message: fmt.Sprintf("ExecuteCommandAgent is %s; the task role needs ssmmessages:CreateControlChannel, CreateDataChannel, OpenControlChannel, and OpenDataChannel", status),
+				Remedy:  RemedyIAM,
+			}, true
 		}
 	}
-	return false
+	return Reason{
+		Code:    "agent-missing",
+		Message: "container has no ExecuteCommandAgent; the task started without execute command or the image lacks a supported platform",
+		Remedy:  RemedyDeployment,
+	}, true
+}
+
+func statusLabel(status string) string {
+	if strings.TrimSpace(status) == "" {
+		return "in an unknown state"
+	}
+	return status
+}
+
+func containerLabel(container types.Container) string {
+	if name := strings.TrimSpace(aws.ToString(container.Name)); name != "" {
+		return name
+	}
+	return "<unnamed>"
+}
+
+// EligibleContainers returns the containers ready for ECS Exec or port forwarding.
+func EligibleContainers(task types.Task) []types.Container {
+	return DiagnoseTask(task).EligibleContainers()
+}
+
+// IsEligibleTask reports whether a task contains at least one ready container.
+func IsEligibleTask(task types.Task) bool {
+	return DiagnoseTask(task).Eligible()
 }
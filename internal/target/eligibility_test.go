@@ -2,6 +2,7 @@ package target
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -147,6 +148,101 @@ func TestEligibleContainersReturnsOnlyReadyContainers(t *testing.T) {
 	}
 }
 
+func TestDiagnoseTaskExplainsEachRuleWithRemedy(t *testing.T) {
+	tests := []struct {
+		name       string
+		mutate     func(*types.Task)
+		wantCodes  []string
+		wantRemedy Remedy
+		wantInSum  string
+	}{
+		{
+			name:       "execute command disabled",
+			mutate:     func(task *types.Task) { task.EnableExecuteCommand = false },
+			wantCodes:  []string{"execute-command-disabled"},
+			wantRemedy: RemedyDeployment,
+			wantInSum:  "execute command is not enabled",
+		},
+		{
+			name:       "task provisioning",
+			mutate:     func(task *types.Task) { task.LastStatus = aws.String("PROVISIONING") },
+			wantCodes:  []string{"task-not-running"},
+			wantRemedy: RemedyWait,
+			wantInSum:  "task is PROVISIONING",
+		},
+		{
+			name:       "agent pending",
+			mutate:     func(task *types.Task) { task.Containers[0].ManagedAgents[0].LastStatus = aws.String("PENDING") },
+			wantCodes:  []string{"agent-pending"},
+			wantRemedy: RemedyWait,
+			wantInSum:  "container app: ExecuteCommandAgent is PENDING",
+		},
+		{
+			name:       "agent stopped",
+			mutate:     func(task *types.Task) { task.Containers[0].ManagedAgents[0].LastStatus = aws.String("STOPPED") },
+			wantCodes:  []string{"agent-not-running"},
+			wantRemedy: RemedyIAM,
+			wantInSum:  "ssmmessages",
+		},
+		{
+			name:       "agent missing",
+			mutate:     func(task *types.Task) { task.Containers[0].ManagedAgents = nil },
+			wantCodes:  []string{"agent-missing"},
+			wantRemedy: RemedyDeployment,
+			wantInSum:  "no ExecuteCommandAgent",
+		},
+		{
+			name:       "runtime ID missing",
+			mutate:     func(task *types.Task) { task.Containers[0].RuntimeId = nil },
+			wantCodes:  []string{"missing-runtime-id"},
+			wantRemedy: RemedyWait,
+			wantInSum:  "no runtime ID",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := fullyReadyTask()
+			tt.mutate(&task)
+			diagnosis := DiagnoseTask(task)
+			if diagnosis.Eligible() {
+				t.Fatal("Eligible() = true, want false")
+			}
+			reasons := diagnosis.Reasons
+			if len(reasons) == 0 {
+				reasons = diagnosis.Containers[0].Reasons
+			}
+			var codes []string
+			for _, reason := range reasons {
+				codes = append(codes, reason.Code)
+			}
+			if !reflect.DeepEqual(codes, tt.wantCodes) {
+				t.Fatalf("reason codes = %v, want %v", codes, tt.wantCodes)
+			}
+			if reasons[0].Remedy != tt.wantRemedy {
+				t.Fatalf("Remedy = %q, want %q", reasons[0].Remedy, tt.wantRemedy)
+			}
+			summary := diagnosis.Summary()
+			if !strings.Contains(summary, tt.wantInSum) || !strings.Contains(summary, "(fix: "+string(tt.wantRemedy)+")") {
+				t.Fatalf("Summary() = %q, want %q and the remedy", summary, tt.wantInSum)
+			}
+		})
+	}
+}
+
+func TestDiagnoseTaskReadyTaskHasNoReasons(t *testing.T) {
+	diagnosis := DiagnoseTask(fullyReadyTask())
+	if !diagnosis.Eligible() || diagnosis.Summary() != "" || len(diagnosis.Reasons) != 0 {
+		t.Fatalf("DiagnoseTask() = %#v, want eligible without reasons", diagnosis)
+	}
+
+	task := fullyReadyTask()
+	task.Containers = nil
+	if got := DiagnoseTask(task).Summary(); got != "task has no containers (fix: task definition)" {
+		t.Fatalf("Summary() without containers = %q", got)
+	}
+}
+
 func fullyReadyTask() types.Task {
 	return types.Task{
 		EnableExecuteCommand: true,
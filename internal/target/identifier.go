@@ -73,6 +73,55 @@ func TaskID(input string) (string, error) {
 	return id, nil
 }
 
+// TaskCluster returns the ARN of the cluster a long task ARN names. A task ID
+// or a short ARN does not name its cluster.
+func TaskCluster(input string) (string, error) {
+	original := input
+	input = strings.TrimSpace(input)
+	if !strings.HasPrefix(input, "arn:") {
+		return "", fmt.Errorf("task identifier %q is not an ARN that names its cluster", original)
+	}
+	parsed, err := parseECSARN(input)
+	if err != nil {
+		return "", fmt.Errorf("invalid task ARN %q: %w", original, err)
+	}
+	parts := strings.Split(parsed.Resource, "/")
+	if len(parts) != 3 || parts[0] != "task" || strings.TrimSpace(parts[1]) == "" {
+		return "", fmt.Errorf("task ARN %q must have resource task/<cluster>/<id> to name its cluster", original)
+	}
+	parsed.Resource = "cluster/" + parts[1]
+	return parsed.String(), nil
+}
+
+// TaskDefinitionFamily returns the family from a task definition ARN or a
+// family:revision reference.
+func TaskDefinitionFamily(input string) (string, error) {
+	original := input
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return "", fmt.Errorf("task definition identifier %q is empty", original)
+	}
+
+	resource := input
+	if strings.HasPrefix(input, "arn:") {
+		parsed, err := parseECSARN(input)
+		if err != nil {
+			return "", fmt.Errorf("invalid task definition ARN %q: %w", original, err)
+		}
+		parts := strings.Split(parsed.Resource, "/")
+		if len(parts) != 2 || parts[0] != "task-definition" {
+			return "", fmt.Errorf("task definition ARN %q must have resource task-definition/<family>:<revision>", original)
+		}
+		resource = parts[1]
+	}
+
+	family, _, _ := strings.Cut(resource, ":")
+	if family == "" || strings.Contains(family, "/") {
+		return "", fmt.Errorf("task definition identifier %q has no family", original)
+	}
+	return family, nil
+}
+
 func parseECSARN(input string) (arn.ARN, error) {
 	parsed, err := arn.Parse(input)
 	if err != nil {
@@ -189,3 +189,60 @@ func requireIdentifierError(t *testing.T, input string, err error) {
 		t.Fatalf("identifier %q error = %q, want original input included", input, err)
 	}
 }
+
+func TestTaskCluster(t *testing.T) {
+	got, err := TaskCluster(" arn:aws:ecs:us-east-1:123456789012:task/production/abc ")
+	if err != nil {
+		t.Fatalf("TaskCluster() error = %v, want nil", err)
+	}
+	if want := "arn:aws:ecs:us-east-1:123456789012:cluster/production"; got != want {
+		t.Fatalf("TaskCluster() = %q, want %q", got, want)
+	}
+
+	for _, input := range []string{
+		"",
+		"abc",
+		"task/production/abc",
+		"arn:aws:ecs:us-east-1:123456789012:task/abc",
+		"arn:aws:ecs:us-east-1:123456789012:task//abc",
+		"arn:aws:ecs:us-east-1:123456789012:cluster/production",
+	} {
+		if _, err := TaskCluster(input); err == nil {
+			t.Errorf("TaskCluster(%q) error = nil, want an error", input)
+		}
+	}
+}
+
+func TestTaskDefinitionFamily(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "ARN", input: "arn:aws:ecs:us-east-1:123456789012:task-definition/web:12", want: "web"},
+		{name: "family revision", input: " web:12 ", want: "web"},
+		{name: "family", input: "web", want: "web"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TaskDefinitionFamily(tt.input)
+			if err != nil {
+				t.Fatalf("TaskDefinitionFamily(%q) error = %v, want nil", tt.input, err)
+			}
+			if got != tt.want {
+				t.Fatalf("TaskDefinitionFamily(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+
+	for _, input := range []string{
+		"",
+		":12",
+		"arn:aws:ecs:us-east-1:123456789012:task/web",
+		"arn:aws:iam::123456789012:role/web",
+	} {
+		if _, err := TaskDefinitionFamily(input); err == nil {
+			t.Errorf("TaskDefinitionFamily(%q) error = nil, want error", input)
+		}
+	}
+}
@@ -2,7 +2,9 @@ package target
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 )
 
@@ -16,9 +18,89 @@ type Resolved struct {
 	Container     types.Container
 	ContainerName string
 	RuntimeID     string
+	// Service is the service the task was looked up in, empty when the
+	// lookup covered the whole cluster.
+	Service string
 }
 
 // SSMTarget returns the ECS target identifier expected by Session Manager.
 func (r Resolved) SSMTarget() string {
 	return fmt.Sprintf("ecs:%s_%s_%s", r.ClusterName, r.TaskID, r.RuntimeID)
 }
+
+// SessionTarget is the container a Session Manager ECS target identifier
+// names.
+type SessionTarget struct {
+	ClusterName string
+	TaskID      string
+	RuntimeID   string
+}
+
+// ParseSSMTarget splits an identifier built by Resolved.SSMTarget. Cluster
+// names may contain underscores and task and runtime IDs may not, so the
+// identifier is split from the right.
+func ParseSSMTarget(input string) (SessionTarget, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(input), "ecs:")
+	if !ok {
+		return SessionTarget{}, fmt.Errorf("SSM target %q is not an ECS target", input)
+	}
+	rest, runtimeID, ok := cutLast(rest, "_")
+	if !ok || runtimeID == "" {
+		return SessionTarget{}, fmt.Errorf("SSM target %q has no runtime ID", input)
+	}
+	rest, taskID, ok := cutLast(rest, "_")
+	if !ok {
+		return SessionTarget{}, fmt.Errorf("SSM target %q has no task ID", input)
+	}
+	if _, err := TaskID("task/" + taskID); err != nil {
+		return SessionTarget{}, fmt.Errorf("SSM target %q: %w", input, err)
+	}
+	clusterName, err := ClusterName(rest)
+	if err != nil {
+		return SessionTarget{}, fmt.Errorf("SSM target %q: %w", input, err)
+	}
+	return SessionTarget{ClusterName: clusterName, TaskID: taskID, RuntimeID: runtimeID}, nil
+}
+
+func cutLast(s, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
+
+// NewResolved validates the identifiers of a selected task and container.
+func NewResolved(ecsCluster string, task types.Task, container types.Container) (Resolved, error) {
+	clusterName, err := ClusterName(ecsCluster)
+	if err != nil {
+		return Resolved{}, fmt.Errorf("resolve ECS cluster: %w", err)
+	}
+	taskARN := strings.TrimSpace(aws.ToString(task.TaskArn))
+	if taskARN == "" {
+		return Resolved{}, fmt.Errorf("resolve selected ECS task: task ARN is empty")
+	}
+	taskID, err := TaskID(taskARN)
+	if err != nil {
+		return Resolved{}, fmt.Errorf("resolve selected ECS task ID: %w", err)
+	}
+	containerName := strings.TrimSpace(aws.ToString(container.Name))
+	if containerName == "" {
+		return Resolved{}, fmt.Errorf("resolve selected ECS container: container name is empty")
+	}
+	runtimeID := strings.TrimSpace(aws.ToString(container.RuntimeId))
+	if runtimeID == "" {
+		return Resolved{}, fmt.Errorf("resolve selected ECS container: runtime ID is empty")
+	}
+
+	return Resolved{
+		ECSCluster:    ecsCluster,
+		ClusterName:   clusterName,
+		Task:          task,
+		TaskARN:       taskARN,
+		TaskID:        taskID,
+		Container:     container,
+		ContainerName: containerName,
+		RuntimeID:     runtimeID,
+	}, nil
+}
@@ -0,0 +1,55 @@
+package target
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestNewResolvedBuildsSSMTarget(t *testing.T) {
+	task := fullyReadyTask()
+	got, err := NewResolved("arn:aws:ecs:us-east-1:123456789012:cluster/cluster", task, task.Containers[0])
+	if err != nil {
+		t.Fatalf("NewResolved() error = %v", err)
+	}
+	if got.TaskID != "abc" || got.ContainerName != "app" || got.SSMTarget() != "ecs:cluster_abc_runtime-app" {
+		t.Fatalf("NewResolved() = %#v, want task abc container app", got)
+	}
+}
+
+func TestNewResolvedRejectsMissingIdentifiers(t *testing.T) {
+	task := fullyReadyTask()
+	container := task.Containers[0]
+	container.RuntimeId = aws.String(" ")
+
+	_, err := NewResolved("cluster", task, container)
+	if err == nil || !strings.Contains(err.Error(), "runtime ID is empty") {
+		t.Fatalf("NewResolved() error = %v, want runtime ID error", err)
+	}
+	_, err = NewResolved("cluster/bad", task, task.Containers[0])
+	if err == nil || !strings.Contains(err.Error(), "resolve ECS cluster") {
+		t.Fatalf("NewResolved() error = %v, want cluster error", err)
+	}
+}
+
+func TestParseSSMTargetReversesSSMTarget(t *testing.T) {
+	task := fullyReadyTask()
+	resolved, err := NewResolved("arn:aws:ecs:us-east-1:123456789012:cluster/web_prod", task, task.Containers[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseSSMTarget(resolved.SSMTarget())
+	want := SessionTarget{ClusterName: "web_prod", TaskID: "abc", RuntimeID: "runtime-app"}
+	if err != nil || got != want {
+		t.Fatalf("ParseSSMTarget() = %#v, %v, want %#v", got, err, want)
+	}
+}
+
+func TestParseSSMTargetRejectsOtherTargets(t *testing.T) {
+	for _, input := range []string{"i-0123456789abcdef0", "ecs:cluster", "ecs:cluster_abc_", "ecs:_abc_runtime", "ecs:cluster_ _runtime"} {
+		if got, err := ParseSSMTarget(input); err == nil {
+			t.Errorf("ParseSSMTarget(%q) = %#v, want error", input, got)
+		}
+	}
+}
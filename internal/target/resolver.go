@@ -16,6 +16,8 @@ const describeTasksLimit = 100
 // ECSAPI is the subset of the ECS client used to resolve executable targets.
 type ECSAPI interface {
 	ListClusters(context.Context, *ecs.ListClustersInput, ...func(*ecs.Options)) (*ecs.ListClustersOutput, error)
+	ListServices(context.Context, *ecs.ListServicesInput, ...func(*ecs.Options)) (*ecs.ListServicesOutput, error)
+	DescribeServices(context.Context, *ecs.DescribeServicesInput, ...func(*ecs.Options)) (*ecs.DescribeServicesOutput, error)
 	ListTasks(context.Context, *ecs.ListTasksInput, ...func(*ecs.Options)) (*ecs.ListTasksOutput, error)
 	DescribeTasks(context.Context, *ecs.DescribeTasksInput, ...func(*ecs.Options)) (*ecs.DescribeTasksOutput, error)
 }
@@ -84,6 +86,29 @@ func (r *Resolver) EligibleTasks(ctx context.Context, cluster, serviceName strin
 	return eligible, nil
 }
 
+// DiagnoseTasks returns the eligibility of every task in cluster that is
+// meant to be running, eligible or not. When serviceName is non-empty, only
+// that service is queried.
+func (r *Resolver) DiagnoseTasks(ctx context.Context, cluster, serviceName string) ([]TaskDiagnosis, error) {
+	arns, err := r.taskARNs(ctx, cluster, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	if len(arns) == 0 {
+		return nil, nil
+	}
+
+	tasks, err := r.describeTasks(ctx, cluster, arns)
+	if err != nil {
+		return nil, err
+	}
+	diagnoses := make([]TaskDiagnosis, 0, len(tasks))
+	for _, task := range tasks {
+		diagnoses = append(diagnoses, DiagnoseTask(task))
+	}
+	return diagnoses, nil
+}
+
 func (r *Resolver) taskARNs(ctx context.Context, cluster, serviceName string) ([]string, error) {
 	var (
 		arns      []string
@@ -38,6 +38,8 @@ type fakeECS struct {
 	taskErrors            map[string]error
 	nilTaskOutputs        map[string]bool
 	describe              func(context.Context, *ecs.DescribeTasksInput) (*ecs.DescribeTasksOutput, error)
+	servicePages          map[string]*ecs.ListServicesOutput
+	describeServices      func(*ecs.DescribeServicesInput) (*ecs.DescribeServicesOutput, error)
 	callLimitError        error
 	listClustersCallLimit int
 	listTasksCallLimit    int
@@ -45,10 +47,38 @@ type fakeECS struct {
 	listClustersCalls  []listClustersCall
 	listTasksCalls     []listTasksCall
 	describeTasksCalls []describeTasksCall
+	listServicesCalls  []ecs.ListServicesInput
+	describeServiceIns []ecs.DescribeServicesInput
 }
 
 var _ ECSAPI = (*fakeECS)(nil)
 
+func (f *fakeECS) ListServices(
+	_ context.Context,
+	input *ecs.ListServicesInput,
+	_ ...func(*ecs.Options),
+) (*ecs.ListServicesOutput, error) {
+	f.listServicesCalls = append(f.listServicesCalls, *input)
+	if page, ok := f.servicePages[aws.ToString(input.NextToken)]; ok {
+		return page, nil
+	}
+	return &ecs.ListServicesOutput{}, nil
+}
+
+func (f *fakeECS) DescribeServices(
+	_ context.Context,
+	input *ecs.DescribeServicesInput,
+	_ ...func(*ecs.Options),
+) (*ecs.DescribeServicesOutput, error) {
+	inputCopy := *input
+	inputCopy.Services = append([]string(nil), input.Services...)
+	f.describeServiceIns = append(f.describeServiceIns, inputCopy)
+	if f.describeServices != nil {
+		return f.describeServices(input)
+	}
+	return &ecs.DescribeServicesOutput{}, nil
+}
+
 func (f *fakeECS) ListClusters(
 	ctx context.Context,
 	input *ecs.ListClustersInput,
@@ -280,6 +310,34 @@ func TestResolverEligibleTasksPaginatesAndOrderedDeduplicates(t *testing.T) {
 	}
 }
 
+func TestResolverDiagnoseTasksKeepsIneligibleTasks(t *testing.T) {
+	client := &fakeECS{
+		taskPages: map[string]*ecs.ListTasksOutput{
+			"": {TaskArns: []string{"task-a", "task-b"}},
+		},
+		describe: func(_ context.Context, input *ecs.DescribeTasksInput) (*ecs.DescribeTasksOutput, error) {
+			ready := resolverEligibleTask("task-a")
+			disabled := resolverEligibleTask("task-b")
+			disabled.EnableExecuteCommand = false
+			return &ecs.DescribeTasksOutput{Tasks: []types.Task{ready, disabled}}, nil
+		},
+	}
+
+	got, err := NewResolver(client).DiagnoseTasks(context.Background(), "production", "")
+	if err != nil {
+		t.Fatalf("DiagnoseTasks() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("DiagnoseTasks() returned %d diagnoses, want 2", len(got))
+	}
+	if !got[0].Eligible() || got[1].Eligible() {
+		t.Fatalf("DiagnoseTasks() eligibility = %t, %t; want true, false", got[0].Eligible(), got[1].Eligible())
+	}
+	if code := got[1].Reasons[0].Code; code != "execute-command-disabled" {
+		t.Fatalf("DiagnoseTasks() reason = %q, want execute-command-disabled", code)
+	}
+}
+
 func TestResolverEligibleTasksRejectsRepeatedPaginationTokens(t *testing.T) {
 	tests := []struct {
 		name      string
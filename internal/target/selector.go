@@ -0,0 +1,190 @@
+package target
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// Strategies that break ties when a selector matches several eligible tasks.
+const (
+	StrategyFirst  = "first"
+	StrategyRandom = "random"
+	StrategyNewest = "newest"
+)
+
+var (
+	// ErrNoMatch reports that a selector matched no eligible task or container.
+	ErrNoMatch = errors.New("selector matches nothing")
+	// ErrAmbiguous reports that a selector matched several candidates and no
+	// strategy chose between them.
+	ErrAmbiguous = errors.New("selector is ambiguous")
+	// ErrNoEligible reports that there was no eligible task or container to
+	// choose from.
+	ErrNoEligible = errors.New("no eligible items")
+)
+
+var randomIndex = rand.IntN
+
+// Selector chooses an eligible task and container without prompting.
+type Selector struct {
+	Task      string
+	Container string
+	Family    string
+	Strategy  string
+}
+
+// Active reports whether any selector field is set.
+func (s Selector) Active() bool {
+	return s != Selector{}
+}
+
+// SelectTask returns the single eligible task matching s, applying the
+// strategy when several tasks match.
+func (s Selector) SelectTask(tasks []types.Task) (types.Task, error) {
+	matches := s.MatchingTasks(tasks)
+
+	switch {
+	case len(matches) == 0:
+		return types.Task{}, fmt.Errorf("%w: none of %d eligible ECS tasks match %s", ErrNoMatch, len(tasks), s.describe())
+	case len(matches) == 1:
+		return matches[0], nil
+	}
+
+	switch s.Strategy {
+	case StrategyFirst:
+		return matches[0], nil
+	case StrategyRandom:
+		return matches[randomIndex(len(matches))], nil
+	case StrategyNewest:
+		return newestTask(matches), nil
+	default:
+		ids := make([]string, 0, len(matches))
+		for _, task := range matches {
+			id, err := TaskID(aws.ToString(task.TaskArn))
+			if err != nil {
+				id = aws.ToString(task.TaskArn)
+			}
+			ids = append(ids, id)
+		}
+		return types.Task{}, fmt.Errorf(
+			"%w: %d eligible ECS tasks match %s (%s); set a task, family, or strategy",
+			ErrAmbiguous, len(matches), s.describe(), strings.Join(ids, ", "),
+		)
+	}
+}
+
+// MatchingTasks returns every eligible task matching s, ignoring the
+// strategy, for commands that act on all of them.
+func (s Selector) MatchingTasks(tasks []types.Task) []types.Task {
+	var matches []types.Task
+	for _, task := range tasks {
+		if s.matchesTask(task) {
+			matches = append(matches, task)
+		}
+	}
+	return matches
+}
+
+// SelectContainer returns the eligible container named by s, or the only
+// eligible container when s does not name one.
+func (s Selector) SelectContainer(containers []types.Container) (types.Container, error) {
+	if s.Container == "" {
+		switch len(containers) {
+		case 0:
+			return types.Container{}, fmt.Errorf("%w: task has no eligible ECS container", ErrNoMatch)
+		case 1:
+			return containers[0], nil
+		}
+		return types.Container{}, fmt.Errorf(
+			"%w: task has %d eligible ECS containers (%s); set a container",
+			ErrAmbiguous, len(containers), strings.Join(containerNames(containers), ", "),
+		)
+	}
+
+	for _, container := range containers {
+		if aws.ToString(container.Name) == s.Container {
+			return container, nil
+		}
+	}
+	return types.Container{}, fmt.Errorf(
+		"%w: container %q is not an eligible ECS container (eligible: %s)",
+		ErrNoMatch, s.Container, strings.Join(containerNames(containers), ", "),
+	)
+}
+
+func (s Selector) matchesTask(task types.Task) bool {
+	taskARN := aws.ToString(task.TaskArn)
+	if s.Task != "" && s.Task != taskARN {
+		id, err := TaskID(taskARN)
+		if err != nil || id != s.Task {
+			return false
+		}
+	}
+	if s.Family != "" {
+		family, err := TaskDefinitionFamily(aws.ToString(task.TaskDefinitionArn))
+		if err != nil || family != s.Family {
+			return false
+		}
+	}
+	if s.Container != "" {
+		found := false
+		for _, container := range EligibleContainers(task) {
+			if aws.ToString(container.Name) == s.Container {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (s Selector) describe() string {
+	var parts []string
+	if s.Task != "" {
+		parts = append(parts, fmt.Sprintf("task %q", s.Task))
+	}
+	if s.Family != "" {
+		parts = append(parts, fmt.Sprintf("family %q", s.Family))
+	}
+	if s.Container != "" {
+		parts = append(parts, fmt.Sprintf("container %q", s.Container))
+	}
+	if len(parts) == 0 {
+		return "the selector"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func newestTask(tasks []types.Task) types.Task {
+	newest := tasks[0]
+	for _, task := range tasks[1:] {
+		if taskStartedAt(task).After(taskStartedAt(newest)) {
+			newest = task
+		}
+	}
+	return newest
+}
+
+func taskStartedAt(task types.Task) time.Time {
+	if task.StartedAt != nil {
+		return *task.StartedAt
+	}
+	return aws.ToTime(task.CreatedAt)
+}
+
+func containerNames(containers []types.Container) []string {
+	names := make([]string, 0, len(containers))
+	for _, container := range containers {
+		names = append(names, aws.ToString(container.Name))
+	}
+	return names
+}
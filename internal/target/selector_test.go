@@ -0,0 +1,177 @@
+package target
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+const (
+	selectorWebARN    = "arn:aws:ecs:us-east-1:123456789012:task/production/web-old"
+	selectorWebNewARN = "arn:aws:ecs:us-east-1:123456789012:task/production/web-new"
+	selectorJobARN    = "arn:aws:ecs:us-east-1:123456789012:task/production/job-one"
+)
+
+func TestSelectorSelectTask(t *testing.T) {
+	tasks := selectorTasks()
+
+	tests := []struct {
+		name     string
+		selector Selector
+		want     string
+	}{
+		{name: "task ID", selector: Selector{Task: "job-one"}, want: selectorJobARN},
+		{name: "task ARN", selector: Selector{Task: selectorWebNewARN}, want: selectorWebNewARN},
+		{name: "container narrows to one task", selector: Selector{Container: "worker"}, want: selectorJobARN},
+		{name: "family with first", selector: Selector{Family: "web", Strategy: StrategyFirst}, want: selectorWebARN},
+		{name: "family with newest", selector: Selector{Family: "web", Strategy: StrategyNewest}, want: selectorWebNewARN},
+		{name: "strategy alone", selector: Selector{Strategy: StrategyFirst}, want: selectorWebARN},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.selector.SelectTask(tasks)
+			if err != nil {
+				t.Fatalf("SelectTask() error = %v", err)
+			}
+			if aws.ToString(got.TaskArn) != tt.want {
+				t.Fatalf("SelectTask() = %q, want %q", aws.ToString(got.TaskArn), tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectorSelectTaskRandomUsesMatchesOnly(t *testing.T) {
+	original := randomIndex
+	t.Cleanup(func() { randomIndex = original })
+	var gotN int
+	randomIndex = func(n int) int {
+		gotN = n
+		return n - 1
+	}
+
+	got, err := Selector{Family: "web", Strategy: StrategyRandom}.SelectTask(selectorTasks())
+	if err != nil {
+		t.Fatalf("SelectTask() error = %v", err)
+	}
+	if gotN != 2 {
+		t.Fatalf("random candidate count = %d, want 2", gotN)
+	}
+	if aws.ToString(got.TaskArn) != selectorWebNewARN {
+		t.Fatalf("SelectTask() = %q, want %q", aws.ToString(got.TaskArn), selectorWebNewARN)
+	}
+}
+
+func TestSelectorSelectTaskReportsNoMatchAndAmbiguity(t *testing.T) {
+	tests := []struct {
+		name      string
+		selector  Selector
+		wantErr   error
+		fragments []string
+	}{
+		{
+			name:      "unknown task",
+			selector:  Selector{Task: "missing"},
+			wantErr:   ErrNoMatch,
+			fragments: []string{`task "missing"`, "none of 3 eligible ECS tasks"},
+		},
+		{
+			name:      "family and container disagree",
+			selector:  Selector{Family: "web", Container: "worker"},
+			wantErr:   ErrNoMatch,
+			fragments: []string{`family "web"`, `container "worker"`},
+		},
+		{
+			name:      "family without strategy",
+			selector:  Selector{Family: "web"},
+			wantErr:   ErrAmbiguous,
+			fragments: []string{"2 eligible ECS tasks", "web-old, web-new", "strategy"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.selector.SelectTask(selectorTasks())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SelectTask() error = %v, want %v", err, tt.wantErr)
+			}
+			for _, fragment := range tt.fragments {
+				if !strings.Contains(err.Error(), fragment) {
+					t.Errorf("SelectTask() error = %q, want it to contain %q", err, fragment)
+				}
+			}
+		})
+	}
+}
+
+func TestSelectorMatchingTasksKeepsEveryMatch(t *testing.T) {
+	got := Selector{Family: "web", Strategy: StrategyFirst}.MatchingTasks(selectorTasks())
+	if len(got) != 2 || aws.ToString(got[0].TaskArn) != selectorWebARN || aws.ToString(got[1].TaskArn) != selectorWebNewARN {
+		t.Fatalf("MatchingTasks() = %d tasks, want both web tasks in order", len(got))
+	}
+	if got := (Selector{}).MatchingTasks(selectorTasks()); len(got) != len(selectorTasks()) {
+		t.Fatalf("MatchingTasks() with an empty selector = %d tasks, want all", len(got))
+	}
+}
+
+func TestSelectorSelectContainer(t *testing.T) {
+	containers := []types.Container{readyContainer("app", "runtime-app"), readyContainer("sidecar", "runtime-sidecar")}
+
+	got, err := Selector{Container: "sidecar"}.SelectContainer(containers)
+	if err != nil {
+		t.Fatalf("SelectContainer() error = %v", err)
+	}
+	if aws.ToString(got.Name) != "sidecar" {
+		t.Fatalf("SelectContainer() = %q, want sidecar", aws.ToString(got.Name))
+	}
+
+	got, err = Selector{Strategy: StrategyFirst}.SelectContainer(containers[:1])
+	if err != nil {
+		t.Fatalf("SelectContainer() single error = %v", err)
+	}
+	if aws.ToString(got.Name) != "app" {
+		t.Fatalf("SelectContainer() single = %q, want app", aws.ToString(got.Name))
+	}
+
+	_, err = Selector{Strategy: StrategyFirst}.SelectContainer(containers)
+	if !errors.Is(err, ErrAmbiguous) || !strings.Contains(err.Error(), "app, sidecar") {
+		t.Fatalf("SelectContainer() error = %v, want ambiguity listing containers", err)
+	}
+
+	_, err = Selector{Container: "db"}.SelectContainer(containers)
+	if !errors.Is(err, ErrNoMatch) || !strings.Contains(err.Error(), `container "db"`) {
+		t.Fatalf("SelectContainer() error = %v, want no match for db", err)
+	}
+}
+
+func TestSelectorActive(t *testing.T) {
+	if (Selector{}).Active() {
+		t.Fatal("zero Selector.Active() = true, want false")
+	}
+	if !(Selector{Strategy: StrategyNewest}).Active() {
+		t.Fatal("Selector{Strategy}.Active() = false, want true")
+	}
+}
+
+func selectorTasks() []types.Task {
+	started := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	webOld := fullyReadyTask()
+	webOld.TaskArn = aws.String(selectorWebARN)
+	webOld.TaskDefinitionArn = aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/web:3")
+	webOld.StartedAt = aws.Time(started)
+
+	webNew := fullyReadyTask()
+	webNew.TaskArn = aws.String(selectorWebNewARN)
+	webNew.TaskDefinitionArn = aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/web:4")
+	webNew.StartedAt = aws.Time(started.Add(time.Hour))
+
+	job := fullyReadyTask()
+	job.TaskArn = aws.String(selectorJobARN)
+	job.TaskDefinitionArn = aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/job:1")
+	job.Containers = []types.Container{readyContainer("worker", "runtime-worker")}
+	job.StartedAt = aws.Time(started.Add(2 * time.Hour))
+
+	return []types.Task{webOld, webNew, job}
+}
@@ -0,0 +1,119 @@
+package target
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+const (
+	listServicesLimit     = 100
+	describeServicesLimit = 10
+)
+
+// Services returns every service in cluster, sorted by name.
+func (r *Resolver) Services(ctx context.Context, cluster string) ([]types.Service, error) {
+	arns, err := r.serviceARNs(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	var services []types.Service
+	for start := 0; start < len(arns); start += describeServicesLimit {
+		end := min(start+describeServicesLimit, len(arns))
+		output, err := r.client.DescribeServices(ctx, &ecs.DescribeServicesInput{
+			Cluster:  aws.String(cluster),
+			Services: append([]string(nil), arns[start:end]...),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("describe ECS services in cluster %q: %w", cluster, err)
+		}
+		if output == nil {
+			return nil, fmt.Errorf("describe ECS services in cluster %q: nil response", cluster)
+		}
+		if err := describeServiceFailuresError(output.Failures); err != nil {
+			return nil, err
+		}
+		services = append(services, output.Services...)
+	}
+	slices.SortStableFunc(services, func(a, b types.Service) int {
+		return strings.Compare(aws.ToString(a.ServiceName), aws.ToString(b.ServiceName))
+	})
+	return services, nil
+}
+
+func (r *Resolver) serviceARNs(ctx context.Context, cluster string) ([]string, error) {
+	var (
+		arns      []string
+		nextToken *string
+	)
+	seenTokens := make(map[string]struct{})
+
+	for {
+		output, err := r.client.ListServices(ctx, &ecs.ListServicesInput{
+			Cluster:    aws.String(cluster),
+			MaxResults: aws.Int32(listServicesLimit),
+			NextToken:  nextToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("list ECS services in cluster %q: %w", cluster, err)
+		}
+		if output == nil {
+			return nil, fmt.Errorf("list ECS services in cluster %q: nil response", cluster)
+		}
+		arns = append(arns, output.ServiceArns...)
+		token := aws.ToString(output.NextToken)
+		if token == "" {
+			return arns, nil
+		}
+		if _, seen := seenTokens[token]; seen {
+			return nil, fmt.Errorf("list ECS services in cluster %q: repeated pagination token %q", cluster, token)
+		}
+		seenTokens[token] = struct{}{}
+		nextToken = output.NextToken
+	}
+}
+
+func describeServiceFailuresError(failures []types.Failure) error {
+	errs := make([]error, 0, len(failures))
+	for _, failure := range failures {
+		errs = append(errs, fmt.Errorf(
+			"describe ECS service %s: %s: %s",
+			failureValue(failure.Arn, "<unknown ARN>"),
+			failureValue(failure.Reason, "<unknown reason>"),
+			failureValue(failure.Detail, "<no detail>"),
+		))
+	}
+	return errors.Join(errs...)
+}
+
+// DeploymentStatus summarizes the rollout of the service's primary
+// deployment for display.
+func DeploymentStatus(service types.Service) string {
+	for _, deployment := range service.Deployments {
+		if aws.ToString(deployment.Status) != "PRIMARY" {
+			continue
+		}
+		switch deployment.RolloutState {
+		case types.DeploymentRolloutStateCompleted:
+			return "deployed"
+		case types.DeploymentRolloutStateInProgress:
+			return "deploying"
+		case types.DeploymentRolloutStateFailed:
+			return "deployment failed"
+		}
+	}
+	if len(service.Deployments) > 1 {
+		return "deploying"
+	}
+	if status := strings.TrimSpace(aws.ToString(service.Status)); status != "" {
+		return strings.ToLower(status)
+	}
+	return "unknown"
+}
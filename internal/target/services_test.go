@@ -0,0 +1,107 @@
+package target
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+func TestResolverServicesPaginatesBatchesAndSorts(t *testing.T) {
+	var firstPage []string
+	for i := range 12 {
+		firstPage = append(firstPage, fmt.Sprintf("arn:aws:ecs:us-east-1:123456789012:service/production/svc-%02d", 11-i))
+	}
+	client := &fakeECS{
+		servicePages: map[string]*ecs.ListServicesOutput{
+			"":     {ServiceArns: firstPage, NextToken: aws.String("next")},
+			"next": {ServiceArns: []string{"arn:aws:ecs:us-east-1:123456789012:service/production/api"}},
+		},
+		describeServices: func(in *ecs.DescribeServicesInput) (*ecs.DescribeServicesOutput, error) {
+			output := &ecs.DescribeServicesOutput{}
+			for _, arn := range in.Services {
+				output.Services = append(output.Services, types.Service{ServiceName: aws.String(arn[strings.LastIndex(arn, "/")+1:])})
+			}
+			return output, nil
+		},
+	}
+
+	services, err := NewResolver(client).Services(context.Background(), "production")
+	if err != nil {
+		t.Fatalf("Services() error = %v", err)
+	}
+	var names []string
+	for _, service := range services {
+		names = append(names, aws.ToString(service.ServiceName))
+	}
+	if len(names) != 13 || names[0] != "api" || names[1] != "svc-00" || names[12] != "svc-11" {
+		t.Fatalf("Services() names = %v, want sorted by name", names)
+	}
+	if len(client.listServicesCalls) != 2 || aws.ToInt32(client.listServicesCalls[0].MaxResults) != 100 {
+		t.Fatalf("ListServices calls = %#v, want two pages of up to 100", client.listServicesCalls)
+	}
+	var batches []int
+	for _, in := range client.describeServiceIns {
+		if aws.ToString(in.Cluster) != "production" {
+			t.Fatalf("DescribeServices cluster = %q, want production", aws.ToString(in.Cluster))
+		}
+		batches = append(batches, len(in.Services))
+	}
+	if !reflect.DeepEqual(batches, []int{10, 3}) {
+		t.Fatalf("DescribeServices batch sizes = %v, want [10 3]", batches)
+	}
+}
+
+func TestResolverServicesReportsDescribeFailures(t *testing.T) {
+	client := &fakeECS{
+		servicePages: map[string]*ecs.ListServicesOutput{"": {ServiceArns: []string{"svc"}}},
+		describeServices: func(*ecs.DescribeServicesInput) (*ecs.DescribeServicesOutput, error) {
+			return &ecs.DescribeServicesOutput{Failures: []types.Failure{{Arn: aws.String("svc"), Reason: aws.String("MISSING")}}}, nil
+		},
+	}
+	_, err := NewResolver(client).Services(context.Background(), "production")
+	if err == nil || !strings.Contains(err.Error(), "describe ECS service svc: MISSING") {
+		t.Fatalf("Services() error = %v, want describe failure", err)
+	}
+}
+
+func TestResolverServicesWithoutServices(t *testing.T) {
+	client := &fakeECS{}
+	services, err := NewResolver(client).Services(context.Background(), "production")
+	if err != nil || len(services) != 0 {
+		t.Fatalf("Services() = %v, %v; want none", services, err)
+	}
+	if len(client.describeServiceIns) != 0 {
+		t.Fatalf("DescribeServices calls = %d, want 0", len(client.describeServiceIns))
+	}
+}
+
+func TestDeploymentStatus(t *testing.T) {
+	primary := func(state types.DeploymentRolloutState) types.Deployment {
+		return types.Deployment{Status: aws.String("PRIMARY"), RolloutState: state}
+	}
+	tests := []struct {
+		name    string
+		service types.Service
+		want    string
+	}{
+		{"completed", types.Service{Deployments: []types.Deployment{primary(types.DeploymentRolloutStateCompleted)}}, "deployed"},
+		{"in progress", types.Service{Deployments: []types.Deployment{primary(types.DeploymentRolloutStateInProgress)}}, "deploying"},
+		{"failed", types.Service{Deployments: []types.Deployment{primary(types.DeploymentRolloutStateFailed)}}, "deployment failed"},
+		{"several deployments", types.Service{Deployments: []types.Deployment{primary(""), {Status: aws.String("ACTIVE")}}}, "deploying"},
+		{"service status", types.Service{Status: aws.String("DRAINING")}, "draining"},
+		{"nothing known", types.Service{}, "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DeploymentStatus(tt.service); got != tt.want {
+				t.Fatalf("DeploymentStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -119,9 +119,22 @@ func noEligibleTasksError(cluster, service string, maxWait time.Duration) error
 	if service != "" {
 		scope += fmt.Sprintf(" service %q", service)
 	}
-	return fmt.Errorf(
+	return notReadyError{message: fmt.Sprintf(
 		"no eligible ECS task became ready in %s within %s: readiness requires a RUNNING task with execute command enabled and a RUNNING container, ExecuteCommandAgent, and non-empty runtime ID",
 		scope,
 		maxWait,
-	)
+	)}
+}
+
+// notReadyError is an ErrNoEligible that explains what readiness requires.
+type notReadyError struct {
+	message string
+}
+
+func (e notReadyError) Error() string {
+	return e.message
+}
+
+func (e notReadyError) Is(target error) bool {
+	return target == ErrNoEligible
 }
@@ -51,6 +51,22 @@ func (f *waitECS) ListClusters(
 	return &ecs.ListClustersOutput{}, nil
 }
 
+func (f *waitECS) ListServices(
+	context.Context,
+	*ecs.ListServicesInput,
+	...func(*ecs.Options),
+) (*ecs.ListServicesOutput, error) {
+	return &ecs.ListServicesOutput{}, nil
+}
+
+func (f *waitECS) DescribeServices(
+	context.Context,
+	*ecs.DescribeServicesInput,
+	...func(*ecs.Options),
+) (*ecs.DescribeServicesOutput, error) {
+	return &ecs.DescribeServicesOutput{}, nil
+}
+
 func (f *waitECS) ListTasks(
 	ctx context.Context,
 	input *ecs.ListTasksInput,
@@ -169,6 +185,9 @@ func TestWaitForEligibleTasks(t *testing.T) {
 
 		_, err := NewResolver(client).WaitForEligibleTasks(context.Background(), "production", "payments", 3*time.Second, clock)
 		assertNoEligibleTasksError(t, err, "production", "payments", "3s", "eligible", "ready")
+		if !errors.Is(err, ErrNoEligible) {
+			t.Fatalf("WaitForEligibleTasks() error = %v, want errors.Is(ErrNoEligible)", err)
+		}
 		if calls := len(client.listContexts); calls != 2 {
 			t.Fatalf("EligibleTasks lookup count = %d, want 2", calls)
 		}
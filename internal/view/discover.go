@@ -0,0 +1,40 @@
+package view
+
+import (
+	"fmt"
+
+	"github.com/wim-web/tnnl/internal/listview"
+	"github.com/wim-web/tnnl/internal/recent"
+	"github.com/wim-web/tnnl/internal/target"
+)
+
+// ChooseDiscoveredCluster asks which of clusters to use. Each option is
+// labelled with the account and Region the cluster lives in, and the
+// clusters chosen lately in history come first.
+func ChooseDiscoveredCluster(choose Choose, history recent.History, clusters []target.DiscoveredCluster) (target.DiscoveredCluster, bool, error) {
+	options := byRecency(discoveredClusterOptions(clusters), history.Clusters())
+	selected, quit, err := chooseOption(clusterChoiceTitle, options, false, choose)
+	if err != nil {
+		return target.DiscoveredCluster{}, false, fmt.Errorf("select ECS cluster: %w", err)
+	}
+	if quit {
+		return target.DiscoveredCluster{}, true, nil
+	}
+	for _, cluster := range clusters {
+		if cluster.ARN == selected {
+			return cluster, false, nil
+		}
+	}
+	return target.DiscoveredCluster{}, false, fmt.Errorf("selected ECS cluster %q is no longer available", selected)
+}
+
+func discoveredClusterOptions(clusters []target.DiscoveredCluster) []listview.Option {
+	options := make([]listview.Option, 0, len(clusters))
+	for _, cluster := range clusters {
+		options = append(options, listview.Option{
+			Label: fmt.Sprintf("%s (%s/%s)", cluster.Name, cluster.Account, cluster.Region),
+			Value: cluster.ARN,
+		})
+	}
+	return options
+}
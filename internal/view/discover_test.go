@@ -0,0 +1,51 @@
+package view
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/wim-web/tnnl/internal/listview"
+	"github.com/wim-web/tnnl/internal/target"
+)
+
+func TestChooseDiscoveredClusterLabelsAccountAndRegion(t *testing.T) {
+	clusters := []target.DiscoveredCluster{
+		{ARN: "arn:aws:ecs:ap-northeast-1:111111111111:cluster/web", Name: "web", Account: "111111111111", Region: "ap-northeast-1"},
+		{ARN: "arn:aws:ecs:us-east-1:222222222222:cluster/web", Name: "web", Account: "222222222222", Region: "us-east-1", Location: target.Location{Profile: "stg"}},
+	}
+	var labels []string
+	got, quit, err := ChooseDiscoveredCluster(func(_ string, options []listview.Option) (string, bool, error) {
+		for _, option := range options {
+			labels = append(labels, option.Label)
+		}
+		return options[1].Value, false, nil
+	}, nil, clusters)
+	if err != nil || quit {
+		t.Fatalf("ChooseDiscoveredCluster() quit = %v, error = %v", quit, err)
+	}
+	if got != clusters[1] {
+		t.Fatalf("ChooseDiscoveredCluster() = %#v, want %#v", got, clusters[1])
+	}
+	want := []string{"web (111111111111/ap-northeast-1)", "web (222222222222/us-east-1)"}
+	if strings.Join(labels, "|") != strings.Join(want, "|") {
+		t.Fatalf("labels = %q, want %q", labels, want)
+	}
+}
+
+func TestChooseDiscoveredClusterQuitAndEmpty(t *testing.T) {
+	_, quit, err := ChooseDiscoveredCluster(func(string, []listview.Option) (string, bool, error) {
+		return "", true, nil
+	}, nil, []target.DiscoveredCluster{{ARN: "arn:aws:ecs:us-east-1:111111111111:cluster/web", Name: "web"}})
+	if err != nil || !quit {
+		t.Fatalf("ChooseDiscoveredCluster() quit = %v, error = %v; want quit", quit, err)
+	}
+
+	_, _, err = ChooseDiscoveredCluster(func(string, []listview.Option) (string, bool, error) {
+		t.Fatal("choose called without clusters")
+		return "", false, nil
+	}, nil, nil)
+	if !errors.Is(err, target.ErrNoEligible) || !strings.Contains(err.Error(), "no eligible items") {
+		t.Fatalf("ChooseDiscoveredCluster() error = %v, want no eligible items", err)
+	}
+}
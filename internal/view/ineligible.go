@@ -0,0 +1,133 @@
+package view
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/wim-web/tnnl/internal/listview"
+	"github.com/wim-web/tnnl/internal/target"
+)
+
+// taskLookup reads the tasks of one cluster and service, with or without the
+// ineligible ones.
+type taskLookup struct {
+	resolver       targetResolver
+	cluster        string
+	service        string
+	showIneligible bool
+}
+
+// tasks returns the eligible tasks and, when showIneligible is set, the
+// diagnoses of the ineligible ones.
+func (l taskLookup) tasks(ctx context.Context) ([]types.Task, []target.TaskDiagnosis, error) {
+	if !l.showIneligible {
+		tasks, err := l.resolver.EligibleTasks(ctx, l.cluster, l.service)
+		return tasks, nil, err
+	}
+	diagnoses, err := l.resolver.DiagnoseTasks(ctx, l.cluster, l.service)
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		eligible   []types.Task
+		ineligible []target.TaskDiagnosis
+	)
+	for _, diagnosis := range diagnoses {
+		if diagnosis.Eligible() {
+			eligible = append(eligible, diagnosis.Task)
+		} else {
+			ineligible = append(ineligible, diagnosis)
+		}
+	}
+	return eligible, ineligible, nil
+}
+
+// explain adds why each task is ineligible to a failed lookup when
+// showIneligible is set.
+func (l taskLookup) explain(ctx context.Context, lookupErr error) error {
+	if !l.showIneligible || ctx.Err() != nil {
+		return lookupErr
+	}
+	_, ineligible, err := l.tasks(ctx)
+	if err != nil {
+		return errors.Join(lookupErr, fmt.Errorf("diagnose ECS tasks: %w", err))
+	}
+	if len(ineligible) == 0 {
+		return fmt.Errorf("%w\nno tasks are running", lookupErr)
+	}
+	lines := make([]string, 0, len(ineligible))
+	for _, diagnosis := range ineligible {
+		lines = append(lines, fmt.Sprintf("  %s: %s", taskLabel(diagnosis.Task), strings.Join(diagnosisReasons(diagnosis), "; ")))
+	}
+	return fmt.Errorf("%w\nineligible tasks:\n%s", lookupErr, strings.Join(lines, "\n"))
+}
+
+// diagnosisReasons lists every reason of a task: the task-level ones, or
+// else the reasons of each ineligible container.
+func diagnosisReasons(diagnosis target.TaskDiagnosis) []string {
+	var reasons []string
+	for _, reason := range diagnosis.Reasons {
+		reasons = append(reasons, reason.String())
+	}
+	if len(reasons) > 0 {
+		return reasons
+	}
+	for _, container := range diagnosis.Containers {
+		for _, reason := range container.Reasons {
+			reasons = append(reasons, fmt.Sprintf("container %s: %s", containerName(container.Container), reason))
+		}
+	}
+	if len(reasons) == 0 {
+		reasons = append(reasons, diagnosis.Summary())
+	}
+	return reasons
+}
+
+func taskLabel(task types.Task) string {
+	if _, id, group, err := taskIdentity(task); err == nil {
+		return fmt.Sprintf("%s %s", group, id)
+	}
+	if arn := strings.TrimSpace(aws.ToString(task.TaskArn)); arn != "" {
+		return arn
+	}
+	return "<task without ARN>"
+}
+
+func containerName(container types.Container) string {
+	if name := strings.TrimSpace(aws.ToString(container.Name)); name != "" {
+		return name
+	}
+	return "<unnamed>"
+}
+
+func ineligibleTaskOptions(diagnoses []target.TaskDiagnosis) []listview.Option {
+	options := make([]listview.Option, 0, len(diagnoses))
+	for _, diagnosis := range diagnoses {
+		options = append(options, listview.Option{
+			Label:    taskLabel(diagnosis.Task),
+			Value:    aws.ToString(diagnosis.Task.TaskArn),
+			Disabled: diagnosis.Summary(),
+		})
+	}
+	return options
+}
+
+func ineligibleContainerOptions(task types.Task) []listview.Option {
+	var options []listview.Option
+	for _, container := range target.DiagnoseTask(task).Containers {
+		if container.Eligible() {
+			continue
+		}
+		reasons := make([]string, 0, len(container.Reasons))
+		for _, reason := range container.Reasons {
+			reasons = append(reasons, reason.String())
+		}
+		name := containerName(container.Container)
+		options = append(options, listview.Option{Label: name, Value: name, Disabled: strings.Join(reasons, "; ")})
+	}
+	return options
+}
@@ -0,0 +1,112 @@
+package view
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/wim-web/tnnl/internal/listview"
+)
+
+func TestResolveTargetShowsIneligibleTasksAndContainersAsDisabled(t *testing.T) {
+	stopped := viewReadyContainer("sidecar", "runtime-sidecar")
+	stopped.LastStatus = aws.String("STOPPED")
+	ready := viewReadyTask(viewFirstARN, "service:payments", viewReadyContainer("app", "runtime-first"), stopped)
+	disabled := viewReadyTask(viewSecondARN, "service:payments", viewReadyContainer("app", "runtime-second"))
+	disabled.EnableExecuteCommand = false
+	resolver := &fakeTargetResolver{tasks: []types.Task{ready}, running: []types.Task{ready, disabled}}
+
+	var seen [][]listview.Option
+	choose := func(title string, options []listview.Option) (string, bool, error) {
+		seen = append(seen, options)
+		return options[0].Value, false, nil
+	}
+
+	got, quit, err := ResolveTarget(context.Background(), resolver, choose, ResolveOptions{Cluster: "production", Service: "payments", ShowIneligible: true})
+	if err != nil || quit {
+		t.Fatalf("ResolveTarget() = (%#v, %t, %v), want successful selection", got, quit, err)
+	}
+	if got.TaskARN != viewFirstARN || got.ContainerName != "app" {
+		t.Fatalf("ResolveTarget() = %#v, want first task app container", got)
+	}
+	if len(seen) != 2 {
+		t.Fatalf("chooser calls = %d, want task and container steps", len(seen))
+	}
+	if task := seen[0][1]; task.Value != viewSecondARN || !strings.Contains(task.Disabled, "execute command is not enabled") {
+		t.Fatalf("ineligible task option = %#v, want disabled with reason", task)
+	}
+	if container := seen[1][1]; container.Value != "sidecar" || container.Disabled != "container is STOPPED, not RUNNING (fix: wait)" {
+		t.Fatalf("ineligible container option = %#v, want disabled with reason", container)
+	}
+}
+
+func TestResolveTargetWithoutShowIneligibleDoesNotDiagnose(t *testing.T) {
+	task := viewReadyTask(viewFirstARN, "service:payments", viewReadyContainer("app", "runtime-first"))
+	resolver := &fakeTargetResolver{tasks: []types.Task{task}}
+	choose := func(title string, options []listview.Option) (string, bool, error) {
+		t.Fatalf("chooser called with %q, want the only task and container auto-selected", title)
+		return "", false, nil
+	}
+
+	if _, _, err := ResolveTarget(context.Background(), resolver, choose, ResolveOptions{Cluster: "production", Service: "payments"}); err != nil {
+		t.Fatalf("ResolveTarget() error = %v", err)
+	}
+	if want := []string{"wait"}; !reflect.DeepEqual(resolver.calls, want) {
+		t.Fatalf("resolver calls = %v, want %v", resolver.calls, want)
+	}
+}
+
+func TestResolveTargetExplainsWhyNoTaskIsEligible(t *testing.T) {
+	noAgent := viewReadyTask(viewFirstARN, "service:payments", viewReadyContainer("app", "runtime-first"))
+	noAgent.Containers[0].ManagedAgents = nil
+	pending := viewReadyTask(viewSecondARN, "service:payments", viewReadyContainer("app", "runtime-second"))
+	pending.LastStatus = aws.String("PENDING")
+	waitErr := errors.New("no eligible tasks")
+	resolver := &fakeTargetResolver{waitErr: waitErr, running: []types.Task{noAgent, pending}}
+
+	got, quit, err := ResolveTarget(context.Background(), resolver, nil, ResolveOptions{Cluster: "production", Service: "payments", ShowIneligible: true})
+	assertResolveError(t, got, quit, err,
+		"ineligible tasks:",
+		"service:payments task-first: container app: container has no ExecuteCommandAgent",
+		"(fix: deployment)",
+		"service:payments task-second: task is PENDING, not RUNNING (fix: wait)",
+	)
+	if !errors.Is(err, waitErr) {
+		t.Fatalf("ResolveTarget() error = %v, want it to wrap the lookup error", err)
+	}
+}
+
+func TestChooseTaskFromTableAddsIneligibleRows(t *testing.T) {
+	ready := viewReadyTask(viewFirstARN, "service:payments", viewReadyContainer("app", "runtime-first"))
+	stopped := viewReadyTask(viewSecondARN, "service:payments", viewReadyContainer("app", "runtime-second"))
+	stopped.LastStatus = aws.String("DEACTIVATING")
+	resolver := &fakeTargetResolver{running: []types.Task{ready, stopped}}
+	lookup := taskLookup{resolver: resolver, cluster: "production", service: "payments", showIneligible: true}
+
+	var table listview.Table
+	chooseTable := func(shown listview.Table) (string, bool, error) {
+		table = shown
+		return viewFirstARN, false, nil
+	}
+	_, ineligible, err := lookup.tasks(context.Background())
+	if err != nil {
+		t.Fatalf("tasks() error = %v", err)
+	}
+
+	got, quit, err := chooseTaskFromTable(context.Background(), lookup, chooseTable, []types.Task{ready}, ineligible, time.Now)
+	if err != nil || quit || aws.ToString(got.TaskArn) != viewFirstARN {
+		t.Fatalf("chooseTaskFromTable() = (%v, %t, %v), want first task", aws.ToString(got.TaskArn), quit, err)
+	}
+	if len(table.Rows) != 2 || table.Rows[1].Value != viewSecondARN || !strings.Contains(table.Rows[1].Disabled, "task is DEACTIVATING") {
+		t.Fatalf("table rows = %#v, want the ineligible task disabled", table.Rows)
+	}
+	rows, err := table.Refresh()
+	if err != nil || len(rows) != 2 || rows[1].Disabled == "" {
+		t.Fatalf("Refresh() = (%#v, %v), want ineligible row kept", rows, err)
+	}
+}
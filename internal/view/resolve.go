@@ -3,137 +3,269 @@ package view
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/wim-web/tnnl/internal/listview"
+	"github.com/wim-web/tnnl/internal/recent"
 	"github.com/wim-web/tnnl/internal/target"
 )
 
 const (
 	clusterChoiceTitle   = "Select an ECS cluster"
+	serviceChoiceTitle   = "Select an ECS service"
 	taskChoiceTitle      = "Select an ECS task"
 	containerChoiceTitle = "Select an ECS container"
+
+	allTasksLabel = "(all tasks)"
 )
 
 type targetResolver interface {
 	Clusters(context.Context) ([]string, error)
+	Services(context.Context, string) ([]types.Service, error)
+	EligibleTasks(context.Context, string, string) ([]types.Task, error)
+	DiagnoseTasks(context.Context, string, string) ([]target.TaskDiagnosis, error)
 	WaitForEligibleTasks(context.Context, string, string, time.Duration, target.Clock) ([]types.Task, error)
 }
 
 // Choose presents typed options and returns the selected option value.
 type Choose func(string, []listview.Option) (string, bool, error)
 
-// ResolveTarget resolves an exact eligible ECS task and container.
-func ResolveTarget(
-	ctx context.Context,
-	resolver targetResolver,
-	choose Choose,
-	inputCluster string,
-	inputService string,
-	maxWait time.Duration,
-) (target.Resolved, bool, error) {
+// ResolveOptions narrows and orders what ResolveTarget offers.
+type ResolveOptions struct {
+	// Cluster and Service skip their steps when set.
+	Cluster string
+	Service string
+	// Selector, when active, picks the task and container without asking.
+	Selector target.Selector
+	// ShowIneligible adds the tasks and containers that cannot be used as
+	// disabled choices and explains an empty lookup.
+	ShowIneligible bool
+	// MaxWait is how long to wait for an eligible task.
+	MaxWait time.Duration
+	// History puts the clusters, services, and containers chosen lately
+	// first, the last choice marked as such.
+	History recent.History
+	// ChooseTable asks the task step when it is set; choose does otherwise.
+	ChooseTable ChooseTable
+}
+
+// ResolveTarget resolves an exact eligible ECS task and container, asking
+// choose for each step that options leave open.
+func ResolveTarget(ctx context.Context, resolver targetResolver, choose Choose, options ResolveOptions) (target.Resolved, bool, error) {
 	var resolved target.Resolved
 
+	ecsCluster, quit, err := chooseCluster(ctx, resolver, choose, options.History, options.Cluster, options.Selector)
+	if err != nil || quit {
+		return resolved, quit, err
+	}
+
+	service := strings.TrimSpace(options.Service)
+	if service == "" && !options.Selector.Active() {
+		service, quit, err = chooseService(ctx, resolver, choose, options.History, ecsCluster)
+		if err != nil {
+			return resolved, false, err
+		}
+		if quit {
+			return target.Resolved{}, true, nil
+		}
+	}
+
+	lookup := taskLookup{resolver: resolver, cluster: ecsCluster, service: service, showIneligible: options.ShowIneligible}
+	tasks, err := resolver.WaitForEligibleTasks(ctx, ecsCluster, service, options.MaxWait, target.RealClock())
+	if err != nil {
+		return resolved, false, fmt.Errorf("resolve eligible ECS tasks in cluster %q: %w", ecsCluster, lookup.explain(ctx, err))
+	}
+	var ineligible []target.TaskDiagnosis
+	if options.ShowIneligible && !options.Selector.Active() {
+		if _, ineligible, err = lookup.tasks(ctx); err != nil {
+			return resolved, false, fmt.Errorf("diagnose ECS tasks in cluster %q: %w", ecsCluster, err)
+		}
+	}
+	var selectedTask types.Task
+	if options.Selector.Active() {
+		selectedTask, err = options.Selector.SelectTask(tasks)
+		if err != nil {
+			return resolved, false, fmt.Errorf("select ECS task in cluster %q: %w", ecsCluster, err)
+		}
+	} else if options.ChooseTable != nil {
+		selectedTask, quit, err = chooseTaskFromTable(ctx, lookup, options.ChooseTable, tasks, ineligible, time.Now)
+		if err != nil {
+			return resolved, false, err
+		}
+		if quit {
+			return target.Resolved{}, true, nil
+		}
+	} else {
+		taskChoices, err := taskOptions(tasks)
+		if err != nil {
+			return resolved, false, fmt.Errorf("prepare ECS task choices: %w", err)
+		}
+		taskChoices = append(taskChoices, ineligibleTaskOptions(ineligible)...)
+		selectedTaskARN, quit, err := chooseOption(taskChoiceTitle, taskChoices, true, choose)
+		if err != nil {
+			return resolved, false, fmt.Errorf("select ECS task: %w", err)
+		}
+		if quit {
+			return target.Resolved{}, true, nil
+		}
+		selectedTask, err = taskByARN(tasks, selectedTaskARN)
+		if err != nil {
+			return resolved, false, fmt.Errorf("resolve selected ECS task: %w", err)
+		}
+	}
+
+	eligibleContainers := target.EligibleContainers(selectedTask)
+	var selectedContainer types.Container
+	if options.Selector.Active() {
+		selectedContainer, err = options.Selector.SelectContainer(eligibleContainers)
+		if err != nil {
+			return resolved, false, fmt.Errorf("select ECS container in task %q: %w", aws.ToString(selectedTask.TaskArn), err)
+		}
+	} else {
+		containerChoices, err := containerOptions(eligibleContainers)
+		if err != nil {
+			return resolved, false, fmt.Errorf("prepare ECS container choices: %w", err)
+		}
+		if options.ShowIneligible {
+			containerChoices = append(containerChoices, ineligibleContainerOptions(selectedTask)...)
+		}
+		containerChoices = byRecency(containerChoices, options.History.Containers(historyCluster(ecsCluster, taskCluster(selectedTask)), service))
+		selectedContainerName, quit, err := chooseOption(containerChoiceTitle, containerChoices, true, choose)
+		if err != nil {
+			return resolved, false, fmt.Errorf("select ECS container: %w", err)
+		}
+		if quit {
+			return target.Resolved{}, true, nil
+		}
+		selectedContainer, err = containerByName(eligibleContainers, selectedContainerName)
+		if err != nil {
+			return resolved, false, fmt.Errorf("resolve selected ECS container: %w", err)
+		}
+	}
+
+	resolved, err = target.NewResolved(ecsCluster, selectedTask, selectedContainer)
+	if err != nil {
+		return target.Resolved{}, false, err
+	}
+	resolved.Service = service
+	return resolved, false, nil
+}
+
+// chooseCluster returns inputCluster, or asks which cluster to use when it is
+// empty. An active selector never asks: its task ARN names the cluster, or
+// there is none to use.
+func chooseCluster(ctx context.Context, resolver targetResolver, choose Choose, history recent.History, inputCluster string, selector target.Selector) (string, bool, error) {
 	ecsCluster := strings.TrimSpace(inputCluster)
+	if ecsCluster == "" && selector.Active() {
+		cluster, err := SelectorCluster(selector)
+		if err != nil {
+			return "", false, err
+		}
+		ecsCluster = cluster
+	}
 	if ecsCluster == "" {
 		clusters, err := resolver.Clusters(ctx)
 		if err != nil {
-			return resolved, false, fmt.Errorf("resolve ECS clusters: %w", err)
+			return "", false, fmt.Errorf("resolve ECS clusters: %w", err)
 		}
 		options, err := clusterOptions(clusters)
 		if err != nil {
-			return resolved, false, fmt.Errorf("prepare ECS cluster choices: %w", err)
+			return "", false, fmt.Errorf("prepare ECS cluster choices: %w", err)
 		}
+		options = byRecency(options, history.Clusters())
 		selected, quit, err := chooseOption(clusterChoiceTitle, options, false, choose)
 		if err != nil {
-			return resolved, false, fmt.Errorf("select ECS cluster: %w", err)
+			return "", false, fmt.Errorf("select ECS cluster: %w", err)
 		}
 		if quit {
-			return target.Resolved{}, true, nil
+			return "", true, nil
 		}
 		if !hasOptionValue(options, selected) {
-			return resolved, false, fmt.Errorf("selected ECS cluster %q is no longer available", selected)
+			return "", false, fmt.Errorf("selected ECS cluster %q is no longer available", selected)
 		}
 		ecsCluster = selected
 	}
 
-	clusterName, err := target.ClusterName(ecsCluster)
-	if err != nil {
-		return resolved, false, fmt.Errorf("resolve ECS cluster: %w", err)
+	if _, err := target.ClusterName(ecsCluster); err != nil {
+		return "", false, fmt.Errorf("resolve ECS cluster: %w", err)
 	}
+	return ecsCluster, false, nil
+}
 
-	tasks, err := resolver.WaitForEligibleTasks(ctx, ecsCluster, inputService, maxWait, target.RealClock())
-	if err != nil {
-		return resolved, false, fmt.Errorf("resolve eligible ECS tasks in cluster %q: %w", ecsCluster, err)
-	}
-	taskChoices, err := taskOptions(tasks)
-	if err != nil {
-		return resolved, false, fmt.Errorf("prepare ECS task choices: %w", err)
-	}
-	selectedTaskARN, quit, err := chooseOption(taskChoiceTitle, taskChoices, true, choose)
-	if err != nil {
-		return resolved, false, fmt.Errorf("select ECS task: %w", err)
-	}
-	if quit {
-		return target.Resolved{}, true, nil
-	}
-	selectedTask, err := taskByARN(tasks, selectedTaskARN)
+// SelectorCluster returns the cluster the task ARN of selector names, for
+// resolving a selector when no cluster is given.
+func SelectorCluster(selector target.Selector) (string, error) {
+	cluster, err := target.TaskCluster(selector.Task)
 	if err != nil {
-		return resolved, false, fmt.Errorf("resolve selected ECS task: %w", err)
+		return "", fmt.Errorf("resolve ECS cluster without the cluster chooser: set --cluster or select the task by its full ARN: %w", err)
 	}
+	return cluster, nil
+}
 
-	eligibleContainers := target.EligibleContainers(selectedTask)
-	containerChoices, err := containerOptions(eligibleContainers)
+// chooseService asks which service of cluster to narrow the tasks to. The
+// last option keeps every task so that standalone tasks stay reachable, and a
+// cluster without services skips the step.
+func chooseService(ctx context.Context, resolver targetResolver, choose Choose, history recent.History, cluster string) (string, bool, error) {
+	services, err := resolver.Services(ctx, cluster)
 	if err != nil {
-		return resolved, false, fmt.Errorf("prepare ECS container choices: %w", err)
+		return "", false, fmt.Errorf("resolve ECS services in cluster %q: %w", cluster, err)
 	}
-	selectedContainerName, quit, err := chooseOption(containerChoiceTitle, containerChoices, true, choose)
+	if len(services) == 0 {
+		return "", false, nil
+	}
+	options := byRecency(serviceOptions(services), history.Services(historyCluster(cluster, aws.ToString(services[0].ClusterArn))))
+	selected, quit, err := choose(serviceChoiceTitle, options)
 	if err != nil {
-		return resolved, false, fmt.Errorf("select ECS container: %w", err)
+		return "", false, fmt.Errorf("select ECS service: %w", err)
 	}
 	if quit {
-		return target.Resolved{}, true, nil
+		return "", true, nil
 	}
-	selectedContainer, err := containerByName(eligibleContainers, selectedContainerName)
-	if err != nil {
-		return resolved, false, fmt.Errorf("resolve selected ECS container: %w", err)
+	if !hasOptionValue(options, selected) {
+		return "", false, fmt.Errorf("selected ECS service %q is no longer available", selected)
 	}
+	return selected, false, nil
+}
 
-	taskARN := strings.TrimSpace(aws.ToString(selectedTask.TaskArn))
-	if taskARN == "" {
-		return resolved, false, fmt.Errorf("resolve selected ECS task: task ARN is empty")
+// historyCluster returns the cluster ARN that history keeps the choices in
+// cluster under. A bare name, as input JSON may give it, takes the ARN that a
+// resource listed in the cluster reports, since the name alone does not say
+// which account and Region the cluster is in.
+func historyCluster(cluster, reported string) string {
+	if strings.HasPrefix(cluster, "arn:") || reported == "" {
+		return cluster
 	}
-	taskID, err := target.TaskID(taskARN)
-	if err != nil {
-		return resolved, false, fmt.Errorf("resolve selected ECS task ID: %w", err)
-	}
-	containerName := strings.TrimSpace(aws.ToString(selectedContainer.Name))
-	if containerName == "" {
-		return resolved, false, fmt.Errorf("resolve selected ECS container: container name is empty")
-	}
-	runtimeID := strings.TrimSpace(aws.ToString(selectedContainer.RuntimeId))
-	if runtimeID == "" {
-		return resolved, false, fmt.Errorf("resolve selected ECS container: runtime ID is empty")
+	return reported
+}
+
+// taskCluster returns the ARN of the cluster task runs in.
+func taskCluster(task types.Task) string {
+	if cluster := aws.ToString(task.ClusterArn); cluster != "" {
+		return cluster
 	}
+	cluster, _ := target.TaskCluster(aws.ToString(task.TaskArn))
+	return cluster
+}
 
-	return target.Resolved{
-		ECSCluster:    ecsCluster,
-		ClusterName:   clusterName,
-		Task:          selectedTask,
-		TaskARN:       taskARN,
-		TaskID:        taskID,
-		Container:     selectedContainer,
-		ContainerName: containerName,
-		RuntimeID:     runtimeID,
-	}, false, nil
+func serviceOptions(services []types.Service) []listview.Option {
+	options := make([]listview.Option, 0, len(services)+1)
+	for _, service := range services {
+		name := aws.ToString(service.ServiceName)
+		options = append(options, listview.Option{
+			Label: fmt.Sprintf("%s  %d/%d running  %s", name, service.RunningCount, service.DesiredCount, target.DeploymentStatus(service)),
+			Value: name,
+		})
+	}
+	return append(options, listview.Option{Label: allTasksLabel, Value: ""})
 }
 
 func chooseOption(title string, options []listview.Option, auto bool, choose Choose) (string, bool, error) {
-	if len(options) == 0 {
-		return "", false, fmt.Errorf("%s: no eligible items", title)
+	if !slices.ContainsFunc(options, func(option listview.Option) bool { return option.Disabled == "" }) {
+		return "", false, fmt.Errorf("%s: %w", title, target.ErrNoEligible)
 	}
 	if auto && len(options) == 1 {
 		return options[0].Value, false, nil
@@ -141,6 +273,30 @@ func chooseOption(title string, options []listview.Option, auto bool, choose Cho
 	return choose(title, options)
 }
 
+// byRecency moves the selectable options whose values were chosen lately to
+// the front, most recent first, and marks the latest as the last choice. The
+// other options keep their order.
+func byRecency(options []listview.Option, chosen []string) []listview.Option {
+	ordered := make([]listview.Option, 0, len(options))
+	for _, value := range chosen {
+		i := slices.IndexFunc(options, func(option listview.Option) bool {
+			return option.Value == value && option.Disabled == ""
+		})
+		if i < 0 {
+			continue
+		}
+		option := options[i]
+		option.Last = len(ordered) == 0
+		ordered = append(ordered, option)
+	}
+	for _, option := range options {
+		if !slices.ContainsFunc(ordered, func(recent listview.Option) bool { return recent.Value == option.Value }) {
+			ordered = append(ordered, option)
+		}
+	}
+	return ordered
+}
+
 func hasOptionValue(options []listview.Option, selected string) bool {
 	for _, option := range options {
 		if option.Value == selected {
@@ -165,15 +321,10 @@ func clusterOptions(clusters []string) ([]listview.Option, error) {
 func taskOptions(tasks []types.Task) ([]listview.Option, error) {
 	options := make([]listview.Option, 0, len(tasks))
 	for _, task := range tasks {
-		arnValue := strings.TrimSpace(aws.ToString(task.TaskArn))
-		id, err := target.TaskID(arnValue)
+		arnValue, id, group, err := taskIdentity(task)
 		if err != nil {
 			return nil, err
 		}
-		group := strings.TrimSpace(aws.ToString(task.Group))
-		if group == "" {
-			group = "task"
-		}
 		options = append(options, listview.Option{
 			Label: fmt.Sprintf("%s %s", group, id),
 			Value: arnValue,
@@ -182,6 +333,21 @@ func taskOptions(tasks []types.Task) ([]listview.Option, error) {
 	return options, nil
 }
 
+// taskIdentity returns the full ARN, the task ID, and the group shown for
+// task.
+func taskIdentity(task types.Task) (string, string, string, error) {
+	arnValue := strings.TrimSpace(aws.ToString(task.TaskArn))
+	id, err := target.TaskID(arnValue)
+	if err != nil {
+		return "", "", "", err
+	}
+	group := strings.TrimSpace(aws.ToString(task.Group))
+	if group == "" {
+		group = "task"
+	}
+	return arnValue, id, group, nil
+}
+
 func taskByARN(tasks []types.Task, selected string) (types.Task, error) {
 	for _, task := range tasks {
 		if aws.ToString(task.TaskArn) == selected {
@@ -11,6 +11,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/wim-web/tnnl/internal/listview"
+	"github.com/wim-web/tnnl/internal/recent"
 	"github.com/wim-web/tnnl/internal/target"
 )
 
@@ -23,8 +24,12 @@ const (
 type fakeTargetResolver struct {
 	clusters    []string
 	clustersErr error
+	services    []types.Service
+	servicesErr error
 	tasks       []types.Task
 	waitErr     error
+	refreshed   [][]types.Task
+	running     []types.Task
 
 	calls       []string
 	waitCluster string
@@ -38,6 +43,30 @@ func (f *fakeTargetResolver) Clusters(context.Context) ([]string, error) {
 	return append([]string(nil), f.clusters...), f.clustersErr
 }
 
+func (f *fakeTargetResolver) Services(context.Context, string) ([]types.Service, error) {
+	f.calls = append(f.calls, "services")
+	return append([]types.Service(nil), f.services...), f.servicesErr
+}
+
+func (f *fakeTargetResolver) EligibleTasks(context.Context, string, string) ([]types.Task, error) {
+	f.calls = append(f.calls, "eligible")
+	if len(f.refreshed) == 0 {
+		return nil, nil
+	}
+	tasks := f.refreshed[0]
+	f.refreshed = f.refreshed[1:]
+	return tasks, nil
+}
+
+func (f *fakeTargetResolver) DiagnoseTasks(context.Context, string, string) ([]target.TaskDiagnosis, error) {
+	f.calls = append(f.calls, "diagnose")
+	diagnoses := make([]target.TaskDiagnosis, 0, len(f.running))
+	for _, task := range f.running {
+		diagnoses = append(diagnoses, target.DiagnoseTask(task))
+	}
+	return diagnoses, nil
+}
+
 func (f *fakeTargetResolver) WaitForEligibleTasks(
 	_ context.Context,
 	cluster string,
@@ -78,7 +107,7 @@ func TestResolveTargetSelectsExactReadyTaskARN(t *testing.T) {
 		return options[1].Value, false, nil
 	}
 
-	got, quit, err := ResolveTarget(context.Background(), resolver, choose, "production", "payments", 9*time.Second)
+	got, quit, err := ResolveTarget(context.Background(), resolver, choose, ResolveOptions{Cluster: "production", Service: "payments", MaxWait: 9 * time.Second})
 	if err != nil {
 		t.Fatalf("ResolveTarget() error = %v", err)
 	}
@@ -125,7 +154,7 @@ func TestResolveTargetWaitsAfterClusterResolution(t *testing.T) {
 		return options[1].Value, false, nil
 	}
 
-	got, quit, err := ResolveTarget(context.Background(), resolver, choose, "", "payments", 7*time.Second)
+	got, quit, err := ResolveTarget(context.Background(), resolver, choose, ResolveOptions{Service: "payments", MaxWait: 7 * time.Second})
 	if err != nil {
 		t.Fatalf("ResolveTarget() error = %v", err)
 	}
@@ -183,7 +212,7 @@ func TestResolveTargetRejectsUnofferedClusterChoice(t *testing.T) {
 				return tt.selected, false, nil
 			}
 
-			got, quit, err := ResolveTarget(context.Background(), resolver, choose, "", "", 0)
+			got, quit, err := ResolveTarget(context.Background(), resolver, choose, ResolveOptions{})
 			assertResolveError(t, got, quit, err, "cluster", tt.selected, "no longer available")
 			if wantCalls := []string{"clusters"}; !reflect.DeepEqual(resolver.calls, wantCalls) {
 				t.Fatalf("resolver calls = %v, want %v with no wait for unoffered cluster", resolver.calls, wantCalls)
@@ -201,7 +230,7 @@ func TestResolveTargetRejectsEmptyResourcesBeforeChooser(t *testing.T) {
 			return "", false, nil
 		}
 
-		got, quit, err := ResolveTarget(context.Background(), resolver, choose, "", "", 0)
+		got, quit, err := ResolveTarget(context.Background(), resolver, choose, ResolveOptions{})
 		assertResolveError(t, got, quit, err, "cluster", "no")
 		if chooseCalls != 0 {
 			t.Fatalf("chooser call count = %d, want 0", chooseCalls)
@@ -219,7 +248,7 @@ func TestResolveTargetRejectsEmptyResourcesBeforeChooser(t *testing.T) {
 			return "", false, nil
 		}
 
-		got, quit, err := ResolveTarget(context.Background(), resolver, choose, "production", "", 0)
+		got, quit, err := ResolveTarget(context.Background(), resolver, choose, ResolveOptions{Cluster: "production"})
 		assertResolveError(t, got, quit, err, "task", "eligible")
 		if chooseCalls != 0 {
 			t.Fatalf("chooser call count = %d, want 0", chooseCalls)
@@ -239,7 +268,7 @@ func TestResolveTargetRejectsEmptyResourcesBeforeChooser(t *testing.T) {
 			return "", false, nil
 		}
 
-		got, quit, err := ResolveTarget(context.Background(), resolver, choose, "production", "", 0)
+		got, quit, err := ResolveTarget(context.Background(), resolver, choose, ResolveOptions{Cluster: "production"})
 		assertResolveError(t, got, quit, err, "container", "eligible")
 		if chooseCalls != 0 {
 			t.Fatalf("chooser call count = %d, want 0 before container chooser", chooseCalls)
@@ -265,7 +294,7 @@ func TestResolveTargetAutomaticSelection(t *testing.T) {
 			return options[1].Value, false, nil
 		}
 
-		got, quit, err := ResolveTarget(context.Background(), resolver, choose, "production", "", 0)
+		got, quit, err := ResolveTarget(context.Background(), resolver, choose, ResolveOptions{Cluster: "production"})
 		if err != nil || quit {
 			t.Fatalf("ResolveTarget() = (%#v, %t, %v), want successful selection", got, quit, err)
 		}
@@ -297,7 +326,7 @@ func TestResolveTargetAutomaticSelection(t *testing.T) {
 			return options[0].Value, false, nil
 		}
 
-		got, quit, err := ResolveTarget(context.Background(), resolver, choose, "production", "", 0)
+		got, quit, err := ResolveTarget(context.Background(), resolver, choose, ResolveOptions{Cluster: "production"})
 		if err != nil || quit {
 			t.Fatalf("ResolveTarget() = (%#v, %t, %v), want successful selection", got, quit, err)
 		}
@@ -310,6 +339,289 @@ func TestResolveTargetAutomaticSelection(t *testing.T) {
 	})
 }
 
+func TestResolveTargetSelectorSkipsChooser(t *testing.T) {
+	first := viewReadyTask(viewFirstARN, "service:payments", viewReadyContainer("app", "runtime-first"))
+	second := viewReadyTask(
+		viewSecondARN,
+		"service:payments",
+		viewReadyContainer("app", "runtime-second"),
+		viewReadyContainer("sidecar", "runtime-sidecar"),
+	)
+	resolver := &fakeTargetResolver{
+		services: []types.Service{{ServiceName: aws.String("payments")}},
+		tasks:    []types.Task{first, second},
+	}
+	choose := func(title string, options []listview.Option) (string, bool, error) {
+		t.Fatalf("chooser called with %q, want selector to skip it", title)
+		return "", false, nil
+	}
+
+	got, quit, err := ResolveTarget(context.Background(), resolver, choose, ResolveOptions{
+		Cluster:  "production",
+		Selector: target.Selector{Task: "task-second", Container: "sidecar"},
+	})
+	if err != nil || quit {
+		t.Fatalf("ResolveTarget() = (%#v, %t, %v), want successful selection", got, quit, err)
+	}
+	if got.TaskARN != viewSecondARN || got.ContainerName != "sidecar" || got.RuntimeID != "runtime-sidecar" {
+		t.Errorf("resolved target = (%q, %q, %q), want second task sidecar", got.TaskARN, got.ContainerName, got.RuntimeID)
+	}
+	if !reflect.DeepEqual(resolver.calls, []string{"wait"}) {
+		t.Errorf("resolver calls = %v, want the service step skipped", resolver.calls)
+	}
+}
+
+func TestResolveTargetServiceStepFiltersTasks(t *testing.T) {
+	task := viewReadyTask(viewFirstARN, "service:payments", viewReadyContainer("app", "runtime-first"))
+	services := []types.Service{
+		{
+			ServiceName:  aws.String("payments"),
+			RunningCount: 2,
+			DesiredCount: 3,
+			Deployments:  []types.Deployment{{Status: aws.String("PRIMARY"), RolloutState: types.DeploymentRolloutStateInProgress}},
+		},
+		{ServiceName: aws.String("worker"), Status: aws.String("ACTIVE")},
+	}
+	tests := []struct {
+		name        string
+		choice      string
+		wantService string
+	}{
+		{name: "service", choice: "payments", wantService: "payments"},
+		{name: "all tasks", choice: "", wantService: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := &fakeTargetResolver{services: services, tasks: []types.Task{task}}
+			var labels []string
+			choose := func(title string, options []listview.Option) (string, bool, error) {
+				if title != serviceChoiceTitle {
+					t.Fatalf("chooser title = %q, want only the service step", title)
+				}
+				for _, option := range options {
+					labels = append(labels, option.Label)
+				}
+				return tt.choice, false, nil
+			}
+
+			got, quit, err := ResolveTarget(context.Background(), resolver, choose, ResolveOptions{Cluster: "production"})
+			if err != nil || quit {
+				t.Fatalf("ResolveTarget() = (%#v, %t, %v), want successful selection", got, quit, err)
+			}
+			wantLabels := []string{"payments  2/3 running  deploying", "worker  0/0 running  active", "(all tasks)"}
+			if !reflect.DeepEqual(labels, wantLabels) {
+				t.Fatalf("service labels = %q, want %q", labels, wantLabels)
+			}
+			if resolver.waitService != tt.wantService || got.Service != tt.wantService {
+				t.Fatalf("task lookup service = %q, resolved service = %q; want %q", resolver.waitService, got.Service, tt.wantService)
+			}
+		})
+	}
+}
+
+func TestResolveTargetOffersRecentChoicesFirst(t *testing.T) {
+	const stagingARN = "arn:aws:ecs:us-east-1:123456789012:cluster/staging"
+	task := viewReadyTask(viewFirstARN, "service:worker", viewReadyContainer("app", "runtime-app"), viewReadyContainer("sidecar", "runtime-sidecar"))
+	resolver := &fakeTargetResolver{
+		clusters: []string{stagingARN, viewClusterARN},
+		services: []types.Service{{ServiceName: aws.String("payments")}, {ServiceName: aws.String("worker")}},
+		tasks:    []types.Task{task},
+	}
+	history := recent.History{
+		{Cluster: viewClusterARN, Service: "worker", Container: "sidecar"},
+		{Cluster: stagingARN, Service: "payments", Container: "app"},
+		{Cluster: viewClusterARN, Service: "payments", Container: "app"},
+	}
+	var offered []string
+	choose := func(title string, options []listview.Option) (string, bool, error) {
+		var values []string
+		for _, option := range options {
+			value := option.Value
+			if option.Last {
+				value += " (last)"
+			}
+			values = append(values, value)
+		}
+		offered = append(offered, title+": "+strings.Join(values, ", "))
+		return options[0].Value, false, nil
+	}
+
+	got, quit, err := ResolveTarget(context.Background(), resolver, choose, ResolveOptions{History: history})
+	if err != nil || quit {
+		t.Fatalf("ResolveTarget() = (%#v, %t, %v), want successful selection", got, quit, err)
+	}
+	want := []string{
+		clusterChoiceTitle + ": " + viewClusterARN + " (last), " + stagingARN,
+		serviceChoiceTitle + ": worker (last), payments, ",
+		containerChoiceTitle + ": sidecar (last), app",
+	}
+	if !reflect.DeepEqual(offered, want) {
+		t.Fatalf("offered =\n%s\nwant\n%s", strings.Join(offered, "\n"), strings.Join(want, "\n"))
+	}
+	if got.ECSCluster != viewClusterARN || got.Service != "worker" || got.ContainerName != "sidecar" {
+		t.Fatalf("ResolveTarget() = %s/%s/%s, want the last choice", got.ECSCluster, got.Service, got.ContainerName)
+	}
+}
+
+func TestResolveTargetScopesRecentChoicesOfABareClusterName(t *testing.T) {
+	// Another account has a cluster of the same name.
+	const otherAccountARN = "arn:aws:ecs:us-east-1:210987654321:cluster/production"
+	task := viewReadyTask(viewFirstARN, "service:worker", viewReadyContainer("app", "runtime-app"), viewReadyContainer("sidecar", "runtime-sidecar"))
+	resolver := &fakeTargetResolver{
+		services: []types.Service{
+			{ServiceName: aws.String("payments"), ClusterArn: aws.String(viewClusterARN)},
+			{ServiceName: aws.String("worker"), ClusterArn: aws.String(viewClusterARN)},
+		},
+		tasks: []types.Task{task},
+	}
+	history := recent.History{
+		{Cluster: otherAccountARN, Service: "payments", Container: "sidecar"},
+		{Cluster: viewClusterARN, Service: "worker", Container: "app"},
+	}
+	var offered []string
+	choose := func(title string, options []listview.Option) (string, bool, error) {
+		var values []string
+		for _, option := range options {
+			values = append(values, option.Value)
+		}
+		offered = append(offered, title+": "+strings.Join(values, ", "))
+		return options[0].Value, false, nil
+	}
+
+	_, quit, err := ResolveTarget(context.Background(), resolver, choose, ResolveOptions{Cluster: "production", History: history})
+	if err != nil || quit {
+		t.Fatalf("ResolveTarget() = (%t, %v), want successful selection", quit, err)
+	}
+	want := []string{
+		serviceChoiceTitle + ": worker, payments, ",
+		containerChoiceTitle + ": app, sidecar",
+	}
+	if !reflect.DeepEqual(offered, want) {
+		t.Fatalf("offered =\n%s\nwant\n%s", strings.Join(offered, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestByRecencySkipsDisabledAndUnofferedChoices(t *testing.T) {
+	options := []listview.Option{
+		{Label: "app", Value: "app"},
+		{Label: "sidecar", Value: "sidecar", Disabled: "container is STOPPED"},
+		{Label: "worker", Value: "worker"},
+	}
+
+	got := byRecency(options, []string{"gone", "sidecar", "worker"})
+	want := []listview.Option{
+		{Label: "worker", Value: "worker", Last: true},
+		{Label: "app", Value: "app"},
+		{Label: "sidecar", Value: "sidecar", Disabled: "container is STOPPED"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("byRecency() = %#v, want %#v", got, want)
+	}
+	if options[2].Last {
+		t.Fatal("byRecency() marked the caller's option")
+	}
+}
+
+func TestResolveTargetServiceStepQuitAndUnknownChoice(t *testing.T) {
+	resolver := &fakeTargetResolver{services: []types.Service{{ServiceName: aws.String("payments")}}}
+	_, quit, err := ResolveTarget(context.Background(), resolver, func(string, []listview.Option) (string, bool, error) {
+		return "", true, nil
+	}, ResolveOptions{
+		Cluster: "production",
+	})
+	if err != nil || !quit {
+		t.Fatalf("ResolveTarget() quit = %t, error = %v; want quit", quit, err)
+	}
+
+	_, _, err = ResolveTarget(context.Background(), resolver, func(string, []listview.Option) (string, bool, error) {
+		return "billing", false, nil
+	}, ResolveOptions{
+		Cluster: "production",
+	})
+	if err == nil || !strings.Contains(err.Error(), `selected ECS service "billing" is no longer available`) {
+		t.Fatalf("ResolveTarget() error = %v, want unknown service", err)
+	}
+}
+
+func TestResolveTargetSelectorFailsInsteadOfPrompting(t *testing.T) {
+	tasks := []types.Task{
+		viewReadyTask(viewFirstARN, "service:payments", viewReadyContainer("app", "runtime-first")),
+		viewReadyTask(
+			viewSecondARN,
+			"service:payments",
+			viewReadyContainer("app", "runtime-second"),
+			viewReadyContainer("sidecar", "runtime-sidecar"),
+		),
+	}
+	tests := []struct {
+		name      string
+		selector  target.Selector
+		wantErr   error
+		fragments []string
+	}{
+		{
+			name:      "no matching task",
+			selector:  target.Selector{Task: "task-third"},
+			wantErr:   target.ErrNoMatch,
+			fragments: []string{"select ECS task", `task "task-third"`},
+		},
+		{
+			name:      "ambiguous task",
+			selector:  target.Selector{Container: "app"},
+			wantErr:   target.ErrAmbiguous,
+			fragments: []string{"select ECS task", "task-first, task-second"},
+		},
+		{
+			name:      "ambiguous container",
+			selector:  target.Selector{Task: "task-second"},
+			wantErr:   target.ErrAmbiguous,
+			fragments: []string{"select ECS container", "app, sidecar"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := &fakeTargetResolver{tasks: tasks}
+			choose := func(title string, options []listview.Option) (string, bool, error) {
+				t.Fatalf("chooser called with %q, want selector error", title)
+				return "", false, nil
+			}
+
+			got, quit, err := ResolveTarget(context.Background(), resolver, choose, ResolveOptions{Cluster: "production", Selector: tt.selector})
+			assertResolveError(t, got, quit, err, tt.fragments...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ResolveTarget() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolveTargetSelectorNeverAsksForCluster(t *testing.T) {
+	task := viewReadyTask(viewSecondARN, "service:payments", viewReadyContainer("app", "runtime-second"))
+	choose := func(title string, options []listview.Option) (string, bool, error) {
+		t.Fatalf("chooser called with %q, want the selector to skip it", title)
+		return "", false, nil
+	}
+
+	resolver := &fakeTargetResolver{clusters: []string{viewClusterARN}, tasks: []types.Task{task}}
+	got, quit, err := ResolveTarget(context.Background(), resolver, choose, ResolveOptions{Selector: target.Selector{Task: viewSecondARN}})
+	if err != nil || quit {
+		t.Fatalf("ResolveTarget() = (%#v, %t, %v), want the task ARN's cluster", got, quit, err)
+	}
+	if resolver.waitCluster != viewClusterARN || got.TaskARN != viewSecondARN {
+		t.Errorf("lookup cluster = %q, task = %q; want %q, %q", resolver.waitCluster, got.TaskARN, viewClusterARN, viewSecondARN)
+	}
+	if !reflect.DeepEqual(resolver.calls, []string{"wait"}) {
+		t.Errorf("resolver calls = %v, want the clusters left unlisted", resolver.calls)
+	}
+
+	resolver = &fakeTargetResolver{clusters: []string{viewClusterARN}, tasks: []types.Task{task}}
+	got, quit, err = ResolveTarget(context.Background(), resolver, choose, ResolveOptions{Selector: target.Selector{Task: "task-second"}})
+	assertResolveError(t, got, quit, err, "--cluster", "task-second")
+	if len(resolver.calls) != 0 {
+		t.Errorf("resolver calls = %v, want none", resolver.calls)
+	}
+}
+
 func TestResolveTargetUserCancellation(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -358,7 +670,7 @@ func TestResolveTargetUserCancellation(t *testing.T) {
 				return "", true, nil
 			}
 
-			got, quit, err := ResolveTarget(context.Background(), tt.resolver, choose, tt.inputCluster, "", 0)
+			got, quit, err := ResolveTarget(context.Background(), tt.resolver, choose, ResolveOptions{Cluster: tt.inputCluster})
 			if err != nil {
 				t.Fatalf("ResolveTarget() error = %v, want nil on user cancellation", err)
 			}
@@ -427,7 +739,7 @@ func TestResolveTargetChooserErrorWinsOverCancellation(t *testing.T) {
 				return "ignored", true, chooseErr
 			}
 
-			got, quit, err := ResolveTarget(context.Background(), tt.resolver, choose, tt.inputCluster, "", 0)
+			got, quit, err := ResolveTarget(context.Background(), tt.resolver, choose, ResolveOptions{Cluster: tt.inputCluster})
 			assertResolveError(t, got, quit, err, tt.wantResource)
 			if !errors.Is(err, chooseErr) {
 				t.Fatalf("ResolveTarget() error = %v, want errors.Is(chooser sentinel)", err)
@@ -454,7 +766,7 @@ func TestResolveTargetPropagatesChooserError(t *testing.T) {
 		return "", false, chooseErr
 	}
 
-	got, quit, err := ResolveTarget(context.Background(), resolver, choose, "production", "", 0)
+	got, quit, err := ResolveTarget(context.Background(), resolver, choose, ResolveOptions{Cluster: "production"})
 	if !errors.Is(err, chooseErr) {
 		t.Fatalf("ResolveTarget() error = %v, want errors.Is(chooser sentinel)", err)
 	}
@@ -478,7 +790,7 @@ func TestResolveTargetPreservesFullClusterARN(t *testing.T) {
 		return "", false, nil
 	}
 
-	got, quit, err := ResolveTarget(context.Background(), resolver, choose, "  "+viewClusterARN+"  ", "payments", 4*time.Second)
+	got, quit, err := ResolveTarget(context.Background(), resolver, choose, ResolveOptions{Cluster: "  " + viewClusterARN + "  ", Service: "payments", MaxWait: 4 * time.Second})
 	if err != nil || quit {
 		t.Fatalf("ResolveTarget() = (%#v, %t, %v), want successful selection", got, quit, err)
 	}
@@ -570,7 +882,7 @@ func TestResolveTargetRejectsMalformedMetadata(t *testing.T) {
 				return "", false, nil
 			}
 
-			got, quit, err := ResolveTarget(context.Background(), tt.resolver, choose, tt.inputCluster, "", 0)
+			got, quit, err := ResolveTarget(context.Background(), tt.resolver, choose, ResolveOptions{Cluster: tt.inputCluster})
 			assertResolveError(t, got, quit, err, tt.wantResource)
 			if chooseCalls != 0 {
 				t.Fatalf("chooser call count = %d, want malformed metadata rejected first", chooseCalls)
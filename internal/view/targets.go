@@ -0,0 +1,92 @@
+package view
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/wim-web/tnnl/internal/listview"
+	"github.com/wim-web/tnnl/internal/recent"
+	"github.com/wim-web/tnnl/internal/target"
+)
+
+// ResolveTargets resolves every eligible task in a cluster or service that
+// matches selector, each with the same container, for commands that act on
+// all of them at once. The strategy is ignored. When selector names no
+// container and the tasks offer several, choose picks one for all tasks.
+// history orders the choices as it does for ResolveTarget.
+func ResolveTargets(
+	ctx context.Context,
+	resolver targetResolver,
+	choose Choose,
+	history recent.History,
+	inputCluster string,
+	inputService string,
+	selector target.Selector,
+) ([]target.Resolved, bool, error) {
+	ecsCluster, quit, err := chooseCluster(ctx, resolver, choose, history, inputCluster, selector)
+	if err != nil || quit {
+		return nil, quit, err
+	}
+
+	service := strings.TrimSpace(inputService)
+	if service == "" && !selector.Active() {
+		service, quit, err = chooseService(ctx, resolver, choose, history, ecsCluster)
+		if err != nil || quit {
+			return nil, quit, err
+		}
+	}
+
+	tasks, err := resolver.EligibleTasks(ctx, ecsCluster, service)
+	if err != nil {
+		return nil, false, fmt.Errorf("resolve eligible ECS tasks in cluster %q: %w", ecsCluster, err)
+	}
+	selector.Strategy = ""
+	if selector.Container == "" {
+		var names []string
+		reported := ""
+		for _, task := range selector.MatchingTasks(tasks) {
+			reported = taskCluster(task)
+			for _, container := range target.EligibleContainers(task) {
+				if name := aws.ToString(container.Name); !slices.Contains(names, name) {
+					names = append(names, name)
+				}
+			}
+		}
+		options := make([]listview.Option, 0, len(names))
+		for _, name := range names {
+			options = append(options, listview.Option{Label: name, Value: name})
+		}
+		options = byRecency(options, history.Containers(historyCluster(ecsCluster, reported), service))
+		if len(options) > 0 {
+			selector.Container, quit, err = chooseOption(containerChoiceTitle, options, true, choose)
+			if err != nil {
+				return nil, false, fmt.Errorf("select ECS container: %w", err)
+			}
+			if quit {
+				return nil, true, nil
+			}
+		}
+	}
+
+	matches := selector.MatchingTasks(tasks)
+	if len(matches) == 0 {
+		return nil, false, fmt.Errorf("select ECS tasks in cluster %q: %w: none of %d eligible ECS tasks match", ecsCluster, target.ErrNoMatch, len(tasks))
+	}
+	targets := make([]target.Resolved, 0, len(matches))
+	for _, task := range matches {
+		container, err := selector.SelectContainer(target.EligibleContainers(task))
+		if err != nil {
+			return nil, false, fmt.Errorf("select ECS container in task %q: %w", aws.ToString(task.TaskArn), err)
+		}
+		resolved, err := target.NewResolved(ecsCluster, task, container)
+		if err != nil {
+			return nil, false, err
+		}
+		resolved.Service = service
+		targets = append(targets, resolved)
+	}
+	return targets, false, nil
+}
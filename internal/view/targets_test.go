@@ -0,0 +1,66 @@
+package view
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/wim-web/tnnl/internal/listview"
+	"github.com/wim-web/tnnl/internal/target"
+)
+
+func TestResolveTargetsReturnsEveryEligibleTaskWithChosenContainer(t *testing.T) {
+	first := viewReadyTask(viewFirstARN, "service:payments", viewReadyContainer("app", "runtime-first"), viewReadyContainer("sidecar", "sidecar-first"))
+	second := viewReadyTask(viewSecondARN, "service:payments", viewReadyContainer("app", "runtime-second"))
+	resolver := &fakeTargetResolver{refreshed: [][]types.Task{{first, second}}}
+	var titles []string
+	choose := func(title string, options []listview.Option) (string, bool, error) {
+		titles = append(titles, title)
+		if len(options) != 2 || options[0].Value != "app" || options[1].Value != "sidecar" {
+			t.Fatalf("container options = %#v, want app and sidecar", options)
+		}
+		return "app", false, nil
+	}
+
+	got, quit, err := ResolveTargets(context.Background(), resolver, choose, nil, "production", "payments", target.Selector{})
+	if err != nil || quit {
+		t.Fatalf("ResolveTargets() = (%d targets, %t, %v), want success", len(got), quit, err)
+	}
+	if !reflect.DeepEqual(titles, []string{containerChoiceTitle}) {
+		t.Fatalf("chooser titles = %v, want one container step", titles)
+	}
+	if len(got) != 2 || got[0].TaskID != "task-first" || got[1].TaskID != "task-second" {
+		t.Fatalf("ResolveTargets() = %#v, want both tasks", got)
+	}
+	for _, resolved := range got {
+		if resolved.ContainerName != "app" || resolved.Service != "payments" {
+			t.Fatalf("target = %#v, want app container in payments", resolved)
+		}
+	}
+}
+
+func TestResolveTargetsAppliesSelectorWithoutPrompting(t *testing.T) {
+	first := viewReadyTask(viewFirstARN, "service:payments", viewReadyContainer("app", "runtime-first"))
+	second := viewReadyTask(viewSecondARN, "service:payments", viewReadyContainer("worker", "runtime-second"))
+	resolver := &fakeTargetResolver{refreshed: [][]types.Task{{first, second}}}
+	choose := func(title string, _ []listview.Option) (string, bool, error) {
+		t.Fatalf("chooser called with %q, want the selector to decide", title)
+		return "", false, nil
+	}
+
+	got, _, err := ResolveTargets(context.Background(), resolver, choose, nil, "production", "", target.Selector{Container: "worker", Strategy: target.StrategyFirst})
+	if err != nil {
+		t.Fatalf("ResolveTargets() error = %v", err)
+	}
+	if len(got) != 1 || got[0].TaskID != "task-second" || got[0].ContainerName != "worker" {
+		t.Fatalf("ResolveTargets() = %#v, want the worker task only", got)
+	}
+
+	resolver = &fakeTargetResolver{refreshed: [][]types.Task{{first}}}
+	_, _, err = ResolveTargets(context.Background(), resolver, choose, nil, "production", "", target.Selector{Family: "missing"})
+	if !errors.Is(err, target.ErrNoMatch) {
+		t.Fatalf("ResolveTargets() error = %v, want ErrNoMatch", err)
+	}
+}
@@ -0,0 +1,84 @@
+package view
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/wim-web/tnnl/internal/listview"
+	"github.com/wim-web/tnnl/internal/recent"
+	"github.com/wim-web/tnnl/internal/target"
+)
+
+// ResolveTask resolves a cluster and one of its running tasks, eligible or
+// not, for commands that inspect a task rather than connect to it. An active
+// selector picks the task without calling choose. history orders the
+// cluster and service choices as it does for ResolveTarget.
+func ResolveTask(
+	ctx context.Context,
+	resolver targetResolver,
+	choose Choose,
+	history recent.History,
+	inputCluster string,
+	inputService string,
+	selector target.Selector,
+) (string, target.TaskDiagnosis, bool, error) {
+	ecsCluster, quit, err := chooseCluster(ctx, resolver, choose, history, inputCluster, selector)
+	if err != nil || quit {
+		return "", target.TaskDiagnosis{}, quit, err
+	}
+
+	service := strings.TrimSpace(inputService)
+	if service == "" && !selector.Active() {
+		service, quit, err = chooseService(ctx, resolver, choose, history, ecsCluster)
+		if err != nil || quit {
+			return "", target.TaskDiagnosis{}, quit, err
+		}
+	}
+
+	diagnoses, err := resolver.DiagnoseTasks(ctx, ecsCluster, service)
+	if err != nil {
+		return "", target.TaskDiagnosis{}, false, fmt.Errorf("resolve ECS tasks in cluster %q: %w", ecsCluster, err)
+	}
+
+	var selectedARN string
+	if selector.Active() {
+		tasks := make([]types.Task, 0, len(diagnoses))
+		for _, diagnosis := range diagnoses {
+			tasks = append(tasks, diagnosis.Task)
+		}
+		task, err := selector.SelectTask(tasks)
+		if err != nil {
+			return "", target.TaskDiagnosis{}, false, fmt.Errorf("select ECS task in cluster %q: %w", ecsCluster, err)
+		}
+		selectedARN = aws.ToString(task.TaskArn)
+	} else {
+		options := make([]listview.Option, 0, len(diagnoses))
+		for _, diagnosis := range diagnoses {
+			label := taskLabel(diagnosis.Task)
+			if summary := diagnosis.Summary(); summary != "" {
+				label += "  " + summary
+			}
+			options = append(options, listview.Option{Label: label, Value: aws.ToString(diagnosis.Task.TaskArn)})
+		}
+		if len(options) == 0 {
+			return "", target.TaskDiagnosis{}, false, fmt.Errorf("%s: no running tasks", taskChoiceTitle)
+		}
+		selectedARN, quit, err = chooseOption(taskChoiceTitle, options, true, choose)
+		if err != nil {
+			return "", target.TaskDiagnosis{}, false, fmt.Errorf("select ECS task: %w", err)
+		}
+		if quit {
+			return "", target.TaskDiagnosis{}, true, nil
+		}
+	}
+
+	for _, diagnosis := range diagnoses {
+		if aws.ToString(diagnosis.Task.TaskArn) == selectedARN {
+			return ecsCluster, diagnosis, false, nil
+		}
+	}
+	return "", target.TaskDiagnosis{}, false, fmt.Errorf("selected ECS task %q is no longer available", selectedARN)
+}
@@ -0,0 +1,66 @@
+package view
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/wim-web/tnnl/internal/listview"
+	"github.com/wim-web/tnnl/internal/target"
+)
+
+func TestResolveTaskOffersIneligibleTasksWithSummary(t *testing.T) {
+	ready := viewReadyTask(viewFirstARN, "service:payments", viewReadyContainer("app", "runtime-first"))
+	disabled := viewReadyTask(viewSecondARN, "service:payments", viewReadyContainer("app", "runtime-second"))
+	disabled.EnableExecuteCommand = false
+	resolver := &fakeTargetResolver{running: []types.Task{ready, disabled}}
+	var labels []string
+	choose := func(title string, options []listview.Option) (string, bool, error) {
+		for _, option := range options {
+			if option.Disabled != "" {
+				t.Fatalf("option %q is disabled, want every task selectable", option.Label)
+			}
+			labels = append(labels, option.Label)
+		}
+		return options[1].Value, false, nil
+	}
+
+	cluster, diagnosis, quit, err := ResolveTask(context.Background(), resolver, choose, nil, "production", "payments", target.Selector{})
+	if err != nil || quit {
+		t.Fatalf("ResolveTask() quit = %t, error = %v", quit, err)
+	}
+	if cluster != "production" || aws.ToString(diagnosis.Task.TaskArn) != viewSecondARN || diagnosis.Eligible() {
+		t.Fatalf("ResolveTask() = %q, %#v; want the ineligible second task", cluster, diagnosis)
+	}
+	if len(labels) != 2 || labels[0] != "service:payments task-first" || !strings.Contains(labels[1], "execute command is not enabled") {
+		t.Fatalf("labels = %q, want the ineligible task explained", labels)
+	}
+}
+
+func TestResolveTaskSelectorAndEmptyCluster(t *testing.T) {
+	stopped := viewReadyTask(viewFirstARN, "service:payments", viewReadyContainer("app", "runtime-first"))
+	stopped.LastStatus = aws.String("STOPPING")
+	resolver := &fakeTargetResolver{running: []types.Task{stopped}}
+	choose := func(title string, _ []listview.Option) (string, bool, error) {
+		t.Fatalf("chooser called with %q, want the selector to pick", title)
+		return "", false, nil
+	}
+
+	_, diagnosis, _, err := ResolveTask(context.Background(), resolver, choose, nil, "production", "", target.Selector{Task: "task-first"})
+	if err != nil || aws.ToString(diagnosis.Task.TaskArn) != viewFirstARN {
+		t.Fatalf("ResolveTask() = %#v, %v; want the stopping task", diagnosis.Task.TaskArn, err)
+	}
+
+	_, _, _, err = ResolveTask(context.Background(), &fakeTargetResolver{}, choose, nil, "production", "payments", target.Selector{})
+	if err == nil || !strings.Contains(err.Error(), "no running tasks") {
+		t.Fatalf("ResolveTask() error = %v, want no running tasks", err)
+	}
+
+	_, _, _, err = ResolveTask(context.Background(), resolver, choose, nil, "production", "", target.Selector{Task: "task-missing"})
+	if !errors.Is(err, target.ErrNoMatch) {
+		t.Fatalf("ResolveTask() error = %v, want ErrNoMatch", err)
+	}
+}
@@ -0,0 +1,204 @@
+package view
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/wim-web/tnnl/internal/listview"
+	"github.com/wim-web/tnnl/internal/target"
+)
+
+// ChooseTable presents a table picker and returns the selected row value.
+type ChooseTable func(listview.Table) (string, bool, error)
+
+var taskColumns = []string{"TASK", "GROUP", "REVISION", "STARTED", "AZ", "PRIVATE IP", "LAUNCH", "CPU/MEM", "HEALTH"}
+
+// chooseTaskFromTable shows tasks as a table that re-reads the tasks of the
+// lookup while it is open. Ineligible tasks appear as disabled rows. The
+// selected task comes from the latest read.
+func chooseTaskFromTable(
+	ctx context.Context,
+	lookup taskLookup,
+	chooseTable ChooseTable,
+	tasks []types.Task,
+	ineligible []target.TaskDiagnosis,
+	now func() time.Time,
+) (types.Task, bool, error) {
+	rows, err := taskRows(tasks, now())
+	if err != nil {
+		return types.Task{}, false, fmt.Errorf("prepare ECS task choices: %w", err)
+	}
+	if len(rows) == 0 {
+		return types.Task{}, false, fmt.Errorf("%s: %w", taskChoiceTitle, target.ErrNoEligible)
+	}
+	if len(rows) == 1 && len(ineligible) == 0 {
+		return tasks[0], false, nil
+	}
+
+	var mu sync.Mutex
+	latest := tasks
+	selected, quit, err := chooseTable(listview.Table{
+		Title:   taskChoiceTitle,
+		Columns: taskColumns,
+		Rows:    append(rows, ineligibleTaskRows(ineligible, now())...),
+		Refresh: func() ([]listview.Row, error) {
+			refreshed, ineligible, err := lookup.tasks(ctx)
+			if err != nil {
+				return nil, err
+			}
+			rows, err := taskRows(refreshed, now())
+			if err != nil {
+				return nil, err
+			}
+			mu.Lock()
+			latest = refreshed
+			mu.Unlock()
+			return append(rows, ineligibleTaskRows(ineligible, now())...), nil
+		},
+	})
+	if err != nil {
+		return types.Task{}, false, fmt.Errorf("select ECS task: %w", err)
+	}
+	if quit {
+		return types.Task{}, true, nil
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	task, err := taskByARN(latest, selected)
+	if err != nil {
+		return types.Task{}, false, fmt.Errorf("resolve selected ECS task: %w", err)
+	}
+	return task, false, nil
+}
+
+// ineligibleTaskRows lays out ineligible tasks as disabled rows. A task
+// without a usable ARN cannot be shown in the table and is left out.
+func ineligibleTaskRows(diagnoses []target.TaskDiagnosis, now time.Time) []listview.Row {
+	var rows []listview.Row
+	for _, diagnosis := range diagnoses {
+		taskRow, err := taskRows([]types.Task{diagnosis.Task}, now)
+		if err != nil {
+			continue
+		}
+		taskRow[0].Disabled = diagnosis.Summary()
+		rows = append(rows, taskRow[0])
+	}
+	return rows
+}
+
+func taskRows(tasks []types.Task, now time.Time) ([]listview.Row, error) {
+	rows := make([]listview.Row, 0, len(tasks))
+	for _, task := range tasks {
+		arnValue, id, group, err := taskIdentity(task)
+		if err != nil {
+			return nil, err
+		}
+		family, revision := taskRevision(task)
+		startedAt := aws.ToTime(task.StartedAt)
+		rows = append(rows, listview.Row{
+			Value: arnValue,
+			Cells: []string{
+				id,
+				group,
+				cell(strings.TrimSuffix(family+":"+revision, ":")),
+				cell(taskAge(startedAt, now)),
+				cell(aws.ToString(task.AvailabilityZone)),
+				cell(privateIP(task)),
+				cell(launchType(task)),
+				cell(strings.TrimSuffix(aws.ToString(task.Cpu)+"/"+aws.ToString(task.Memory), "/")),
+				cell(string(task.HealthStatus)),
+			},
+			Keys: []string{
+				"",
+				"",
+				family + ":" + padNumber(revision),
+				startedKey(startedAt),
+				"",
+				"",
+				"",
+				padNumber(aws.ToString(task.Cpu)) + "/" + padNumber(aws.ToString(task.Memory)),
+				"",
+			},
+		})
+	}
+	return rows, nil
+}
+
+func cell(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "-"
+	}
+	return value
+}
+
+// taskRevision splits the task definition ARN into family and revision.
+func taskRevision(task types.Task) (string, string) {
+	definition := aws.ToString(task.TaskDefinitionArn)
+	definition = definition[strings.LastIndex(definition, "/")+1:]
+	family, revision, _ := strings.Cut(definition, ":")
+	return family, revision
+}
+
+func taskAge(startedAt, now time.Time) string {
+	if startedAt.IsZero() {
+		return ""
+	}
+	age := max(now.Sub(startedAt), 0)
+	switch {
+	case age < time.Minute:
+		return fmt.Sprintf("%ds", int(age.Seconds()))
+	case age < time.Hour:
+		return fmt.Sprintf("%dm", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh%dm", int(age.Hours()), int(age.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dd%dh", int(age.Hours())/24, int(age.Hours())%24)
+	}
+}
+
+// startedKey sorts the oldest task first; tasks that have not started sort
+// last.
+func startedKey(startedAt time.Time) string {
+	if startedAt.IsZero() {
+		return "~"
+	}
+	return startedAt.UTC().Format("20060102150405.000000000")
+}
+
+func privateIP(task types.Task) string {
+	for _, container := range task.Containers {
+		for _, iface := range container.NetworkInterfaces {
+			if ip := aws.ToString(iface.PrivateIpv4Address); ip != "" {
+				return ip
+			}
+		}
+	}
+	for _, attachment := range task.Attachments {
+		for _, detail := range attachment.Details {
+			if aws.ToString(detail.Name) == "privateIPv4Address" {
+				return aws.ToString(detail.Value)
+			}
+		}
+	}
+	return ""
+}
+
+func launchType(task types.Task) string {
+	if provider := aws.ToString(task.CapacityProviderName); provider != "" {
+		return provider
+	}
+	return string(task.LaunchType)
+}
+
+func padNumber(value string) string {
+	const width = 10
+	if len(value) >= width {
+		return value
+	}
+	return strings.Repeat("0", width-len(value)) + value
+}
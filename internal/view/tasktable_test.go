@@ -0,0 +1,140 @@
+package view
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/wim-web/tnnl/internal/listview"
+)
+
+const viewThirdARN = "arn:aws:ecs:us-east-1:123456789012:task/production/task-third"
+
+func TestTaskRowsShowTaskDetails(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC)
+	task := viewReadyTask(viewFirstARN, "service:payments")
+	task.TaskDefinitionArn = aws.String("arn:aws:ecs:us-east-1:123456789012:task-definition/payments:42")
+	task.StartedAt = aws.Time(now.Add(-(2*time.Hour + 5*time.Minute)))
+	task.AvailabilityZone = aws.String("us-east-1a")
+	task.Attachments = []types.Attachment{{Details: []types.KeyValuePair{
+		{Name: aws.String("subnetId"), Value: aws.String("subnet-1")},
+		{Name: aws.String("privateIPv4Address"), Value: aws.String("10.0.1.23")},
+	}}}
+	task.LaunchType = types.LaunchTypeFargate
+	task.Cpu = aws.String("256")
+	task.Memory = aws.String("512")
+	task.HealthStatus = types.HealthStatusHealthy
+	bare := viewReadyTask(viewSecondARN, "")
+
+	rows, err := taskRows([]types.Task{task, bare}, now)
+	if err != nil {
+		t.Fatalf("taskRows() error = %v", err)
+	}
+	want := []string{"task-first", "service:payments", "payments:42", "2h5m", "us-east-1a", "10.0.1.23", "FARGATE", "256/512", "HEALTHY"}
+	if rows[0].Value != viewFirstARN || !reflect.DeepEqual(rows[0].Cells, want) {
+		t.Fatalf("taskRows()[0] = %#v, want cells %q", rows[0], want)
+	}
+	if len(rows[0].Cells) != len(taskColumns) || len(rows[0].Keys) != len(taskColumns) {
+		t.Fatalf("row has %d cells and %d keys, want %d", len(rows[0].Cells), len(rows[0].Keys), len(taskColumns))
+	}
+	wantBare := []string{"task-second", "task", "-", "-", "-", "-", "-", "-", "-"}
+	if !reflect.DeepEqual(rows[1].Cells, wantBare) {
+		t.Fatalf("taskRows()[1] cells = %q, want %q", rows[1].Cells, wantBare)
+	}
+	if rows[0].Keys[3] >= rows[1].Keys[3] {
+		t.Fatalf("started keys %q >= %q, want unstarted tasks last", rows[0].Keys[3], rows[1].Keys[3])
+	}
+}
+
+func TestTaskRowKeysSortNumerically(t *testing.T) {
+	revision := func(arn string) types.Task {
+		task := viewReadyTask(viewFirstARN, "")
+		task.TaskDefinitionArn = aws.String(arn)
+		task.Cpu = aws.String(arn[strings.LastIndex(arn, ":")+1:])
+		return task
+	}
+	rows, err := taskRows([]types.Task{revision("task-definition/web:9"), revision("task-definition/web:10")}, time.Now())
+	if err != nil {
+		t.Fatalf("taskRows() error = %v", err)
+	}
+	if rows[0].Keys[2] >= rows[1].Keys[2] || rows[0].Keys[7] >= rows[1].Keys[7] {
+		t.Fatalf("revision keys %q/%q and CPU keys %q/%q, want 9 before 10", rows[0].Keys[2], rows[1].Keys[2], rows[0].Keys[7], rows[1].Keys[7])
+	}
+}
+
+func TestTaskAge(t *testing.T) {
+	now := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	tests := map[time.Duration]string{
+		30 * time.Second:            "30s",
+		5 * time.Minute:             "5m",
+		3*time.Hour + 4*time.Minute: "3h4m",
+		50 * time.Hour:              "2d2h",
+		-time.Minute:                "0s",
+	}
+	for age, want := range tests {
+		if got := taskAge(now.Add(-age), now); got != want {
+			t.Errorf("taskAge(%s) = %q, want %q", age, got, want)
+		}
+	}
+	if got := taskAge(time.Time{}, now); got != "" {
+		t.Errorf("taskAge(zero) = %q, want empty", got)
+	}
+}
+
+func TestResolveTargetTaskTableRefreshesAndSelectsLatestTask(t *testing.T) {
+	first := viewReadyTask(viewFirstARN, "service:payments", viewReadyContainer("app", "runtime-first"))
+	second := viewReadyTask(viewSecondARN, "service:payments", viewReadyContainer("app", "runtime-second"))
+	third := viewReadyTask(viewThirdARN, "service:payments", viewReadyContainer("app", "runtime-third"))
+	resolver := &fakeTargetResolver{
+		tasks:     []types.Task{first, second},
+		refreshed: [][]types.Task{{second, third}},
+	}
+	choose := func(title string, _ []listview.Option) (string, bool, error) {
+		t.Fatalf("list chooser called with %q, want the table for tasks", title)
+		return "", false, nil
+	}
+	chooseTable := func(table listview.Table) (string, bool, error) {
+		if table.Title != taskChoiceTitle || len(table.Rows) != 2 || table.Rows[0].Value != viewFirstARN {
+			t.Fatalf("table = %#v, want the two eligible tasks", table)
+		}
+		rows, err := table.Refresh()
+		if err != nil {
+			t.Fatalf("Refresh() error = %v", err)
+		}
+		if len(rows) != 2 || rows[1].Value != viewThirdARN {
+			t.Fatalf("refreshed rows = %#v, want the replacement task", rows)
+		}
+		return viewThirdARN, false, nil
+	}
+
+	got, quit, err := ResolveTarget(context.Background(), resolver, choose, ResolveOptions{Cluster: "production", Service: "payments", ChooseTable: chooseTable})
+	if err != nil || quit {
+		t.Fatalf("ResolveTarget() = (%#v, %t, %v), want the refreshed task", got, quit, err)
+	}
+	if got.TaskARN != viewThirdARN || got.RuntimeID != "runtime-third" {
+		t.Fatalf("resolved task = %q (%q), want the task only seen after refresh", got.TaskARN, got.RuntimeID)
+	}
+}
+
+func TestResolveTargetTaskTableQuitAndSingleTask(t *testing.T) {
+	first := viewReadyTask(viewFirstARN, "service:payments", viewReadyContainer("app", "runtime-first"))
+	second := viewReadyTask(viewSecondARN, "service:payments", viewReadyContainer("app", "runtime-second"))
+	quitTable := func(listview.Table) (string, bool, error) { return "", true, nil }
+	_, quit, err := ResolveTarget(context.Background(), &fakeTargetResolver{tasks: []types.Task{first, second}}, nil, ResolveOptions{Cluster: "production", Service: "payments", ChooseTable: quitTable})
+	if err != nil || !quit {
+		t.Fatalf("ResolveTarget() quit = %t, error = %v; want quit", quit, err)
+	}
+
+	unexpected := func(listview.Table) (string, bool, error) {
+		t.Fatal("table shown for a single task")
+		return "", false, nil
+	}
+	got, _, err := ResolveTarget(context.Background(), &fakeTargetResolver{tasks: []types.Task{second}}, nil, ResolveOptions{Cluster: "production", Service: "payments", ChooseTable: unexpected})
+	if err != nil || got.TaskARN != viewSecondARN {
+		t.Fatalf("ResolveTarget() = %q, %v; want the only task", got.TaskARN, err)
+	}
+}
@@ -2,24 +2,53 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
 
 	"github.com/wim-web/tnnl/cmd"
+	_ "github.com/wim-web/tnnl/cmd/check"
+	_ "github.com/wim-web/tnnl/cmd/cp"
 	_ "github.com/wim-web/tnnl/cmd/exec"
+	_ "github.com/wim-web/tnnl/cmd/history"
+	_ "github.com/wim-web/tnnl/cmd/ls"
+	_ "github.com/wim-web/tnnl/cmd/multiportforward"
 	_ "github.com/wim-web/tnnl/cmd/portforward"
 	_ "github.com/wim-web/tnnl/cmd/remoteportforward"
+	_ "github.com/wim-web/tnnl/cmd/replay"
+	_ "github.com/wim-web/tnnl/cmd/run"
+	_ "github.com/wim-web/tnnl/cmd/sessions"
 	_ "github.com/wim-web/tnnl/cmd/update"
+	"github.com/wim-web/tnnl/internal/event"
+	"github.com/wim-web/tnnl/internal/interrupt"
+	"github.com/wim-web/tnnl/pkg/command"
 )
 
 func main() {
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	ctx, stop := interrupt.Notify(context.Background())
 	defer stop()
 
-	if err := cmd.ExecuteContext(ctx); err != nil {
+	ran, err := cmd.ExecuteContextC(ctx)
+	if err == nil {
+		return
+	}
+	status := 1
+	var exitErr *command.ExitError
+	if errors.As(err, &exitErr) {
+		status = exitErr.Code
+	} else if caught, ok := interrupt.Cause(ctx); ok {
+		// A command ended by a signal exits the way the signal would have.
+		status = caught.ExitCode()
+	}
+	// A batch exec ends with the remote command's status; its own stderr
+	// already explains a failure, so only tnnl's reasons are printed.
+	if exitErr == nil || exitErr.Err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
 	}
+	if events := event.FromContext(ran.Context()); events.Enabled() {
+		info := event.Describe(err)
+		info.ExitStatus = status
+		_ = events.Emit(event.Error, info)
+	}
+	os.Exit(status)
 }
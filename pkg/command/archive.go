@@ -0,0 +1,237 @@
+package command
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Checksums maps each regular file of a copy to its SHA-256 digest in hex.
+// Keys are slash-separated paths below the copied file or directory, so the
+// copied file itself is "" and a source and its renamed copy compare equal.
+type Checksums map[string]string
+
+// WriteArchive writes a tar archive of the file or directory at source to w,
+// with name as its top-level entry, and returns the checksums of its files.
+func WriteArchive(w io.Writer, source, name string) (Checksums, error) {
+	sums := make(Checksums)
+	archive := tar.NewWriter(w)
+	err := filepath.WalkDir(source, func(current string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relative, err := filepath.Rel(source, current)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(relative)
+		if key == "." {
+			key = ""
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		var link string
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(current); err != nil {
+				return err
+			}
+		} else if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(name, key)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		file, err := os.Open(current)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		hasher := sha256.New()
+		if _, err := io.Copy(io.MultiWriter(archive, hasher), file); err != nil {
+			return err
+		}
+		sums[key] = hex.EncodeToString(hasher.Sum(nil))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("archive %s: %w", source, err)
+	}
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("archive %s: %w", source, err)
+	}
+	return sums, nil
+}
+
+// ExtractArchive extracts a tar archive with a single top-level entry into
+// dir, renaming that entry to name, and returns the checksums of the files it
+// wrote. Entries that would land outside the copy are rejected.
+func ExtractArchive(r io.Reader, dir, name string) (Checksums, error) {
+	sums := make(Checksums)
+	archive := tar.NewReader(r)
+	top := ""
+	var links []string
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read archive: %w", err)
+		}
+		entryName := strings.TrimSuffix(path.Clean(header.Name), "/")
+		if path.IsAbs(entryName) || entryName == ".." || strings.HasPrefix(entryName, "../") {
+			return nil, fmt.Errorf("archive entry %q is outside the copy", header.Name)
+		}
+		first, key, _ := strings.Cut(entryName, "/")
+		if top == "" {
+			top = first
+		}
+		if first != top {
+			return nil, fmt.Errorf("archive entry %q is outside the copy of %q", header.Name, top)
+		}
+		for _, link := range links {
+			if strings.HasPrefix(key, link+"/") {
+				return nil, fmt.Errorf("archive entry %q is below the symbolic link %q", header.Name, link)
+			}
+		}
+		target := filepath.Join(dir, name, filepath.FromSlash(key))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, header.FileInfo().Mode().Perm()|0o700); err != nil {
+				return nil, err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return nil, err
+			}
+			digest, err := writeFile(target, archive, header.FileInfo().Mode().Perm())
+			if err != nil {
+				return nil, err
+			}
+			sums[key] = digest
+		case tar.TypeSymlink:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return nil, err
+			}
+			if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return nil, err
+			}
+			links = append(links, key)
+		}
+	}
+	if top == "" {
+		return nil, errors.New("read archive: archive is empty")
+	}
+	return sums, nil
+}
+
+// Extractor runs ExtractArchive on the bytes written to it, so a download can
+// be extracted while it arrives.
+type Extractor struct {
+	pipe *io.PipeWriter
+	done chan struct{}
+	sums Checksums
+	err  error
+}
+
+func NewExtractor(dir, name string) *Extractor {
+	reader, writer := io.Pipe()
+	e := &Extractor{pipe: writer, done: make(chan struct{})}
+	go func() {
+		defer close(e.done)
+		e.sums, e.err = ExtractArchive(reader, dir, name)
+		if e.err == nil {
+			// Padding after the end of the archive is still written.
+			_, _ = io.Copy(io.Discard, reader)
+		}
+		reader.CloseWithError(e.err)
+	}()
+	return e
+}
+
+func (e *Extractor) Write(p []byte) (int, error) {
+	return e.pipe.Write(p)
+}
+
+// Close ends the archive and returns the checksums of the extracted files.
+func (e *Extractor) Close() (Checksums, error) {
+	e.pipe.Close()
+	<-e.done
+	return e.sums, e.err
+}
+
+func writeFile(target string, r io.Reader, mode fs.FileMode) (string, error) {
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return "", err
+	}
+	hasher := sha256.New()
+	_, copyErr := io.Copy(io.MultiWriter(file, hasher), r)
+	if err := errors.Join(copyErr, file.Close()); err != nil {
+		return "", fmt.Errorf("write %s: %w", target, err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// ParseChecksums reads sha256sum output listing the files of a copy. The
+// first component of each path, the copied file or directory, is dropped.
+func ParseChecksums(lines []string) (Checksums, error) {
+	sums := make(Checksums)
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		digest, name, ok := strings.Cut(line, "  ")
+		if !ok || len(digest) != hex.EncodedLen(sha256.Size) {
+			return nil, fmt.Errorf("checksum line %q is not sha256sum output", line)
+		}
+		_, key, _ := strings.Cut(strings.TrimPrefix(name, "*"), "/")
+		sums[key] = digest
+	}
+	return sums, nil
+}
+
+// VerifyChecksums reports the files of source that are missing from copied or
+// differ from it.
+func VerifyChecksums(source, copied Checksums) error {
+	var mismatched []string
+	for key, digest := range source {
+		if copied[key] != digest {
+			if key == "" {
+				key = "."
+			}
+			mismatched = append(mismatched, key)
+		}
+	}
+	if len(mismatched) == 0 {
+		return nil
+	}
+	sort.Strings(mismatched)
+	return fmt.Errorf("checksum mismatch for %d of %d files: %s", len(mismatched), len(source), strings.Join(mismatched, ", "))
+}
@@ -0,0 +1,197 @@
+package command
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrTimedOut explains the status of a batch command that ran out of time.
+var ErrTimedOut = errors.New("remote command timed out")
+
+// ExitError carries the exit status tnnl should end with. Err, when set,
+// explains a status that did not come from the remote command itself.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("remote command exited with status %d", e.Code)
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// NewBatchMarker returns a random token that tags the lines BatchCommand adds
+// to the session output.
+func NewBatchMarker() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate batch marker: %w", err)
+	}
+	return "tnnl-" + hex.EncodeToString(buf), nil
+}
+
+// BatchCommand wraps command for a non-interactive run. ECS Exec sessions
+// always run in a terminal, which merges stdout and stderr and drops the exit
+// status, so the wrapper prefixes stderr lines with marker and prints the
+// exit status on a marked line; BatchOutput takes them apart again.
+func BatchCommand(command, marker string) string {
+	return "sh -c " + shellQuote(batchScript(command, marker))
+}
+
+func batchScript(command, marker string) string {
+	return fmt.Sprintf(
+		`printf "%%s:start\n" %[1]s; `+
+			`{ { (eval %[2]s); printf "\n%%s:exit:%%s\n" %[1]s "$?"; } 2>&1 1>&3 3>&- | `+
+			`while IFS= read -r line || [ -n "$line" ]; do printf "%%s:err:%%s\n" %[1]s "$line"; done; } 3>&1`,
+		marker, shellQuote(command),
+	)
+}
+
+// shellQuote quotes value as one POSIX shell word.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// BatchOutput splits the terminal stream of a BatchCommand session into the
+// remote command's stdout and stderr and records its exit status. Output
+// before the start marker, such as the session client's banner, is dropped,
+// and terminal line endings are turned back into plain newlines.
+type BatchOutput struct {
+	stdout io.Writer
+	stderr io.Writer
+	start  string
+	err    string
+	exit   string
+
+	buf            []byte
+	started        bool
+	finished       bool
+	pendingNewline bool
+	code           int
+}
+
+func NewBatchOutput(marker string, stdout, stderr io.Writer) *BatchOutput {
+	return &BatchOutput{
+		stdout: stdout,
+		stderr: stderr,
+		start:  marker + ":start",
+		err:    marker + ":err:",
+		exit:   marker + ":exit:",
+	}
+}
+
+// Write routes every complete line in p and keeps a trailing partial line
+// until more output or Close arrives.
+func (o *BatchOutput) Write(p []byte) (int, error) {
+	o.buf = append(o.buf, p...)
+	for {
+		i := bytes.IndexByte(o.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := strings.TrimSuffix(string(o.buf[:i]), "\r")
+		o.buf = o.buf[i+1:]
+		if err := o.line(line); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+// Close routes a final line that did not end in a newline. Without an exit
+// marker the held back newline did end a line of the command's output, so it
+// is written too.
+func (o *BatchOutput) Close() error {
+	if len(o.buf) > 0 {
+		line := strings.TrimSuffix(string(o.buf), "\r")
+		o.buf = nil
+		if err := o.line(line); err != nil {
+			return err
+		}
+	}
+	if o.pendingNewline && !o.finished {
+		o.pendingNewline = false
+		_, err := io.WriteString(o.stdout, "\n")
+		return err
+	}
+	return nil
+}
+
+// ExitCode returns the remote command's exit status once its marker was seen.
+func (o *BatchOutput) ExitCode() (int, bool) {
+	return o.code, o.finished
+}
+
+func (o *BatchOutput) line(line string) error {
+	if !o.started {
+		if strings.Contains(line, o.start) {
+			o.started = true
+		}
+		return nil
+	}
+	if i := strings.Index(line, o.err); i >= 0 {
+		if err := o.stdoutText(line[:i]); err != nil {
+			return err
+		}
+		_, err := io.WriteString(o.stderr, line[i+len(o.err):]+"\n")
+		return err
+	}
+	if i := strings.Index(line, o.exit); i >= 0 {
+		if err := o.stdoutText(line[:i]); err != nil {
+			return err
+		}
+		code, err := strconv.Atoi(strings.TrimSpace(line[i+len(o.exit):]))
+		if err != nil {
+			return fmt.Errorf("read remote exit status %q: %w", line[i+len(o.exit):], err)
+		}
+		// The wrapper puts a newline before the marker; the last line's
+		// newline belongs to it rather than to the command.
+		o.code, o.finished, o.pendingNewline = code, true, false
+		return nil
+	}
+	if o.finished {
+		return nil
+	}
+	if err := o.flushNewline(); err != nil {
+		return err
+	}
+	if err := o.stdoutText(line); err != nil {
+		return err
+	}
+	o.pendingNewline = true
+	return nil
+}
+
+// stdoutText writes text as the continuation of the current stdout line. Text
+// cut short by a marker keeps the held back newline pending, since the
+// wrapper's own newline before the exit marker may be the one held back.
+func (o *BatchOutput) stdoutText(text string) error {
+	if o.finished || text == "" {
+		return nil
+	}
+	if err := o.flushNewline(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(o.stdout, text)
+	return err
+}
+
+func (o *BatchOutput) flushNewline() error {
+	if !o.pendingNewline {
+		return nil
+	}
+	o.pendingNewline = false
+	_, err := io.WriteString(o.stdout, "\n")
+	return err
+}
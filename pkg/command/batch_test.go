@@ -0,0 +1,130 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+const testMarker = "tnnl-0123456789abcdef"
+
+func TestBatchCommandSplitsOutputAndExitStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		command    string
+		wantStdout string
+		wantStderr string
+		wantCode   int
+	}{
+		{name: "both streams", command: "echo out; echo err >&2; echo more", wantStdout: "out\nmore\n", wantStderr: "err\n"},
+		{name: "exit status", command: "echo failing >&2; exit 7", wantStderr: "failing\n", wantCode: 7},
+		{name: "no trailing newline", command: "printf 'a\\nb'; printf e >&2", wantStdout: "a\nb", wantStderr: "e\n"},
+		{name: "blank lines", command: "printf '\\n\\nx\\n\\n'", wantStdout: "\n\nx\n\n"},
+		{name: "quotes", command: `echo "it's" '$HOME'`, wantStdout: "it's $HOME\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			output := NewBatchOutput(testMarker, &stdout, &stderr)
+			cmd := exec.Command("/bin/sh", "-c", BatchCommand(tt.command, testMarker))
+			cmd.Stdout, cmd.Stderr = output, output
+			if err := cmd.Run(); err != nil {
+				t.Fatalf("run batch command: %v", err)
+			}
+			if err := output.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			if stdout.String() != tt.wantStdout || stderr.String() != tt.wantStderr {
+				t.Fatalf("output = (%q, %q), want (%q, %q)", stdout.String(), stderr.String(), tt.wantStdout, tt.wantStderr)
+			}
+			if code, ok := output.ExitCode(); !ok || code != tt.wantCode {
+				t.Fatalf("ExitCode() = (%d, %t), want (%d, true)", code, ok, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestBatchOutputHandlesTerminalStream(t *testing.T) {
+	stream := "Starting session with SessionId: abc\r\n" +
+		testMarker + ":start\r\n" +
+		"out\r\n" +
+		"half" + testMarker + ":err:warning\r\n" +
+		"line\r\n" +
+		"\r\n" + testMarker + ":exit:2\r\n" +
+		testMarker + ":err:late\r\n" +
+		"Exiting session with sessionId: abc.\r\n"
+
+	var stdout, stderr strings.Builder
+	output := NewBatchOutput(testMarker, &stdout, &stderr)
+	for _, chunk := range []string{stream[:20], stream[20:51], stream[51:]} {
+		if _, err := output.Write([]byte(chunk)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := output.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if want := "out\nhalfline\n"; stdout.String() != want {
+		t.Fatalf("stdout = %q, want %q", stdout.String(), want)
+	}
+	if want := "warning\nlate\n"; stderr.String() != want {
+		t.Fatalf("stderr = %q, want %q", stderr.String(), want)
+	}
+	if code, ok := output.ExitCode(); !ok || code != 2 {
+		t.Fatalf("ExitCode() = (%d, %t), want (2, true)", code, ok)
+	}
+}
+
+func TestBatchOutputWithoutExitMarker(t *testing.T) {
+	var stdout strings.Builder
+	output := NewBatchOutput(testMarker, &stdout, &strings.Builder{})
+	if _, err := fmt.Fprintf(output, "%s:start\nstarted\n", testMarker); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := output.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if stdout.String() != "started\n" {
+		t.Fatalf("stdout = %q, want the complete line", stdout.String())
+	}
+	if _, ok := output.ExitCode(); ok {
+		t.Fatal("ExitCode() ok = true, want false without an exit marker")
+	}
+}
+
+func TestBatchOutputRejectsMalformedExitStatus(t *testing.T) {
+	output := NewBatchOutput(testMarker, &strings.Builder{}, &strings.Builder{})
+	_, err := fmt.Fprintf(output, "%s:start\n%s:exit:oops\n", testMarker, testMarker)
+	if err == nil || !strings.Contains(err.Error(), "read remote exit status") {
+		t.Fatalf("Write() error = %v, want exit status error", err)
+	}
+}
+
+func TestExitErrorMessage(t *testing.T) {
+	if got := (&ExitError{Code: 3}).Error(); got != "remote command exited with status 3" {
+		t.Fatalf("Error() = %q", got)
+	}
+	cause := errors.New("timed out")
+	err := &ExitError{Code: 124, Err: cause}
+	if err.Error() != "timed out" || !errors.Is(err, cause) {
+		t.Fatalf("ExitError = %v, want it to report and wrap %v", err, cause)
+	}
+}
+
+func TestNewBatchMarkerIsUnique(t *testing.T) {
+	first, err := NewBatchMarker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewBatchMarker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second || !strings.HasPrefix(first, "tnnl-") || len(first) != len("tnnl-")+16 {
+		t.Fatalf("NewBatchMarker() = %q, %q, want distinct tnnl- tokens", first, second)
+	}
+}
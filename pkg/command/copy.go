@@ -0,0 +1,225 @@
+package command
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Copies travel over the exec session's terminal as a base64 tar stream, one
+// encoded line at a time, since a terminal would mangle raw bytes. After the
+// archive the container prints a line ending in copySumsSuffix and then the
+// sha256sum of each copied file, when sha256sum is available.
+const (
+	copySumsSuffix = ":sums"
+	copyLineLength = 76
+	// endOfInput makes the container's terminal report end of file to
+	// base64 -d once every line before it was read.
+	endOfInput = "\x04"
+)
+
+// UploadScript returns the container side of an upload: it reads the archive
+// of name from standard input and places it at destination, inside it when
+// destination is a directory and under that name otherwise.
+func UploadScript(destination, name, marker string) string {
+	return fmt.Sprintf(`stty -echo 2>/dev/null
+dst=%[1]s; name=%[2]s
+if [ -d "$dst" ]; then dir=$dst; target=$name; else dir=$(dirname "$dst"); target=$(basename "$dst"); fi
+base64 -d | tar xf - -C "$dir" || exit $?
+if [ "$target" != "$name" ]; then rm -f "$dir/$target" && mv "$dir/$name" "$dir/$target" || exit $?; fi
+printf '%%s\n' %[3]s
+cd "$dir" && if command -v sha256sum >/dev/null 2>&1; then find "$target" -type f -exec sha256sum {} +; fi`,
+		shellQuote(destination), shellQuote(name), shellQuote(marker+copySumsSuffix))
+}
+
+// DownloadScript returns the container side of a download: it prints the
+// archive of the file or directory at source.
+func DownloadScript(source, marker string) string {
+	return fmt.Sprintf(`src=%[1]s
+cd "$(dirname "$src")" || exit $?
+name=$(basename "$src")
+[ -e "$name" ] || { echo "$src: No such file or directory" >&2; exit 1; }
+tar cf - "$name" | base64 || exit $?
+printf '%%s\n' %[2]s
+if command -v sha256sum >/dev/null 2>&1; then find "$name" -type f -exec sha256sum {} +; fi`,
+		shellQuote(source), shellQuote(marker+copySumsSuffix))
+}
+
+// WriteUpload writes the terminal input of an upload of source, named name,
+// to w and returns the checksums of the files sent. The archive is also
+// written to progress.
+func WriteUpload(w io.Writer, source, name string, progress io.Writer) (Checksums, error) {
+	lines := &lineBreaker{w: w}
+	encoder := base64.NewEncoder(base64.StdEncoding, lines)
+	sums, err := WriteArchive(io.MultiWriter(encoder, progress), source, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	if err := lines.Close(); err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(w, endOfInput); err != nil {
+		return nil, err
+	}
+	return sums, nil
+}
+
+// lineBreaker ends a line after every copyLineLength bytes, keeping each
+// under the terminal's line length limit.
+type lineBreaker struct {
+	w      io.Writer
+	column int
+}
+
+func (l *lineBreaker) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), copyLineLength-l.column)
+		if _, err := l.w.Write(p[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		l.column += n
+		p = p[n:]
+		if l.column == copyLineLength {
+			if _, err := io.WriteString(l.w, "\n"); err != nil {
+				return written, err
+			}
+			l.column = 0
+		}
+	}
+	return written, nil
+}
+
+// Close ends a final partial line.
+func (l *lineBreaker) Close() error {
+	if l.column == 0 {
+		return nil
+	}
+	l.column = 0
+	_, err := io.WriteString(l.w, "\n")
+	return err
+}
+
+// CopyOutput reads the standard output of an UploadScript or DownloadScript
+// session. Before the checksum line it decodes the archive to archive, which
+// may be nil for an upload; after it, it collects the container's checksums.
+type CopyOutput struct {
+	archive io.Writer
+	sums    string
+
+	buf       []byte
+	inSums    bool
+	sumsLines []string
+}
+
+func NewCopyOutput(marker string, archive io.Writer) *CopyOutput {
+	return &CopyOutput{archive: archive, sums: marker + copySumsSuffix}
+}
+
+func (o *CopyOutput) Write(p []byte) (int, error) {
+	o.buf = append(o.buf, p...)
+	for {
+		i := bytes.IndexByte(o.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := strings.TrimSpace(string(o.buf[:i]))
+		o.buf = o.buf[i+1:]
+		if err := o.line(line); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+// Close handles a final line that did not end in a newline.
+func (o *CopyOutput) Close() error {
+	if len(o.buf) == 0 {
+		return nil
+	}
+	line := strings.TrimSpace(string(o.buf))
+	o.buf = nil
+	return o.line(line)
+}
+
+// Checksums returns the checksums the container reported, and false when it
+// reported none because the copy did not finish or sha256sum is missing.
+func (o *CopyOutput) Checksums() (Checksums, bool, error) {
+	if !o.inSums || len(o.sumsLines) == 0 {
+		return nil, false, nil
+	}
+	sums, err := ParseChecksums(o.sumsLines)
+	if err != nil {
+		return nil, false, err
+	}
+	return sums, true, nil
+}
+
+func (o *CopyOutput) line(line string) error {
+	switch {
+	case line == o.sums:
+		o.inSums = true
+		return nil
+	case o.inSums:
+		o.sumsLines = append(o.sumsLines, line)
+		return nil
+	case o.archive == nil || line == "":
+		// An upload echoes its input until the terminal stops echoing.
+		return nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(line)
+	if err != nil {
+		return fmt.Errorf("decode copied data: %w", err)
+	}
+	_, err = o.archive.Write(decoded)
+	return err
+}
+
+// Progress counts the bytes written to it and reports them on w as label
+// followed by the amount, rewriting one line as the copy advances.
+type Progress struct {
+	w     io.Writer
+	label string
+	total int64
+	shown int64
+}
+
+const progressStep = 1 << 20
+
+func NewProgress(w io.Writer, label string) *Progress {
+	return &Progress{w: w, label: label}
+}
+
+func (p *Progress) Write(b []byte) (int, error) {
+	p.total += int64(len(b))
+	if p.total-p.shown >= progressStep {
+		p.shown = p.total
+		fmt.Fprintf(p.w, "\r%s  %s", p.label, formatBytes(p.total))
+	}
+	return len(b), nil
+}
+
+// Done writes the final amount and ends the progress line.
+func (p *Progress) Done() {
+	fmt.Fprintf(p.w, "\r%s  %s\n", p.label, formatBytes(p.total))
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, suffix := float64(n)/unit, "KiB"
+	for _, next := range []string{"MiB", "GiB", "TiB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, next
+	}
+	return fmt.Sprintf("%.1f %s", value, suffix)
+}
@@ -0,0 +1,217 @@
+package command
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDownloadScriptRoundTripsDirectory(t *testing.T) {
+	requireTools(t, "tar", "base64", "sha256sum")
+	source := writeCopyTree(t)
+	destination := t.TempDir()
+
+	extractor := NewExtractor(destination, "copy")
+	var stderr strings.Builder
+	copyOutput := NewCopyOutput(testMarker, extractor)
+	output := NewBatchOutput(testMarker, copyOutput, &stderr)
+	runCopyScript(t, DownloadScript(source, testMarker), nil, output)
+	if err := copyOutput.Close(); err != nil {
+		t.Fatalf("CopyOutput.Close() error = %v", err)
+	}
+	local, err := extractor.Close()
+	if err != nil {
+		t.Fatalf("Extractor.Close() error = %v", err)
+	}
+
+	remote, ok, err := copyOutput.Checksums()
+	if err != nil || !ok {
+		t.Fatalf("Checksums() = (%v, %t, %v), want the container's checksums", remote, ok, err)
+	}
+	if err := VerifyChecksums(remote, local); err != nil {
+		t.Fatalf("VerifyChecksums() error = %v", err)
+	}
+	if len(local) != 2 {
+		t.Fatalf("copied files = %v, want 2", local)
+	}
+	assertFile(t, filepath.Join(destination, "copy", "nested", "b.txt"), "bravo\n")
+	if code, _ := output.ExitCode(); code != 0 || stderr.String() != "" {
+		t.Fatalf("exit = %d, stderr = %q", code, stderr.String())
+	}
+}
+
+func TestUploadScriptPlacesCopyAndReportsChecksums(t *testing.T) {
+	requireTools(t, "tar", "base64", "sha256sum")
+	source := writeCopyTree(t)
+
+	tests := []struct {
+		name        string
+		destination func(dir string) string
+		want        string
+	}{
+		{name: "into directory", destination: func(dir string) string { return dir }, want: "tree"},
+		{name: "renamed", destination: func(dir string) string { return filepath.Join(dir, "renamed") }, want: "renamed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remoteDir := t.TempDir()
+			var upload, progress bytes.Buffer
+			local, err := WriteUpload(&upload, source, "tree", &progress)
+			if err != nil {
+				t.Fatalf("WriteUpload() error = %v", err)
+			}
+			input := upload.Bytes()
+			if !bytes.HasSuffix(input, []byte(endOfInput)) {
+				t.Fatalf("upload does not end with the end of input character")
+			}
+			for _, line := range strings.Split(strings.TrimSuffix(string(input), "\n"+endOfInput), "\n") {
+				if len(line) > copyLineLength {
+					t.Fatalf("upload line has %d characters, want at most %d", len(line), copyLineLength)
+				}
+			}
+			if progress.Len() == 0 {
+				t.Fatal("WriteUpload() wrote nothing to progress")
+			}
+
+			copyOutput := NewCopyOutput(testMarker, nil)
+			// A local pipe has no terminal to turn the end of input character
+			// into end of file, so it is dropped here.
+			stdin := bytes.NewReader(bytes.TrimSuffix(input, []byte(endOfInput)))
+			runCopyScript(t, UploadScript(tt.destination(remoteDir), "tree", testMarker), stdin, NewBatchOutput(testMarker, copyOutput, io.Discard))
+			if err := copyOutput.Close(); err != nil {
+				t.Fatalf("CopyOutput.Close() error = %v", err)
+			}
+
+			remote, ok, err := copyOutput.Checksums()
+			if err != nil || !ok {
+				t.Fatalf("Checksums() = (%v, %t, %v), want the container's checksums", remote, ok, err)
+			}
+			if err := VerifyChecksums(local, remote); err != nil {
+				t.Fatalf("VerifyChecksums() error = %v", err)
+			}
+			assertFile(t, filepath.Join(remoteDir, tt.want, "a.txt"), "alpha\n")
+		})
+	}
+}
+
+func TestExtractArchiveRejectsEntriesOutsideTheCopy(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []tar.Header
+	}{
+		{name: "parent", entries: []tar.Header{{Name: "../evil", Typeflag: tar.TypeReg}}},
+		{name: "absolute", entries: []tar.Header{{Name: "/etc/evil", Typeflag: tar.TypeReg}}},
+		{name: "second top entry", entries: []tar.Header{{Name: "a", Typeflag: tar.TypeReg}, {Name: "b", Typeflag: tar.TypeReg}}},
+		{name: "through symlink", entries: []tar.Header{
+			{Name: "top/", Typeflag: tar.TypeDir, Mode: 0o755},
+			{Name: "top/link", Typeflag: tar.TypeSymlink, Linkname: "/tmp"},
+			{Name: "top/link/evil", Typeflag: tar.TypeReg},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var archive bytes.Buffer
+			writer := tar.NewWriter(&archive)
+			for _, header := range tt.entries {
+				if err := writer.WriteHeader(&header); err != nil {
+					t.Fatal(err)
+				}
+			}
+			writer.Close()
+
+			if _, err := ExtractArchive(&archive, t.TempDir(), "copy"); err == nil {
+				t.Fatal("ExtractArchive() error = nil, want the entry rejected")
+			}
+		})
+	}
+}
+
+func TestVerifyChecksumsReportsMissingAndChangedFiles(t *testing.T) {
+	source := Checksums{"": strings.Repeat("a", 64)}
+	if err := VerifyChecksums(source, Checksums{"": strings.Repeat("a", 64)}); err != nil {
+		t.Fatalf("VerifyChecksums() error = %v", err)
+	}
+	source = Checksums{"a": strings.Repeat("a", 64), "b": strings.Repeat("b", 64), "c": strings.Repeat("c", 64)}
+	err := VerifyChecksums(source, Checksums{"a": strings.Repeat("a", 64), "b": strings.Repeat("0", 64)})
+	if err == nil || err.Error() != "checksum mismatch for 2 of 3 files: b, c" {
+		t.Fatalf("VerifyChecksums() error = %v", err)
+	}
+}
+
+func TestParseChecksumsDropsTopLevelName(t *testing.T) {
+	digest := strings.Repeat("f", 64)
+	got, err := ParseChecksums([]string{digest + "  dump.hprof", digest + "  conf/app/settings.yml", ""})
+	if err != nil {
+		t.Fatalf("ParseChecksums() error = %v", err)
+	}
+	if got[""] != digest || got["app/settings.yml"] != digest || len(got) != 2 {
+		t.Fatalf("ParseChecksums() = %v", got)
+	}
+	if _, err := ParseChecksums([]string{"not a checksum"}); err == nil {
+		t.Fatal("ParseChecksums() error = nil, want malformed line rejected")
+	}
+}
+
+func TestProgressReportsCopiedBytes(t *testing.T) {
+	var out strings.Builder
+	progress := NewProgress(&out, "heap.hprof")
+	progress.Write(make([]byte, progressStep+512))
+	progress.Done()
+	if want := "\rheap.hprof  1.0 MiB\rheap.hprof  1.0 MiB\n"; out.String() != want {
+		t.Fatalf("progress = %q, want %q", out.String(), want)
+	}
+}
+
+func runCopyScript(t *testing.T, script string, stdin io.Reader, output *BatchOutput) {
+	t.Helper()
+	cmd := exec.Command("/bin/sh", "-c", BatchCommand(script, testMarker))
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdin, output, output
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("run copy script: %v", err)
+	}
+	if err := output.Close(); err != nil {
+		t.Fatalf("BatchOutput.Close() error = %v", err)
+	}
+	if code, ok := output.ExitCode(); !ok || code != 0 {
+		t.Fatalf("copy script exit = (%d, %t), want 0", code, ok)
+	}
+}
+
+func writeCopyTree(t *testing.T) string {
+	t.Helper()
+	source := filepath.Join(t.TempDir(), "tree")
+	if err := os.MkdirAll(filepath.Join(source, "nested"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"a.txt": "alpha\n", "nested/b.txt": "bravo\n"} {
+		if err := os.WriteFile(filepath.Join(source, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return source
+}
+
+func assertFile(t *testing.T, path, want string) {
+	t.Helper()
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read copied file: %v", err)
+	}
+	if string(got) != want {
+		t.Fatalf("%s = %q, want %q", path, got, want)
+	}
+}
+
+func requireTools(t *testing.T, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if _, err := exec.LookPath(name); err != nil {
+			t.Skipf("%s is not installed", name)
+		}
+	}
+}
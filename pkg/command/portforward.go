@@ -17,6 +17,7 @@ const remoteSessionCleanupTimeout = 5 * time.Second
 var errInvalidSessionResponse = errors.New("invalid remote session response")
 
 type SessionAPI interface {
+	DescribeSessions(context.Context, *ssm.DescribeSessionsInput, ...func(*ssm.Options)) (*ssm.DescribeSessionsOutput, error)
 	StartSession(context.Context, *ssm.StartSessionInput, ...func(*ssm.Options)) (*ssm.StartSessionOutput, error)
 	TerminateSession(context.Context, *ssm.TerminateSessionInput, ...func(*ssm.Options)) (*ssm.TerminateSessionOutput, error)
 }
@@ -34,6 +34,22 @@ type fakeSessionAPI struct {
 	terminateCalls int
 	terminateCtx   context.Context
 	terminateInput *ssm.TerminateSessionInput
+
+	describeOutputs []*ssm.DescribeSessionsOutput
+	describeErr     error
+	describeInputs  []ssm.DescribeSessionsInput
+}
+
+func (f *fakeSessionAPI) DescribeSessions(
+	_ context.Context,
+	input *ssm.DescribeSessionsInput,
+	_ ...func(*ssm.Options),
+) (*ssm.DescribeSessionsOutput, error) {
+	f.describeInputs = append(f.describeInputs, *input)
+	if f.describeErr != nil {
+		return nil, f.describeErr
+	}
+	return f.describeOutputs[len(f.describeInputs)-1], nil
 }
 
 func (f *fakeSessionAPI) StartSession(
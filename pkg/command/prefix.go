@@ -0,0 +1,74 @@
+package command
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// SharedOutput lets several sessions write to one writer without their lines
+// interleaving.
+type SharedOutput struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewSharedOutput(w io.Writer) *SharedOutput {
+	return &SharedOutput{w: w}
+}
+
+// Write writes p to the shared output as one piece.
+func (o *SharedOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.w.Write(p)
+}
+
+// Prefixed returns a writer that puts prefix before each line it passes to
+// the shared output. Lines are written whole; Close writes a final partial
+// line.
+func (o *SharedOutput) Prefixed(prefix string) *PrefixWriter {
+	return &PrefixWriter{output: o, prefix: prefix}
+}
+
+type PrefixWriter struct {
+	output *SharedOutput
+	prefix string
+	buf    []byte
+}
+
+func (w *PrefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	end := bytes.LastIndexByte(w.buf, '\n')
+	if end < 0 {
+		return len(p), nil
+	}
+	lines := w.buf[:end+1]
+	w.buf = append([]byte(nil), w.buf[end+1:]...)
+	if err := w.write(lines); err != nil {
+		return len(p), err
+	}
+	return len(p), nil
+}
+
+// Close writes a buffered line that did not end in a newline, adding one.
+func (w *PrefixWriter) Close() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	line := append(w.buf, '\n')
+	w.buf = nil
+	return w.write(line)
+}
+
+func (w *PrefixWriter) write(lines []byte) error {
+	var prefixed bytes.Buffer
+	for line := range bytes.Lines(lines) {
+		prefixed.WriteString(w.prefix)
+		prefixed.Write(line)
+	}
+	w.output.mu.Lock()
+	defer w.output.mu.Unlock()
+	_, err := w.output.w.Write(prefixed.Bytes())
+	return err
+}
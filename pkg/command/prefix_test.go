@@ -0,0 +1,54 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPrefixWriterWritesWholePrefixedLines(t *testing.T) {
+	var out strings.Builder
+	writer := NewSharedOutput(&out).Prefixed("[abc] ")
+
+	for _, chunk := range []string{"one\ntw", "o\n", "", "three"} {
+		if _, err := writer.Write([]byte(chunk)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if want := "[abc] one\n[abc] two\n"; out.String() != want {
+		t.Fatalf("output before Close = %q, want %q", out.String(), want)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if want := "[abc] one\n[abc] two\n[abc] three\n"; out.String() != want {
+		t.Fatalf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestSharedOutputKeepsLinesFromWritersWhole(t *testing.T) {
+	var out strings.Builder
+	shared := NewSharedOutput(&out)
+	var wg sync.WaitGroup
+	for i := range 4 {
+		writer := shared.Prefixed(fmt.Sprintf("[%d] ", i))
+		wg.Go(func() {
+			for range 50 {
+				writer.Write([]byte("hello "))
+				writer.Write([]byte("world\n"))
+			}
+		})
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 200 {
+		t.Fatalf("line count = %d, want 200", len(lines))
+	}
+	for _, line := range lines {
+		if !strings.HasSuffix(line, "] hello world") {
+			t.Fatalf("line %q was interleaved", line)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/wim-web/tnnl/internal/interrupt"
 	"github.com/wim-web/tnnl/internal/session_manager"
 )
 
@@ -18,12 +19,31 @@ type RemoteSession struct {
 	cleanupTimeout time.Duration
 }
 
+// Run hands the session to plugin and terminates it once the plugin exits,
+// however it exits: a plugin stopped by a signal or a dropped connection
+// leaves the session open. When a signal ended ctx, the returned error names
+// the signal and reports whether the session was terminated.
 func (s RemoteSession) Run(ctx context.Context, plugin session_manager.Plugin) error {
+	var runErr error
 	if err := plugin.Run(ctx, s.Invocation); err != nil {
-		pluginErr := fmt.Errorf("session-manager-plugin handoff failed: %w", err)
-		return cleanupCreatedSession(ctx, s.ID, s.cleanupTimeout, s.terminate, pluginErr)
+		runErr = fmt.Errorf("session-manager-plugin handoff failed: %w", err)
 	}
-	return nil
+	caught, interrupted := interrupt.Cause(ctx)
+	if interrupted {
+		// The plugin failed because it was stopped; the signal is the reason.
+		runErr = caught
+	}
+	err := cleanupCreatedSession(ctx, s.ID, s.cleanupTimeout, s.terminate, runErr)
+	if interrupted && err == caught && s.ID != "" {
+		return fmt.Errorf("%w; terminated remote session %s", caught, s.ID)
+	}
+	return err
+}
+
+// Terminate ends a remote session that was never run, bounded by the
+// cleanup timeout and independent of ctx cancellation.
+func (s RemoteSession) Terminate(ctx context.Context) error {
+	return cleanupCreatedSession(ctx, s.ID, s.cleanupTimeout, s.terminate, nil)
 }
 
 func cleanupCreatedSession(
@@ -5,9 +5,11 @@ import (
 	"errors"
 	"reflect"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/wim-web/tnnl/internal/interrupt"
 	"github.com/wim-web/tnnl/internal/session_manager"
 )
 
@@ -50,7 +52,7 @@ func TestRemoteSessionTerminatesWhenPluginFails(t *testing.T) {
 	}
 }
 
-func TestRemoteSessionDoesNotTerminateAfterSuccessfulPlugin(t *testing.T) {
+func TestRemoteSessionTerminatesAfterSuccessfulPlugin(t *testing.T) {
 	terminateCalls := 0
 	session := validRemoteSession(func(context.Context, string) error {
 		terminateCalls++
@@ -62,8 +64,68 @@ func TestRemoteSessionDoesNotTerminateAfterSuccessfulPlugin(t *testing.T) {
 	})); err != nil {
 		t.Fatalf("Run() error = %v, want nil", err)
 	}
-	if terminateCalls != 0 {
-		t.Fatalf("terminate calls = %d, want 0", terminateCalls)
+	if terminateCalls != 1 {
+		t.Fatalf("terminate calls = %d, want 1", terminateCalls)
+	}
+}
+
+func TestRemoteSessionReportsCleanupAfterSignal(t *testing.T) {
+	tests := []struct {
+		name         string
+		terminateErr error
+		want         string
+	}{
+		{name: "terminated", want: "received SIGTERM; terminated remote session s-1"},
+		{name: "cleanup fails", terminateErr: errors.New("throttled"), want: "received SIGTERM\nterminate remote session s-1: throttled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancelCause(context.Background())
+			session := validRemoteSession(func(ctx context.Context, _ string) error {
+				if err := ctx.Err(); err != nil {
+					t.Fatalf("terminate context already canceled: %v", err)
+				}
+				return tt.terminateErr
+			})
+
+			err := session.Run(ctx, pluginFunc(func(context.Context, session_manager.Invocation) error {
+				cancel(&interrupt.Error{Signal: syscall.SIGTERM})
+				return errors.New("signal: terminated")
+			}))
+			var caught *interrupt.Error
+			if !errors.As(err, &caught) || caught.ExitCode() != 143 {
+				t.Fatalf("Run() error = %v, want the signal", err)
+			}
+			if err.Error() != tt.want {
+				t.Fatalf("Run() error = %q, want %q", err.Error(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoteSessionTerminateUsesCanceledCallerContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var terminatedID string
+	session := validRemoteSession(func(ctx context.Context, id string) error {
+		if err := ctx.Err(); err != nil {
+			t.Fatalf("terminate context already canceled: %v", err)
+		}
+		terminatedID = id
+		return nil
+	})
+
+	if err := session.Terminate(ctx); err != nil {
+		t.Fatalf("Terminate() error = %v, want nil", err)
+	}
+	if terminatedID != session.ID {
+		t.Fatalf("terminated session ID = %q, want %q", terminatedID, session.ID)
+	}
+
+	cleanupErr := errors.New("terminate failed")
+	err := validRemoteSession(func(context.Context, string) error { return cleanupErr }).Terminate(context.Background())
+	if !errors.Is(err, cleanupErr) || !strings.Contains(err.Error(), "terminate remote session s-1") {
+		t.Fatalf("Terminate() error = %v, want wrapped cleanup error", err)
 	}
 }
 
@@ -0,0 +1,50 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// ecsTargetPrefix starts the Session Manager target of every ECS session.
+const ecsTargetPrefix = "ecs:"
+
+// ListSessions returns the active Session Manager sessions on ECS targets,
+// only those started by owner when it is not empty.
+func ListSessions(ctx context.Context, ssmClient SessionAPI, owner string) ([]ssmtypes.Session, error) {
+	in := &ssm.DescribeSessionsInput{State: ssmtypes.SessionStateActive}
+	if owner != "" {
+		in.Filters = []ssmtypes.SessionFilter{{Key: ssmtypes.SessionFilterKeyOwner, Value: aws.String(owner)}}
+	}
+	var sessions []ssmtypes.Session
+	for {
+		output, err := ssmClient.DescribeSessions(ctx, in)
+		if err != nil {
+			return nil, fmt.Errorf("DescribeSessions: %w", err)
+		}
+		if output == nil {
+			return nil, invalidSessionResponse("DescribeSessions", "output is nil")
+		}
+		for _, session := range output.Sessions {
+			if strings.HasPrefix(aws.ToString(session.Target), ecsTargetPrefix) {
+				sessions = append(sessions, session)
+			}
+		}
+		if aws.ToString(output.NextToken) == "" {
+			return sessions, nil
+		}
+		in.NextToken = output.NextToken
+	}
+}
+
+// TerminateSession ends the Session Manager session sessionID.
+func TerminateSession(ctx context.Context, ssmClient SessionAPI, sessionID string) error {
+	if err := terminateSessionFunc(ssmClient)(ctx, sessionID); err != nil {
+		return fmt.Errorf("terminate remote session %s: %w", sessionID, err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+const sessionOwner = "arn:aws:sts::123456789012:assumed-role/dev/alice"
+
+func TestListSessionsPagesAndKeepsECSTargets(t *testing.T) {
+	client := &fakeSessionAPI{describeOutputs: []*ssm.DescribeSessionsOutput{
+		{
+			Sessions: []ssmtypes.Session{
+				{SessionId: aws.String("alice-1"), Target: aws.String("ecs:cluster_task_runtime")},
+				{SessionId: aws.String("alice-2"), Target: aws.String("i-0123456789abcdef0")},
+			},
+			NextToken: aws.String("next"),
+		},
+		{Sessions: []ssmtypes.Session{{SessionId: aws.String("alice-3"), Target: aws.String("ecs:other_task_runtime")}}},
+	}}
+
+	sessions, err := ListSessions(context.Background(), client, sessionOwner)
+	if err != nil {
+		t.Fatalf("ListSessions() error = %v", err)
+	}
+	var ids []string
+	for _, session := range sessions {
+		ids = append(ids, aws.ToString(session.SessionId))
+	}
+	if want := []string{"alice-1", "alice-3"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("session IDs = %v, want %v", ids, want)
+	}
+	filters := []ssmtypes.SessionFilter{{Key: ssmtypes.SessionFilterKeyOwner, Value: aws.String(sessionOwner)}}
+	want := []ssm.DescribeSessionsInput{
+		{State: ssmtypes.SessionStateActive, Filters: filters},
+		{State: ssmtypes.SessionStateActive, Filters: filters, NextToken: aws.String("next")},
+	}
+	if !reflect.DeepEqual(client.describeInputs, want) {
+		t.Fatalf("DescribeSessions inputs = %#v, want %#v", client.describeInputs, want)
+	}
+}
+
+func TestListSessionsWithoutOwnerListsEveryone(t *testing.T) {
+	client := &fakeSessionAPI{describeOutputs: []*ssm.DescribeSessionsOutput{{}}}
+
+	if _, err := ListSessions(context.Background(), client, ""); err != nil {
+		t.Fatalf("ListSessions() error = %v", err)
+	}
+	if filters := client.describeInputs[0].Filters; filters != nil {
+		t.Fatalf("filters = %#v, want none", filters)
+	}
+}
+
+func TestListSessionsReportsErrors(t *testing.T) {
+	sentinel := errors.New("describe sentinel")
+	_, err := ListSessions(context.Background(), &fakeSessionAPI{describeErr: sentinel}, sessionOwner)
+	if !errors.Is(err, sentinel) {
+		t.Fatalf("ListSessions() error = %v, want %v", err, sentinel)
+	}
+
+	_, err = ListSessions(context.Background(), &fakeSessionAPI{describeOutputs: []*ssm.DescribeSessionsOutput{nil}}, sessionOwner)
+	if !errors.Is(err, errInvalidSessionResponse) {
+		t.Fatalf("ListSessions() error = %v, want invalid response", err)
+	}
+}
+
+func TestTerminateSessionNamesTheSession(t *testing.T) {
+	client := &fakeSessionAPI{}
+	if err := TerminateSession(context.Background(), client, "alice-1"); err != nil {
+		t.Fatalf("TerminateSession() error = %v", err)
+	}
+	if got := aws.ToString(client.terminateInput.SessionId); got != "alice-1" {
+		t.Fatalf("terminated %q, want alice-1", got)
+	}
+
+	client.terminateErr = errors.New("access denied")
+	err := TerminateSession(context.Background(), client, "bob-1")
+	if err == nil || !strings.Contains(err.Error(), "terminate remote session bob-1: access denied") {
+		t.Fatalf("TerminateSession() error = %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package port
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Listener keeps a local port bound for as long as tnnl runs and hands
+// accepted connections to whichever session currently serves it. Sessions
+// can come and go without releasing the port.
+type Listener struct {
+	listener net.Listener
+	conns    chan net.Conn
+	done     chan struct{}
+	once     sync.Once
+}
+
+// Listen binds the loopback port. An empty port lets the kernel choose one.
+func Listen(port string) (*Listener, error) {
+	return listen(net.Listen, port)
+}
+
+func listen(listenFn listenFunc, port string) (*Listener, error) {
+	port = strings.TrimSpace(port)
+	if port == "" {
+		port = "0"
+	}
+	l, err := listenFn("tcp", net.JoinHostPort("127.0.0.1", port))
+	if err != nil {
+		return nil, fmt.Errorf("listen on local port %s: %w", port, err)
+	}
+
+	owned := &Listener{
+		listener: l,
+		conns:    make(chan net.Conn),
+		done:     make(chan struct{}),
+	}
+	go owned.acceptLoop()
+	return owned, nil
+}
+
+func (l *Listener) acceptLoop() {
+	defer l.shutdown()
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			return
+		}
+		select {
+		case l.conns <- conn:
+		case <-l.done:
+			_ = conn.Close()
+			return
+		}
+	}
+}
+
+// Port returns the bound local port.
+func (l *Listener) Port() int {
+	if tcp, ok := l.listener.Addr().(*net.TCPAddr); ok {
+		return tcp.Port
+	}
+	return 0
+}
+
+// Session returns a view that accepts connections for one session. Closing
+// the view stops that session from accepting without releasing the port;
+// connections that arrive in between wait for the next view.
+func (l *Listener) Session() net.Listener {
+	return &sessionListener{parent: l, done: make(chan struct{})}
+}
+
+// Close releases the port and ends every session view.
+func (l *Listener) Close() error {
+	err := l.listener.Close()
+	l.shutdown()
+	return err
+}
+
+func (l *Listener) shutdown() {
+	l.once.Do(func() { close(l.done) })
+}
+
+type sessionListener struct {
+	parent *Listener
+	done   chan struct{}
+	once   sync.Once
+}
+
+func (s *sessionListener) Accept() (net.Conn, error) {
+	select {
+	case <-s.done:
+		return nil, net.ErrClosed
+	case <-s.parent.done:
+		return nil, net.ErrClosed
+	default:
+	}
+	select {
+	case conn := <-s.parent.conns:
+		return conn, nil
+	case <-s.done:
+		return nil, net.ErrClosed
+	case <-s.parent.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (s *sessionListener) Close() error {
+	s.once.Do(func() { close(s.done) })
+	return nil
+}
+
+func (s *sessionListener) Addr() net.Addr {
+	return s.parent.listener.Addr()
+}
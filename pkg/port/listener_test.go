@@ -0,0 +1,108 @@
+package port
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const listenerTestLimit = 5 * time.Second
+
+func dialListener(t *testing.T, l *Listener) net.Conn {
+	t.Helper()
+	conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(l.Port())))
+	if err != nil {
+		t.Fatalf("dial owned listener: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func acceptWithin(t *testing.T, session net.Listener) (net.Conn, error) {
+	t.Helper()
+	type accepted struct {
+		conn net.Conn
+		err  error
+	}
+	result := make(chan accepted, 1)
+	go func() {
+		conn, err := session.Accept()
+		result <- accepted{conn, err}
+	}()
+	select {
+	case got := <-result:
+		return got.conn, got.err
+	case <-time.After(listenerTestLimit):
+		t.Fatal("Accept() did not return")
+		return nil, nil
+	}
+}
+
+func TestListenerKeepsPortAcrossSessions(t *testing.T) {
+	l, err := Listen("")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer l.Close()
+
+	first := l.Session()
+	if err := first.Close(); err != nil {
+		t.Fatalf("session Close() error = %v", err)
+	}
+	if _, err := acceptWithin(t, first); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("closed session Accept() error = %v, want net.ErrClosed", err)
+	}
+
+	// A client that connects between sessions is served by the next one.
+	client := dialListener(t, l)
+	if _, err := client.Write([]byte("x")); err != nil {
+		t.Fatalf("write client: %v", err)
+	}
+	conn, err := acceptWithin(t, l.Session())
+	if err != nil {
+		t.Fatalf("next session Accept() error = %v", err)
+	}
+	defer conn.Close()
+	buf := make([]byte, 1)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "x" {
+		t.Fatalf("accepted connection read = %q, %v; want x", buf, err)
+	}
+}
+
+func TestListenerCloseReleasesPortAndEndsSessions(t *testing.T) {
+	l, err := Listen("")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	session := l.Session()
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := acceptWithin(t, session); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("session Accept() after Close error = %v, want net.ErrClosed", err)
+	}
+
+	rebound, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(l.Port())))
+	if err != nil {
+		t.Fatalf("port was not released: %v", err)
+	}
+	_ = rebound.Close()
+}
+
+func TestListenPreservesListenError(t *testing.T) {
+	wantErr := errors.New("listen failed")
+	var gotAddress string
+	_, err := listen(func(_, address string) (net.Listener, error) {
+		gotAddress = address
+		return nil, wantErr
+	}, "18080")
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("listen() error = %v, want error wrapping %v", err, wantErr)
+	}
+	if gotAddress != "127.0.0.1:18080" {
+		t.Fatalf("listen address = %q, want 127.0.0.1:18080", gotAddress)
+	}
+}